@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/config"
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/ledger"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/txmanager"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/wallet"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+var (
+	ErrUnknownCommand = errors.New("unknown command")
+	ErrUsage          = errors.New("invalid arguments")
+	ErrAPIRequired    = errors.New("the command requires running router, set -api flag")
+)
+
+// command is the operator subcommand, the router is started if no subcommand is given
+type command struct {
+	name  string
+	usage string
+	desc  string
+	run   func(ctx context.Context, args []string, out io.Writer) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"contracts", "[-api URL] [-json]", "list contracts and their state", cmdContracts},
+		{"miners", "-api URL [-json]", "list miners connected to the running router", cmdMiners},
+		{"close", "[-api URL] [-reason 0-3] <contract>", "close the contract early, reasons: 0 unspecified, 1 underdelivery, 2 destination unavailable, 3 share timeout", cmdClose},
+		{"claim", "validator <contract> | futures <delivery date>", "claim validator reward of the contract or futures delivery payment, date is RFC3339 or unix timestamp", cmdClaim},
+		{"encrypt", "-pubkey HEX <url>", "encrypt destination url with the public key", cmdEncrypt},
+		{"decrypt", "<ciphertext>", "decrypt destination url with the wallet key", cmdDecrypt},
+		{"ledger", "[-api URL] [-from YYYY-MM-DD] [-to YYYY-MM-DD] [-summary day|week|month] [-csv|-json]", "print recorded contract starts, closeouts, reward claims and validator fees, or their totals per period", cmdLedger},
+		{"validator", "[-api URL] [-dry-run] status | complaints [-from-block N] | register <stake LMR> <host:port> | host <host:port> | stake <amount LMR> | deregister", "manage validator registration, deregister returns the whole stake", cmdValidator},
+		{"attestation", "[-api URL] list | get <position> | verify <file>", "list and download the signed delivery reports of the validator node, verify the report against the validator registry", cmdAttestation},
+		{"evidence", "[-api URL] [-o file] get <contract> | verify <file>", "download the signed dispute evidence archive of the contract, verify the archive signature and checksums", cmdEvidence},
+		{"config", "[-api URL] | print [-json] [-config file] [router flags]", "print derived config, or the effective router config with the source of each value", cmdConfig},
+		{"pubkey", "", "print public key of the wallet", cmdPubkey},
+		{"help", "", "print this help", cmdHelp},
+	}
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// runCommand runs the subcommand, the offline commands read router config from environment, .env file and CONFIG_FILE
+func runCommand(name string, args []string) error {
+	cmd, ok := findCommand(name)
+	if !ok {
+		return lib.WrapError(ErrUnknownCommand, fmt.Errorf("%s, run \"proxy-router help\" to see the list of commands", name))
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	return cmd.run(ctx, args, os.Stdout)
+}
+
+func cmdHelp(ctx context.Context, args []string, out io.Writer) error {
+	fmt.Fprintf(out, "Usage:\n  proxy-router [flags]               start the router\n  proxy-router <command> [arguments]\n\nCommands:\n")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %s %s\t%s\n", cmd.name, cmd.usage, cmd.desc)
+	}
+	_ = w.Flush()
+	fmt.Fprintf(out, "\nWithout -api the commands use the blockchain directly, router config is read from environment, .env file and the CONFIG_FILE config file\n")
+	return nil
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	cmd, _ := findCommand(name)
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: proxy-router %s %s\n  %s\n", cmd.name, cmd.usage, cmd.desc)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs parses the command flags and checks the number of positional arguments
+func parseArgs(fs *flag.FlagSet, args []string, positional int) error {
+	err := fs.Parse(args)
+	if err != nil {
+		return lib.WrapError(ErrUsage, err)
+	}
+	if fs.NArg() != positional {
+		fs.Usage()
+		return lib.WrapError(ErrUsage, fmt.Errorf("expected %d argument(s), got %d", positional, fs.NArg()))
+	}
+	return nil
+}
+
+func printJSON(out io.Writer, v any) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// walletConfig is the wallet part of the router config
+type walletConfig struct {
+	WalletPrivateKey     string `env:"WALLET_PRIVATE_KEY"`
+	KeystorePath         string `env:"WALLET_KEYSTORE_PATH"`
+	KeystorePasswordFile string `env:"WALLET_KEYSTORE_PASSWORD_FILE"`
+	RemoteSignerURL      string `env:"WALLET_REMOTE_SIGNER_URL" validate:"omitempty,url"`
+	RemoteSignerAddress  string `env:"WALLET_REMOTE_SIGNER_ADDRESS" validate:"omitempty,eth_addr"`
+	DecryptionKey        string `env:"WALLET_DECRYPTION_KEY"`
+}
+
+func (cfg *walletConfig) SetDefaults() {
+}
+
+func (cfg *walletConfig) newSigner(ctx context.Context) (interfaces.Signer, error) {
+	return wallet.NewSigner(ctx, cfg.WalletPrivateKey, cfg.KeystorePath, cfg.KeystorePasswordFile, cfg.RemoteSignerURL, cfg.RemoteSignerAddress, cfg.DecryptionKey)
+}
+
+// chainConfig is the part of the router config required to work with the contracts directly
+type chainConfig struct {
+	EthNodeAddress      string        `env:"ETH_NODE_ADDRESS" validate:"required,url"`
+	EthLegacyTx         bool          `env:"ETH_NODE_LEGACY_TX"`
+	MulticallAddress    string        `env:"MULTICALL_ADDRESS" validate:"omitempty,eth_addr"`
+	MulticallBatch      int           `env:"MULTICALL_BATCH_SIZE"`
+	MulticallWorkers    int           `env:"MULTICALL_CONCURRENCY"`
+	CloneFactoryAddress string        `env:"CLONE_FACTORY_ADDRESS" validate:"omitempty,eth_addr"`
+	FuturesAddress      string        `env:"FUTURES_ADDRESS" validate:"omitempty,eth_addr"`
+	RegistryAddress     string        `env:"VALIDATOR_REGISTRY_ADDRESS" validate:"omitempty,eth_addr"`
+	TxBumpInterval      time.Duration `env:"ETH_TX_BUMP_INTERVAL"`
+	TxBumpPercent       int           `env:"ETH_TX_BUMP_PERCENT"`
+	TxMaxBumps          int           `env:"ETH_TX_MAX_BUMPS"`
+	TxMaxRetries        int           `env:"ETH_TX_MAX_RETRIES"`
+	LedgerPath          string        `env:"LEDGER_PATH"`
+	Wallet              walletConfig
+}
+
+func (cfg *chainConfig) SetDefaults() {
+	var defaults config.Config
+	defaults.SetDefaults()
+
+	if cfg.MulticallAddress == "" {
+		cfg.MulticallAddress = defaults.Blockchain.MulticallAddress
+	}
+	if cfg.MulticallBatch == 0 {
+		cfg.MulticallBatch = defaults.Blockchain.MulticallBatch
+	}
+	if cfg.MulticallWorkers == 0 {
+		cfg.MulticallWorkers = defaults.Blockchain.MulticallWorkers
+	}
+	if cfg.TxBumpInterval == 0 {
+		cfg.TxBumpInterval = defaults.Blockchain.TxBumpInterval
+	}
+	if cfg.TxBumpPercent == 0 {
+		cfg.TxBumpPercent = defaults.Blockchain.TxBumpPercent
+	}
+	if cfg.TxMaxBumps == 0 {
+		cfg.TxMaxBumps = defaults.Blockchain.TxMaxBumps
+	}
+	if cfg.TxMaxRetries == 0 {
+		cfg.TxMaxRetries = defaults.Blockchain.TxMaxRetries
+	}
+	if cfg.LedgerPath == "" {
+		cfg.LedgerPath = defaults.Marketplace.LedgerPath
+	}
+}
+
+func loadConfig(cfg config.ConfigInterface) error {
+	// command flags are parsed separately, the config is read from environment only
+	return config.LoadConfig(cfg, &[]string{os.Args[0]})
+}
+
+// chain is the set of blockchain dependencies of the offline commands
+type chain struct {
+	cfg       *chainConfig
+	client    *ethclient.Client
+	txManager *txmanager.TxManager
+	store     *contracts.HashrateEthereum
+	futures   *contracts.FuturesEthereum
+	registry  *contracts.ValidatorRegistryEthereum
+	log       interfaces.ILogger
+}
+
+// newChain connects to the ethereum node, the transaction manager runs until the context is cancelled
+func newChain(ctx context.Context) (*chain, error) {
+	var cfg chainConfig
+	err := loadConfig(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	log, err := lib.NewLogger("warn", true, false, false, "")
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := ethclient.DialContext(ctx, cfg.EthNodeAddress)
+	if err != nil {
+		return nil, lib.WrapError(ErrConnectToEthNode, err)
+	}
+
+	txManager := txmanager.NewTxManager(client, txmanager.NewMemoryTxStore(), cfg.EthLegacyTx, txmanager.ReceiptPollInterval, cfg.TxBumpInterval, cfg.TxBumpPercent, cfg.TxMaxBumps, cfg.TxMaxRetries, log.Named("TXM"))
+	go func() {
+		_ = txManager.Run(ctx)
+	}()
+
+	// the transactions sent by the commands are recorded to the same ledger as the router ones
+	earnings := ledger.NewLedger(cfg.LedgerPath)
+	err = earnings.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	multicallAddr := common.HexToAddress(cfg.MulticallAddress)
+	return &chain{
+		cfg:       &cfg,
+		client:    client,
+		txManager: txManager,
+		store:     contracts.NewHashrateEthereum(common.HexToAddress(cfg.CloneFactoryAddress), multicallAddr, cfg.MulticallBatch, cfg.MulticallWorkers, client, txManager, nil, earnings, log),
+		futures:   contracts.NewFuturesEthereum(common.HexToAddress(cfg.FuturesAddress), multicallAddr, client, txManager, nil, earnings, log),
+		registry:  contracts.NewValidatorRegistryEthereum(common.HexToAddress(cfg.RegistryAddress), client, txManager, log),
+		log:       log,
+	}, nil
+}
+
+func (c *chain) requireCloneFactory() error {
+	if c.cfg.CloneFactoryAddress == "" {
+		return fmt.Errorf("CLONE_FACTORY_ADDRESS is not set")
+	}
+	return nil
+}
+
+func (c *chain) requireFutures() error {
+	if c.cfg.FuturesAddress == "" {
+		return fmt.Errorf("FUTURES_ADDRESS is not set")
+	}
+	return nil
+}
+
+func (c *chain) requireRegistry() error {
+	if c.cfg.RegistryAddress == "" {
+		return contracts.ErrRegistryDisabled
+	}
+	return nil
+}
+
+func (c *chain) Close() {
+	c.client.Close()
+	_ = c.log.Close()
+}
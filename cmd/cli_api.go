@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+)
+
+const apiTimeout = 3 * time.Minute // closing the contract waits for the transaction to be mined
+
+var (
+	ErrAPIRequest = errors.New("router api request failed")
+)
+
+// apiConfig is the part of the router config used to call its api
+type apiConfig struct {
+	APIToken string `env:"WEB_API_TOKEN"`
+}
+
+func (cfg *apiConfig) SetDefaults() {
+}
+
+// apiClient calls the http api of the running router
+type apiClient struct {
+	baseURL *url.URL
+	token   string
+	client  *http.Client
+}
+
+func newAPIClient(baseURL string) (*apiClient, error) {
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, lib.WrapError(ErrUsage, err)
+	}
+	// the token is sent if configured, the write endpoints of the remote router require it
+	var cfg apiConfig
+	err = loadConfig(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &apiClient{
+		baseURL: u,
+		token:   cfg.APIToken,
+		client:  &http.Client{Timeout: apiTimeout},
+	}, nil
+}
+
+func (c *apiClient) get(ctx context.Context, path string, query url.Values, res any) error {
+	return c.do(ctx, http.MethodGet, path, query, res)
+}
+
+func (c *apiClient) post(ctx context.Context, path string, query url.Values, res any) error {
+	return c.do(ctx, http.MethodPost, path, query, res)
+}
+
+// download returns the raw response body, e.g. the archive
+func (c *apiClient) download(ctx context.Context, path string, query url.Values) ([]byte, error) {
+	return c.request(ctx, http.MethodGet, path, query)
+}
+
+func (c *apiClient) do(ctx context.Context, method string, path string, query url.Values, res any) error {
+	body, err := c.request(ctx, method, path, query)
+	if err != nil {
+		return err
+	}
+
+	if res == nil {
+		return nil
+	}
+	err = json.Unmarshal(body, res)
+	if err != nil {
+		return lib.WrapError(ErrAPIRequest, err)
+	}
+	return nil
+}
+
+func (c *apiClient) request(ctx context.Context, method string, path string, query url.Values) ([]byte, error) {
+	u := c.baseURL.JoinPath(path)
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, lib.WrapError(ErrAPIRequest, err)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, lib.WrapError(ErrAPIRequest, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, lib.WrapError(ErrAPIRequest, err)
+	}
+
+	if resp.StatusCode >= 300 {
+		var errRes struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &errRes) == nil && errRes.Error != "" {
+			return nil, lib.WrapError(ErrAPIRequest, fmt.Errorf("%s: %s", resp.Status, errRes.Error))
+		}
+		return nil, lib.WrapError(ErrAPIRequest, fmt.Errorf("%s", resp.Status))
+	}
+	return body, nil
+}
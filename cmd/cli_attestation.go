@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/attestation"
+)
+
+var (
+	ErrAttestationNotVerified = errors.New("attestation is not verified")
+)
+
+// attestationArgs are the number of positional arguments of the attestation subcommands
+var attestationArgs = map[string]int{
+	"list":   0,
+	"get":    1,
+	"verify": 1,
+}
+
+func cmdAttestation(ctx context.Context, args []string, out io.Writer) error {
+	fs := newFlagSet("attestation")
+	api := fs.String("api", "", apiFlagDesc)
+
+	err := fs.Parse(args)
+	if err != nil {
+		return lib.WrapError(ErrUsage, err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return lib.WrapError(ErrUsage, fmt.Errorf("attestation subcommand is required"))
+	}
+	sub := fs.Arg(0)
+	n, ok := attestationArgs[sub]
+	if !ok {
+		fs.Usage()
+		return lib.WrapError(ErrUsage, fmt.Errorf("unknown attestation subcommand %s", sub))
+	}
+	// flags are allowed after the subcommand as well
+	err = parseArgs(fs, fs.Args()[1:], n)
+	if err != nil {
+		return err
+	}
+
+	if sub == "verify" {
+		return verifyAttestation(ctx, fs.Arg(0), out)
+	}
+
+	// the reports are kept by the validator node
+	if *api == "" {
+		return ErrAPIRequired
+	}
+	client, err := newAPIClient(*api)
+	if err != nil {
+		return err
+	}
+
+	switch sub {
+	case "list":
+		var res []attestation.Report
+		err = client.get(ctx, "/attestations", nil, &res)
+		if err != nil {
+			return err
+		}
+		return printJSON(out, res)
+	default:
+		res := new(attestation.SignedReport)
+		err = client.get(ctx, "/attestations/"+fs.Arg(0), nil, res)
+		if err != nil {
+			return err
+		}
+		return printJSON(out, res)
+	}
+}
+
+// verifyAttestation checks the signature of the report file and the key of the validator in the registry
+func verifyAttestation(ctx context.Context, path string, out io.Writer) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var signed attestation.SignedReport
+	err = json.Unmarshal(data, &signed)
+	if err != nil {
+		return lib.WrapError(ErrUsage, err)
+	}
+
+	ch, err := newChain(ctx)
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+	if err := ch.requireRegistry(); err != nil {
+		return err
+	}
+
+	res := attestation.Check(ctx, &signed, ch.registry)
+	err = printJSON(out, res)
+	if err != nil {
+		return err
+	}
+	if !res.Valid || !res.Registered {
+		return lib.WrapError(ErrAttestationNotVerified, errors.New(res.Error))
+	}
+	return nil
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/config"
+	"github.com/Lumerin-protocol/proxy-router/internal/handlers/httphandlers"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/wallet"
+)
+
+const apiFlagDesc = "url of the running router api, e.g. http://localhost:8080"
+
+// contractRow is the contract summary printed by the contracts command
+type contractRow struct {
+	ID       string
+	Role     string
+	State    string
+	Seller   string
+	Buyer    string
+	PriceLMR float64
+	Duration string
+	EndsAt   string
+}
+
+func cmdContracts(ctx context.Context, args []string, out io.Writer) error {
+	fs := newFlagSet("contracts")
+	api := fs.String("api", "", apiFlagDesc)
+	asJSON := fs.Bool("json", false, "print json")
+	err := parseArgs(fs, args, 0)
+	if err != nil {
+		return err
+	}
+
+	var rows []contractRow
+	if *api != "" {
+		client, err := newAPIClient(*api)
+		if err != nil {
+			return err
+		}
+		var res httphandlers.ContractsResponse
+		err = client.get(ctx, "/contracts-v2", nil, &res)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return printJSON(out, res)
+		}
+		for _, c := range res.Contracts {
+			rows = append(rows, contractRow{
+				ID:       c.ID,
+				Role:     c.Role,
+				State:    fmt.Sprintf("%s/%s", c.BlockchainStatus, c.ApplicationStatus),
+				Seller:   c.SellerAddr,
+				Buyer:    c.BuyerAddr,
+				PriceLMR: c.PriceLMR,
+				Duration: c.Duration,
+				EndsAt:   c.EndTimestamp,
+			})
+		}
+	} else {
+		ch, err := newChain(ctx)
+		if err != nil {
+			return err
+		}
+		defer ch.Close()
+		if err := ch.requireCloneFactory(); err != nil {
+			return err
+		}
+
+		ids, err := ch.store.GetContractsIDs(ctx)
+		if err != nil {
+			return err
+		}
+		terms, err := ch.store.GetContracts(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for _, t := range terms {
+			row := contractRow{
+				ID:       t.ID(),
+				Role:     "-",
+				State:    t.BlockchainState().String(),
+				Seller:   t.Seller(),
+				Buyer:    t.Buyer(),
+				PriceLMR: httphandlers.LMRWithDecimalsToLMR(t.Price()),
+				Duration: t.Duration().String(),
+			}
+			if t.IsDeleted() {
+				row.State += " (deleted)"
+			}
+			if !t.StartTime().IsZero() {
+				row.EndsAt = t.EndTime().Format(time.RFC3339)
+			}
+			rows = append(rows, row)
+		}
+		if *asJSON {
+			return printJSON(out, rows)
+		}
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tROLE\tSTATE\tSELLER\tBUYER\tPRICE LMR\tDURATION\tENDS AT")
+	for _, r := range rows {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%.4f\t%s\t%s\n", r.ID, r.Role, r.State, r.Seller, r.Buyer, r.PriceLMR, r.Duration, r.EndsAt)
+	}
+	return w.Flush()
+}
+
+func cmdMiners(ctx context.Context, args []string, out io.Writer) error {
+	fs := newFlagSet("miners")
+	api := fs.String("api", "", apiFlagDesc)
+	asJSON := fs.Bool("json", false, "print json")
+	err := parseArgs(fs, args, 0)
+	if err != nil {
+		return err
+	}
+	if *api == "" {
+		return ErrAPIRequired
+	}
+
+	client, err := newAPIClient(*api)
+	if err != nil {
+		return err
+	}
+	var res httphandlers.MinersResponse
+	err = client.get(ctx, "/miners", nil, &res)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(out, res)
+	}
+
+	fmt.Fprintf(out, "miners: %d total, %d vetting, %d free, %d partially busy, %d busy\n", res.TotalMiners, res.VettingMiners, res.FreeMiners, res.PartialBusyMiners, res.BusyMiners)
+	fmt.Fprintf(out, "hashrate: %d GHS total, %d GHS used, %d GHS available\n\n", res.TotalHashrateGHS, res.UsedHashrateGHS, res.AvailableHashrateGHS)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tWORKER\tSTATUS\tHASHRATE GHS\tDESTINATION\tUPTIME")
+	for _, m := range res.Miners {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", m.ID, m.WorkerName, m.Status, formatHashrates(m.HashrateAvgGHS), m.CurrentDestination, m.Uptime)
+	}
+	return w.Flush()
+}
+
+func formatHashrates(hr map[string]int) string {
+	names := make([]string, 0, len(hr))
+	for name := range hr {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s:%d", name, hr[name])
+	}
+	return strings.Join(parts, " ")
+}
+
+func cmdClose(ctx context.Context, args []string, out io.Writer) error {
+	fs := newFlagSet("close")
+	api := fs.String("api", "", apiFlagDesc)
+	reason := fs.Uint("reason", uint(contracts.CloseReasonUnspecified), "close reason")
+	err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if *reason > uint(contracts.CloseReasonShareTimeout) {
+		return lib.WrapError(ErrUsage, fmt.Errorf("invalid close reason %d", *reason))
+	}
+	contractID := fs.Arg(0)
+
+	if *api != "" {
+		client, err := newAPIClient(*api)
+		if err != nil {
+			return err
+		}
+		query := url.Values{"reason": {strconv.FormatUint(uint64(*reason), 10)}}
+		err = client.post(ctx, fmt.Sprintf("/contracts/%s/close", contractID), query, nil)
+		if err != nil {
+			return err
+		}
+	} else {
+		ch, err := newChain(ctx)
+		if err != nil {
+			return err
+		}
+		defer ch.Close()
+		signer, err := ch.cfg.Wallet.newSigner(ctx)
+		if err != nil {
+			return err
+		}
+		err = ch.store.EarlyClose(ctx, contractID, contracts.CloseReason(*reason), signer)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(out, "contract %s closed\n", contractID)
+	return nil
+}
+
+func cmdClaim(ctx context.Context, args []string, out io.Writer) error {
+	fs := newFlagSet("claim")
+	err := parseArgs(fs, args, 2)
+	if err != nil {
+		return err
+	}
+	kind, arg := fs.Arg(0), fs.Arg(1)
+
+	var deliveryDate time.Time
+	switch kind {
+	case "validator":
+	case "futures":
+		deliveryDate, err = parseDate(arg)
+		if err != nil {
+			return lib.WrapError(ErrUsage, err)
+		}
+	default:
+		fs.Usage()
+		return lib.WrapError(ErrUsage, fmt.Errorf("unknown reward kind %s", kind))
+	}
+
+	ch, err := newChain(ctx)
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+	signer, err := ch.cfg.Wallet.newSigner(ctx)
+	if err != nil {
+		return err
+	}
+
+	if kind == "validator" {
+		err = ch.store.ClaimValidatorReward(ctx, arg, signer)
+	} else {
+		if err := ch.requireFutures(); err != nil {
+			return err
+		}
+		err = ch.futures.ClaimReward(ctx, deliveryDate, signer)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "%s reward claimed\n", kind)
+	return nil
+}
+
+func parseDate(s string) (time.Time, error) {
+	if unix, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func cmdEncrypt(ctx context.Context, args []string, out io.Writer) error {
+	fs := newFlagSet("encrypt")
+	pubKey := fs.String("pubkey", "", "hex encoded public key of the recipient, compressed or uncompressed")
+	err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if *pubKey == "" {
+		fs.Usage()
+		return lib.WrapError(ErrUsage, fmt.Errorf("public key is required"))
+	}
+
+	pubKeyHex, err := lib.NormalizePubKey(*pubKey)
+	if err != nil {
+		return lib.WrapError(ErrUsage, err)
+	}
+
+	encrypted, err := lib.EncryptString(fs.Arg(0), pubKeyHex)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, encrypted)
+	return nil
+}
+
+func cmdDecrypt(ctx context.Context, args []string, out io.Writer) error {
+	fs := newFlagSet("decrypt")
+	err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	var cfg walletConfig
+	err = loadConfig(&cfg)
+	if err != nil {
+		return err
+	}
+	signer, err := cfg.newSigner(ctx)
+	if err != nil {
+		return err
+	}
+
+	decrypted, err := lib.DecryptStringWith(fs.Arg(0), signer.Decrypt)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, decrypted)
+	return nil
+}
+
+func cmdConfig(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) > 0 && args[0] == "print" {
+		return printConfig(args[1:], out)
+	}
+
+	fs := newFlagSet("config")
+	api := fs.String("api", "", apiFlagDesc)
+	err := parseArgs(fs, args, 0)
+	if err != nil {
+		return err
+	}
+
+	if *api != "" {
+		client, err := newAPIClient(*api)
+		if err != nil {
+			return err
+		}
+		var res httphandlers.ConfigResponse
+		err = client.get(ctx, "/config", nil, &res)
+		if err != nil {
+			return err
+		}
+		return printJSON(out, res.DerivedConfig)
+	}
+
+	ch, err := newChain(ctx)
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	derived := new(config.DerivedConfig)
+	signer, err := ch.cfg.Wallet.newSigner(ctx)
+	switch {
+	case err == nil:
+		derived.WalletAddress = signer.Address().String()
+	case !errors.Is(err, wallet.ErrNoWallet):
+		return err
+	}
+
+	if ch.cfg.FuturesAddress != "" {
+		specs, err := ch.futures.GetContractSpecs(ctx)
+		if err != nil {
+			return err
+		}
+		derived.ValidatorAddress = specs.ValidatorAddress.String()
+		derived.ValidatorURL = specs.ValidatorURL.String()
+		derived.ContractDurationDays = int(specs.DeliveryDuration.Hours() / 24)
+		derived.ContractHashrateGHS = float64(specs.SpeedHps / 1e9)
+	}
+
+	return printJSON(out, derived)
+}
+
+// printConfig prints the effective router config loaded from the config file, environment and the given router
+// flags with the source of each value, the secrets are hidden
+func printConfig(args []string, out io.Writer) error {
+	asJSON := false
+	routerArgs := []string{os.Args[0]}
+	for _, arg := range args {
+		if arg == "-json" || arg == "--json" {
+			asJSON = true
+			continue
+		}
+		routerArgs = append(routerArgs, arg)
+	}
+
+	var cfg config.Config
+	sources, err := config.LoadConfigWithSources(&cfg, &routerArgs)
+	if err != nil {
+		return err
+	}
+	entries, err := config.Effective(&cfg, sources)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(out, entries)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVALUE\tSOURCE")
+	for _, e := range entries {
+		value := e.Value
+		if e.Hidden {
+			value = "(hidden)"
+		}
+		source := string(e.Source)
+		if e.Source == config.SourceUnset {
+			source = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", e.Name, value, source)
+	}
+	return w.Flush()
+}
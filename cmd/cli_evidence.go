@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/evidence"
+)
+
+// evidenceArgs are the number of positional arguments of the evidence subcommands
+var evidenceArgs = map[string]int{
+	"get":    1,
+	"verify": 1,
+}
+
+func cmdEvidence(ctx context.Context, args []string, out io.Writer) error {
+	fs := newFlagSet("evidence")
+	api := fs.String("api", "", apiFlagDesc)
+	output := fs.String("o", "", "path of the downloaded archive, defaults to evidence-<contract>.tar.gz")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return lib.WrapError(ErrUsage, err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return lib.WrapError(ErrUsage, fmt.Errorf("evidence subcommand is required"))
+	}
+	sub := fs.Arg(0)
+	n, ok := evidenceArgs[sub]
+	if !ok {
+		fs.Usage()
+		return lib.WrapError(ErrUsage, fmt.Errorf("unknown evidence subcommand %s", sub))
+	}
+	// flags are allowed after the subcommand as well
+	err = parseArgs(fs, fs.Args()[1:], n)
+	if err != nil {
+		return err
+	}
+
+	if sub == "verify" {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		manifest, _, err := evidence.Verify(f)
+		if err != nil {
+			return err
+		}
+		return printJSON(out, manifest)
+	}
+
+	if *api == "" {
+		return ErrAPIRequired
+	}
+	client, err := newAPIClient(*api)
+	if err != nil {
+		return err
+	}
+
+	contractID := fs.Arg(0)
+	archive, err := client.download(ctx, "/contracts/"+contractID+"/evidence", nil)
+	if err != nil {
+		return err
+	}
+	// the archive is saved only if it is signed by the node
+	manifest, _, err := evidence.Verify(bytes.NewReader(archive))
+	if err != nil {
+		return err
+	}
+
+	path := *output
+	if path == "" {
+		path = fmt.Sprintf("evidence-%s.tar.gz", contractID)
+	}
+	err = os.WriteFile(path, archive, 0o644)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "saved to %s\n", path)
+	return printJSON(out, manifest)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/config"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/ledger"
+)
+
+// ledgerConfig is the part of the router config required to read the ledger file
+type ledgerConfig struct {
+	LedgerPath string `env:"LEDGER_PATH"`
+}
+
+func (cfg *ledgerConfig) SetDefaults() {
+	if cfg.LedgerPath == "" {
+		var defaults config.Config
+		defaults.SetDefaults()
+		cfg.LedgerPath = defaults.Marketplace.LedgerPath
+	}
+}
+
+func cmdLedger(ctx context.Context, args []string, out io.Writer) error {
+	fs := newFlagSet("ledger")
+	api := fs.String("api", "", apiFlagDesc)
+	fromStr := fs.String("from", "", "first date of the range, YYYY-MM-DD")
+	toStr := fs.String("to", "", "date after the end of the range, YYYY-MM-DD")
+	period := fs.String("summary", "", "print totals per day, week or month instead of the entries")
+	asCSV := fs.Bool("csv", false, "print csv")
+	asJSON := fs.Bool("json", false, "print json")
+	err := parseArgs(fs, args, 0)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	var from, to time.Time
+	if *fromStr != "" {
+		from, err = time.Parse(time.DateOnly, *fromStr)
+		if err != nil {
+			return lib.WrapError(ErrUsage, err)
+		}
+		query.Set("from", *fromStr)
+	}
+	if *toStr != "" {
+		to, err = time.Parse(time.DateOnly, *toStr)
+		if err != nil {
+			return lib.WrapError(ErrUsage, err)
+		}
+		query.Set("to", *toStr)
+	}
+	if *period != "" {
+		_, err = ledger.ParsePeriod(*period)
+		if err != nil {
+			return lib.WrapError(ErrUsage, err)
+		}
+		query.Set("period", *period)
+	}
+
+	var entries []ledger.Entry
+	var summaries []*ledger.Summary
+	if *api != "" {
+		client, err := newAPIClient(*api)
+		if err != nil {
+			return err
+		}
+		if *period != "" {
+			err = client.get(ctx, "/ledger/summary", query, &summaries)
+		} else {
+			err = client.get(ctx, "/ledger", query, &entries)
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		var cfg ledgerConfig
+		err := loadConfig(&cfg)
+		if err != nil {
+			return err
+		}
+		l := ledger.NewLedger(cfg.LedgerPath)
+		err = l.Load()
+		if err != nil {
+			return err
+		}
+		entries = l.GetEntries(from, to)
+		if *period != "" {
+			summaries = ledger.Summarize(entries, ledger.Period(*period))
+		}
+	}
+
+	if *period != "" {
+		switch {
+		case *asCSV:
+			return ledger.WriteSummaryCSV(out, summaries)
+		case *asJSON:
+			return printJSON(out, summaries)
+		}
+		return printLedgerSummary(out, summaries)
+	}
+
+	switch {
+	case *asCSV:
+		return ledger.WriteEntriesCSV(out, entries)
+	case *asJSON:
+		return printJSON(out, entries)
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tTYPE\tROLE\tCONTRACT\tTOKEN\tAMOUNT\tREASON\tBLAME")
+	for _, e := range entries {
+		token, amount := "-", "-"
+		if e.Token != nil {
+			token = e.Token.Hex()
+		}
+		if e.Amount != nil {
+			amount = e.Amount.String()
+		} else if e.Price != nil {
+			amount = e.Price.String() + " (price)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", e.Time.UTC().Format(time.RFC3339), e.Type, e.Role, e.ContractID, token, amount, e.Reason, e.Blame)
+	}
+	return w.Flush()
+}
+
+func printLedgerSummary(out io.Writer, summaries []*ledger.Summary) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PERIOD\tSTARTS\tCLOSEOUTS\tREWARD CLAIMS\tVALIDATOR FEES\tTOKEN\tSALES\tSPENT\tRECEIVED")
+	for _, s := range summaries {
+		counts := fmt.Sprintf("%s\t%d\t%d\t%d\t%d", s.PeriodStart.Format(time.DateOnly), s.ContractStarts, s.Closeouts, s.RewardClaims, s.ValidatorFees)
+		tokens := s.Tokens()
+		if len(tokens) == 0 {
+			fmt.Fprintf(w, "%s\t-\t0\t0\t0\n", counts)
+			continue
+		}
+		for _, token := range tokens {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", counts, token.Hex(), s.GetSales(token), s.GetSpent(token), s.GetReceived(token))
+		}
+	}
+	return w.Flush()
+}
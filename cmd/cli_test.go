@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/handlers/httphandlers"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/txmanager"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+const testPrivKey = "b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291"
+
+func TestEncryptDecrypt(t *testing.T) {
+	t.Setenv("WALLET_PRIVATE_KEY", testPrivKey)
+
+	key, err := crypto.HexToECDSA(testPrivKey)
+	require.NoError(t, err)
+	compressed := "0x" + hex.EncodeToString(crypto.CompressPubkey(&key.PublicKey))
+
+	var encrypted bytes.Buffer
+	err = cmdEncrypt(context.Background(), []string{"-pubkey", compressed, "stratum+tcp://worker:@pool.dev:3333"}, &encrypted)
+	require.NoError(t, err)
+
+	var decrypted bytes.Buffer
+	err = cmdDecrypt(context.Background(), []string{strings.TrimSpace(encrypted.String())}, &decrypted)
+	require.NoError(t, err)
+	require.Equal(t, "stratum+tcp://worker:@pool.dev:3333\n", decrypted.String())
+}
+
+func TestPubkeyKeystore(t *testing.T) {
+	key, err := crypto.HexToECDSA(testPrivKey)
+	require.NoError(t, err)
+	data, err := keystore.EncryptKey(&keystore.Key{
+		Address:    crypto.PubkeyToAddress(key.PublicKey),
+		PrivateKey: key,
+	}, "secret", keystore.LightScryptN, keystore.LightScryptP)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "keystore.json"), data, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "password"), []byte("secret\n"), 0600))
+	t.Setenv("WALLET_KEYSTORE_PATH", filepath.Join(dir, "keystore.json"))
+	t.Setenv("WALLET_KEYSTORE_PASSWORD_FILE", filepath.Join(dir, "password"))
+
+	var out bytes.Buffer
+	err = cmdPubkey(context.Background(), nil, &out)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), hex.EncodeToString(crypto.FromECDSAPub(&key.PublicKey)))
+}
+
+func TestCommandsUseAPI(t *testing.T) {
+	var closeQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/contracts-v2":
+			_ = json.NewEncoder(w).Encode(httphandlers.ContractsResponse{
+				Contracts: []httphandlers.Contract{{ID: "0x01", Role: "seller", BlockchainStatus: "running", ApplicationStatus: "running"}},
+			})
+		case r.Method == http.MethodPost && r.URL.Path == "/contracts/0x01/close":
+			closeQuery = r.URL.RawQuery
+			_, _ = w.Write([]byte(`{"status":"ok"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"contract not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := cmdContracts(context.Background(), []string{"-api", server.URL}, &out)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "0x01")
+	require.Contains(t, out.String(), "running/running")
+
+	out.Reset()
+	err = cmdClose(context.Background(), []string{"-api", server.URL, "-reason", "1", "0x01"}, &out)
+	require.NoError(t, err)
+	require.Equal(t, "reason=1", closeQuery)
+
+	err = cmdClose(context.Background(), []string{"-api", server.URL, "0x02"}, &out)
+	require.ErrorIs(t, err, ErrAPIRequest)
+	require.ErrorContains(t, err, "contract not found")
+
+	err = cmdMiners(context.Background(), nil, &out)
+	require.ErrorIs(t, err, ErrAPIRequired)
+}
+
+func TestValidatorCommandUsesAPI(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/validator/register", r.URL.Path)
+		query = r.URL.RawQuery
+		_ = json.NewEncoder(w).Encode(httphandlers.ValidatorTxResponse{
+			DryRun: true,
+			Txs:    []contracts.TxResult{{Estimate: &txmanager.Estimate{Label: "register", Gas: 100000}}},
+		})
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := cmdValidator(context.Background(), []string{"-api", server.URL, "register", "-dry-run", "1000", "validator.dev:3333"}, &out)
+	require.NoError(t, err)
+	require.Equal(t, "dryRun=true&host=validator.dev%3A3333&stake=1000", query)
+	require.Contains(t, out.String(), `"Gas": 100000`)
+
+	err = cmdValidator(context.Background(), []string{"-api", server.URL, "register", "1000"}, &out)
+	require.ErrorIs(t, err, ErrUsage)
+}
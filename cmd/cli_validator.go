@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"net/url"
+	"strconv"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/handlers/httphandlers"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
+)
+
+// validatorArgs are the number of positional arguments of the validator subcommands
+var validatorArgs = map[string]int{
+	"status":     0,
+	"complaints": 0,
+	"register":   2,
+	"host":       1,
+	"stake":      1,
+	"deregister": 0,
+}
+
+func cmdValidator(ctx context.Context, args []string, out io.Writer) error {
+	fs := newFlagSet("validator")
+	api := fs.String("api", "", apiFlagDesc)
+	dryRun := fs.Bool("dry-run", false, "only estimate the transactions without sending them")
+	fromBlock := fs.Int64("from-block", -1, "block to search complaints from, defaults to the recent blocks")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return lib.WrapError(ErrUsage, err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return lib.WrapError(ErrUsage, fmt.Errorf("validator subcommand is required"))
+	}
+	sub := fs.Arg(0)
+	n, ok := validatorArgs[sub]
+	if !ok {
+		fs.Usage()
+		return lib.WrapError(ErrUsage, fmt.Errorf("unknown validator subcommand %s", sub))
+	}
+	// flags are allowed after the subcommand as well
+	err = parseArgs(fs, fs.Args()[1:], n)
+	if err != nil {
+		return err
+	}
+
+	var res any
+	if *api != "" {
+		res, err = validatorViaAPI(ctx, *api, sub, fs.Args(), *dryRun, *fromBlock)
+	} else {
+		res, err = validatorViaChain(ctx, sub, fs.Args(), *dryRun, *fromBlock)
+	}
+	if err != nil {
+		return err
+	}
+	return printJSON(out, res)
+}
+
+func validatorViaAPI(ctx context.Context, api string, sub string, args []string, dryRun bool, fromBlock int64) (any, error) {
+	client, err := newAPIClient(api)
+	if err != nil {
+		return nil, err
+	}
+
+	switch sub {
+	case "status":
+		res := new(httphandlers.ValidatorResponse)
+		err := client.get(ctx, "/validator", nil, res)
+		return res, err
+	case "complaints":
+		query := url.Values{}
+		if fromBlock >= 0 {
+			query.Set("fromBlock", strconv.FormatInt(fromBlock, 10))
+		}
+		var res []contracts.Complaint
+		err := client.get(ctx, "/validator/complaints", query, &res)
+		return res, err
+	}
+
+	query := url.Values{"dryRun": {strconv.FormatBool(dryRun)}}
+	var path string
+	switch sub {
+	case "register":
+		path = "/validator/register"
+		query.Set("stake", args[0])
+		query.Set("host", args[1])
+	case "host":
+		path = "/validator/host"
+		query.Set("host", args[0])
+	case "stake":
+		path = "/validator/stake"
+		query.Set("amount", args[0])
+	case "deregister":
+		path = "/validator/deregister"
+	}
+
+	res := new(httphandlers.ValidatorTxResponse)
+	err = client.post(ctx, path, query, res)
+	return res, err
+}
+
+func validatorViaChain(ctx context.Context, sub string, args []string, dryRun bool, fromBlock int64) (any, error) {
+	ch, err := newChain(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer ch.Close()
+	if err := ch.requireRegistry(); err != nil {
+		return nil, err
+	}
+	signer, err := ch.cfg.Wallet.newSigner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var txs []contracts.TxResult
+	switch sub {
+	case "status":
+		validator, err := ch.registry.GetValidator(ctx, signer.Address())
+		if err != nil {
+			return nil, err
+		}
+		params, err := ch.registry.GetParams(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return httphandlers.ValidatorResponse{Validator: validator, Params: params}, nil
+	case "complaints":
+		var from *big.Int
+		if fromBlock >= 0 {
+			from = big.NewInt(fromBlock)
+		}
+		return ch.registry.GetComplaints(ctx, signer.Address(), from)
+	case "register":
+		stake, parseErr := lib.ParseUnits(args[0], contracts.LMRDecimals)
+		if parseErr != nil {
+			return nil, lib.WrapError(ErrUsage, parseErr)
+		}
+		txs, err = ch.registry.Register(ctx, signer, stake, args[1], dryRun)
+	case "host":
+		txs, err = ch.registry.UpdateHost(ctx, signer, args[0], dryRun)
+	case "stake":
+		amount, parseErr := lib.ParseUnits(args[0], contracts.LMRDecimals)
+		if parseErr != nil {
+			return nil, lib.WrapError(ErrUsage, parseErr)
+		}
+		txs, err = ch.registry.AddStake(ctx, signer, amount, dryRun)
+	case "deregister":
+		txs, err = ch.registry.Deregister(ctx, signer, dryRun)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return httphandlers.ValidatorTxResponse{DryRun: dryRun, Txs: txs}, nil
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
@@ -17,19 +18,26 @@ import (
 	"github.com/Lumerin-protocol/proxy-router/internal/handlers/tcphandlers"
 	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
 	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/attestation"
 	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/ethpool"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/hashprice"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/ledger"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/reputation"
 	"github.com/Lumerin-protocol/proxy-router/internal/repositories/subgraph"
 	"github.com/Lumerin-protocol/proxy-router/internal/repositories/transport"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/txmanager"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/wallet"
 	"github.com/Lumerin-protocol/proxy-router/internal/resources"
 	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/allocator"
 	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/contract"
 	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/hashrate"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/history"
 	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/peervalidator"
 	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/proxy"
 	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/validator"
 	"github.com/Lumerin-protocol/proxy-router/internal/system"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -43,8 +51,8 @@ var (
 )
 
 func main() {
-	if len(os.Args) > 1 && os.Args[1] == "pubkey" {
-		err := logCompressedPublicKey()
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		err := runCommand(os.Args[1], os.Args[2:])
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
@@ -188,42 +196,78 @@ func start() error {
 		os.Exit(1)
 	}()
 
+	hashrateCounters, err := hashrate.ParseCounterSpecs(cfg.Hashrate.Counters)
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{cfg.Hashrate.CounterAllocation, cfg.Hashrate.CounterBuyer} {
+		if err := hashrate.CheckCounterName(hashrateCounters, name); err != nil {
+			return err
+		}
+	}
+	appLog.Infof("hashrate counters: %v", hashrateCounters)
+
 	var (
-		HashrateCounterDefault = "ema--5m"
-		HashrateCounterBuyer   = hashrate.MeanCounterKey
+		HashrateCounterDefault = cfg.Hashrate.CounterAllocation
+		HashrateCounterBuyer   = cfg.Hashrate.CounterBuyer
 	)
 
-	hashrateFactory := func() *hashrate.Hashrate {
-		return hashrate.NewHashrate(
-			map[string]hashrate.Counter{
-				HashrateCounterDefault: hashrate.NewEma(5 * time.Minute),
-				"ema-10m":              hashrate.NewEma(10 * time.Minute),
-				"ema-30m":              hashrate.NewEma(30 * time.Minute),
-				HashrateCounterBuyer:   hashrate.NewMean(),
-			},
-		)
+	hashrateFactory := hashrate.NewHashrateFactoryFromSpecs(hashrateCounters)
+
+	algorithm, err := hashrate.ParseAlgorithm(cfg.Proxy.Algorithm)
+	if err != nil {
+		return err
+	}
+	listeners, err := hashrate.ParseAlgorithms(cfg.Proxy.Listeners)
+	if err != nil {
+		return err
 	}
+	if _, ok := listeners[cfg.Proxy.Address]; ok {
+		return fmt.Errorf("listener %s is already set in PROXY_ADDRESS", cfg.Proxy.Address)
+	}
+	listeners[cfg.Proxy.Address] = algorithm
+	contractAlgorithms, err := hashrate.ParseAlgorithms(cfg.Proxy.ContractAlgorithms)
+	if err != nil {
+		return err
+	}
+	appLog.Infof("proof of work algorithm: %s, listeners %v, contracts %v", algorithm, listeners, contractAlgorithms)
 
-	destFactory := func(ctx context.Context, url *url.URL, srcWorker string, srcAddr string) (*proxy.ConnDest, error) {
-		validator := validator.NewValidator(cfg.Pool.CleanJobTimeout)
-		return proxy.ConnectDest(ctx, url, validator, IDLE_READ_CLOSE_TIMEOUT, cfg.Pool.IdleWriteTimeout, connLog.With("SrcWorker", srcWorker, "SrcAddr", srcAddr))
+	// the shares are validated with the algorithm of the listener the miner is connected to
+	newDestFactory := func(algorithm hashrate.Algorithm) proxy.DestConnFactory {
+		return func(ctx context.Context, url *url.URL, srcWorker string, srcAddr string) (*proxy.ConnDest, error) {
+			validator := validator.NewValidator(algorithm, cfg.Pool.CleanJobTimeout)
+			return proxy.ConnectDest(ctx, url, validator, IDLE_READ_CLOSE_TIMEOUT, cfg.Pool.IdleWriteTimeout, connLog.With("SrcWorker", srcWorker, "SrcAddr", srcAddr))
+		}
 	}
 
-	globalHashrate := hashrate.NewGlobalHashrate(hashrateFactory)
+	globalHashrate := hashrate.NewGlobalHashrate(hashrateFactory, cfg.Hashrate.WorkerIdleTimeout, cfg.Hashrate.WorkerTTL)
 	alloc := allocator.NewAllocator(lib.NewCollection[*allocator.Scheduler](), log.Named("ALC"))
 
-	ethClient, err := ethclient.DialContext(ctx, cfg.Blockchain.EthNodeAddress)
+	ethNodeURLs := []string{cfg.Blockchain.EthNodeAddress}
+	for _, addr := range strings.Split(cfg.Blockchain.EthNodeFallback, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			ethNodeURLs = append(ethNodeURLs, addr)
+		}
+	}
+	ethClient, err := ethpool.NewPool(ethNodeURLs, ethpool.DialEthClient, cfg.Blockchain.EthNodeQuorum, cfg.Blockchain.EthNodeHedge, cfg.Blockchain.EthNodeHealth, cfg.Blockchain.EthNodeMaxLag, rpcLog.Named("ETH"))
 	if err != nil {
 		return lib.WrapError(ErrConnectToEthNode, err)
 	}
-	log.Infof("connected to ethereum node")
+	ethClient.CheckHealth(ctx, time.Now())
+	log.Infof("using %d ethereum node(s), quorum %d", len(ethNodeURLs), cfg.Blockchain.EthNodeQuorum)
+
+	checkpoints := contracts.NewFileCheckpointStore(cfg.Blockchain.CheckpointPath, contracts.CheckpointFlushInterval, rpcLog)
+	err = checkpoints.Load()
+	if err != nil {
+		return lib.WrapError(fmt.Errorf("can't load log checkpoints"), err)
+	}
 
 	var logWatcher contracts.LogWatcher
 	if cfg.Blockchain.UseSubscriptions {
-		logWatcher = contracts.NewLogWatcherSubscription(ethClient, cfg.Blockchain.MaxReconnects, rpcLog)
+		logWatcher = contracts.NewLogWatcherSubscription(ethClient, cfg.Blockchain.MaxReconnects, cfg.Blockchain.Confirmations, checkpoints, rpcLog)
 		appLog.Infof("using websocket log subscription for blockchain events")
 	} else {
-		logWatcher = contracts.NewLogWatcherPolling(ethClient, cfg.Blockchain.PollingInterval, cfg.Blockchain.MaxReconnects, rpcLog)
+		logWatcher = contracts.NewLogWatcherPolling(ethClient, cfg.Blockchain.PollingInterval, cfg.Blockchain.MaxReconnects, cfg.Blockchain.Confirmations, checkpoints, rpcLog)
 		appLog.Infof("using polling for blockchain events")
 	}
 
@@ -232,17 +276,48 @@ func start() error {
 		return err
 	}
 
-	store := contracts.NewHashrateEthereum(common.HexToAddress(cfg.Marketplace.CloneFactoryAddress), ethClient, logWatcher, log)
-	futuresStore := contracts.NewFuturesEthereum(common.HexToAddress(cfg.Futures.Address), common.HexToAddress(cfg.Blockchain.MulticallAddress), ethClient, logWatcher, log)
+	signer, err := wallet.NewSigner(ctx,
+		cfg.Marketplace.WalletPrivateKey,
+		cfg.Marketplace.KeystorePath,
+		cfg.Marketplace.KeystorePasswordFile,
+		cfg.Marketplace.RemoteSignerURL,
+		cfg.Marketplace.RemoteSignerAddress,
+		cfg.Marketplace.DecryptionKey,
+	)
+	if err != nil {
+		return err
+	}
+	walletAddr := signer.Address()
+	if remoteSigner, ok := signer.(*wallet.RemoteSigner); ok && !remoteSigner.CanDecrypt() {
+		return lib.WrapError(wallet.ErrDecryptNotSupported, fmt.Errorf("encrypted contract destinations can't be decrypted, set WALLET_DECRYPTION_KEY"))
+	}
 
-	store.SetLegacyTx(cfg.Blockchain.EthLegacyTx)
-	futuresStore.SetLegacyTx(cfg.Blockchain.EthLegacyTx)
+	txManager := txmanager.NewTxManager(
+		ethClient,
+		txmanager.NewFileTxStore(cfg.Blockchain.TxQueuePath),
+		cfg.Blockchain.EthLegacyTx,
+		txmanager.ReceiptPollInterval,
+		cfg.Blockchain.TxBumpInterval,
+		cfg.Blockchain.TxBumpPercent,
+		cfg.Blockchain.TxMaxBumps,
+		cfg.Blockchain.TxMaxRetries,
+		log.Named("TXM"),
+	)
+	err = txManager.Load()
+	if err != nil {
+		return err
+	}
+	txManager.AddSigner(signer)
 
-	walletAddr, err := lib.PrivKeyStringToAddr(cfg.Marketplace.WalletPrivateKey)
+	earnings := ledger.NewLedger(cfg.Marketplace.LedgerPath)
+	err = earnings.Load()
 	if err != nil {
 		return err
 	}
 
+	store := contracts.NewHashrateEthereum(common.HexToAddress(cfg.Marketplace.CloneFactoryAddress), common.HexToAddress(cfg.Blockchain.MulticallAddress), cfg.Blockchain.MulticallBatch, cfg.Blockchain.MulticallWorkers, ethClient, txManager, logWatcher, earnings, log)
+	futuresStore := contracts.NewFuturesEthereum(common.HexToAddress(cfg.Futures.Address), common.HexToAddress(cfg.Blockchain.MulticallAddress), ethClient, txManager, logWatcher, earnings, log)
+
 	specs, err := futuresStore.GetContractSpecs(ctx)
 	if err != nil {
 		return err
@@ -267,6 +342,50 @@ func start() error {
 	derived.ValidatorURL = specs.ValidatorURL.String()
 	derived.ContractDurationDays = int(specs.DeliveryDuration.Hours() / 24)
 	derived.ContractHashrateGHS = float64(specs.SpeedHps / 1e9)
+	derived.HashrateCounters = hashrate.CounterNames(hashrateCounters)
+
+	// the ledger tracks only the own purchases, the reputation store adds the on-chain history of all buyers
+	var (
+		sellerReputation  *reputation.Store
+		validations       contract.ValidationRecorder
+		autoBuyReputation contractmanager.SellerReputation = earnings
+	)
+	if cfg.Buyer.ReputationEnable {
+		sellerReputation = reputation.NewStore(cfg.Buyer.ReputationPath, cfg.Buyer.ReputationSync, cfg.Buyer.ReputationHistory, cfg.Buyer.ReputationConfirmations, store, log.Named("REP"))
+		err = sellerReputation.Load()
+		if err != nil {
+			return err
+		}
+		validations = sellerReputation
+		autoBuyReputation = sellerReputation
+	}
+
+	validationPolicy := contract.ValidationPolicyConfig{
+		Mode:            contract.ValidationMode(cfg.Hashrate.ValidationMode),
+		ErrorThreshold:  cfg.Hashrate.ErrorThreshold,
+		Flatness:        cfg.Hashrate.ValidatorFlatness,
+		FalsePositive:   cfg.Hashrate.ValidationFalsePositive,
+		ShareTimeout:    cfg.Hashrate.ShareTimeout,
+		Window:          cfg.Hashrate.ValidationWindow,
+		DestChangeGrace: cfg.Hashrate.ValidationDestGrace,
+		OutageTolerance: cfg.Hashrate.ValidationOutageTolerance,
+	}
+	validationPolicies, err := contract.ParseValidationPolicies(cfg.Hashrate.ValidationPolicies, validationPolicy)
+	if err != nil {
+		return err
+	}
+
+	// the futures validator signs the delivery report of every validated position
+	attestations := attestation.NewStore(cfg.Hashrate.ValidationAttestations, signer, log.Named("ATS"))
+	var attestor contract.DeliveryAttestor
+	if walletAddr.Cmp(specs.ValidatorAddress) == 0 {
+		attestor = attestations
+	}
+
+	destRoutes, err := contract.ParseDestRoutes(cfg.Buyer.DestRoutes)
+	if err != nil {
+		return err
+	}
 
 	hrContractFactory, err := contract.NewContractFactory(
 		alloc,
@@ -276,23 +395,30 @@ func start() error {
 		futuresStore,
 		contractLogFactory,
 
-		cfg.Marketplace.WalletPrivateKey,
+		signer,
 		cfg.Hashrate.CycleDuration,
-		cfg.Hashrate.ShareTimeout,
-		cfg.Hashrate.ErrorThreshold,
 		HashrateCounterBuyer,
-		cfg.Hashrate.ValidatorFlatness,
+		HashrateCounterDefault,
+		validationPolicy,
+		validationPolicies,
 		appStartTime.Add(cfg.Hashrate.ValidationTimeoutAppStart),
 		destUrl,
 		specs.ValidatorURL,
 		specs.DeliveryDuration,
 		float64(specs.SpeedHps)/1e9,
+		destRoutes,
+		contract.DestRoutingMode(cfg.Buyer.DestRoutingMode),
+		algorithm,
+		contractAlgorithms,
+		validations,
+		attestor,
 	)
 	if err != nil {
 		return err
 	}
 
 	cc := lib.NewCollection[resources.Contract]()
+	closedContracts := contractmanager.NewClosedContracts(contractmanager.ClosedContractsRetention)
 	setErrorFn := func(contractID string, err error) bool {
 		ctr, ok := cc.Load(contractID)
 		if !ok {
@@ -302,48 +428,212 @@ func start() error {
 		return true
 	}
 
-	subgraph := subgraph.NewClient(cfg.Futures.SubgraphURL)
-	cm := contractmanager.NewContractManager(common.HexToAddress(cfg.Marketplace.CloneFactoryAddress), walletAddr, hrContractFactory.CreateContract, store, cc, log.Named("MNG"))
+	subgraphClient := subgraph.NewClient(cfg.Futures.SubgraphURL, cfg.Futures.SubgraphRetries, subgraph.DefaultRetryBackoff, log.Named("SGR"))
+	positions := contractmanager.NewFuturesPositions(subgraphClient, futuresStore, cfg.Futures.SubgraphMaxLag, log.Named("FPS"))
+	cm := contractmanager.NewContractManager(common.HexToAddress(cfg.Marketplace.CloneFactoryAddress), walletAddr, hrContractFactory.CreateContract, store, signer, cc, log.Named("MNG"))
+
+	var autoLister *contractmanager.SellerAutoLister
+	if cfg.Seller.AutoListEnable {
+		autoLister = contractmanager.NewSellerAutoLister(
+			walletAddr,
+			cfg.Seller.AutoListInterval,
+			cfg.Seller.AutoListDryRun,
+			cfg.Seller.ContractHashrateGHS,
+			cfg.Seller.ContractDuration,
+			cfg.Seller.ReserveGHS,
+			cfg.Seller.MarkupPercent,
+			int8(cfg.Seller.MinProfitTarget),
+			int8(cfg.Seller.MaxProfitTarget),
+			cfg.Seller.MaxContracts,
+			cfg.Seller.MaxChangesPerRun,
+			cfg.Seller.HashpriceMaxAge,
+			cfg.Seller.PubKey,
+			store,
+			cc,
+			alloc,
+			hashprice.NewSource(cfg.Seller.HashpriceSource),
+			signer,
+			log.Named("SAL"),
+		)
+	}
+
+	var autoBuyer *contractmanager.BuyerAutoBuyer
+	if cfg.Buyer.AutoBuyEnable {
+		validatorURL, err := url.Parse(cfg.Buyer.ValidatorURL)
+		if err != nil {
+			return err
+		}
+		buyerDestURL, err := url.Parse(cfg.Buyer.DestURL)
+		if err != nil {
+			return err
+		}
+		autoBuyer = contractmanager.NewBuyerAutoBuyer(
+			walletAddr,
+			cfg.Buyer.AutoBuyInterval,
+			cfg.Buyer.AutoBuyDryRun,
+			cfg.Buyer.MaxPricePerTHDay,
+			cfg.Buyer.MinDuration,
+			cfg.Buyer.MaxDuration,
+			cfg.Buyer.MaxSellerFailure,
+			cfg.Buyer.Budget,
+			cfg.Buyer.BudgetPeriod,
+			cfg.Buyer.MaxPurchasesPerRun,
+			validatorURL,
+			buyerDestURL,
+			store,
+			earnings,
+			autoBuyReputation,
+			signer,
+			log.Named("BAB"),
+		)
+	}
 
-	var fm interfaces.Runnable
+	var (
+		fm        interfaces.Runnable
+		readiness *contractmanager.FuturesReadiness
+	)
 	if walletAddr.Cmp(specs.ValidatorAddress) == 0 {
-		fm = contractmanager.NewFuturesManagerValidator(cfg.Marketplace.WalletPrivateKey, common.HexToAddress(cfg.Futures.Address), walletAddr, futuresStore, subgraph, cc, hrContractFactory.CreateFuturesContractBuyer, log.Named("FMV"))
+		fm = contractmanager.NewFuturesManagerValidator(signer, common.HexToAddress(cfg.Futures.Address), walletAddr, futuresStore, positions, cc, hrContractFactory.CreateFuturesContractBuyer, log.Named("FMV"))
 	} else {
-		fm = contractmanager.NewFuturesManagerSeller(cfg.Marketplace.WalletPrivateKey, common.HexToAddress(cfg.Futures.Address), walletAddr, futuresStore, subgraph, cc, hrContractFactory.CreateFuturesContractSeller, log.Named("FMG"))
+		fleetHashrate := func() float64 {
+			var total float64
+			alloc.GetMiners().Range(func(m *allocator.Scheduler) bool {
+				if hrGHS, ok := m.GetHashrate().GetHashrateAvgGHSCustom(HashrateCounterDefault); ok {
+					total += hrGHS
+				}
+				return true
+			})
+			return total
+		}
+		readiness = contractmanager.NewFuturesReadiness(
+			walletAddr,
+			cfg.Futures.ReadinessLead,
+			float64(specs.SpeedHps)/1e9,
+			specs.ValidatorURL,
+			cfg.Futures.ReadinessWebhook,
+			futuresStore,
+			positions,
+			fleetHashrate,
+			log.Named("FRD"),
+		)
+		fm = contractmanager.NewFuturesManagerSeller(signer, common.HexToAddress(cfg.Futures.Address), walletAddr, futuresStore, positions, readiness, cc, closedContracts, hrContractFactory.CreateFuturesContractSeller, log.Named("FMG"))
 	}
 
-	tcpServer := transport.NewTCPServer(cfg.Proxy.Address, connLog.Named("TCP"))
-	tcpHandler := tcphandlers.NewTCPHandler(
-		log, connLog, proxyLog, schedulerLogFactory,
-		cfg.Miner.NotPropagateWorkerName, cfg.Miner.IdleReadTimeout, IDLE_WRITE_CLOSE_TIMEOUT,
-		cfg.Miner.VettingShares, cfg.Proxy.MaxCachedDests,
-		destUrl,
-		destFactory, hashrateFactory,
-		globalHashrate, HashrateCounterDefault,
-		alloc,
-		setErrorFn,
-		cc.Load,
-	)
-	tcpServer.SetConnectionHandler(tcpHandler)
+	blockCandidates := proxy.NewBlockCandidateLog(proxy.BlockCandidateLogSize)
+	onBlockCandidate := func(c proxy.BlockCandidate) {
+		cc.Range(func(item resources.Contract) bool {
+			if item.ID() == c.IncomingContractID {
+				c.ContractIDs = append(c.ContractIDs, item.ID())
+				return true
+			}
+			if dest, err := url.Parse(item.Dest()); err == nil && item.Dest() != "" && dest.Redacted() == c.DestURL {
+				c.ContractIDs = append(c.ContractIDs, item.ID())
+			}
+			return true
+		})
+		blockCandidates.Add(c)
+		appLog.Warnf("block candidate %s found by worker %s (%s), contracts %v, pool accepted %t",
+			c.BlockHash, c.SourceWorker, c.SourceAddr, c.ContractIDs, c.PoolAccepted)
+	}
+
+	tcpServers := make([]*transport.TCPServer, 0, len(listeners))
+	for address, listenerAlgorithm := range listeners {
+		tcpServer := transport.NewTCPServer(address, connLog.Named("TCP"))
+		tcpHandler := tcphandlers.NewTCPHandler(
+			log, connLog, proxyLog, schedulerLogFactory,
+			cfg.Miner.NotPropagateWorkerName, cfg.Miner.IdleReadTimeout, IDLE_WRITE_CLOSE_TIMEOUT,
+			cfg.Miner.VettingShares, cfg.Proxy.MaxCachedDests,
+			destUrl,
+			newDestFactory(listenerAlgorithm), listenerAlgorithm, hashrateFactory,
+			globalHashrate, HashrateCounterDefault,
+			alloc,
+			setErrorFn,
+			cc.Load,
+			onBlockCandidate,
+		)
+		tcpServer.SetConnectionHandler(tcpHandler)
+		tcpServers = append(tcpServers, tcpServer)
+	}
 
-	handl := httphandlers.NewHTTPHandler(cm, cc, alloc, globalHashrate, sysConfig, publicUrl, HashrateCounterDefault, cfg.Hashrate.CycleDuration, &cfg, derived, appStartTime, contractLogStorage, log)
+	hrHistory := history.NewHistory(map[history.Kind]history.Source{
+		history.KindWorker: func() map[string]float64 {
+			res := make(map[string]float64)
+			globalHashrate.Range(func(w *hashrate.WorkerHashrateModel) bool {
+				res[w.ID()], _ = w.GetHashRateGHS(HashrateCounterDefault)
+				return true
+			})
+			return res
+		},
+		history.KindMiner: func() map[string]float64 {
+			res := make(map[string]float64)
+			alloc.GetMiners().Range(func(m *allocator.Scheduler) bool {
+				res[m.ID()], _ = m.GetHashrate().GetHashrateAvgGHSCustom(HashrateCounterDefault)
+				return true
+			})
+			return res
+		},
+		history.KindContract: func() map[string]float64 {
+			res := make(map[string]float64)
+			cc.Range(func(c resources.Contract) bool {
+				if c.State() == resources.ContractStateRunning {
+					res[c.ID()] = c.ResourceEstimatesActual()[HashrateCounterDefault]
+				}
+				return true
+			})
+			return res
+		},
+	}, time.Minute, history.DefaultResolutions, cfg.Hashrate.WorkerTTL, log.Named("HST"))
+
+	var registry *contracts.ValidatorRegistryEthereum
+	if cfg.Marketplace.ValidatorRegistryAddress != "" {
+		registry = contracts.NewValidatorRegistryEthereum(common.HexToAddress(cfg.Marketplace.ValidatorRegistryAddress), ethClient, txManager, log.Named("VRG"))
+	}
+
+	handl := httphandlers.NewHTTPHandler(cm, cc, alloc, globalHashrate, hrHistory, blockCandidates, txManager, registry, signer, readiness, closedContracts, autoLister, autoBuyer, sellerReputation, attestations, earnings, ethClient, sysConfig, publicUrl, cfg.Web.APIToken, HashrateCounterDefault, cfg.Hashrate.CycleDuration, &cfg, derived, appStartTime, contractLogStorage, log)
 	httpServer := transport.NewServer(cfg.Web.Address, handl, log.Named("HTP"))
 
-	peerValidator, err := peervalidator.NewPeerValidator(common.HexToAddress(cfg.Marketplace.ValidatorRegistryAddress), walletAddr, ethClient, cfg.Hashrate.PeerValidationInterval, log)
+	peerValidator, err := peervalidator.NewPeerValidator(
+		common.HexToAddress(cfg.Marketplace.ValidatorRegistryAddress),
+		signer,
+		ethClient,
+		txManager,
+		cfg.Hashrate.PeerValidationInterval,
+		cfg.Hashrate.PeerValidationFailures,
+		cfg.Hashrate.PeerValidationTimeout,
+		peervalidator.NewEvidenceStore(cfg.Hashrate.PeerValidationEvidence),
+		log,
+	)
 	if err != nil {
 		return err
 	}
 
 	ctx, cancel = context.WithCancel(ctx)
 	g, errCtx := errgroup.WithContext(ctx)
-	g.Go(func() error {
-		return tcpServer.Run(errCtx)
-	})
+	for _, tcpServer := range tcpServers {
+		g.Go(func() error {
+			return tcpServer.Run(errCtx)
+		})
+	}
 
 	if cfg.Marketplace.CloneFactoryAddress != "" {
 		g.Go(func() error {
 			return cm.Run(errCtx)
 		})
+		if autoLister != nil {
+			g.Go(func() error {
+				return autoLister.Run(errCtx)
+			})
+		}
+		if autoBuyer != nil {
+			g.Go(func() error {
+				return autoBuyer.Run(errCtx)
+			})
+		}
+		if sellerReputation != nil {
+			g.Go(func() error {
+				return sellerReputation.Run(errCtx)
+			})
+		}
 	} else {
 		appLog.Warnf("clonefactory address is not set, skipping contract manager")
 	}
@@ -352,6 +642,11 @@ func start() error {
 		g.Go(func() error {
 			return fm.Run(errCtx)
 		})
+		if readiness != nil {
+			g.Go(func() error {
+				return readiness.Run(errCtx)
+			})
+		}
 	} else {
 		appLog.Warnf("futures address is not set, skipping futures manager")
 	}
@@ -364,6 +659,26 @@ func start() error {
 		appLog.Warnf("validator registry address is not set, skipping peer validator")
 	}
 
+	g.Go(func() error {
+		return ethClient.Run(errCtx)
+	})
+
+	g.Go(func() error {
+		return txManager.Run(errCtx)
+	})
+
+	g.Go(func() error {
+		return checkpoints.Run(errCtx)
+	})
+
+	g.Go(func() error {
+		return hrHistory.Run(errCtx)
+	})
+
+	g.Go(func() error {
+		return globalHashrate.Run(errCtx)
+	})
+
 	g.Go(func() error {
 		for {
 			select {
@@ -1,52 +1,50 @@
 package main
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
+	"errors"
 	"fmt"
-	"os"
+	"io"
 
-	"github.com/Lumerin-protocol/proxy-router/internal/config"
-	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/wallet"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
 )
 
-type Config struct {
-	WalletPrivateKey string `env:"WALLET_PRIVATE_KEY"    flag:"wallet-private-key" validate:"required"`
-}
-
-func (cfg *Config) SetDefaults() {
-}
+var ErrNoPublicKey = errors.New("remote signer doesn't expose the public key, set WALLET_DECRYPTION_KEY")
 
-func logCompressedPublicKey() error {
-	fmt.Printf("Compressed public key script\n\n")
-	var cfg Config
-	err := config.LoadConfig(&cfg, &os.Args)
+func cmdPubkey(ctx context.Context, args []string, out io.Writer) error {
+	fmt.Fprintf(out, "Compressed public key script\n\n")
+	var cfg walletConfig
+	err := loadConfig(&cfg)
 	if err != nil {
 		return err
 	}
-
-	prkeyHash := common.HexToHash(cfg.WalletPrivateKey)
-	privKey, err := crypto.HexToECDSA(prkeyHash.Hex()[2:])
+	signer, err := cfg.newSigner(ctx)
 	if err != nil {
 		return err
 	}
 
-	yParity, x, err := lib.PrKeyToCompressedPubKey(privKey)
-	if err != nil {
-		return err
+	// the destinations are encrypted with this key, for the remote signer it is the separate decryption key
+	var publicKey *ecdsa.PublicKey
+	switch s := signer.(type) {
+	case *wallet.KeySigner:
+		publicKey = s.PublicKey()
+	case *wallet.RemoteSigner:
+		defer s.Close()
+		publicKey = s.DecryptionPublicKey()
 	}
-
-	publicKey, ok := privKey.Public().(*ecdsa.PublicKey)
-	if !ok {
-		return fmt.Errorf("invalid public key")
+	if publicKey == nil {
+		return ErrNoPublicKey
 	}
 
+	yParity := publicKey.Y.Bit(0) == 1
+	x := common.BigToHash(publicKey.X)
 	publicKeyBytes := elliptic.Marshal(publicKey.Curve, publicKey.X, publicKey.Y)
 
-	fmt.Printf("Uncompressed public key:\n%s\n\n", "0x"+common.Bytes2Hex(publicKeyBytes))
-	fmt.Printf("Compressed public key:\n")
-	fmt.Printf("yParity: %t\nx: %s\n\n", yParity, x.String())
+	fmt.Fprintf(out, "Uncompressed public key:\n%s\n\n", "0x"+common.Bytes2Hex(publicKeyBytes))
+	fmt.Fprintf(out, "Compressed public key:\n")
+	fmt.Fprintf(out, "yParity: %t\nx: %s\n\n", yParity, x.String())
 	return nil
 }
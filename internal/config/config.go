@@ -14,38 +14,99 @@ type DerivedConfig struct {
 	ValidatorURL         string
 	ContractDurationDays int
 	ContractHashrateGHS  float64
+	HashrateCounters     []string
 }
 
 // Validation tags described here: https://pkg.go.dev/github.com/go-playground/validator/v10
 type Config struct {
 	Blockchain struct {
 		EthNodeAddress   string        `env:"ETH_NODE_ADDRESS"   flag:"eth-node-address"   validate:"required,url"`
+		EthNodeFallback  string        `env:"ETH_NODE_FALLBACK_ADDRESSES" flag:"eth-node-fallback-addresses" desc:"comma separated list of additional ethereum node addresses used for failover"`
+		EthNodeQuorum    int           `env:"ETH_NODE_QUORUM" flag:"eth-node-quorum" validate:"omitempty,gte=1" desc:"number of nodes that must return the same result for critical reads (contract terms, delivery ranges), 1 disables quorum"`
+		EthNodeHedge     time.Duration `env:"ETH_NODE_HEDGE_DELAY" flag:"eth-node-hedge-delay" validate:"omitempty,duration" desc:"read request is also sent to the next node if the current one did not respond within this duration"`
+		EthNodeHealth    time.Duration `env:"ETH_NODE_HEALTH_CHECK_INTERVAL" flag:"eth-node-health-check-interval" validate:"omitempty,duration" desc:"interval between ethereum node health checks"`
+		EthNodeMaxLag    uint64        `env:"ETH_NODE_MAX_BLOCK_LAG" flag:"eth-node-max-block-lag" desc:"node is considered unhealthy if its latest block is behind the other nodes by more than this number of blocks"`
 		UseSubscriptions bool          `env:"ETH_USE_SUBSCRIPTIONS" flag:"eth-use-subscriptions" desc:"use websocket subscriptions for blockchain events"`
 		PollingInterval  time.Duration `env:"ETH_POLLING_INTERVAL" flag:"eth-polling-interval" validate:"omitempty,duration" desc:"interval between polling for blockchain events"`
 		MaxReconnects    int           `env:"ETH_MAX_RECONNECTS" flag:"eth-max-reconnects" validate:"omitempty,number" desc:"maximum number of reconnect attempts"`
+		Confirmations    uint64        `env:"ETH_CONFIRMATIONS" flag:"eth-confirmations" desc:"number of blocks on top of the block with the contract event before it is processed, 0 processes events immediately, events removed by reorg are reverted in both cases"`
+		CheckpointPath   string        `env:"ETH_LOG_CHECKPOINT_PATH" flag:"eth-log-checkpoint-path" desc:"file to persist the last processed block of each watched contract, so the events are not missed or re-read after restart"`
 		EthLegacyTx      bool          `env:"ETH_NODE_LEGACY_TX" flag:"eth-node-legacy-tx" desc:"use it to disable EIP-1559 transactions"`
 		MulticallAddress string        `env:"MULTICALL_ADDRESS" flag:"multicall-address" validate:"required,eth_addr"`
+		MulticallBatch   int           `env:"MULTICALL_BATCH_SIZE" flag:"multicall-batch-size" validate:"omitempty,gte=1" desc:"maximum number of calls in a single multicall request, used to load contracts at startup"`
+		MulticallWorkers int           `env:"MULTICALL_CONCURRENCY" flag:"multicall-concurrency" validate:"omitempty,gte=1" desc:"maximum number of multicall requests executed in parallel"`
+		TxQueuePath      string        `env:"ETH_TX_QUEUE_PATH" flag:"eth-tx-queue-path" desc:"file to persist the pending transactions, so they are monitored after restart"`
+		TxBumpInterval   time.Duration `env:"ETH_TX_BUMP_INTERVAL" flag:"eth-tx-bump-interval" validate:"omitempty,duration" desc:"pending transaction is replaced with higher fee if not mined within this duration"`
+		TxBumpPercent    int           `env:"ETH_TX_BUMP_PERCENT" flag:"eth-tx-bump-percent" validate:"omitempty,gte=10" desc:"fee increase of the replacement transaction in percents, minimum 10"`
+		TxMaxBumps       int           `env:"ETH_TX_MAX_BUMPS" flag:"eth-tx-max-bumps" validate:"omitempty,number" desc:"maximum number of fee increases of the transaction"`
+		TxMaxRetries     int           `env:"ETH_TX_MAX_RETRIES" flag:"eth-tx-max-retries" validate:"omitempty,number" desc:"maximum number of attempts to resend the transaction if it failed to send or its nonce was taken"`
+	}
+	Buyer struct {
+		AutoBuyEnable           bool          `env:"BUYER_AUTOBUY_ENABLE" flag:"buyer-autobuy-enable" desc:"purchase the available contracts matching the rules within the budget, the router validates the purchased contracts"`
+		AutoBuyDryRun           bool          `env:"BUYER_AUTOBUY_DRY_RUN" flag:"buyer-autobuy-dry-run" desc:"only estimate the purchase transactions, nothing is sent to the chain"`
+		AutoBuyInterval         time.Duration `env:"BUYER_AUTOBUY_INTERVAL" flag:"buyer-autobuy-interval" validate:"omitempty,duration" desc:"interval between evaluations of the available contracts"`
+		MaxPricePerTHDay        float64       `env:"BUYER_AUTOBUY_MAX_PRICE_PER_TH_DAY" flag:"buyer-autobuy-max-price-per-th-day" validate:"required_if=AutoBuyEnable true,omitempty,gt=0" desc:"maximum price of 1 TH/s for a day in the payment token units"`
+		MinDuration             time.Duration `env:"BUYER_AUTOBUY_MIN_DURATION" flag:"buyer-autobuy-min-duration" validate:"omitempty,duration" desc:"contracts shorter than this are not purchased"`
+		MaxDuration             time.Duration `env:"BUYER_AUTOBUY_MAX_DURATION" flag:"buyer-autobuy-max-duration" validate:"omitempty,duration" desc:"contracts longer than this are not purchased, 0 means no limit"`
+		MaxSellerFailure        float64       `env:"BUYER_AUTOBUY_MAX_SELLER_FAILURE_RATE" flag:"buyer-autobuy-max-seller-failure-rate" validate:"omitempty,gte=0,lte=1" desc:"sellers whose contracts were closed for underdelivery more often than this share of our purchases are skipped"`
+		Budget                  string        `env:"BUYER_AUTOBUY_BUDGET" flag:"buyer-autobuy-budget" validate:"required_if=AutoBuyEnable true,omitempty,numeric" desc:"maximum amount spent per budget period in the payment token units"`
+		BudgetPeriod            time.Duration `env:"BUYER_AUTOBUY_BUDGET_PERIOD" flag:"buyer-autobuy-budget-period" validate:"omitempty,duration" desc:"rolling period of the budget"`
+		MaxPurchasesPerRun      int           `env:"BUYER_AUTOBUY_MAX_PURCHASES" flag:"buyer-autobuy-max-purchases" validate:"omitempty,gte=1" desc:"maximum number of the contracts purchased per evaluation"`
+		ValidatorURL            string        `env:"BUYER_AUTOBUY_VALIDATOR_URL" flag:"buyer-autobuy-validator-url" validate:"required_if=AutoBuyEnable true,omitempty,url" desc:"public stratum url of this router the sellers direct the purchased hashrate to, e.g. stratum+tcp://my-router.com:3333"`
+		DestURL                 string        `env:"BUYER_AUTOBUY_DEST" flag:"buyer-autobuy-dest" validate:"required_if=AutoBuyEnable true,omitempty,url" desc:"pool url with the worker credentials the purchased hashrate is forwarded to"`
+		DestRoutes              string        `env:"BUYER_DEST_ROUTES" flag:"buyer-dest-routes" desc:"comma separated list of pools in format [weight:]url the hashrate of the purchased contracts is split among, overrides the destination of every purchased contract including its later updates"`
+		DestRoutingMode         string        `env:"BUYER_DEST_ROUTING_MODE" flag:"buyer-dest-routing-mode" validate:"omitempty,oneof=weight failover" desc:"'weight' splits the incoming connections proportionally to the weights, 'failover' sends them to the first available pool"`
+		ReputationEnable        bool          `env:"BUYER_REPUTATION_ENABLE" flag:"buyer-reputation-enable" desc:"track the delivery history of the sellers from the chain and from the own validation, used by the auto-buyer instead of the ledger"`
+		ReputationPath          string        `env:"BUYER_REPUTATION_PATH" flag:"buyer-reputation-path" desc:"file to keep the seller reputation between restarts"`
+		ReputationSync          time.Duration `env:"BUYER_REPUTATION_SYNC_INTERVAL" flag:"buyer-reputation-sync-interval" validate:"omitempty,duration" desc:"interval between reading the seller history from the chain"`
+		ReputationHistory       uint64        `env:"BUYER_REPUTATION_HISTORY_BLOCKS" flag:"buyer-reputation-history-blocks" desc:"number of the past blocks searched for the early closeouts on the first sync"`
+		ReputationConfirmations uint64        `env:"BUYER_REPUTATION_CONFIRMATIONS" flag:"buyer-reputation-confirmations" desc:"number of the blocks the early closeouts are read behind the head, so the closeouts removed by reorg are not recorded"`
 	}
 	Environment string `env:"ENVIRONMENT" flag:"environment"`
 	Futures     struct {
-		Address              string `env:"FUTURES_ADDRESS" flag:"futures-address" validate:"required,eth_addr"`
-		SubgraphURL          string `env:"FUTURES_SUBGRAPH_URL" flag:"futures-subgraph-url" validate:"required,url"`
-		ValidatorURLOverride string `env:"FUTURES_VALIDATOR_URL_OVERRIDE" flag:"futures-validator-url-override" validate:"omitempty,url"`
+		Address              string        `env:"FUTURES_ADDRESS" flag:"futures-address" validate:"required,eth_addr"`
+		SubgraphURL          string        `env:"FUTURES_SUBGRAPH_URL" flag:"futures-subgraph-url" validate:"required,url"`
+		ValidatorURLOverride string        `env:"FUTURES_VALIDATOR_URL_OVERRIDE" flag:"futures-validator-url-override" validate:"omitempty,url"`
+		SubgraphRetries      int           `env:"FUTURES_SUBGRAPH_RETRIES" flag:"futures-subgraph-retries" validate:"omitempty,gte=0" desc:"number of retries of the failed subgraph request"`
+		SubgraphMaxLag       time.Duration `env:"FUTURES_SUBGRAPH_MAX_LAG" flag:"futures-subgraph-max-lag" validate:"omitempty,duration" desc:"maximum time the subgraph can be behind the chain, otherwise positions are loaded from the chain"`
+		ReadinessLead        time.Duration `env:"FUTURES_READINESS_LEAD" flag:"futures-readiness-lead" validate:"omitempty,duration" desc:"how long before the delivery the seller checks the fleet hashrate and the validator connection"`
+		ReadinessWebhook     string        `env:"FUTURES_READINESS_WEBHOOK" flag:"futures-readiness-webhook" validate:"omitempty,url" desc:"url the readiness alerts are posted to"`
 	}
 	Hashrate struct {
+		Counters                  string        `env:"HASHRATE_COUNTERS"                     flag:"hashrate-counters"                                                    desc:"comma separated list of hashrate counters in format name:type:window, where type is one of ema, sma, window, mean (mean has no window)"`
+		CounterAllocation         string        `env:"HASHRATE_COUNTER_ALLOCATION"           flag:"hashrate-counter-allocation"                                          desc:"name of the hashrate counter used for miner allocation and displayed by default"`
+		CounterBuyer              string        `env:"HASHRATE_COUNTER_BUYER"                flag:"hashrate-counter-buyer"                                               desc:"name of the hashrate counter used for buyer validation"`
 		CycleDuration             time.Duration `env:"HASHRATE_CYCLE_DURATION"               flag:"hashrate-cycle-duration"               validate:"omitempty,duration"  desc:"duration of the hashrate cycle, after which the hashrate is evaluated, applies to both seller and buyer"`
 		ErrorThreshold            float64       `env:"HASHRATE_ERROR_THRESHOLD"              flag:"hashrate-error-threshold"                                             desc:"hashrate relative error threshold for the contract to be considered fulfilling accurately, applies for buyer"`
 		PeerValidationInterval    time.Duration `env:"HASHRATE_PEER_VALIDATION_INTERVAL"     flag:"hashrate-peer-validation-interval"     validate:"omitempty,duration"  desc:"interval between peer validation attempts, applies for validator"`
+		PeerValidationTimeout     time.Duration `env:"HASHRATE_PEER_VALIDATION_TIMEOUT"      flag:"hashrate-peer-validation-timeout"      validate:"omitempty,duration"  desc:"maximum duration of the single peer probe, applies for validator"`
+		PeerValidationFailures    int           `env:"HASHRATE_PEER_VALIDATION_FAILURES"     flag:"hashrate-peer-validation-failures"     validate:"omitempty,gte=1"     desc:"number of consecutive failed probes of the peer after which the complaint is submitted, applies for validator"`
+		PeerValidationEvidence    string        `env:"HASHRATE_PEER_VALIDATION_EVIDENCE"     flag:"hashrate-peer-validation-evidence"                                    desc:"json lines file to record the peer probes, applies for validator"`
+		ValidationAttestations    string        `env:"HASHRATE_VALIDATION_ATTESTATIONS"      flag:"hashrate-validation-attestations"                                     desc:"directory the signed delivery reports of the validated futures positions are saved to, applies for validator"`
 		ShareTimeout              time.Duration `env:"HASHRATE_SHARE_TIMEOUT"                flag:"hashrate-share-timeout"                validate:"omitempty,duration"  desc:"time to wait for the share to arrive, otherwise close contract, applies for buyer"`
 		ValidatorFlatness         time.Duration `env:"HASHRATE_VALIDATION_FLATNESS"          flag:"hashrate-validation-flatness"          validate:"omitempty,duration"  desc:"artificial parameter of validation function, applies for buyer"`
 		ValidationTimeoutAppStart time.Duration `env:"HASHRATE_VALIDATION_TIMEOUT_APP_START" flag:"hashrate-validation-timeout-app-start" validate:"omitempty,duration"  desc:"disables validation of the incoming hashrate for specified amount of time right after application startup"`
 		ValidationAutoClaimReward bool          `env:"HASHRATE_VALIDATION_AUTO_CLAIM_REWARD" flag:"hashrate-validation-auto-claim-reward" validate:"omitempty"           desc:"automatically claim reward if the hashrate is validated successfully"`
+		WorkerIdleTimeout         time.Duration `env:"HASHRATE_WORKER_IDLE_TIMEOUT"          flag:"hashrate-worker-idle-timeout"          validate:"omitempty,duration"  desc:"worker is considered idle if there were no shares or connections within this duration"`
+		WorkerTTL                 time.Duration `env:"HASHRATE_WORKER_TTL"                   flag:"hashrate-worker-ttl"                   validate:"omitempty,duration"  desc:"hashrate counters of the worker are evicted from memory if there were no shares or connections within this duration, the hashrate history with zero hashrate for this duration is evicted too"`
+		ValidationMode            string        `env:"HASHRATE_VALIDATION_MODE"              flag:"hashrate-validation-mode"              validate:"omitempty,oneof=flatness confidence rolling" desc:"buyer validation mode: 'flatness' compares relative error against the flatness curve, 'confidence' uses share count confidence interval, 'rolling' compares the hashrate over the last window against the error threshold"`
+		ValidationFalsePositive   float64       `env:"HASHRATE_VALIDATION_FALSE_POSITIVE"    flag:"hashrate-validation-false-positive"    validate:"omitempty,gt=0,lt=1" desc:"acceptable probability of false underdelivery verdict, applies for buyer in 'confidence' validation mode"`
+		ValidationWindow          time.Duration `env:"HASHRATE_VALIDATION_WINDOW"            flag:"hashrate-validation-window"            validate:"omitempty,duration"  desc:"averaging window of the hashrate, applies for buyer in 'rolling' validation mode"`
+		ValidationDestGrace       time.Duration `env:"HASHRATE_VALIDATION_DEST_CHANGE_GRACE" flag:"hashrate-validation-dest-change-grace" validate:"omitempty,duration"  desc:"failing validation is ignored for this duration after the contract destination change, applies for buyer"`
+		ValidationOutageTolerance time.Duration `env:"HASHRATE_VALIDATION_OUTAGE_TOLERANCE"  flag:"hashrate-validation-outage-tolerance"  validate:"omitempty,duration"  desc:"contract is closed only if the validation keeps failing for this duration, applies for buyer"`
+		ValidationPolicies        string        `env:"HASHRATE_VALIDATION_POLICIES"          flag:"hashrate-validation-policies"                                         desc:"validation rules of specific contracts in format contractID:[mode][,key=value...] separated by semicolon, contractID is the contract address or the futures position id, keys are threshold, flatness, falsePositive, shareTimeout, window, grace, tolerance"`
 	}
 	Marketplace struct {
 		CloneFactoryAddress      string `env:"CLONE_FACTORY_ADDRESS" flag:"contract-address"   validate:"required_if=Disable false,omitempty,eth_addr"`
 		ValidatorRegistryAddress string `env:"VALIDATOR_REGISTRY_ADDRESS" flag:"validator-registry-address" validate:"omitempty,eth_addr"`
-		Mnemonic                 string `env:"CONTRACT_MNEMONIC"     flag:"contract-mnemonic"  validate:"required_without=WalletPrivateKey|required_if=Disable false"`
-		WalletPrivateKey         string `env:"WALLET_PRIVATE_KEY"    flag:"wallet-private-key" validate:"required_without=Mnemonic|required_if=Disable false"`
+		Mnemonic                 string `env:"CONTRACT_MNEMONIC"     flag:"contract-mnemonic"  validate:"required_without_all=WalletPrivateKey KeystorePath RemoteSignerURL|required_if=Disable false"`
+		WalletPrivateKey         string `env:"WALLET_PRIVATE_KEY"    flag:"wallet-private-key" validate:"required_without_all=Mnemonic KeystorePath RemoteSignerURL|required_if=Disable false"`
+		KeystorePath             string `env:"WALLET_KEYSTORE_PATH" flag:"wallet-keystore-path" desc:"go-ethereum encrypted keystore json file of the wallet, used if the private key is not set"`
+		LedgerPath               string `env:"LEDGER_PATH" flag:"ledger-path" desc:"file to record contract starts, closeouts, reward claims and validator fees of the wallet"`
+		KeystorePasswordFile     string `env:"WALLET_KEYSTORE_PASSWORD_FILE" flag:"wallet-keystore-password-file" desc:"file with the keystore passphrase, if not set the passphrase is prompted on startup"`
+		RemoteSignerURL          string `env:"WALLET_REMOTE_SIGNER_URL" flag:"wallet-remote-signer-url" validate:"omitempty,url" desc:"url of the external signer supporting eth_signTransaction (e.g. clef), used if neither private key nor keystore is set. Requires WALLET_DECRYPTION_KEY to decrypt contract destinations"`
+		RemoteSignerAddress      string `env:"WALLET_REMOTE_SIGNER_ADDRESS" flag:"wallet-remote-signer-address" validate:"omitempty,eth_addr" desc:"address of the remote signer account, the first account of the signer is used if not set"`
+		DecryptionKey            string `env:"WALLET_DECRYPTION_KEY" flag:"wallet-decryption-key" desc:"private key decrypting the contract destinations in the remote signer mode, the destinations must be encrypted with its public key"`
 	}
 	Miner struct {
 		NotPropagateWorkerName bool          `env:"MINER_NOT_PROPAGATE_WORKER_NAME" flag:"miner-not-propagate-worker-name"     validate:""                      desc:"not preserve worker name from the source in the destination pool. Preserving works only if the source miner worker name is defined as 'accountName.workerName'. Does not apply for contracts"`
@@ -70,8 +131,27 @@ type Config struct {
 		IdleWriteTimeout time.Duration `env:"POOL_IDLE_WRITE_TIMEOUT" flag:"pool-idle-write-timeout" validate:"duration" desc:"if there are no writes for this duration, the connection is going to be closed"`
 	}
 	Proxy struct {
-		Address        string `env:"PROXY_ADDRESS" flag:"proxy-address" validate:"required,hostname_port"`
-		MaxCachedDests int    `env:"PROXY_MAX_CACHED_DESTS" flag:"proxy-max-cached-dests" validate:"required,number" desc:"maximum number of cached destinations per proxy"`
+		Address            string `env:"PROXY_ADDRESS" flag:"proxy-address" validate:"required,hostname_port"`
+		Algorithm          string `env:"PROXY_ALGORITHM" flag:"proxy-algorithm" validate:"omitempty,oneof=sha256 scrypt" desc:"proof of work algorithm of the miners connected to PROXY_ADDRESS and the default algorithm of the sold contracts"`
+		Listeners          string `env:"PROXY_LISTENERS" flag:"proxy-listeners" desc:"additional listeners for the miners of other algorithms in format address:algorithm separated by comma, e.g. 0.0.0.0:3334:scrypt"`
+		ContractAlgorithms string `env:"PROXY_CONTRACT_ALGORITHMS" flag:"proxy-contract-algorithms" desc:"algorithm of the specific sold contracts in format contractID:algorithm separated by comma, only the miners of this algorithm are allocated to the contract"`
+		MaxCachedDests     int    `env:"PROXY_MAX_CACHED_DESTS" flag:"proxy-max-cached-dests" validate:"required,number" desc:"maximum number of cached destinations per proxy"`
+	}
+	Seller struct {
+		AutoListEnable      bool          `env:"SELLER_AUTOLIST_ENABLE" flag:"seller-autolist-enable" desc:"create, delist and reprice the seller contracts on the clonefactory to match the fleet capacity"`
+		AutoListDryRun      bool          `env:"SELLER_AUTOLIST_DRY_RUN" flag:"seller-autolist-dry-run" desc:"only estimate the auto-listing transactions, nothing is sent to the chain"`
+		AutoListInterval    time.Duration `env:"SELLER_AUTOLIST_INTERVAL" flag:"seller-autolist-interval" validate:"omitempty,duration" desc:"interval between auto-listing runs"`
+		HashpriceSource     string        `env:"SELLER_AUTOLIST_HASHPRICE_SOURCE" flag:"seller-autolist-hashprice-source" validate:"required_if=AutoListEnable true" desc:"file path or http url of the hashprice json with pricePerPHDay (in payment token units) and updatedAt fields"`
+		HashpriceMaxAge     time.Duration `env:"SELLER_AUTOLIST_HASHPRICE_MAX_AGE" flag:"seller-autolist-hashprice-max-age" validate:"omitempty,duration" desc:"auto-listing is skipped if the hashprice is older than this"`
+		ContractHashrateGHS float64       `env:"SELLER_AUTOLIST_CONTRACT_HASHRATE_GHS" flag:"seller-autolist-contract-hashrate-ghs" validate:"omitempty,gt=0" desc:"hashrate of the single listed contract in GH/s"`
+		ContractDuration    time.Duration `env:"SELLER_AUTOLIST_CONTRACT_DURATION" flag:"seller-autolist-contract-duration" validate:"omitempty,duration" desc:"duration of the listed contract"`
+		ReserveGHS          float64       `env:"SELLER_AUTOLIST_RESERVE_GHS" flag:"seller-autolist-reserve-ghs" validate:"omitempty,gte=0" desc:"fleet hashrate in GH/s that is never listed"`
+		MarkupPercent       float64       `env:"SELLER_AUTOLIST_MARKUP" flag:"seller-autolist-markup" desc:"contract price over the hashprice in percents, can be negative"`
+		MinProfitTarget     int           `env:"SELLER_AUTOLIST_MIN_PROFIT_TARGET" flag:"seller-autolist-min-profit-target" validate:"omitempty,gte=-100,lte=127" desc:"lower bound of the profit target set on the contract"`
+		MaxProfitTarget     int           `env:"SELLER_AUTOLIST_MAX_PROFIT_TARGET" flag:"seller-autolist-max-profit-target" validate:"omitempty,gte=-100,lte=127" desc:"upper bound of the profit target set on the contract"`
+		MaxContracts        int           `env:"SELLER_AUTOLIST_MAX_CONTRACTS" flag:"seller-autolist-max-contracts" validate:"omitempty,gte=0" desc:"maximum number of the listed contracts"`
+		MaxChangesPerRun    int           `env:"SELLER_AUTOLIST_MAX_CHANGES" flag:"seller-autolist-max-changes" validate:"omitempty,gte=1" desc:"maximum number of the clonefactory transactions per run"`
+		PubKey              string        `env:"SELLER_AUTOLIST_PUBKEY" flag:"seller-autolist-pubkey" desc:"public key of the new contracts, taken from the existing contracts of the wallet if not set"`
 	}
 	System struct {
 		Enable           bool   `env:"SYS_ENABLE"              flag:"sys-enable" desc:"enable system level configuration adjustments"`
@@ -85,6 +165,7 @@ type Config struct {
 	Web struct {
 		Address   string `env:"WEB_ADDRESS"    flag:"web-address"    validate:"required,hostname_port" desc:"http server address host:port"`
 		PublicUrl string `env:"WEB_PUBLIC_URL" flag:"web-public-url" validate:"omitempty,url"          desc:"public url of the proxyrouter, falls back to web-address if empty" `
+		APIToken  string `env:"WEB_API_TOKEN"  flag:"web-api-token"                                     desc:"bearer token required by the endpoints sending transactions, without it they are available only from localhost"`
 	}
 }
 
@@ -94,18 +175,105 @@ func (cfg *Config) SetDefaults() {
 	}
 
 	// Blockchain
+	if cfg.Blockchain.EthNodeQuorum == 0 {
+		cfg.Blockchain.EthNodeQuorum = 1
+	}
+	if cfg.Blockchain.EthNodeHedge == 0 {
+		cfg.Blockchain.EthNodeHedge = 3 * time.Second
+	}
+	if cfg.Blockchain.EthNodeHealth == 0 {
+		cfg.Blockchain.EthNodeHealth = 30 * time.Second
+	}
+	if cfg.Blockchain.EthNodeMaxLag == 0 {
+		cfg.Blockchain.EthNodeMaxLag = 20
+	}
 	if cfg.Blockchain.MaxReconnects == 0 {
 		cfg.Blockchain.MaxReconnects = 30
 	}
 	if cfg.Blockchain.PollingInterval == 0 {
 		cfg.Blockchain.PollingInterval = 10 * time.Second
 	}
+	if cfg.Blockchain.MulticallBatch == 0 {
+		cfg.Blockchain.MulticallBatch = 100
+	}
+	if cfg.Blockchain.MulticallWorkers == 0 {
+		cfg.Blockchain.MulticallWorkers = 4
+	}
+	if cfg.Blockchain.CheckpointPath == "" {
+		cfg.Blockchain.CheckpointPath = "data/log-checkpoints.json"
+	}
 	if len(cfg.Blockchain.MulticallAddress) == 0 {
 		cfg.Blockchain.MulticallAddress = multicall.MULTICALL3_ADDR.Hex()
 	}
+	if cfg.Blockchain.TxQueuePath == "" {
+		cfg.Blockchain.TxQueuePath = "data/tx-queue.json"
+	}
+	if cfg.Blockchain.TxBumpInterval == 0 {
+		cfg.Blockchain.TxBumpInterval = time.Minute
+	}
+	if cfg.Blockchain.TxBumpPercent == 0 {
+		cfg.Blockchain.TxBumpPercent = 20
+	}
+	if cfg.Blockchain.TxMaxBumps == 0 {
+		cfg.Blockchain.TxMaxBumps = 5
+	}
+	if cfg.Blockchain.TxMaxRetries == 0 {
+		cfg.Blockchain.TxMaxRetries = 3
+	}
+
+	// Buyer
+
+	if cfg.Buyer.AutoBuyInterval == 0 {
+		cfg.Buyer.AutoBuyInterval = 5 * time.Minute
+	}
+	if cfg.Buyer.MaxSellerFailure == 0 {
+		cfg.Buyer.MaxSellerFailure = 0.25
+	}
+	if cfg.Buyer.BudgetPeriod == 0 {
+		cfg.Buyer.BudgetPeriod = 24 * time.Hour
+	}
+	if cfg.Buyer.MaxPurchasesPerRun == 0 {
+		cfg.Buyer.MaxPurchasesPerRun = 1
+	}
+	if cfg.Buyer.DestRoutingMode == "" {
+		cfg.Buyer.DestRoutingMode = "weight"
+	}
+	if cfg.Buyer.ReputationPath == "" {
+		cfg.Buyer.ReputationPath = "data/reputation.json"
+	}
+	if cfg.Buyer.ReputationSync == 0 {
+		cfg.Buyer.ReputationSync = time.Hour
+	}
+	if cfg.Buyer.ReputationHistory == 0 {
+		cfg.Buyer.ReputationHistory = 2_000_000
+	}
+	if cfg.Buyer.ReputationConfirmations == 0 {
+		cfg.Buyer.ReputationConfirmations = 64
+	}
+
+	// Futures
+
+	if cfg.Futures.SubgraphRetries == 0 {
+		cfg.Futures.SubgraphRetries = 3
+	}
+	if cfg.Futures.SubgraphMaxLag == 0 {
+		cfg.Futures.SubgraphMaxLag = 5 * time.Minute
+	}
+	if cfg.Futures.ReadinessLead == 0 {
+		cfg.Futures.ReadinessLead = time.Hour
+	}
 
 	// Hashrate
 
+	if cfg.Hashrate.Counters == "" {
+		cfg.Hashrate.Counters = "ema--5m:ema:5m,ema-10m:ema:10m,ema-30m:ema:30m,mean:mean"
+	}
+	if cfg.Hashrate.CounterAllocation == "" {
+		cfg.Hashrate.CounterAllocation = "ema--5m"
+	}
+	if cfg.Hashrate.CounterBuyer == "" {
+		cfg.Hashrate.CounterBuyer = "mean"
+	}
 	if cfg.Hashrate.CycleDuration == 0 {
 		cfg.Hashrate.CycleDuration = 5 * time.Minute
 	}
@@ -118,9 +286,36 @@ func (cfg *Config) SetDefaults() {
 	if cfg.Hashrate.ValidatorFlatness == 0 {
 		cfg.Hashrate.ValidatorFlatness = 20 * time.Minute
 	}
+	if cfg.Hashrate.WorkerIdleTimeout == 0 {
+		cfg.Hashrate.WorkerIdleTimeout = 10 * time.Minute
+	}
+	if cfg.Hashrate.WorkerTTL == 0 {
+		cfg.Hashrate.WorkerTTL = 24 * time.Hour
+	}
+	if cfg.Hashrate.ValidationMode == "" {
+		cfg.Hashrate.ValidationMode = "flatness"
+	}
+	if cfg.Hashrate.ValidationFalsePositive == 0 {
+		cfg.Hashrate.ValidationFalsePositive = 0.01
+	}
+	if cfg.Hashrate.ValidationWindow == 0 {
+		cfg.Hashrate.ValidationWindow = 30 * time.Minute
+	}
 	if cfg.Hashrate.PeerValidationInterval == 0 {
 		cfg.Hashrate.PeerValidationInterval = 5 * time.Minute
 	}
+	if cfg.Hashrate.PeerValidationTimeout == 0 {
+		cfg.Hashrate.PeerValidationTimeout = time.Minute
+	}
+	if cfg.Hashrate.PeerValidationFailures == 0 {
+		cfg.Hashrate.PeerValidationFailures = 3
+	}
+	if cfg.Hashrate.PeerValidationEvidence == "" {
+		cfg.Hashrate.PeerValidationEvidence = "data/peer-validation-evidence.jsonl"
+	}
+	if cfg.Hashrate.ValidationAttestations == "" {
+		cfg.Hashrate.ValidationAttestations = "data/attestations"
+	}
 
 	// If validator is down, the next attempt to connect is going to be performed after "CycleDuration".
 	// So simplest fix to avoid closeout due to no share is to delay starting validation when application has
@@ -136,6 +331,11 @@ func (cfg *Config) SetDefaults() {
 	// normalizes private key
 	// TODO: convert and validate to ecies.PrivateKey
 	cfg.Marketplace.WalletPrivateKey = strings.TrimPrefix(cfg.Marketplace.WalletPrivateKey, "0x")
+	cfg.Marketplace.DecryptionKey = strings.TrimPrefix(cfg.Marketplace.DecryptionKey, "0x")
+
+	if cfg.Marketplace.LedgerPath == "" {
+		cfg.Marketplace.LedgerPath = "data/ledger.jsonl"
+	}
 
 	// Miner
 
@@ -174,6 +374,9 @@ func (cfg *Config) SetDefaults() {
 	}
 
 	// Proxy
+	if cfg.Proxy.Algorithm == "" {
+		cfg.Proxy.Algorithm = "sha256"
+	}
 	if cfg.Proxy.MaxCachedDests == 0 {
 		cfg.Proxy.MaxCachedDests = 5
 	}
@@ -212,6 +415,31 @@ func (cfg *Config) SetDefaults() {
 	if cfg.Proxy.Address == "" {
 		cfg.Proxy.Address = "0.0.0.0:3333"
 	}
+
+	// Seller
+
+	if cfg.Seller.AutoListInterval == 0 {
+		cfg.Seller.AutoListInterval = 10 * time.Minute
+	}
+	if cfg.Seller.HashpriceMaxAge == 0 {
+		cfg.Seller.HashpriceMaxAge = 2 * time.Hour
+	}
+	if cfg.Seller.ContractHashrateGHS == 0 {
+		cfg.Seller.ContractHashrateGHS = 100_000
+	}
+	if cfg.Seller.ContractDuration == 0 {
+		cfg.Seller.ContractDuration = 24 * time.Hour
+	}
+	if cfg.Seller.MaxProfitTarget == 0 {
+		cfg.Seller.MaxProfitTarget = 100
+	}
+	if cfg.Seller.MaxContracts == 0 {
+		cfg.Seller.MaxContracts = 10
+	}
+	if cfg.Seller.MaxChangesPerRun == 0 {
+		cfg.Seller.MaxChangesPerRun = 3
+	}
+
 	if cfg.Web.Address == "" {
 		cfg.Web.Address = "0.0.0.0:8080"
 	}
@@ -226,16 +454,66 @@ func (cfg *Config) GetSanitized() interface{} {
 	publicCfg := Config{}
 
 	publicCfg.Blockchain.EthLegacyTx = cfg.Blockchain.EthLegacyTx
+	publicCfg.Blockchain.EthNodeQuorum = cfg.Blockchain.EthNodeQuorum
+	publicCfg.Blockchain.EthNodeHedge = cfg.Blockchain.EthNodeHedge
+	publicCfg.Blockchain.EthNodeHealth = cfg.Blockchain.EthNodeHealth
+	publicCfg.Blockchain.EthNodeMaxLag = cfg.Blockchain.EthNodeMaxLag
+	publicCfg.Blockchain.Confirmations = cfg.Blockchain.Confirmations
+	publicCfg.Blockchain.MulticallBatch = cfg.Blockchain.MulticallBatch
+	publicCfg.Blockchain.MulticallWorkers = cfg.Blockchain.MulticallWorkers
+	publicCfg.Blockchain.CheckpointPath = cfg.Blockchain.CheckpointPath
+	publicCfg.Blockchain.TxQueuePath = cfg.Blockchain.TxQueuePath
+	publicCfg.Blockchain.TxBumpInterval = cfg.Blockchain.TxBumpInterval
+	publicCfg.Blockchain.TxBumpPercent = cfg.Blockchain.TxBumpPercent
+	publicCfg.Blockchain.TxMaxBumps = cfg.Blockchain.TxMaxBumps
+	publicCfg.Blockchain.TxMaxRetries = cfg.Blockchain.TxMaxRetries
 	publicCfg.Environment = cfg.Environment
 
+	publicCfg.Buyer.AutoBuyEnable = cfg.Buyer.AutoBuyEnable
+	publicCfg.Buyer.AutoBuyDryRun = cfg.Buyer.AutoBuyDryRun
+	publicCfg.Buyer.AutoBuyInterval = cfg.Buyer.AutoBuyInterval
+	publicCfg.Buyer.MaxPricePerTHDay = cfg.Buyer.MaxPricePerTHDay
+	publicCfg.Buyer.MinDuration = cfg.Buyer.MinDuration
+	publicCfg.Buyer.MaxDuration = cfg.Buyer.MaxDuration
+	publicCfg.Buyer.MaxSellerFailure = cfg.Buyer.MaxSellerFailure
+	publicCfg.Buyer.Budget = cfg.Buyer.Budget
+	publicCfg.Buyer.BudgetPeriod = cfg.Buyer.BudgetPeriod
+	publicCfg.Buyer.MaxPurchasesPerRun = cfg.Buyer.MaxPurchasesPerRun
+	publicCfg.Buyer.ValidatorURL = cfg.Buyer.ValidatorURL
+	publicCfg.Buyer.DestRoutingMode = cfg.Buyer.DestRoutingMode
+	publicCfg.Buyer.ReputationEnable = cfg.Buyer.ReputationEnable
+	publicCfg.Buyer.ReputationPath = cfg.Buyer.ReputationPath
+	publicCfg.Buyer.ReputationSync = cfg.Buyer.ReputationSync
+	publicCfg.Buyer.ReputationHistory = cfg.Buyer.ReputationHistory
+	publicCfg.Buyer.ReputationConfirmations = cfg.Buyer.ReputationConfirmations
+
+	publicCfg.Hashrate.Counters = cfg.Hashrate.Counters
+	publicCfg.Hashrate.CounterAllocation = cfg.Hashrate.CounterAllocation
+	publicCfg.Hashrate.CounterBuyer = cfg.Hashrate.CounterBuyer
 	publicCfg.Hashrate.CycleDuration = cfg.Hashrate.CycleDuration
 	publicCfg.Hashrate.ErrorThreshold = cfg.Hashrate.ErrorThreshold
+	publicCfg.Hashrate.PeerValidationInterval = cfg.Hashrate.PeerValidationInterval
+	publicCfg.Hashrate.PeerValidationTimeout = cfg.Hashrate.PeerValidationTimeout
+	publicCfg.Hashrate.PeerValidationFailures = cfg.Hashrate.PeerValidationFailures
+	publicCfg.Hashrate.PeerValidationEvidence = cfg.Hashrate.PeerValidationEvidence
+	publicCfg.Hashrate.ValidationAttestations = cfg.Hashrate.ValidationAttestations
 	publicCfg.Hashrate.ShareTimeout = cfg.Hashrate.ShareTimeout
 	publicCfg.Hashrate.ValidatorFlatness = cfg.Hashrate.ValidatorFlatness
 	publicCfg.Hashrate.ValidationTimeoutAppStart = cfg.Hashrate.ValidationTimeoutAppStart
+	publicCfg.Hashrate.WorkerIdleTimeout = cfg.Hashrate.WorkerIdleTimeout
+	publicCfg.Hashrate.WorkerTTL = cfg.Hashrate.WorkerTTL
+	publicCfg.Hashrate.ValidationMode = cfg.Hashrate.ValidationMode
+	publicCfg.Hashrate.ValidationFalsePositive = cfg.Hashrate.ValidationFalsePositive
+	publicCfg.Hashrate.ValidationWindow = cfg.Hashrate.ValidationWindow
+	publicCfg.Hashrate.ValidationDestGrace = cfg.Hashrate.ValidationDestGrace
+	publicCfg.Hashrate.ValidationOutageTolerance = cfg.Hashrate.ValidationOutageTolerance
+	publicCfg.Hashrate.ValidationPolicies = cfg.Hashrate.ValidationPolicies
 
 	publicCfg.Marketplace.CloneFactoryAddress = cfg.Marketplace.CloneFactoryAddress
 	publicCfg.Marketplace.ValidatorRegistryAddress = cfg.Marketplace.ValidatorRegistryAddress
+	publicCfg.Marketplace.KeystorePath = cfg.Marketplace.KeystorePath
+	publicCfg.Marketplace.LedgerPath = cfg.Marketplace.LedgerPath
+	publicCfg.Marketplace.RemoteSignerAddress = cfg.Marketplace.RemoteSignerAddress
 
 	publicCfg.Miner.NotPropagateWorkerName = cfg.Miner.NotPropagateWorkerName
 	publicCfg.Miner.IdleReadTimeout = cfg.Miner.IdleReadTimeout
@@ -254,8 +532,24 @@ func (cfg *Config) GetSanitized() interface{} {
 	publicCfg.Pool.IdleWriteTimeout = cfg.Pool.IdleWriteTimeout
 
 	publicCfg.Proxy.Address = cfg.Proxy.Address
+	publicCfg.Proxy.Algorithm = cfg.Proxy.Algorithm
+	publicCfg.Proxy.Listeners = cfg.Proxy.Listeners
+	publicCfg.Proxy.ContractAlgorithms = cfg.Proxy.ContractAlgorithms
 	publicCfg.Proxy.MaxCachedDests = cfg.Proxy.MaxCachedDests
 
+	publicCfg.Seller.AutoListEnable = cfg.Seller.AutoListEnable
+	publicCfg.Seller.AutoListDryRun = cfg.Seller.AutoListDryRun
+	publicCfg.Seller.AutoListInterval = cfg.Seller.AutoListInterval
+	publicCfg.Seller.HashpriceMaxAge = cfg.Seller.HashpriceMaxAge
+	publicCfg.Seller.ContractHashrateGHS = cfg.Seller.ContractHashrateGHS
+	publicCfg.Seller.ContractDuration = cfg.Seller.ContractDuration
+	publicCfg.Seller.ReserveGHS = cfg.Seller.ReserveGHS
+	publicCfg.Seller.MarkupPercent = cfg.Seller.MarkupPercent
+	publicCfg.Seller.MinProfitTarget = cfg.Seller.MinProfitTarget
+	publicCfg.Seller.MaxProfitTarget = cfg.Seller.MaxProfitTarget
+	publicCfg.Seller.MaxContracts = cfg.Seller.MaxContracts
+	publicCfg.Seller.MaxChangesPerRun = cfg.Seller.MaxChangesPerRun
+
 	publicCfg.System.Enable = cfg.System.Enable
 	publicCfg.System.LocalPortRange = cfg.System.LocalPortRange
 	publicCfg.System.NetdevMaxBacklog = cfg.System.NetdevMaxBacklog
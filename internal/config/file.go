@@ -0,0 +1,127 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/omeid/uconfig/flat"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FlagConfigFile = "config"
+	EnvConfigFile  = "CONFIG_FILE"
+)
+
+var (
+	ErrConfigFileRead  = errors.New("cannot read config file")
+	ErrConfigFileParse = errors.New("cannot parse config file")
+)
+
+// readConfigFile reads the yaml or toml config file, the keys of the file are the env variable names
+// of the config, e.g. ETH_NODE_ADDRESS, the values are returned in the env variable format
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, lib.WrapError(ErrConfigFileRead, err)
+	}
+
+	raw := make(map[string]any)
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, lib.WrapError(ErrConfigFileRead, fmt.Errorf("unsupported extension %s, expected .yaml, .yml or .toml", ext))
+	}
+	if err != nil {
+		return nil, lib.WrapError(ErrConfigFileParse, err)
+	}
+
+	known, err := knownEnvNames()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, v := range raw {
+		if !known[key] {
+			return nil, lib.WrapError(ErrConfigFileParse, fmt.Errorf("unknown key %s", key))
+		}
+		value, err := fileValue(v)
+		if err != nil {
+			return nil, lib.WrapError(ErrConfigFileParse, fmt.Errorf("%s: %w", key, err))
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// knownEnvNames returns the env variable names of the router config, the commands load parts of the router
+// config, so the same file is accepted by all of them
+func knownEnvNames() (map[string]bool, error) {
+	fields, err := flat.View(&Config{})
+	if err != nil {
+		return nil, lib.WrapError(ErrConfigInvalid, err)
+	}
+	known := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if envName, ok := field.Tag(TagEnv); ok {
+			known[envName] = true
+		}
+	}
+	return known, nil
+}
+
+// fileValue converts the scalar or the list of scalars to the env variable format, lists are comma separated
+func fileValue(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339), nil
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := fileValue(item)
+			if err != nil {
+				return "", err
+			}
+			if strings.Contains(s, ",") {
+				return "", fmt.Errorf("list item %q contains comma", s)
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+// configFilePath returns the value of the config flag, both -config and --config forms are accepted
+func configFilePath(args []string) string {
+	for i, arg := range args {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != FlagConfigFile {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
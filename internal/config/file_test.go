@@ -0,0 +1,119 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	Hashrate struct {
+		Counters      string        `env:"HASHRATE_COUNTERS"`
+		CycleDuration time.Duration `env:"HASHRATE_CYCLE_DURATION" validate:"omitempty,duration"`
+	}
+	Log struct {
+		Color    bool   `env:"LOG_COLOR"     flag:"log-color"`
+		LevelApp string `env:"LOG_LEVEL_APP" flag:"log-level-app" validate:"omitempty,oneof=debug info warn error"`
+	}
+	Proxy struct {
+		Address string `env:"PROXY_ADDRESS" flag:"proxy-address" validate:"required,hostname_port"`
+	}
+}
+
+func (cfg *testConfig) SetDefaults() {
+	if cfg.Hashrate.CycleDuration == 0 {
+		cfg.Hashrate.CycleDuration = time.Minute
+	}
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadConfigFilePrecedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+PROXY_ADDRESS: 0.0.0.0:3333
+LOG_LEVEL_APP: debug
+LOG_COLOR: true
+HASHRATE_COUNTERS: [ema-5m:ema:5m, mean:mean]
+`)
+	t.Setenv("LOG_LEVEL_APP", "warn")
+
+	var cfg testConfig
+	sources, err := LoadConfigWithSources(&cfg, &[]string{"proxy-router", "--config", path, "--proxy-address=0.0.0.0:4444"})
+	require.NoError(t, err)
+
+	require.Equal(t, "0.0.0.0:4444", cfg.Proxy.Address)
+	require.Equal(t, "warn", cfg.Log.LevelApp)
+	require.True(t, cfg.Log.Color)
+	require.Equal(t, "ema-5m:ema:5m,mean:mean", cfg.Hashrate.Counters)
+	require.Equal(t, time.Minute, cfg.Hashrate.CycleDuration)
+
+	require.Equal(t, SourceFlag, sources["PROXY_ADDRESS"])
+	require.Equal(t, SourceEnv, sources["LOG_LEVEL_APP"])
+	require.Equal(t, SourceFile, sources["LOG_COLOR"])
+	require.Equal(t, SourceFile, sources["HASHRATE_COUNTERS"])
+	require.Equal(t, SourceDefault, sources["HASHRATE_CYCLE_DURATION"])
+}
+
+func TestLoadConfigFileTOML(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+PROXY_ADDRESS = "0.0.0.0:3333"
+HASHRATE_CYCLE_DURATION = "5m"
+`)
+	t.Setenv(EnvConfigFile, path)
+
+	var cfg testConfig
+	sources, err := LoadConfigWithSources(&cfg, &[]string{"proxy-router"})
+	require.NoError(t, err)
+	require.Equal(t, "0.0.0.0:3333", cfg.Proxy.Address)
+	require.Equal(t, 5*time.Minute, cfg.Hashrate.CycleDuration)
+	require.Equal(t, SourceFile, sources["HASHRATE_CYCLE_DURATION"])
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		err     error
+	}{
+		{"unknown key", "config.yaml", "PROXY_ADDRES: 0.0.0.0:3333\n", ErrConfigFileParse},
+		{"invalid value", "config.yaml", "PROXY_ADDRESS: 0.0.0.0:3333\nHASHRATE_CYCLE_DURATION: 5 minutes\n", ErrConfigFileParse},
+		{"nested value", "config.yaml", "PROXY_ADDRESS:\n  host: 0.0.0.0\n", ErrConfigFileParse},
+		{"validation", "config.yaml", "PROXY_ADDRESS: 0.0.0.0:3333\nLOG_LEVEL_APP: verbose\n", ErrConfigValidation},
+		{"unsupported format", "config.json", "{}", ErrConfigFileRead},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, tt.file, tt.content)
+			var cfg testConfig
+			err := LoadConfig(&cfg, &[]string{"proxy-router", "-config=" + path})
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestEffective(t *testing.T) {
+	var cfg Config
+	cfg.Proxy.Address = "0.0.0.0:3333"
+	cfg.Marketplace.WalletPrivateKey = "secret"
+
+	entries, err := Effective(&cfg, Sources{"PROXY_ADDRESS": SourceFile})
+	require.NoError(t, err)
+
+	byName := make(map[string]Entry, len(entries))
+	for _, e := range entries {
+		byName[e.Name] = e
+	}
+	require.Equal(t, Entry{Name: "PROXY_ADDRESS", Flag: "proxy-address", Value: "0.0.0.0:3333", Source: SourceFile}, byName["PROXY_ADDRESS"])
+
+	secret := byName["WALLET_PRIVATE_KEY"]
+	require.True(t, secret.Hidden)
+	require.Empty(t, secret.Value)
+}
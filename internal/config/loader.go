@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"reflect"
 	"strings"
 
 	"github.com/Lumerin-protocol/proxy-router/internal/lib"
@@ -33,19 +34,59 @@ var (
 )
 
 func LoadConfig(cfg ConfigInterface, osArgs *[]string) error {
+	_, err := LoadConfigWithSources(cfg, osArgs)
+	return err
+}
+
+// LoadConfigWithSources loads the config from the config file, .env file, env variables and flags,
+// the later override the earlier ones, and returns the source of each value
+func LoadConfigWithSources(cfg ConfigInterface, osArgs *[]string) (Sources, error) {
+	// variables set before loading .env file are not overridden by it
+	preset := make(map[string]bool)
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		preset[name] = true
+	}
+
 	err := godotenv.Load(".env")
 	if err != nil {
 		fmt.Println(lib.WrapError(ErrEnvLoad, err))
 	}
 
+	var args []string
+	if osArgs != nil {
+		args = *osArgs
+	} else {
+		// if flargs not provided use global os.Args
+		args = os.Args
+	}
+
+	// skipping program name
+	args = args[1:]
+
+	var fileValues map[string]string
+	configPath := configFilePath(args)
+	if configPath == "" {
+		configPath = os.Getenv(EnvConfigFile)
+	}
+	if configPath != "" {
+		fileValues, err = readConfigFile(configPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// recursively iterates over each field of the nested struct
 	fields, err := flat.View(cfg)
 	if err != nil {
-		return lib.WrapError(ErrConfigInvalid, err)
+		return nil, lib.WrapError(ErrConfigInvalid, err)
 	}
 
+	sources := make(Sources)
 	flagset := flag.NewFlagSet("", flag.ContinueOnError)
 	flagset.Usage = func() {}
+	flagset.String(FlagConfigFile, "", "yaml or toml config file, the keys are the env variable names")
+	flagEnvNames := make(map[string]string)
 
 	for _, field := range fields {
 		envName, ok := field.Tag(TagEnv)
@@ -53,12 +94,31 @@ func LoadConfig(cfg ConfigInterface, osArgs *[]string) error {
 			continue
 		}
 
+		// env variables override config file values, empty variable is the same as not set
 		envValue := os.Getenv(envName)
-		_ = field.Set(envValue)
-		// if err != nil {
-		// TODO: set default value on error
-		// 	return lib.WrapError(ErrEnvParse, fmt.Errorf("%s: %w", envName, err))
-		// }
+		fileValue, inFile := fileValues[envName]
+		switch {
+		case envValue != "" && preset[envName]:
+			sources[envName] = SourceEnv
+		case envValue != "":
+			sources[envName] = SourceDotEnv
+		case inFile:
+			sources[envName] = SourceFile
+		}
+
+		if sources[envName] == SourceFile {
+			// unlike env variables the invalid values of the file are reported
+			err = field.Set(fileValue)
+			if err != nil {
+				return nil, lib.WrapError(ErrConfigFileParse, fmt.Errorf("%s: %w", envName, err))
+			}
+		} else {
+			_ = field.Set(envValue)
+			// if err != nil {
+			// TODO: set default value on error
+			// 	return lib.WrapError(ErrEnvParse, fmt.Errorf("%s: %w", envName, err))
+			// }
+		}
 
 		flagName, ok := field.Tag(TagFlag)
 		if !ok {
@@ -69,19 +129,9 @@ func LoadConfig(cfg ConfigInterface, osArgs *[]string) error {
 
 		// writes flag value to variable
 		flagset.Var(field, flagName, flagDesc)
+		flagEnvNames[flagName] = envName
 	}
 
-	var args []string
-	if osArgs != nil {
-		args = *osArgs
-	} else {
-		// if flargs not provided use global os.Args
-		args = os.Args
-	}
-
-	// skipping program name
-	args = args[1:]
-
 	// flags override .env variables
 	for {
 		if len(args) == 0 {
@@ -101,23 +151,43 @@ func LoadConfig(cfg ConfigInterface, osArgs *[]string) error {
 		}
 
 		if !isErrFlagNotDefined(err) {
-			return lib.WrapError(ErrFlagParse, err)
+			return nil, lib.WrapError(ErrFlagParse, err)
 		}
 
 		args = flagset.Args()[1:]
 	}
 
+	flagset.Visit(func(f *flag.Flag) {
+		if envName, ok := flagEnvNames[f.Name]; ok {
+			sources[envName] = SourceFlag
+		}
+	})
+
+	// values replaced by the defaults are reported as defaults
+	before := make(map[string]string, len(fields))
+	for _, field := range fields {
+		before[field.Name()] = formatValue(fieldValue(reflect.ValueOf(cfg), field.Name()))
+	}
 	cfg.SetDefaults()
+	for _, field := range fields {
+		envName, ok := field.Tag(TagEnv)
+		if !ok {
+			continue
+		}
+		if formatValue(fieldValue(reflect.ValueOf(cfg), field.Name())) != before[field.Name()] {
+			sources[envName] = SourceDefault
+		}
+	}
 
 	validator, err := NewValidator()
 	if err != nil {
-		return lib.WrapError(ErrConfigValidation, err)
+		return nil, lib.WrapError(ErrConfigValidation, err)
 	}
 
 	err = validator.Struct(cfg)
 	if err != nil {
-		return lib.WrapError(ErrConfigValidation, err)
+		return nil, lib.WrapError(ErrConfigValidation, err)
 	}
 
-	return nil
+	return sources, nil
 }
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/omeid/uconfig/flat"
+)
+
+// Source is the layer the config value is taken from, the later layers override the earlier ones:
+// default < file < .env < env < flag
+type Source string
+
+const (
+	SourceUnset   Source = ""
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceDotEnv  Source = ".env"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Sources are the sources of the config values keyed by the env variable name
+type Sources map[string]Source
+
+// Entry is the effective config value
+type Entry struct {
+	Name   string // env variable name
+	Flag   string
+	Value  string
+	Source Source
+	Hidden bool // the value is set but excluded from the sanitized config
+}
+
+// Effective returns the sanitized config values with their sources in the order of the config struct
+func Effective(cfg *Config, sources Sources) ([]Entry, error) {
+	sanitized, ok := cfg.GetSanitized().(Config)
+	if !ok {
+		return nil, lib.WrapError(ErrConfigInvalid, fmt.Errorf("unexpected sanitized config type"))
+	}
+
+	fields, err := flat.View(cfg)
+	if err != nil {
+		return nil, lib.WrapError(ErrConfigInvalid, err)
+	}
+
+	entries := make([]Entry, 0, len(fields))
+	for _, field := range fields {
+		envName, ok := field.Tag(TagEnv)
+		if !ok {
+			continue
+		}
+		flagName, _ := field.Tag(TagFlag)
+
+		value := fieldValue(reflect.ValueOf(&sanitized), field.Name())
+		entry := Entry{
+			Name:   envName,
+			Flag:   flagName,
+			Value:  formatValue(value),
+			Source: sources[envName],
+		}
+		if value.IsZero() && !fieldValue(reflect.ValueOf(cfg), field.Name()).IsZero() {
+			entry.Hidden = true
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// fieldValue returns the field of the struct by the dot separated path of the flat view
+func fieldValue(v reflect.Value, name string) reflect.Value {
+	v = reflect.Indirect(v)
+	for _, part := range strings.Split(name, ".") {
+		v = reflect.Indirect(v.FieldByName(part))
+	}
+	return v
+}
+
+// formatValue formats the value the way it is set in the env variable
+func formatValue(v reflect.Value) string {
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+	if v.Kind() == reflect.Slice {
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = formatValue(v.Index(i))
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
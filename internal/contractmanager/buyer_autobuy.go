@@ -0,0 +1,381 @@
+package contractmanager
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/ledger"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	ErrSignerNoPublicKey = errors.New("signer doesn't expose the public key required to encrypt the destination")
+)
+
+// SellerReputation returns the share of the failed contracts of the seller and the number of the contracts it is based on
+type SellerReputation interface {
+	GetSellerFailureRate(seller common.Address) (rate float64, samples int)
+}
+
+// AutoBuyCandidate is the available contract evaluated by the auto-buyer
+type AutoBuyCandidate struct {
+	ContractID        string
+	Seller            string
+	HashrateGHS       float64
+	Duration          time.Duration
+	Price             *big.Int
+	Fee               *big.Int `json:",omitempty"` // validator fee read before the purchase, in the fee token
+	PricePerTHDay     float64  // in the payment token units
+	SellerFailureRate float64
+	SellerPurchases   int
+	Rejected          string `json:",omitempty"` // the rule the contract failed
+	Purchased         bool
+	Result            []contracts.TxResult `json:",omitempty"`
+	Error             string               `json:",omitempty"`
+
+	termsVersion uint32
+}
+
+// AutoBuyPlan is the result of the single auto-buyer run
+type AutoBuyPlan struct {
+	At         time.Time
+	DryRun     bool
+	Budget     *big.Int // budget of the period in the payment token base units
+	Spent      *big.Int // spent in the period before the run, prices and the validator fees paid in the payment token
+	Remaining  *big.Int // left after the run
+	FeesSpent  *big.Int `json:",omitempty"` // validator fees paid in the period, if the fee token is not the payment token
+	Candidates []*AutoBuyCandidate
+}
+
+// BuyerAutoBuyer purchases the available contracts matching the rules within the budget. The router is set as the
+// validator of the purchased contracts, so the hashrate is validated by the buyer contract and forwarded to the
+// destination. The spend is tracked in the ledger, so the budget survives restarts
+type BuyerAutoBuyer struct {
+	// config
+	walletAddr         common.Address
+	interval           time.Duration
+	dryRun             bool
+	maxPricePerTHDay   float64 // in the payment token units
+	minDuration        time.Duration
+	maxDuration        time.Duration
+	maxFailureRate     float64 // sellers failing more contracts are skipped
+	budget             string  // in the payment token units, parsed with the token decimals
+	budgetPeriod       time.Duration
+	maxPurchasesPerRun int
+	validatorURL       *url.URL // public stratum url of the router, the sellers direct the hashrate there
+	destURL            *url.URL // pool the purchased hashrate is forwarded to
+
+	// state
+	lastPlan *lib.AtomicValue[*AutoBuyPlan]
+	runMutex sync.Mutex
+
+	// deps
+	store      *contracts.HashrateEthereum
+	ledger     *ledger.Ledger
+	reputation SellerReputation
+	signer     interfaces.Signer
+	log        interfaces.ILogger
+}
+
+func NewBuyerAutoBuyer(
+	walletAddr common.Address,
+	interval time.Duration,
+	dryRun bool,
+	maxPricePerTHDay float64,
+	minDuration time.Duration,
+	maxDuration time.Duration,
+	maxFailureRate float64,
+	budget string,
+	budgetPeriod time.Duration,
+	maxPurchasesPerRun int,
+	validatorURL *url.URL,
+	destURL *url.URL,
+	store *contracts.HashrateEthereum,
+	ledger *ledger.Ledger,
+	reputation SellerReputation,
+	signer interfaces.Signer,
+	log interfaces.ILogger,
+) *BuyerAutoBuyer {
+	return &BuyerAutoBuyer{
+		walletAddr:         walletAddr,
+		interval:           interval,
+		dryRun:             dryRun,
+		maxPricePerTHDay:   maxPricePerTHDay,
+		minDuration:        minDuration,
+		maxDuration:        maxDuration,
+		maxFailureRate:     maxFailureRate,
+		budget:             budget,
+		budgetPeriod:       budgetPeriod,
+		maxPurchasesPerRun: maxPurchasesPerRun,
+		validatorURL:       validatorURL,
+		destURL:            destURL,
+		lastPlan:           lib.NewAtomicValue[*AutoBuyPlan](nil),
+		store:              store,
+		ledger:             ledger,
+		reputation:         reputation,
+		signer:             signer,
+		log:                log,
+	}
+}
+
+func (b *BuyerAutoBuyer) Run(ctx context.Context) error {
+	if b.dryRun {
+		b.log.Warnf("auto-buying is in dry run mode, transactions are only estimated")
+	}
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		_, err := b.RunOnce(ctx, b.dryRun)
+		if err != nil {
+			b.log.Errorf("auto-buying failed: %s", err)
+		}
+	}
+}
+
+// GetLastPlan returns the plan of the last run, nil if there was no run yet
+func (b *BuyerAutoBuyer) GetLastPlan() *AutoBuyPlan {
+	return b.lastPlan.Load()
+}
+
+// RunOnce evaluates the available contracts and purchases the cheapest matching ones, with dryRun the transactions
+// are only estimated. The configured dry run mode can't be turned off per run
+func (b *BuyerAutoBuyer) RunOnce(ctx context.Context, dryRun bool) (*AutoBuyPlan, error) {
+	b.runMutex.Lock()
+	defer b.runMutex.Unlock()
+
+	dryRun = dryRun || b.dryRun
+
+	destPubKey, err := b.getOwnPubKey()
+	if err != nil {
+		return nil, err
+	}
+
+	oracle, err := b.store.GetHashpriceOracle(ctx)
+	if err != nil {
+		return nil, lib.WrapError(fmt.Errorf("can't get payment token decimals"), err)
+	}
+	paymentToken, err := b.store.GetPaymentToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	feeToken, err := b.store.GetFeeToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	budget, err := lib.ParseUnits(b.budget, int(oracle.TokenDecimals))
+	if err != nil {
+		return nil, lib.WrapError(fmt.Errorf("invalid budget"), err)
+	}
+
+	spentByToken := b.ledger.GetSpent(time.Now().Add(-b.budgetPeriod))
+	spent := new(big.Int)
+	if s, ok := spentByToken[paymentToken]; ok {
+		spent.Set(s)
+	}
+
+	ids, err := b.store.GetContractsIDs(ctx)
+	if err != nil {
+		return nil, lib.WrapError(fmt.Errorf("can't get contract list"), err)
+	}
+	terms, err := b.store.GetContracts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &AutoBuyPlan{
+		At:         time.Now(),
+		DryRun:     dryRun,
+		Budget:     budget,
+		Spent:      new(big.Int).Set(spent),
+		Candidates: b.evaluate(terms, oracle.TokenDecimals),
+	}
+	if feeToken != paymentToken {
+		plan.FeesSpent = new(big.Int)
+		if s, ok := spentByToken[feeToken]; ok {
+			plan.FeesSpent.Set(s)
+		}
+	}
+
+	remaining := new(big.Int).Sub(budget, spent)
+	purchases := 0
+	for _, c := range plan.Candidates {
+		if c.Rejected != "" {
+			continue
+		}
+		if purchases >= b.maxPurchasesPerRun {
+			c.Rejected = "purchase limit per run reached"
+			continue
+		}
+
+		// the price is read again as the oracle price may have changed since the terms were loaded
+		price, fee, err := b.store.GetPriceAndFee(ctx, c.ContractID)
+		if err != nil {
+			c.Error = err.Error()
+			b.log.Warnf("can't get price of contract %s: %s", c.ContractID, err)
+			continue
+		}
+		if price.Cmp(c.Price) > 0 {
+			c.Error = fmt.Sprintf("price increased from %s to %s", c.Price, price)
+			continue
+		}
+		c.Fee = fee
+
+		cost := PurchaseCost(price, fee, paymentToken, feeToken)
+		if cost.Cmp(remaining) > 0 {
+			c.Rejected = "over budget"
+			continue
+		}
+
+		res, err := b.purchase(ctx, c, price, fee, destPubKey, dryRun)
+		c.Result = res
+		if err != nil {
+			c.Error = err.Error()
+			b.log.Warnf("can't purchase contract %s: %s", c.ContractID, err)
+			continue
+		}
+		c.Purchased = true
+		purchases++
+		remaining.Sub(remaining, cost)
+	}
+	plan.Remaining = remaining
+
+	b.log.Infof("auto-buying: %d contracts evaluated, %d purchased, spent %s of %s in the period (dry run %t)",
+		len(plan.Candidates), purchases, new(big.Int).Sub(budget, remaining), budget, dryRun)
+
+	b.lastPlan.Store(plan)
+	return plan, nil
+}
+
+// evaluate applies the rules to the contracts, the matching contracts go first, cheapest first
+func (b *BuyerAutoBuyer) evaluate(terms []*hashrate.EncryptedTerms, tokenDecimals int64) []*AutoBuyCandidate {
+	var res []*AutoBuyCandidate
+	for _, t := range terms {
+		if t.BlockchainState() != hashrate.BlockchainStateAvailable || t.IsDeleted() {
+			continue
+		}
+		if common.HexToAddress(t.Seller()) == b.walletAddr {
+			continue
+		}
+
+		c := &AutoBuyCandidate{
+			ContractID:    t.ID(),
+			Seller:        t.Seller(),
+			HashrateGHS:   t.HashrateGHS(),
+			Duration:      t.Duration(),
+			Price:         t.Price(),
+			PricePerTHDay: PricePerTHDay(t.Price(), tokenDecimals, t.HashrateGHS(), t.Duration()),
+			termsVersion:  t.Version(),
+		}
+		if b.reputation != nil {
+			c.SellerFailureRate, c.SellerPurchases = b.reputation.GetSellerFailureRate(common.HexToAddress(t.Seller()))
+		}
+
+		switch {
+		case c.PricePerTHDay > b.maxPricePerTHDay:
+			c.Rejected = fmt.Sprintf("price %.4f per TH/day is over %.4f", c.PricePerTHDay, b.maxPricePerTHDay)
+		case c.Duration < b.minDuration:
+			c.Rejected = fmt.Sprintf("duration %s is less than %s", c.Duration, b.minDuration)
+		case b.maxDuration > 0 && c.Duration > b.maxDuration:
+			c.Rejected = fmt.Sprintf("duration %s is more than %s", c.Duration, b.maxDuration)
+		case c.SellerPurchases > 0 && c.SellerFailureRate > b.maxFailureRate:
+			c.Rejected = fmt.Sprintf("seller failed %.0f%% of %d contracts", c.SellerFailureRate*100, c.SellerPurchases)
+		}
+		res = append(res, c)
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if (res[i].Rejected == "") != (res[j].Rejected == "") {
+			return res[i].Rejected == ""
+		}
+		return res[i].PricePerTHDay < res[j].PricePerTHDay
+	})
+	return res
+}
+
+func (b *BuyerAutoBuyer) purchase(ctx context.Context, c *AutoBuyCandidate, price *big.Int, fee *big.Int, destPubKey string, dryRun bool) ([]contracts.TxResult, error) {
+	contractPubKey, err := b.store.GetContractPubKey(ctx, c.ContractID)
+	if err != nil {
+		return nil, err
+	}
+	contractPubKey, err = lib.NormalizePubKey(contractPubKey)
+	if err != nil {
+		return nil, lib.WrapError(fmt.Errorf("invalid contract public key"), err)
+	}
+
+	encrValidatorURL, err := lib.EncryptString(b.validatorURL.String(), contractPubKey)
+	if err != nil {
+		return nil, err
+	}
+	encrDestURL, err := lib.EncryptString(b.destURL.String(), destPubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.store.PurchaseContract(ctx, b.signer, &contracts.Purchase{
+		ContractID:       c.ContractID,
+		Seller:           common.HexToAddress(c.Seller),
+		Validator:        b.walletAddr,
+		EncrValidatorURL: encrValidatorURL,
+		EncrDestURL:      encrDestURL,
+		TermsVersion:     c.termsVersion,
+		Price:            price,
+		Fee:              fee,
+	}, dryRun)
+}
+
+// PurchaseCost returns the amount of the payment token the purchase costs, the validator fee is included
+// if it is paid in the payment token
+func PurchaseCost(price *big.Int, fee *big.Int, paymentToken common.Address, feeToken common.Address) *big.Int {
+	cost := new(big.Int).Set(price)
+	if fee != nil && feeToken == paymentToken {
+		cost.Add(cost, fee)
+	}
+	return cost
+}
+
+// getOwnPubKey returns the public key of the wallet the destination is encrypted with, as the router validates the contract.
+// The remote signer decrypts with the separate decryption key, so its public key is used instead of the wallet one
+func (b *BuyerAutoBuyer) getOwnPubKey() (string, error) {
+	if decSigner, ok := b.signer.(interface{ DecryptionPublicKey() *ecdsa.PublicKey }); ok {
+		if pubKey := decSigner.DecryptionPublicKey(); pubKey != nil {
+			return hex.EncodeToString(crypto.FromECDSAPub(pubKey)), nil
+		}
+	}
+	pkSigner, ok := b.signer.(interface{ PublicKey() *ecdsa.PublicKey })
+	if !ok {
+		return "", ErrSignerNoPublicKey
+	}
+	return hex.EncodeToString(crypto.FromECDSAPub(pkSigner.PublicKey())), nil
+}
+
+// PricePerTHDay returns the price of 1 TH/s for a day in the token units
+func PricePerTHDay(price *big.Int, tokenDecimals int64, hashrateGHS float64, duration time.Duration) float64 {
+	thDays := hashrateGHS / 1000 * duration.Hours() / 24
+	if thDays <= 0 {
+		return math.Inf(1)
+	}
+	tokens, _ := new(big.Rat).SetFrac(price, new(big.Int).Exp(big.NewInt(10), big.NewInt(tokenDecimals), nil)).Float64()
+	return tokens / thDays
+}
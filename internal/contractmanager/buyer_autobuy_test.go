@@ -0,0 +1,110 @@
+package contractmanager
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+type reputationMock map[common.Address]float64
+
+func (r reputationMock) GetSellerFailureRate(seller common.Address) (float64, int) {
+	rate, ok := r[seller]
+	if !ok {
+		return 0, 0
+	}
+	return rate, 4
+}
+
+type remoteSignerMock struct {
+	interfaces.Signer
+	walletKey     *ecdsa.PrivateKey
+	decryptionKey *ecdsa.PrivateKey
+}
+
+func (s *remoteSignerMock) PublicKey() *ecdsa.PublicKey {
+	return &s.walletKey.PublicKey
+}
+
+func (s *remoteSignerMock) DecryptionPublicKey() *ecdsa.PublicKey {
+	if s.decryptionKey == nil {
+		return nil
+	}
+	return &s.decryptionKey.PublicKey
+}
+
+func TestAutoBuyOwnPubKey(t *testing.T) {
+	walletKey, _ := crypto.GenerateKey()
+	decryptionKey, _ := crypto.GenerateKey()
+	signer := &remoteSignerMock{walletKey: walletKey, decryptionKey: decryptionKey}
+	b := NewBuyerAutoBuyer(common.Address{}, time.Minute, true, 0, 0, 0, 0, "0", 0, 1, nil, nil, nil, nil, nil, signer, nil)
+
+	// the destination is encrypted to the key the signer decrypts with
+	pubKey, err := b.getOwnPubKey()
+	require.NoError(t, err)
+	require.Equal(t, hex.EncodeToString(crypto.FromECDSAPub(&decryptionKey.PublicKey)), pubKey)
+
+	signer.decryptionKey = nil
+	pubKey, err = b.getOwnPubKey()
+	require.NoError(t, err)
+	require.Equal(t, hex.EncodeToString(crypto.FromECDSAPub(&walletKey.PublicKey)), pubKey)
+}
+
+func TestPricePerTHDay(t *testing.T) {
+	// 100 TH/s for 2 days for 10 tokens with 6 decimals
+	require.InDelta(t, 0.05, PricePerTHDay(big.NewInt(10_000_000), 6, 100_000, 48*time.Hour), 1e-9)
+}
+
+func TestPurchaseCost(t *testing.T) {
+	usdc := common.HexToAddress("0x0a")
+	lmr := common.HexToAddress("0x0b")
+
+	// the fee paid in the payment token is the part of the budget
+	require.Equal(t, int64(105), PurchaseCost(big.NewInt(100), big.NewInt(5), usdc, usdc).Int64())
+	require.Equal(t, int64(100), PurchaseCost(big.NewInt(100), big.NewInt(5), usdc, lmr).Int64())
+}
+
+func TestAutoBuyEvaluate(t *testing.T) {
+	wallet := common.HexToAddress("0x01")
+	goodSeller := common.HexToAddress("0x02")
+	badSeller := common.HexToAddress("0x03")
+
+	newTerms := func(id string, seller common.Address, duration time.Duration, price int64) *hashrate.EncryptedTerms {
+		return &hashrate.EncryptedTerms{
+			BaseTerms: *hashrate.NewBaseTerms(id, seller.Hex(), "", "", time.Time{}, duration, 100_000, big.NewInt(price), 0, false, big.NewInt(0), false, 1),
+		}
+	}
+	running := &hashrate.EncryptedTerms{
+		BaseTerms: *hashrate.NewBaseTerms("running", goodSeller.Hex(), "", "", time.Now(), time.Hour, 100_000, big.NewInt(1), 0, false, big.NewInt(0), false, 1),
+	}
+
+	b := NewBuyerAutoBuyer(wallet, time.Minute, true, 0.06, 12*time.Hour, 0, 0.25, "100", 24*time.Hour, 1, nil, nil, nil, nil,
+		reputationMock{badSeller: 0.5}, nil, nil)
+
+	candidates := b.evaluate([]*hashrate.EncryptedTerms{
+		newTerms("expensive", goodSeller, 24*time.Hour, 7_000_000),
+		newTerms("cheap", goodSeller, 24*time.Hour, 4_000_000),
+		newTerms("short", goodSeller, time.Hour, 100_000),
+		newTerms("unreliable", badSeller, 24*time.Hour, 3_000_000),
+		newTerms("fair", goodSeller, 24*time.Hour, 5_000_000),
+		newTerms("own", wallet, 24*time.Hour, 1_000_000),
+		running,
+	}, 6)
+
+	require.Len(t, candidates, 5)
+	require.Equal(t, "cheap", candidates[0].ContractID)
+	require.Empty(t, candidates[0].Rejected)
+	require.Equal(t, "fair", candidates[1].ContractID)
+	require.Empty(t, candidates[1].Rejected)
+	for _, c := range candidates[2:] {
+		require.NotEmpty(t, c.Rejected, c.ContractID)
+	}
+}
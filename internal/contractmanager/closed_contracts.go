@@ -0,0 +1,57 @@
+package contractmanager
+
+import (
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources"
+)
+
+const ClosedContractsRetention = 7 * 24 * time.Hour // how long the closed contracts are kept for the dispute evidence
+
+type closedContract struct {
+	resources.Contract
+	closedAt time.Time
+}
+
+// ClosedContracts keeps the contracts removed on the delivery close for the retention period,
+// so the dispute evidence of the delivery can be exported after the contract is removed
+type ClosedContracts struct {
+	// config
+	retention time.Duration
+
+	// state
+	contracts *lib.Collection[*closedContract]
+}
+
+func NewClosedContracts(retention time.Duration) *ClosedContracts {
+	return &ClosedContracts{
+		retention: retention,
+		contracts: lib.NewCollection[*closedContract](),
+	}
+}
+
+// Store keeps the closed contract, the contracts closed before the retention period are dropped
+func (c *ClosedContracts) Store(contract resources.Contract) {
+	c.prune()
+	c.contracts.Store(&closedContract{Contract: contract, closedAt: time.Now()})
+}
+
+// Load returns the contract closed within the retention period
+func (c *ClosedContracts) Load(id string) (resources.Contract, bool) {
+	c.prune()
+	closed, ok := c.contracts.Load(id)
+	if !ok {
+		return nil, false
+	}
+	return closed.Contract, true
+}
+
+func (c *ClosedContracts) prune() {
+	c.contracts.Range(func(closed *closedContract) bool {
+		if time.Since(closed.closedAt) > c.retention {
+			c.contracts.Delete(closed.ID())
+		}
+		return true
+	})
+}
@@ -2,6 +2,7 @@ package contractmanager
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 
@@ -14,6 +15,10 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 )
 
+var (
+	ErrContractNotFound = errors.New("contract not found")
+)
+
 type ContractManager struct {
 	cfAddr    common.Address
 	ownerAddr common.Address
@@ -23,18 +28,20 @@ type ContractManager struct {
 
 	createContract CreateContractFn
 	store          *contracts.HashrateEthereum
+	signer         interfaces.Signer
 	log            interfaces.ILogger
 }
 
 type CreateContractFn func(terms *hashrate.EncryptedTerms) (resources.Contract, error)
 
-func NewContractManager(clonefactoryAddr, ownerAddr common.Address, createContractFn CreateContractFn, store *contracts.HashrateEthereum, contracts *lib.Collection[resources.Contract], log interfaces.ILogger) *ContractManager {
+func NewContractManager(clonefactoryAddr, ownerAddr common.Address, createContractFn CreateContractFn, store *contracts.HashrateEthereum, signer interfaces.Signer, contracts *lib.Collection[resources.Contract], log interfaces.ILogger) *ContractManager {
 	return &ContractManager{
 		cfAddr:         clonefactoryAddr,
 		ownerAddr:      ownerAddr,
 		contracts:      contracts,
 		createContract: createContractFn,
 		store:          store,
+		signer:         signer,
 		contractsWG:    sync.WaitGroup{},
 		log:            log,
 	}
@@ -52,11 +59,13 @@ func (cm *ContractManager) Run(ctx context.Context) error {
 		return lib.WrapError(fmt.Errorf("can't get contract ids"), err)
 	}
 
-	for _, id := range contractIDs {
-		terms, err := cm.store.GetContract(ctx, id)
-		if err != nil {
-			return lib.WrapError(fmt.Errorf("can't get contract"), err)
-		}
+	cm.log.Infof("loading %d contracts", len(contractIDs))
+	contractTerms, err := cm.store.GetContracts(ctx, contractIDs)
+	if err != nil {
+		return lib.WrapError(fmt.Errorf("can't get contracts"), err)
+	}
+
+	for _, terms := range contractTerms {
 		if cm.isOurContract(terms) {
 			cm.AddContract(ctx, terms)
 		}
@@ -93,6 +102,8 @@ func (cm *ContractManager) cloneFactoryController(ctx context.Context, event int
 		return cm.handleContractPurchased(ctx, e)
 	case *clonefactory.ClonefactoryContractDeleteUpdated:
 		return cm.handleContractDeleteUpdated(ctx, e)
+	case *contracts.RemovedEvent:
+		return cm.handleRemovedEvent(ctx, e)
 	}
 	return nil
 }
@@ -132,6 +143,32 @@ func (cm *ContractManager) handleContractDeleteUpdated(ctx context.Context, even
 	return nil
 }
 
+// handleRemovedEvent resyncs the contract which event was reverted by the chain reorg
+func (cm *ContractManager) handleRemovedEvent(ctx context.Context, event *contracts.RemovedEvent) error {
+	var addr common.Address
+	switch e := event.Event.(type) {
+	case *clonefactory.ClonefactoryContractCreated:
+		addr = e.Address
+	case *clonefactory.ClonefactoryClonefactoryContractPurchased:
+		addr = e.Address
+	case *clonefactory.ClonefactoryContractDeleteUpdated:
+		addr = e.Address
+	default:
+		return nil
+	}
+	cm.log.Warnf("clonefactory event %T reverted by chain reorg, address %s", event.Event, addr.Hex())
+
+	ctr, ok := cm.contracts.Load(addr.Hex())
+	if !ok {
+		return nil
+	}
+	err := ctr.SyncState(ctx)
+	if err != nil {
+		cm.log.Errorf("contract sync state error %s", err)
+	}
+	return nil
+}
+
 func (cm *ContractManager) AddContract(ctx context.Context, data *hashrate.EncryptedTerms) {
 	_, ok := cm.contracts.Load(data.ID())
 	if ok {
@@ -162,6 +199,15 @@ func (cm *ContractManager) GetContract(id string) (resources.Contract, bool) {
 	return cm.contracts.Load(id)
 }
 
+// CloseContract closes the contract early, only buyer or validator of the running contract is allowed to do it
+func (cm *ContractManager) CloseContract(ctx context.Context, id string, reason contracts.CloseReason) error {
+	if _, ok := cm.contracts.Load(id); !ok {
+		return ErrContractNotFound
+	}
+	cm.log.Infof("closing contract %s, reason %d", id, reason)
+	return cm.store.EarlyClose(ctx, id, reason, cm.signer)
+}
+
 func (cm *ContractManager) isOurContract(terms TermsCommon) bool {
 	return terms.Seller() == cm.ownerAddr.String() || terms.Buyer() == cm.ownerAddr.String() || terms.Validator() == cm.ownerAddr.String()
 }
@@ -10,7 +10,6 @@ import (
 	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
 	"github.com/Lumerin-protocol/proxy-router/internal/lib"
 	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
-	"github.com/Lumerin-protocol/proxy-router/internal/repositories/subgraph"
 	"github.com/Lumerin-protocol/proxy-router/internal/resources"
 	"github.com/ethereum/go-ethereum/common"
 	"golang.org/x/sync/errgroup"
@@ -25,25 +24,29 @@ type FuturesManagerSeller struct {
 	userAddr    common.Address
 
 	contracts *lib.Collection[resources.Contract]
+	closed    *ClosedContracts // positions removed on the delivery close, kept for the dispute evidence
 
 	createContract CreateFuturesContractFn
 	blockchain     *contracts.FuturesEthereum
-	privKey        string
-	subgraph       *subgraph.SubgraphClient
+	signer         interfaces.Signer
+	positions      *FuturesPositions
+	readiness      *FuturesReadiness // optional, provides the positions loaded before the delivery range
 	log            interfaces.ILogger
 }
 
 type CreateFuturesContractFn func(terms *contracts.FuturesContract) (resources.Contract, error)
 
-func NewFuturesManagerSeller(privKey string, futuresAddr, userAddr common.Address, blockchain *contracts.FuturesEthereum, subgraph *subgraph.SubgraphClient, contracts *lib.Collection[resources.Contract], createContractFn CreateFuturesContractFn, log interfaces.ILogger) *FuturesManagerSeller {
+func NewFuturesManagerSeller(signer interfaces.Signer, futuresAddr, userAddr common.Address, blockchain *contracts.FuturesEthereum, positions *FuturesPositions, readiness *FuturesReadiness, contracts *lib.Collection[resources.Contract], closed *ClosedContracts, createContractFn CreateFuturesContractFn, log interfaces.ILogger) *FuturesManagerSeller {
 	return &FuturesManagerSeller{
-		privKey:        privKey,
+		signer:         signer,
 		futuresAddr:    futuresAddr,
 		userAddr:       userAddr,
 		contracts:      contracts,
+		closed:         closed,
 		createContract: createContractFn,
 		blockchain:     blockchain,
-		subgraph:       subgraph,
+		positions:      positions,
+		readiness:      readiness,
 		log:            log,
 	}
 }
@@ -90,11 +93,21 @@ func (fm *FuturesManagerSeller) Run(ctx context.Context) error {
 func (fm *FuturesManagerSeller) runDeliveryRange(ctx context.Context, start, end time.Time, sub *lib.Subscription) error {
 	fm.log.Infof("delivery range started at %s, proxy started at %s", start, time.Now())
 
-	_contracts, err := fm.subgraph.GetPositionsBySeller(ctx, fm.userAddr, start)
-	if err != nil {
-		return lib.WrapError(fmt.Errorf("can't get contract ids"), err)
+	var (
+		_contracts []contracts.FuturesContract
+		prefetched bool
+	)
+	if fm.readiness != nil {
+		_contracts, prefetched = fm.readiness.TakePositions(start)
+	}
+	if !prefetched {
+		var err error
+		_contracts, err = fm.positions.GetPositionsBySeller(ctx, fm.userAddr, start)
+		if err != nil {
+			return lib.WrapError(fmt.Errorf("can't get contract ids"), err)
+		}
 	}
-	fm.log.Infof("found %d contract units for seller %s at %s", len(_contracts), lib.AddrShort(fm.userAddr.Hex()), start.Format(time.RFC3339))
+	fm.log.Infof("found %d contract units for seller %s at %s, prefetched %t", len(_contracts), lib.AddrShort(fm.userAddr.Hex()), start.Format(time.RFC3339), prefetched)
 
 	errGroup, ctx := errgroup.WithContext(ctx)
 
@@ -107,6 +120,15 @@ func (fm *FuturesManagerSeller) runDeliveryRange(ctx context.Context, start, end
 		}
 	}
 
+	// the positions could be matched, paid or closed after the readiness check, they are reconciled
+	// without delaying the prefetched ones
+	if prefetched {
+		errGroup.Go(func() error {
+			fm.reconcilePositions(ctx, start, _contracts, errGroup)
+			return nil
+		})
+	}
+
 	// wait for delivery range to end, if it ends, context is cancelled and all errgroup tasks are cancelled
 	errGroup.Go(func() error {
 		select {
@@ -124,7 +146,7 @@ func (fm *FuturesManagerSeller) runDeliveryRange(ctx context.Context, start, end
 			case <-ctx.Done():
 				return ctx.Err()
 			case event := <-sub.Events():
-				err := fm.eventController(ctx, event)
+				err := fm.eventController(ctx, event, start, errGroup)
 				if err != nil {
 					fm.log.Errorf("error handling event: %s", err)
 				}
@@ -134,15 +156,48 @@ func (fm *FuturesManagerSeller) runDeliveryRange(ctx context.Context, start, end
 		}
 	})
 
-	err = errGroup.Wait()
+	err := errGroup.Wait()
 	if errors.Is(err, ErrDeliveryRangeEnded) {
 		return nil
 	}
 	return err
 }
 
+// reconcilePositions reloads the positions of the delivery range, removes the prefetched ones that are not
+// deliverable anymore and adds the paid ones that are not running yet
+func (fm *FuturesManagerSeller) reconcilePositions(ctx context.Context, start time.Time, prefetched []contracts.FuturesContract, errGroup *errgroup.Group) {
+	positions, err := fm.positions.GetPositionsBySeller(ctx, fm.userAddr, start)
+	if err != nil {
+		fm.log.Errorf("can't reload positions of the delivery range: %s", err)
+		return
+	}
+
+	paid := make(map[string]bool, len(positions))
+	for _, contract := range positions {
+		paid[contract.ID()] = contract.Paid
+	}
+	for _, contract := range prefetched {
+		if !contract.Paid || paid[contract.ID()] {
+			continue
+		}
+		fm.log.Infof("removing contract %s which is not deliverable after the readiness check", lib.AddrShort(contract.ID()))
+		err := fm.RemoveContract(ctx, &contract)
+		if err != nil {
+			fm.log.Errorf("can't remove contract %s: %s", lib.AddrShort(contract.ID()), err)
+		}
+	}
+
+	for _, contract := range positions {
+		if _, ok := fm.contracts.Load(contract.ID()); ok || !contract.Paid {
+			continue
+		}
+		fm.log.Infof("adding contract %s matched after the readiness check", lib.AddrShort(contract.ID()))
+		fm.AddContract(ctx, &contract, errGroup)
+	}
+}
+
 func (fm *FuturesManagerSeller) tryClaimReward(ctx context.Context, deliveryAt time.Time) {
-	err := fm.blockchain.ClaimReward(ctx, deliveryAt, fm.privKey)
+	err := fm.blockchain.ClaimReward(ctx, deliveryAt, fm.signer)
 	if err != nil {
 		fm.log.Errorf("error claiming reward: %s", err)
 	}
@@ -191,10 +246,42 @@ func (fm *FuturesManagerSeller) RemoveContract(ctx context.Context, data *contra
 	return nil
 }
 
-func (fm *FuturesManagerSeller) eventController(ctx context.Context, event interface{}) error {
+func (fm *FuturesManagerSeller) eventController(ctx context.Context, event interface{}, start time.Time, errGroup *errgroup.Group) error {
 	switch e := event.(type) {
 	case *futures.FuturesPositionDeliveryClosed:
 		return fm.handlePositionDeliveryClosed(ctx, e)
+	case *contracts.RemovedEvent:
+		return fm.handleRemovedEvent(ctx, e, start, errGroup)
+	}
+	return nil
+}
+
+// handleRemovedEvent resyncs the position which event was reverted by the chain reorg
+func (fm *FuturesManagerSeller) handleRemovedEvent(ctx context.Context, event *contracts.RemovedEvent, start time.Time, errGroup *errgroup.Group) error {
+	positionID, deliveryReopened, ok := removedPosition(event)
+	if !ok {
+		return nil
+	}
+	fm.log.Warnf("futures event %T reverted by chain reorg, position %s", event.Event, lib.AddrShort(positionID.Hex()))
+
+	position, err := fm.blockchain.GetPosition(ctx, positionID)
+	if err != nil {
+		return err
+	}
+	deliverable := position.Seller == fm.userAddr && position.Paid && position.DeliveryAt.Equal(start)
+
+	ctr, running := fm.contracts.Load(position.ID())
+	switch {
+	case running && !deliverable:
+		return fm.RemoveContract(ctx, position)
+	case running:
+		err := ctr.SyncState(ctx)
+		if err != nil {
+			fm.log.Errorf("contract sync state error %s", err)
+		}
+	case deliverable && deliveryReopened:
+		fm.log.Infof("restoring contract %s which delivery close was reverted", lib.AddrShort(position.ID()))
+		fm.AddContract(ctx, position, errGroup)
 	}
 	return nil
 }
@@ -205,7 +292,16 @@ func (fm *FuturesManagerSeller) handlePositionDeliveryClosed(ctx context.Context
 		return err
 	}
 	fm.log.Debugf("received position delivery closed event: %s", lib.AddrShort(common.Hash(event.PositionId).Hex()))
-	return fm.RemoveContract(ctx, contract)
+
+	ctr, ok := fm.contracts.Load(contract.ID())
+	err = fm.RemoveContract(ctx, contract)
+	if err != nil {
+		return err
+	}
+	if ok {
+		fm.closed.Store(ctr)
+	}
+	return nil
 }
 
 func (fm *FuturesManagerSeller) GetContracts() *lib.Collection[resources.Contract] {
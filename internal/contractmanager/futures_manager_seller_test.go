@@ -0,0 +1,151 @@
+package contractmanager
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Lumerin-protocol/contracts-go/v3/futures"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+// positionContractMock is the running futures position, only the methods used by the futures manager are implemented
+type positionContractMock struct {
+	resources.Contract
+	id      string
+	stopped chan struct{}
+}
+
+func newPositionContractMock(id string) *positionContractMock {
+	return &positionContractMock{id: id, stopped: make(chan struct{})}
+}
+
+func (c *positionContractMock) ID() string                          { return c.id }
+func (c *positionContractMock) SyncState(ctx context.Context) error { return nil }
+func (c *positionContractMock) Stop(ctx context.Context) error {
+	close(c.stopped)
+	return nil
+}
+func (c *positionContractMock) Run(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.stopped:
+		return nil
+	}
+}
+
+// positionClientMock returns the position for any futures call, only the methods used to read the position are implemented
+type positionClientMock struct {
+	contracts.EthereumClient
+	position futures.FuturesPosition
+}
+
+func (c *positionClientMock) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	futuresABI, err := futures.FuturesMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	return futuresABI.Methods["getPositionById"].Outputs.Pack(c.position)
+}
+
+func TestFuturesSellerKeepsClosedPosition(t *testing.T) {
+	seller := common.HexToAddress("0x01")
+	position := contracts.FuturesContract{ContractID: common.HexToHash("0xa1"), Seller: seller, Paid: true}
+	client := &positionClientMock{position: futures.FuturesPosition{
+		Seller:          seller,
+		SellPricePerDay: big.NewInt(0),
+		BuyPricePerDay:  big.NewInt(0),
+		DeliveryAt:      big.NewInt(1_700_000_000),
+		CreatedAt:       big.NewInt(0),
+		Paid:            true,
+	}}
+	blockchain := contracts.NewFuturesEthereum(common.Address{}, common.Address{}, client, nil, nil, nil, lib.NewTestLogger())
+
+	cc := lib.NewCollection[resources.Contract]()
+	closed := NewClosedContracts(time.Hour)
+	createContract := func(terms *contracts.FuturesContract) (resources.Contract, error) {
+		return newPositionContractMock(terms.ID()), nil
+	}
+	fm := NewFuturesManagerSeller(nil, common.Address{}, seller, blockchain, nil, nil, cc, closed, createContract, lib.NewTestLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errGroup, ctx := errgroup.WithContext(ctx)
+	fm.AddContract(ctx, &position, errGroup)
+
+	err := fm.handlePositionDeliveryClosed(ctx, &futures.FuturesPositionDeliveryClosed{PositionId: position.ContractID})
+	require.NoError(t, err)
+
+	_, ok := cc.Load(position.ID())
+	require.False(t, ok)
+
+	// the removed position is still available for the dispute evidence
+	ctr, ok := closed.Load(position.ID())
+	require.True(t, ok)
+	require.Equal(t, position.ID(), ctr.ID())
+
+	cancel()
+	_ = errGroup.Wait()
+}
+
+func TestClosedContractsRetention(t *testing.T) {
+	closed := NewClosedContracts(time.Hour)
+	closed.Store(newPositionContractMock("0x01"))
+	closed.contracts.Store(&closedContract{Contract: newPositionContractMock("0x02"), closedAt: time.Now().Add(-2 * time.Hour)})
+
+	_, ok := closed.Load("0x01")
+	require.True(t, ok)
+	_, ok = closed.Load("0x02")
+	require.False(t, ok)
+}
+
+func TestFuturesSellerReconcilesPrefetchedPositions(t *testing.T) {
+	seller := common.HexToAddress("0x01")
+	start := time.Unix(1_700_000_000, 0)
+	kept := contracts.FuturesContract{ContractID: common.HexToHash("0xa1"), Seller: seller, Paid: true}
+	closed := contracts.FuturesContract{ContractID: common.HexToHash("0xa2"), Seller: seller, Paid: true}
+	unpaid := contracts.FuturesContract{ContractID: common.HexToHash("0xa3"), Seller: seller, Paid: true}
+	added := contracts.FuturesContract{ContractID: common.HexToHash("0xa4"), Seller: seller, Paid: true}
+
+	// the subgraph is down, the fresh positions are read from the chain
+	unpaidNow := unpaid
+	unpaidNow.Paid = false
+	chain := &positionsChainMock{positions: []contracts.FuturesContract{kept, unpaidNow, added}}
+	positions := NewFuturesPositions(&subgraphMock{err: errors.New("connection refused")}, chain, 5*time.Minute, lib.NewTestLogger())
+
+	cc := lib.NewCollection[resources.Contract]()
+	createContract := func(terms *contracts.FuturesContract) (resources.Contract, error) {
+		return newPositionContractMock(terms.ID()), nil
+	}
+	fm := NewFuturesManagerSeller(nil, common.Address{}, seller, nil, positions, nil, cc, NewClosedContracts(time.Hour), createContract, lib.NewTestLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errGroup, ctx := errgroup.WithContext(ctx)
+
+	prefetched := []contracts.FuturesContract{kept, closed, unpaid}
+	for _, contract := range prefetched {
+		fm.AddContract(ctx, &contract, errGroup)
+	}
+
+	fm.reconcilePositions(ctx, start, prefetched, errGroup)
+
+	var ids []string
+	cc.Range(func(c resources.Contract) bool {
+		ids = append(ids, c.ID())
+		return true
+	})
+	require.ElementsMatch(t, []string{kept.ID(), added.ID()}, ids)
+
+	cancel()
+	_ = errGroup.Wait()
+}
@@ -10,7 +10,6 @@ import (
 	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
 	"github.com/Lumerin-protocol/proxy-router/internal/lib"
 	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
-	"github.com/Lumerin-protocol/proxy-router/internal/repositories/subgraph"
 	"github.com/Lumerin-protocol/proxy-router/internal/resources"
 	"github.com/ethereum/go-ethereum/common"
 	"golang.org/x/sync/errgroup"
@@ -19,25 +18,25 @@ import (
 type FuturesManagerValidator struct {
 	futuresAddr   common.Address
 	validatorAddr common.Address
-	privKey       string
+	signer        interfaces.Signer
 
 	contracts *lib.Collection[resources.Contract]
 
 	createContractValidator CreateFuturesContractFn
 	blockchain              *contracts.FuturesEthereum
-	subgraph                *subgraph.SubgraphClient
+	positions               *FuturesPositions
 	log                     interfaces.ILogger
 }
 
-func NewFuturesManagerValidator(privKey string, futuresAddr, validatorAddr common.Address, blockchain *contracts.FuturesEthereum, subgraph *subgraph.SubgraphClient, contracts *lib.Collection[resources.Contract], createContractValidator CreateFuturesContractFn, log interfaces.ILogger) *FuturesManagerValidator {
+func NewFuturesManagerValidator(signer interfaces.Signer, futuresAddr, validatorAddr common.Address, blockchain *contracts.FuturesEthereum, positions *FuturesPositions, contracts *lib.Collection[resources.Contract], createContractValidator CreateFuturesContractFn, log interfaces.ILogger) *FuturesManagerValidator {
 	return &FuturesManagerValidator{
-		privKey:                 privKey,
+		signer:                  signer,
 		futuresAddr:             futuresAddr,
 		validatorAddr:           validatorAddr,
 		contracts:               contracts,
 		createContractValidator: createContractValidator,
 		blockchain:              blockchain,
-		subgraph:                subgraph,
+		positions:               positions,
 		log:                     log,
 	}
 }
@@ -85,7 +84,7 @@ func (fm *FuturesManagerValidator) Run(ctx context.Context) error {
 func (fm *FuturesManagerValidator) runDeliveryRange(ctx context.Context, deliveryAt time.Time, end time.Time, sub *lib.Subscription) error {
 	fm.log.Infof("delivery range started at %s, proxy started at %s", deliveryAt, time.Now())
 
-	_contracts, err := fm.subgraph.GetAllPositions(ctx, deliveryAt)
+	_contracts, err := fm.positions.GetAllPositions(ctx, deliveryAt)
 	if err != nil {
 		return lib.WrapError(fmt.Errorf("can't get contract ids"), err)
 	}
@@ -110,7 +109,7 @@ func (fm *FuturesManagerValidator) runDeliveryRange(ctx context.Context, deliver
 
 	fm.log.Infof("found %d unpaid contracts", len(closeDeliveryRequests))
 	if len(closeDeliveryRequests) > 0 {
-		err = fm.blockchain.BatchCloseDelivery(ctx, closeDeliveryRequests, fm.privKey)
+		err = fm.blockchain.BatchCloseDelivery(ctx, closeDeliveryRequests, fm.signer)
 		if err != nil {
 			return lib.WrapError(fmt.Errorf("can't close delivery"), err)
 		}
@@ -139,7 +138,7 @@ func (fm *FuturesManagerValidator) runDeliveryRange(ctx context.Context, deliver
 			case <-ctx.Done():
 				return ctx.Err()
 			case event := <-sub.Events():
-				err := fm.eventController(ctx, event)
+				err := fm.eventController(ctx, event, deliveryAt, errGroup)
 				if err != nil {
 					fm.log.Errorf("error handling event: %s", err)
 				}
@@ -203,10 +202,42 @@ func (fm *FuturesManagerValidator) RemoveContract(ctx context.Context, positionI
 	return nil
 }
 
-func (fm *FuturesManagerValidator) eventController(ctx context.Context, event interface{}) error {
+func (fm *FuturesManagerValidator) eventController(ctx context.Context, event interface{}, deliveryAt time.Time, errGroup *errgroup.Group) error {
 	switch e := event.(type) {
 	case *futures.FuturesPositionDeliveryClosed:
 		return fm.handlePositionDeliveryClosed(ctx, e)
+	case *contracts.RemovedEvent:
+		return fm.handleRemovedEvent(ctx, e, deliveryAt, errGroup)
+	}
+	return nil
+}
+
+// handleRemovedEvent resyncs the position which event was reverted by the chain reorg
+func (fm *FuturesManagerValidator) handleRemovedEvent(ctx context.Context, event *contracts.RemovedEvent, deliveryAt time.Time, errGroup *errgroup.Group) error {
+	positionID, deliveryReopened, ok := removedPosition(event)
+	if !ok {
+		return nil
+	}
+	fm.log.Warnf("futures event %T reverted by chain reorg, position %s", event.Event, lib.AddrShort(positionID.Hex()))
+
+	position, err := fm.blockchain.GetPosition(ctx, positionID)
+	if err != nil {
+		return err
+	}
+	deliverable := position.Paid && position.DeliveryAt.Equal(deliveryAt)
+
+	ctr, running := fm.contracts.Load(position.ID())
+	switch {
+	case running && !deliverable:
+		return fm.RemoveContract(ctx, positionID)
+	case running:
+		err := ctr.SyncState(ctx)
+		if err != nil {
+			fm.log.Errorf("contract sync state error %s", err)
+		}
+	case deliverable && deliveryReopened:
+		fm.log.Infof("restoring contract %s which delivery close was reverted", lib.AddrShort(position.ID()))
+		fm.AddContract(ctx, position, errGroup)
 	}
 	return nil
 }
@@ -0,0 +1,124 @@
+package contractmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Lumerin-protocol/contracts-go/v3/futures"
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/subgraph"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	ErrSubgraphStale = errors.New("subgraph is behind the chain")
+)
+
+// PositionsSubgraph is the indexer of the futures positions
+type PositionsSubgraph interface {
+	GetAllPositions(ctx context.Context, deliveryAt time.Time) (*subgraph.Positions, error)
+	GetPositionsBySeller(ctx context.Context, sellerAddr common.Address, deliveryAt time.Time) (*subgraph.Positions, error)
+}
+
+// PositionsChain is the futures contract the positions are read from if the subgraph is not available
+type PositionsChain interface {
+	GetDeliveryContracts(ctx context.Context, deliveryDate time.Time) ([]contracts.FuturesContract, error)
+	GetMatchedContracts(ctx context.Context, userAddress common.Address, deliveryDate time.Time) ([]contracts.FuturesContract, error)
+	GetLatestBlockTime(ctx context.Context) (time.Time, error)
+}
+
+// FuturesPositions loads the positions of the delivery date from the subgraph and falls back to the blockchain
+// if the subgraph is down, has indexing errors or is behind the chain more than maxLag
+type FuturesPositions struct {
+	// config
+	maxLag time.Duration
+
+	// deps
+	subgraph   PositionsSubgraph
+	blockchain PositionsChain
+	log        interfaces.ILogger
+}
+
+func NewFuturesPositions(subgraph PositionsSubgraph, blockchain PositionsChain, maxLag time.Duration, log interfaces.ILogger) *FuturesPositions {
+	return &FuturesPositions{
+		maxLag:     maxLag,
+		subgraph:   subgraph,
+		blockchain: blockchain,
+		log:        log,
+	}
+}
+
+func (p *FuturesPositions) GetAllPositions(ctx context.Context, deliveryAt time.Time) ([]contracts.FuturesContract, error) {
+	res, err := p.subgraph.GetAllPositions(ctx, deliveryAt)
+	if err == nil {
+		err = p.checkLag(ctx, res)
+	}
+	if err == nil {
+		return res.Contracts, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	p.log.Warnf("can't load positions from subgraph, loading from chain: %s", err)
+	return p.blockchain.GetDeliveryContracts(ctx, deliveryAt)
+}
+
+func (p *FuturesPositions) GetPositionsBySeller(ctx context.Context, sellerAddr common.Address, deliveryAt time.Time) ([]contracts.FuturesContract, error) {
+	res, err := p.subgraph.GetPositionsBySeller(ctx, sellerAddr, deliveryAt)
+	if err == nil {
+		err = p.checkLag(ctx, res)
+	}
+	if err == nil {
+		return res.Contracts, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	p.log.Warnf("can't load seller positions from subgraph, loading from chain: %s", err)
+	matched, err := p.blockchain.GetMatchedContracts(ctx, sellerAddr, deliveryAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// matched contracts include the positions where the participant is the buyer
+	positions := make([]contracts.FuturesContract, 0, len(matched))
+	for _, c := range matched {
+		if c.Seller == sellerAddr {
+			positions = append(positions, c)
+		}
+	}
+	return positions, nil
+}
+
+// removedPosition returns the position of the futures event reverted by the chain reorg, reverted delivery
+// close means the position is to be delivered again
+func removedPosition(event *contracts.RemovedEvent) (positionID common.Hash, deliveryReopened bool, ok bool) {
+	switch e := event.Event.(type) {
+	case *futures.FuturesPositionCreated:
+		return e.PositionId, false, true
+	case *futures.FuturesPositionClosed:
+		return e.PositionId, false, true
+	case *futures.FuturesPositionDeliveryClosed:
+		return e.PositionId, true, true
+	default:
+		return common.Hash{}, false, false
+	}
+}
+
+func (p *FuturesPositions) checkLag(ctx context.Context, res *subgraph.Positions) error {
+	latest, err := p.blockchain.GetLatestBlockTime(ctx)
+	if err != nil {
+		return err
+	}
+	lag := latest.Sub(res.BlockTimestamp)
+	if lag > p.maxLag {
+		return lib.WrapError(ErrSubgraphStale, fmt.Errorf("indexed block %d is %s behind", res.BlockNumber, lag))
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package contractmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/subgraph"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+type subgraphMock struct {
+	positions *subgraph.Positions
+	err       error
+}
+
+func (s *subgraphMock) GetAllPositions(ctx context.Context, deliveryAt time.Time) (*subgraph.Positions, error) {
+	return s.positions, s.err
+}
+
+func (s *subgraphMock) GetPositionsBySeller(ctx context.Context, sellerAddr common.Address, deliveryAt time.Time) (*subgraph.Positions, error) {
+	return s.positions, s.err
+}
+
+type positionsChainMock struct {
+	positions []contracts.FuturesContract
+	latest    time.Time
+}
+
+func (c *positionsChainMock) GetDeliveryContracts(ctx context.Context, deliveryDate time.Time) ([]contracts.FuturesContract, error) {
+	return c.positions, nil
+}
+
+func (c *positionsChainMock) GetMatchedContracts(ctx context.Context, userAddress common.Address, deliveryDate time.Time) ([]contracts.FuturesContract, error) {
+	return c.positions, nil
+}
+
+func (c *positionsChainMock) GetLatestBlockTime(ctx context.Context) (time.Time, error) {
+	return c.latest, nil
+}
+
+func TestFuturesPositionsFallback(t *testing.T) {
+	seller, buyer := common.HexToAddress("0x01"), common.HexToAddress("0x02")
+	latest := time.Unix(1_700_000_000, 0)
+	fromSubgraph := []contracts.FuturesContract{{ContractID: common.HexToHash("0xa1"), Seller: seller}}
+	fromChain := []contracts.FuturesContract{
+		{ContractID: common.HexToHash("0xb1"), Seller: seller, Buyer: buyer},
+		{ContractID: common.HexToHash("0xb2"), Seller: buyer, Buyer: seller},
+	}
+	chain := &positionsChainMock{positions: fromChain, latest: latest}
+
+	tests := []struct {
+		name     string
+		subgraph *subgraphMock
+		all      []contracts.FuturesContract
+		bySeller []contracts.FuturesContract
+	}{
+		{
+			name:     "subgraph is synced",
+			subgraph: &subgraphMock{positions: &subgraph.Positions{Contracts: fromSubgraph, BlockTimestamp: latest.Add(-time.Minute)}},
+			all:      fromSubgraph,
+			bySeller: fromSubgraph,
+		},
+		{
+			name:     "subgraph is stale",
+			subgraph: &subgraphMock{positions: &subgraph.Positions{Contracts: fromSubgraph, BlockTimestamp: latest.Add(-time.Hour)}},
+			all:      fromChain,
+			bySeller: fromChain[:1],
+		},
+		{
+			name:     "subgraph is down",
+			subgraph: &subgraphMock{err: errors.New("connection refused")},
+			all:      fromChain,
+			bySeller: fromChain[:1],
+		},
+		{
+			name:     "subgraph has indexing errors",
+			subgraph: &subgraphMock{err: subgraph.ErrIndexingErrors},
+			all:      fromChain,
+			bySeller: fromChain[:1],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewFuturesPositions(tt.subgraph, chain, 5*time.Minute, lib.NewTestLogger())
+
+			all, err := p.GetAllPositions(context.Background(), latest)
+			require.NoError(t, err)
+			require.Equal(t, tt.all, all)
+
+			// the chain returns the positions of both sides, only the seller ones are kept
+			bySeller, err := p.GetPositionsBySeller(context.Background(), seller, latest)
+			require.NoError(t, err)
+			require.Equal(t, tt.bySeller, bySeller)
+		})
+	}
+}
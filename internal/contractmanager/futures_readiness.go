@@ -0,0 +1,292 @@
+package contractmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/proxy"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	WebhookTimeout         = 10 * time.Second
+	WarmUpReconnectTimeout = 30 * time.Second // delay before reopening the dropped destination session
+)
+
+// Readiness is the result of the check of the seller ability to deliver the upcoming delivery range
+type Readiness struct {
+	DeliveryAt         time.Time
+	CheckedAt          time.Time
+	Positions          int // paid positions to be delivered
+	UnpaidPositions    int
+	CommittedGHS       float64
+	FleetGHS           float64
+	ValidatorURL       string
+	ValidatorLatency   time.Duration `json:",omitempty"`
+	ValidatorReachable bool
+	Ready              bool
+	Alerts             []string `json:",omitempty"`
+}
+
+// FleetHashrateFn returns the current hashrate of the connected miners in GH/s
+type FleetHashrateFn func() float64
+
+// prefetchedPositions are the positions of the delivery range loaded by the readiness check
+type prefetchedPositions struct {
+	deliveryAt time.Time
+	positions  []contracts.FuturesContract
+}
+
+// FuturesReadiness checks the upcoming delivery range of the seller in advance: loads the matched positions,
+// compares the committed hashrate with the fleet hashrate and opens the session to the validator. If the seller
+// is not ready the alert is logged and sent to the webhook. After the check the destination sessions of the paid
+// positions are kept open until the delivery starts, and the loaded positions are handed over to the futures manager
+type FuturesReadiness struct {
+	// config
+	userAddr     common.Address
+	lead         time.Duration // how long before the delivery range the check is performed
+	speedGHS     float64       // hashrate of the single position
+	validatorURL *url.URL
+	webhookURL   string
+
+	// state
+	last       *lib.AtomicValue[*Readiness]
+	prefetched *lib.AtomicValue[*prefetchedPositions]
+
+	// deps
+	blockchain *contracts.FuturesEthereum
+	positions  *FuturesPositions
+	fleetHR    FleetHashrateFn
+	httpClient *http.Client
+	log        interfaces.ILogger
+}
+
+func NewFuturesReadiness(userAddr common.Address, lead time.Duration, speedGHS float64, validatorURL *url.URL, webhookURL string, blockchain *contracts.FuturesEthereum, positions *FuturesPositions, fleetHR FleetHashrateFn, log interfaces.ILogger) *FuturesReadiness {
+	return &FuturesReadiness{
+		userAddr:     userAddr,
+		lead:         lead,
+		speedGHS:     speedGHS,
+		validatorURL: validatorURL,
+		webhookURL:   webhookURL,
+		last:         lib.NewAtomicValue[*Readiness](nil),
+		prefetched:   lib.NewAtomicValue[*prefetchedPositions](nil),
+		blockchain:   blockchain,
+		positions:    positions,
+		fleetHR:      fleetHR,
+		httpClient:   &http.Client{Timeout: WebhookTimeout},
+		log:          log,
+	}
+}
+
+func (r *FuturesReadiness) Run(ctx context.Context) error {
+	for {
+		next, err := r.getNextDelivery(ctx)
+		if err != nil {
+			return err
+		}
+
+		checkAt := next.Add(-r.lead)
+		r.log.Infof("readiness check for delivery at %s scheduled at %s", next.Format(time.RFC3339), checkAt.Format(time.RFC3339))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Until(checkAt)):
+		}
+
+		_, err = r.Check(ctx, next)
+		if err != nil {
+			r.log.Errorf("readiness check failed: %s", err)
+		}
+
+		// keep the destination sessions until the delivery range starts before scheduling the next check
+		r.warmUp(ctx, next)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// CheckNext checks the readiness for the next delivery range
+func (r *FuturesReadiness) CheckNext(ctx context.Context) (*Readiness, error) {
+	next, err := r.getNextDelivery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.Check(ctx, next)
+}
+
+// Check checks the readiness for the delivery range starting at deliveryAt and alerts if the seller is not ready
+func (r *FuturesReadiness) Check(ctx context.Context, deliveryAt time.Time) (*Readiness, error) {
+	positions, err := r.positions.GetPositionsBySeller(ctx, r.userAddr, deliveryAt)
+	if err != nil {
+		return nil, err
+	}
+	r.prefetched.Store(&prefetchedPositions{deliveryAt: deliveryAt, positions: positions})
+
+	res := &Readiness{
+		DeliveryAt:   deliveryAt,
+		CheckedAt:    time.Now(),
+		FleetGHS:     r.fleetHR(),
+		ValidatorURL: r.validatorURL.Redacted(),
+	}
+	for _, p := range positions {
+		if p.Paid {
+			res.Positions++
+		} else {
+			res.UnpaidPositions++
+		}
+	}
+	res.CommittedGHS = float64(res.Positions) * r.speedGHS
+
+	if res.CommittedGHS > res.FleetGHS {
+		res.Alerts = append(res.Alerts, fmt.Sprintf("fleet hashrate %.0f GH/s doesn't cover committed %.0f GH/s", res.FleetGHS, res.CommittedGHS))
+	}
+
+	if res.Positions > 0 {
+		latency, err := proxy.CheckDest(ctx, r.validatorURL, r.log)
+		if err != nil {
+			res.Alerts = append(res.Alerts, fmt.Sprintf("validator is not reachable: %s", err))
+		} else {
+			res.ValidatorReachable = true
+			res.ValidatorLatency = latency
+		}
+	}
+
+	res.Ready = len(res.Alerts) == 0
+	r.last.Store(res)
+
+	if res.Ready {
+		r.log.Infof("ready for delivery at %s: %d positions, committed %.0f GH/s, fleet %.0f GH/s", deliveryAt.Format(time.RFC3339), res.Positions, res.CommittedGHS, res.FleetGHS)
+		return res, nil
+	}
+
+	for _, alert := range res.Alerts {
+		r.log.Warnf("not ready for delivery at %s: %s", deliveryAt.Format(time.RFC3339), alert)
+	}
+	if r.webhookURL != "" {
+		err = r.notify(ctx, res)
+		if err != nil {
+			r.log.Errorf("readiness webhook error: %s", err)
+		}
+	}
+	return res, nil
+}
+
+// TakePositions returns the positions of the delivery range loaded by the last check, the positions are
+// returned once. Returns false if the delivery range wasn't checked
+func (r *FuturesReadiness) TakePositions(deliveryAt time.Time) ([]contracts.FuturesContract, bool) {
+	prefetched := r.prefetched.Load()
+	if prefetched == nil || !prefetched.deliveryAt.Equal(deliveryAt) {
+		return nil, false
+	}
+	if !r.prefetched.CompareAndSwap(prefetched, nil) {
+		return nil, false
+	}
+	return prefetched.positions, true
+}
+
+// warmUp keeps the destination sessions of the paid positions of the last check open until the delivery starts,
+// so the validator connection is established and monitored in advance
+func (r *FuturesReadiness) warmUp(ctx context.Context, deliveryAt time.Time) {
+	ctx, cancel := context.WithDeadline(ctx, deliveryAt)
+	defer cancel()
+
+	var dests []*url.URL
+	if prefetched := r.prefetched.Load(); prefetched != nil && prefetched.deliveryAt.Equal(deliveryAt) {
+		for _, p := range prefetched.positions {
+			if !p.Paid {
+				continue
+			}
+			// the same destination as the one the contract uses
+			dest := lib.CopyURL(r.validatorURL)
+			lib.SetUserName(dest, p.ID())
+			dests = append(dests, dest)
+		}
+	}
+	if len(dests) > 0 {
+		r.log.Infof("keeping %d destination sessions open until delivery at %s", len(dests), deliveryAt.Format(time.RFC3339))
+	}
+
+	var wg sync.WaitGroup
+	for _, dest := range dests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.keepDestSession(ctx, dest, deliveryAt)
+		}()
+	}
+	<-ctx.Done()
+	wg.Wait()
+}
+
+// keepDestSession keeps the session to the destination open until the context is done, reopening it if dropped
+func (r *FuturesReadiness) keepDestSession(ctx context.Context, dest *url.URL, until time.Time) {
+	for {
+		session, err := proxy.OpenDestSession(ctx, dest, time.Until(until)+proxy.DIAL_TIMEOUT, r.log)
+		if err != nil {
+			r.log.Warnf("can't open destination session %s before the delivery: %s", dest.Redacted(), err)
+		} else {
+			select {
+			case <-ctx.Done():
+				session.Close()
+				return
+			case <-session.Done():
+				r.log.Warnf("destination session %s closed before the delivery: %s", dest.Redacted(), session.Err())
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(WarmUpReconnectTimeout):
+		}
+	}
+}
+
+// GetLast returns the result of the last check, nil if there was no check yet
+func (r *FuturesReadiness) GetLast() *Readiness {
+	return r.last.Load()
+}
+
+func (r *FuturesReadiness) getNextDelivery(ctx context.Context) (time.Time, error) {
+	start, end, _, err := r.blockchain.GetOngoingDeliveryRange(ctx)
+	if err != nil {
+		return time.Time{}, lib.WrapError(fmt.Errorf("can't get ongoing delivery range"), err)
+	}
+	if time.Now().Before(start) {
+		return start, nil
+	}
+	return end, nil
+}
+
+func (r *FuturesReadiness) notify(ctx context.Context, res *Readiness) error {
+	body, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package contractmanager
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadinessTakePositions(t *testing.T) {
+	validatorURL, err := url.Parse("stratum+tcp://validator.dev:3333")
+	require.NoError(t, err)
+	r := NewFuturesReadiness(common.Address{}, time.Hour, 100, validatorURL, "", nil, nil, func() float64 { return 0 }, lib.NewTestLogger())
+
+	deliveryAt := time.Unix(1_700_000_000, 0)
+	positions := []contracts.FuturesContract{{DeliveryAt: deliveryAt, Paid: true}}
+	r.prefetched.Store(&prefetchedPositions{deliveryAt: deliveryAt, positions: positions})
+
+	_, ok := r.TakePositions(deliveryAt.Add(time.Hour))
+	require.False(t, ok)
+
+	taken, ok := r.TakePositions(deliveryAt)
+	require.True(t, ok)
+	require.Equal(t, positions, taken)
+
+	// positions are handed over once, the next delivery range loads them again
+	_, ok = r.TakePositions(deliveryAt)
+	require.False(t, ok)
+}
@@ -0,0 +1,420 @@
+package contractmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/hashprice"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/allocator"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/contract"
+	hr "github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/hashrate"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	AutoListActionCreate = "create"
+	AutoListActionUpdate = "update"
+	AutoListActionDelete = "delete"
+	AutoListActionRelist = "relist"
+)
+
+// AutoListAwaitTimeout is the minimum time the created contracts are awaited to be loaded, after that the missing
+// contracts are created again, e.g. if the transaction was dropped
+const AutoListAwaitTimeout = 10 * time.Minute
+
+var (
+	ErrHashpriceStale = errors.New("hashprice is stale")
+	ErrNoPubKey       = errors.New("public key for the new contracts is unknown, set SELLER_AUTOLIST_PUBKEY")
+)
+
+// AutoListAction is the clonefactory transaction planned by the auto-lister
+type AutoListAction struct {
+	Type        string
+	ContractIDs []string            `json:",omitempty"`
+	Result      *contracts.TxResult `json:",omitempty"`
+	Error       string              `json:",omitempty"`
+}
+
+// AutoListPlan is the result of the single auto-lister run
+type AutoListPlan struct {
+	At                time.Time
+	DryRun            bool
+	HashpricePerPHDay float64
+	ProfitTarget      int8
+	Price             *big.Int // price of the listed contract in the payment token base units
+	CapacityGHS       float64  // hashrate of the vetted miners
+	ReserveGHS        float64
+	ListedContracts   int
+	DesiredContracts  int
+	Actions           []AutoListAction
+}
+
+// AutoListStore is the clonefactory used by the auto-lister
+type AutoListStore interface {
+	GetHashpriceOracle(ctx context.Context) (*contracts.HashpriceOracle, error)
+	GetContractPubKey(ctx context.Context, contractID string) (string, error)
+	CreateContract(ctx context.Context, signer interfaces.Signer, speedHps *big.Int, length time.Duration, profitTarget int8, pubKey string, dryRun bool) (*contracts.TxResult, error)
+	UpdateContractTerms(ctx context.Context, signer interfaces.Signer, contractID string, speedHps *big.Int, length time.Duration, profitTarget int8, dryRun bool) (*contracts.TxResult, error)
+	SetContractsDeleted(ctx context.Context, signer interfaces.Signer, contractIDs []string, deleted bool, dryRun bool) (*contracts.TxResult, error)
+}
+
+// CapacitySource returns the hashrate of the connected miners
+type CapacitySource interface {
+	GetCapacity() allocator.Capacity
+}
+
+// SellerAutoLister keeps the number of the listed seller contracts in line with the fleet capacity and reprices them
+// following the hashprice feed. The contract price is set on-chain from the hashrate oracle and the profit target,
+// so the policy translates the desired price into the profit target, bounded by the configured limits
+type SellerAutoLister struct {
+	// config
+	walletAddr      common.Address
+	interval        time.Duration
+	dryRun          bool
+	contractGHS     float64 // hashrate of the single listed contract
+	contractLength  time.Duration
+	reserveGHS      float64 // fleet hashrate that is never listed
+	markupPercent   float64 // price over the hashprice
+	minProfitTarget int8
+	maxProfitTarget int8
+	maxContracts    int           // maximum number of the listed contracts
+	maxChanges      int           // maximum number of the transactions per run
+	maxHashpriceAge time.Duration // hashprice older than this is not used
+	pubKey          string        // public key for the new contracts, taken from the existing contracts if empty
+
+	// state
+	lastPlan    *lib.AtomicValue[*AutoListPlan]
+	awaitListed int       // number of the listed contracts expected after the previous run created the contracts
+	awaitUntil  time.Time // the created contracts are not awaited after this time
+	runMutex    sync.Mutex
+
+	// deps
+	store     AutoListStore
+	contracts *lib.Collection[resources.Contract]
+	allocator CapacitySource
+	hashprice hashprice.Source
+	signer    interfaces.Signer
+	log       interfaces.ILogger
+}
+
+func NewSellerAutoLister(
+	walletAddr common.Address,
+	interval time.Duration,
+	dryRun bool,
+	contractGHS float64,
+	contractLength time.Duration,
+	reserveGHS float64,
+	markupPercent float64,
+	minProfitTarget int8,
+	maxProfitTarget int8,
+	maxContracts int,
+	maxChanges int,
+	maxHashpriceAge time.Duration,
+	pubKey string,
+	store AutoListStore,
+	contracts *lib.Collection[resources.Contract],
+	allocator CapacitySource,
+	hashprice hashprice.Source,
+	signer interfaces.Signer,
+	log interfaces.ILogger,
+) *SellerAutoLister {
+	return &SellerAutoLister{
+		walletAddr:      walletAddr,
+		interval:        interval,
+		dryRun:          dryRun,
+		contractGHS:     contractGHS,
+		contractLength:  contractLength,
+		reserveGHS:      reserveGHS,
+		markupPercent:   markupPercent,
+		minProfitTarget: minProfitTarget,
+		maxProfitTarget: maxProfitTarget,
+		maxContracts:    maxContracts,
+		maxChanges:      maxChanges,
+		maxHashpriceAge: maxHashpriceAge,
+		pubKey:          pubKey,
+		lastPlan:        lib.NewAtomicValue[*AutoListPlan](nil),
+		store:           store,
+		contracts:       contracts,
+		allocator:       allocator,
+		hashprice:       hashprice,
+		signer:          signer,
+		log:             log,
+	}
+}
+
+func (a *SellerAutoLister) Run(ctx context.Context) error {
+	if a.dryRun {
+		a.log.Warnf("auto-listing is in dry run mode, transactions are only estimated")
+	}
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		_, err := a.RunOnce(ctx, a.dryRun)
+		if err != nil {
+			a.log.Errorf("auto-listing failed: %s", err)
+		}
+	}
+}
+
+// GetLastPlan returns the plan of the last run, nil if there was no run yet
+func (a *SellerAutoLister) GetLastPlan() *AutoListPlan {
+	return a.lastPlan.Load()
+}
+
+// RunOnce lists, delists and reprices the contracts, with dryRun the transactions are only estimated.
+// The configured dry run mode can't be turned off per run
+func (a *SellerAutoLister) RunOnce(ctx context.Context, dryRun bool) (*AutoListPlan, error) {
+	a.runMutex.Lock()
+	defer a.runMutex.Unlock()
+
+	dryRun = dryRun || a.dryRun
+
+	price, err := a.hashprice.GetHashprice(ctx)
+	if err != nil {
+		return nil, lib.WrapError(fmt.Errorf("can't get hashprice"), err)
+	}
+	if !price.UpdatedAt.IsZero() && time.Since(price.UpdatedAt) > a.maxHashpriceAge {
+		return nil, lib.WrapError(ErrHashpriceStale, fmt.Errorf("updated at %s", price.UpdatedAt.Format(time.RFC3339)))
+	}
+
+	oracle, err := a.store.GetHashpriceOracle(ctx)
+	if err != nil {
+		return nil, lib.WrapError(fmt.Errorf("can't get hashrate oracle"), err)
+	}
+
+	speedHps := a.speedHps()
+	basePrice := oracle.BasePrice(speedHps, a.contractLength)
+	profitTarget, err := CalcProfitTarget(price.PricePerPHDay, a.markupPercent, a.contractGHS, a.contractLength, basePrice, oracle.TokenDecimals, a.minProfitTarget, a.maxProfitTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	listed, delisted := a.getOwnContracts()
+	capacity := a.allocator.GetCapacity()
+
+	// the listed contracts may be also delisted or purchased meanwhile, so the created contracts are awaited
+	// only until their number is reached or the timeout
+	if a.awaitListed > 0 && (len(listed) >= a.awaitListed || time.Now().After(a.awaitUntil)) {
+		a.awaitListed = 0
+	}
+
+	plan := &AutoListPlan{
+		At:                time.Now(),
+		DryRun:            dryRun,
+		HashpricePerPHDay: price.PricePerPHDay,
+		ProfitTarget:      profitTarget,
+		Price:             priceWithProfitTarget(basePrice, profitTarget),
+		CapacityGHS:       capacity.TotalGHS,
+		ReserveGHS:        a.reserveGHS,
+		ListedContracts:   len(listed),
+		DesiredContracts:  DesiredContracts(capacity.TotalGHS, a.reserveGHS, a.contractGHS, a.maxContracts),
+	}
+
+	changes := 0
+	canChange := func() bool {
+		return changes < a.maxChanges
+	}
+
+	switch {
+	case plan.DesiredContracts > len(listed):
+		missing := plan.DesiredContracts - len(listed)
+		if !dryRun && len(listed) < a.awaitListed {
+			a.log.Infof("waiting for %d created contracts to be loaded", a.awaitListed-len(listed))
+			break
+		}
+
+		// the delisted contracts are listed again before creating the new ones
+		if len(delisted) > 0 && canChange() {
+			ids := contractIDs(delisted[:min(missing, len(delisted))])
+			res, err := a.store.SetContractsDeleted(ctx, a.signer, ids, false, dryRun)
+			plan.addAction(AutoListActionRelist, ids, res, err)
+			changes++
+			missing -= len(ids)
+		}
+
+		if missing > 0 {
+			pubKey, err := a.getPubKey(ctx, listed, delisted)
+			if err != nil {
+				return nil, err
+			}
+			created := 0
+			for ; missing > 0 && canChange(); missing-- {
+				res, err := a.store.CreateContract(ctx, a.signer, speedHps, a.contractLength, profitTarget, pubKey, dryRun)
+				plan.addAction(AutoListActionCreate, nil, res, err)
+				changes++
+				if err == nil {
+					created++
+				}
+			}
+			if !dryRun && created > 0 {
+				a.awaitListed = len(listed) + created
+				a.awaitUntil = time.Now().Add(max(3*a.interval, AutoListAwaitTimeout))
+			}
+		}
+
+	case plan.DesiredContracts < len(listed):
+		// only the contracts that are not running are delisted, the running ones are delivered till the end
+		surplus := len(listed) - plan.DesiredContracts
+		var ids []string
+		for _, c := range listed {
+			if len(ids) < surplus && c.BlockchainState() != hashrate.BlockchainStateRunning {
+				ids = append(ids, c.ID())
+			}
+		}
+		if len(ids) > 0 && canChange() {
+			res, err := a.store.SetContractsDeleted(ctx, a.signer, ids, true, dryRun)
+			plan.addAction(AutoListActionDelete, ids, res, err)
+			changes++
+			listed = withoutContracts(listed, ids)
+			if !dryRun && err == nil {
+				a.awaitListed = 0
+			}
+		}
+	}
+
+	for _, c := range listed {
+		if !canChange() {
+			break
+		}
+		if c.BlockchainState() == hashrate.BlockchainStateRunning || !a.needsUpdate(c, profitTarget) {
+			continue
+		}
+		res, err := a.store.UpdateContractTerms(ctx, a.signer, c.ID(), speedHps, a.contractLength, profitTarget, dryRun)
+		plan.addAction(AutoListActionUpdate, []string{c.ID()}, res, err)
+		changes++
+	}
+
+	if changes >= a.maxChanges {
+		a.log.Warnf("auto-listing reached the limit of %d transactions per run, the rest is postponed", a.maxChanges)
+	}
+	a.log.Infof("auto-listing: hashprice %.2f/PH/day, profit target %d%%, listed %d, desired %d, %d transactions (dry run %t)",
+		price.PricePerPHDay, profitTarget, plan.ListedContracts, plan.DesiredContracts, len(plan.Actions), dryRun)
+
+	a.lastPlan.Store(plan)
+	return plan, nil
+}
+
+// getOwnContracts returns the listed and delisted seller contracts of the wallet
+func (a *SellerAutoLister) getOwnContracts() (listed []resources.Contract, delisted []resources.Contract) {
+	a.contracts.Range(func(c resources.Contract) bool {
+		if c.Role() != resources.ContractRoleSeller || common.HexToAddress(c.Seller()) != a.walletAddr {
+			return true
+		}
+		if c.IsDeleted() {
+			delisted = append(delisted, c)
+		} else {
+			listed = append(listed, c)
+		}
+		return true
+	})
+	return listed, delisted
+}
+
+func (a *SellerAutoLister) needsUpdate(c resources.Contract, profitTarget int8) bool {
+	if c.ProfitTarget() != profitTarget || c.Duration() != a.contractLength {
+		return true
+	}
+	return math.Round(c.ResourceEstimates()[contract.ResourceEstimateHashrateGHS]) != math.Round(a.contractGHS)
+}
+
+func (a *SellerAutoLister) getPubKey(ctx context.Context, lists ...[]resources.Contract) (string, error) {
+	if a.pubKey != "" {
+		return a.pubKey, nil
+	}
+	for _, list := range lists {
+		for _, c := range list {
+			pubKey, err := a.store.GetContractPubKey(ctx, c.ID())
+			if err != nil {
+				return "", err
+			}
+			if pubKey != "" {
+				a.pubKey = pubKey
+				return pubKey, nil
+			}
+		}
+	}
+	return "", ErrNoPubKey
+}
+
+func (a *SellerAutoLister) speedHps() *big.Int {
+	return new(big.Int).SetUint64(uint64(hr.GHSToHS(int(a.contractGHS))))
+}
+
+func (p *AutoListPlan) addAction(actionType string, ids []string, res *contracts.TxResult, err error) {
+	action := AutoListAction{Type: actionType, ContractIDs: ids, Result: res}
+	if err != nil {
+		action.Error = err.Error()
+	}
+	p.Actions = append(p.Actions, action)
+}
+
+// DesiredContracts returns the number of the contracts that fit into the fleet capacity without the reserve
+func DesiredContracts(capacityGHS, reserveGHS, contractGHS float64, maxContracts int) int {
+	if contractGHS <= 0 {
+		return 0
+	}
+	n := int(math.Floor((capacityGHS - reserveGHS) / contractGHS))
+	return max(0, min(n, maxContracts))
+}
+
+// CalcProfitTarget returns the profit target percent that makes the contract price equal to the hashprice with markup,
+// base price is the price of the contract with zero profit target in the payment token base units
+func CalcProfitTarget(pricePerPHDay, markupPercent, contractGHS float64, length time.Duration, basePrice *big.Int, tokenDecimals int64, minTarget, maxTarget int8) (int8, error) {
+	if basePrice.Sign() <= 0 {
+		return 0, fmt.Errorf("hashrate oracle returned zero price")
+	}
+
+	target := pricePerPHDay * (contractGHS / 1e6) * (length.Hours() / 24) * (1 + markupPercent/100)
+	targetBase := new(big.Float).Mul(big.NewFloat(target), new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(tokenDecimals), nil)))
+	ratio, _ := new(big.Float).Quo(targetBase, new(big.Float).SetInt(basePrice)).Float64()
+
+	percent := math.Round((ratio - 1) * 100)
+	percent = math.Max(percent, float64(minTarget))
+	percent = math.Min(percent, float64(maxTarget))
+	return int8(percent), nil
+}
+
+func priceWithProfitTarget(basePrice *big.Int, profitTarget int8) *big.Int {
+	res := new(big.Int).Mul(basePrice, big.NewInt(100+int64(profitTarget)))
+	return res.Div(res, big.NewInt(100))
+}
+
+func contractIDs(list []resources.Contract) []string {
+	ids := make([]string, len(list))
+	for i, c := range list {
+		ids[i] = c.ID()
+	}
+	return ids
+}
+
+func withoutContracts(list []resources.Contract, ids []string) []resources.Contract {
+	skip := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		skip[id] = struct{}{}
+	}
+	var res []resources.Contract
+	for _, c := range list {
+		if _, ok := skip[c.ID()]; !ok {
+			res = append(res, c)
+		}
+	}
+	return res
+}
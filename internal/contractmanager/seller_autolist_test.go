@@ -0,0 +1,182 @@
+package contractmanager
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/hashprice"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/allocator"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/contract"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalcProfitTarget(t *testing.T) {
+	// 100 TH/s for a day with zero profit target costs 4 tokens with 6 decimals
+	basePrice := big.NewInt(4_000_000)
+
+	// 50 per PH/day is 5 tokens for 100 TH/s, 25% over the oracle price
+	pt, err := CalcProfitTarget(50, 0, 100_000, 24*time.Hour, basePrice, 6, -10, 100)
+	require.NoError(t, err)
+	require.Equal(t, int8(25), pt)
+
+	// markup is added on top of the hashprice
+	pt, err = CalcProfitTarget(50, 10, 100_000, 24*time.Hour, basePrice, 6, -10, 100)
+	require.NoError(t, err)
+	require.Equal(t, int8(38), pt)
+
+	// clamped to the limits
+	pt, err = CalcProfitTarget(20, 0, 100_000, 24*time.Hour, basePrice, 6, -10, 100)
+	require.NoError(t, err)
+	require.Equal(t, int8(-10), pt)
+	pt, err = CalcProfitTarget(500, 0, 100_000, 24*time.Hour, basePrice, 6, -10, 100)
+	require.NoError(t, err)
+	require.Equal(t, int8(100), pt)
+
+	_, err = CalcProfitTarget(50, 0, 100_000, 24*time.Hour, big.NewInt(0), 6, -10, 100)
+	require.Error(t, err)
+}
+
+func TestDesiredContracts(t *testing.T) {
+	require.Equal(t, 3, DesiredContracts(350_000, 0, 100_000, 10))
+	require.Equal(t, 2, DesiredContracts(350_000, 100_000, 100_000, 10))
+	require.Equal(t, 1, DesiredContracts(350_000, 0, 100_000, 1))
+	require.Equal(t, 0, DesiredContracts(50_000, 100_000, 100_000, 10))
+	require.Equal(t, 0, DesiredContracts(350_000, 0, 0, 10))
+}
+
+type autoListStoreMock struct {
+	created int
+	deleted []string
+	dryRuns []bool
+}
+
+func (s *autoListStoreMock) GetHashpriceOracle(ctx context.Context) (*contracts.HashpriceOracle, error) {
+	return &contracts.HashpriceOracle{HashesForToken: big.NewInt(1_000_000_000_000), TokenDecimals: 6}, nil
+}
+
+func (s *autoListStoreMock) GetContractPubKey(ctx context.Context, contractID string) (string, error) {
+	return "pubkey", nil
+}
+
+func (s *autoListStoreMock) CreateContract(ctx context.Context, signer interfaces.Signer, speedHps *big.Int, length time.Duration, profitTarget int8, pubKey string, dryRun bool) (*contracts.TxResult, error) {
+	s.dryRuns = append(s.dryRuns, dryRun)
+	if !dryRun {
+		s.created++
+	}
+	return &contracts.TxResult{}, nil
+}
+
+func (s *autoListStoreMock) UpdateContractTerms(ctx context.Context, signer interfaces.Signer, contractID string, speedHps *big.Int, length time.Duration, profitTarget int8, dryRun bool) (*contracts.TxResult, error) {
+	s.dryRuns = append(s.dryRuns, dryRun)
+	return &contracts.TxResult{}, nil
+}
+
+func (s *autoListStoreMock) SetContractsDeleted(ctx context.Context, signer interfaces.Signer, contractIDs []string, deleted bool, dryRun bool) (*contracts.TxResult, error) {
+	s.dryRuns = append(s.dryRuns, dryRun)
+	if !dryRun && deleted {
+		s.deleted = append(s.deleted, contractIDs...)
+	}
+	return &contracts.TxResult{}, nil
+}
+
+type capacityMock struct {
+	totalGHS float64
+}
+
+func (c *capacityMock) GetCapacity() allocator.Capacity {
+	return allocator.Capacity{TotalGHS: c.totalGHS}
+}
+
+type hashpriceMock struct{}
+
+func (hashpriceMock) GetHashprice(ctx context.Context) (*hashprice.Hashprice, error) {
+	return &hashprice.Hashprice{PricePerPHDay: 50}, nil
+}
+
+// listedContractMock is the own listed seller contract, only the methods used by the auto-lister are implemented
+type listedContractMock struct {
+	resources.Contract
+	id     string
+	seller common.Address
+}
+
+func (c *listedContractMock) ID() string                   { return c.id }
+func (c *listedContractMock) Role() resources.ContractRole { return resources.ContractRoleSeller }
+func (c *listedContractMock) Seller() string               { return c.seller.Hex() }
+func (c *listedContractMock) IsDeleted() bool              { return false }
+func (c *listedContractMock) BlockchainState() hashrate.BlockchainState {
+	return hashrate.BlockchainStateAvailable
+}
+func (c *listedContractMock) ProfitTarget() int8      { return -10 }
+func (c *listedContractMock) Duration() time.Duration { return 24 * time.Hour }
+func (c *listedContractMock) ResourceEstimates() map[string]float64 {
+	return map[string]float64{contract.ResourceEstimateHashrateGHS: 100_000}
+}
+
+func newTestAutoLister(dryRun bool, store AutoListStore, capacity CapacitySource, contracts *lib.Collection[resources.Contract]) *SellerAutoLister {
+	return NewSellerAutoLister(common.HexToAddress("0x01"), time.Minute, dryRun, 100_000, 24*time.Hour, 0, 0, -10, 100, 10, 10, time.Hour, "pubkey",
+		store, contracts, capacity, hashpriceMock{}, nil, lib.NewTestLogger())
+}
+
+func TestAutoListAwaitsCreatedContracts(t *testing.T) {
+	ctx := context.Background()
+	store := &autoListStoreMock{}
+	capacity := &capacityMock{totalGHS: 200_000}
+	cc := lib.NewCollection[resources.Contract]()
+	a := newTestAutoLister(false, store, capacity, cc)
+
+	_, err := a.RunOnce(ctx, false)
+	require.NoError(t, err)
+	require.Equal(t, 2, store.created)
+
+	// the created contracts are not loaded yet
+	_, err = a.RunOnce(ctx, false)
+	require.NoError(t, err)
+	require.Equal(t, 2, store.created)
+
+	// loaded contracts
+	seller := common.HexToAddress("0x01")
+	cc.Store(&listedContractMock{id: "0xa", seller: seller})
+	cc.Store(&listedContractMock{id: "0xb", seller: seller})
+
+	// the capacity drops, one contract is delisted by the auto-lister
+	capacity.totalGHS = 100_000
+	_, err = a.RunOnce(ctx, false)
+	require.NoError(t, err)
+	require.Len(t, store.deleted, 1)
+	cc.Delete(store.deleted[0])
+
+	// the capacity grows again, the contract is created without waiting
+	capacity.totalGHS = 300_000
+	_, err = a.RunOnce(ctx, false)
+	require.NoError(t, err)
+	require.Equal(t, 4, store.created)
+
+	// the awaited contracts are given up after the timeout
+	a.awaitUntil = time.Now().Add(-time.Second)
+	_, err = a.RunOnce(ctx, false)
+	require.NoError(t, err)
+	require.Equal(t, 6, store.created)
+}
+
+func TestAutoListConfiguredDryRun(t *testing.T) {
+	store := &autoListStoreMock{}
+	a := newTestAutoLister(true, store, &capacityMock{totalGHS: 200_000}, lib.NewCollection[resources.Contract]())
+
+	plan, err := a.RunOnce(context.Background(), false)
+	require.NoError(t, err)
+	require.True(t, plan.DryRun)
+	require.Equal(t, 0, store.created)
+	require.NotEmpty(t, store.dryRuns)
+	for _, dryRun := range store.dryRuns {
+		require.True(t, dryRun)
+	}
+}
@@ -0,0 +1,55 @@
+package httphandlers
+
+import (
+	"errors"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/attestation"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *HTTPHandler) GetAttestations(ctx *gin.Context) {
+	reports, err := h.attestations.GetReports()
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, reports)
+}
+
+func (h *HTTPHandler) GetAttestation(ctx *gin.Context) {
+	positionID := ctx.Param("ID")
+	if id, err := hexutil.Decode(positionID); err != nil || len(id) != 32 {
+		ctx.JSON(400, gin.H{"error": "invalid position id"})
+		return
+	}
+
+	signed, err := h.attestations.Get(positionID)
+	if errors.Is(err, attestation.ErrNotFound) {
+		ctx.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, signed)
+}
+
+func (h *HTTPHandler) VerifyAttestation(ctx *gin.Context) {
+	var signed attestation.SignedReport
+	err := ctx.ShouldBindJSON(&signed)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	// typed nil registry is not passed, so the signature is still checked if the registry is disabled
+	var registry attestation.ValidatorRegistry
+	if h.registry != nil {
+		registry = h.registry
+	}
+	ctx.JSON(200, attestation.Check(ctx, &signed, registry))
+}
@@ -0,0 +1,31 @@
+package httphandlers
+
+import (
+	"crypto/subtle"
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAuth protects the endpoints sending transactions with the node wallet. With the api token configured
+// the request must have the "Authorization: Bearer <token>" header, otherwise only the local requests are allowed
+func (h *HTTPHandler) RequireAuth(ctx *gin.Context) {
+	if h.apiToken != "" {
+		token, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.apiToken)) != 1 {
+			ctx.AbortWithStatusJSON(401, gin.H{"error": "invalid api token"})
+			return
+		}
+		ctx.Next()
+		return
+	}
+
+	// trusted proxies are disabled, so the client ip is the remote address of the connection
+	ip := net.ParseIP(ctx.ClientIP())
+	if ip == nil || !ip.IsLoopback() {
+		ctx.AbortWithStatusJSON(403, gin.H{"error": "the endpoint is available only from localhost, set WEB_API_TOKEN to allow remote access"})
+		return
+	}
+	ctx.Next()
+}
@@ -0,0 +1,54 @@
+package httphandlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func authRouter(token string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := &HTTPHandler{apiToken: token}
+	r := gin.New()
+	_ = r.SetTrustedProxies(nil)
+	r.POST("/write", h.RequireAuth, func(ctx *gin.Context) {
+		ctx.Status(200)
+	})
+	return r
+}
+
+func authRequest(r *gin.Engine, remoteAddr string, header string) int {
+	req := httptest.NewRequest(http.MethodPost, "/write", nil)
+	req.RemoteAddr = remoteAddr
+	if header != "" {
+		req.Header.Set("Authorization", header)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestRequireAuth(t *testing.T) {
+	// without the token only the local requests are allowed
+	r := authRouter("")
+	require.Equal(t, 200, authRequest(r, "127.0.0.1:5000", ""))
+	require.Equal(t, 200, authRequest(r, "[::1]:5000", ""))
+	require.Equal(t, 403, authRequest(r, "10.0.0.2:5000", ""))
+
+	// the forwarded header is not trusted
+	req := httptest.NewRequest(http.MethodPost, "/write", nil)
+	req.RemoteAddr = "10.0.0.2:5000"
+	req.Header.Set("X-Forwarded-For", "127.0.0.1")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, 403, rec.Code)
+
+	// with the token it is required from any address
+	r = authRouter("secret")
+	require.Equal(t, 200, authRequest(r, "10.0.0.2:5000", "Bearer secret"))
+	require.Equal(t, 401, authRequest(r, "127.0.0.1:5000", ""))
+	require.Equal(t, 401, authRequest(r, "10.0.0.2:5000", "Bearer wrong"))
+}
@@ -0,0 +1,46 @@
+package httphandlers
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type BuyerAutoBuyQP struct {
+	DryRun bool `form:"dryRun"`
+}
+
+func (h *HTTPHandler) GetBuyerAutoBuy(ctx *gin.Context) {
+	if h.autoBuyer == nil {
+		ctx.JSON(404, gin.H{"error": "buyer auto-buying is disabled"})
+		return
+	}
+
+	plan := h.autoBuyer.GetLastPlan()
+	if plan == nil {
+		ctx.JSON(404, gin.H{"error": "auto-buying did not run yet"})
+		return
+	}
+
+	ctx.JSON(200, plan)
+}
+
+func (h *HTTPHandler) RunBuyerAutoBuy(ctx *gin.Context) {
+	if h.autoBuyer == nil {
+		ctx.JSON(404, gin.H{"error": "buyer auto-buying is disabled"})
+		return
+	}
+
+	var qp BuyerAutoBuyQP
+	err := ctx.ShouldBindQuery(&qp)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := h.autoBuyer.RunOnce(ctx, qp.DryRun)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, plan)
+}
@@ -2,6 +2,7 @@ package httphandlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
@@ -10,7 +11,9 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/Lumerin-protocol/proxy-router/internal/contractmanager"
 	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
 	"github.com/Lumerin-protocol/proxy-router/internal/resources"
 	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate"
 	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/contract"
@@ -238,6 +241,44 @@ func (c *HTTPHandler) GetContract(ctx *gin.Context) {
 	ctx.JSON(200, contractData)
 }
 
+type CloseContractQP struct {
+	Reason uint8 `form:"reason" validate:"lte=3"`
+}
+
+// CloseContract closes the contract early on behalf of the buyer or validator
+func (c *HTTPHandler) CloseContract(ctx *gin.Context) {
+	contractID := ctx.Param("ID")
+	if contractID == "" {
+		ctx.JSON(400, gin.H{"error": "contract id is required"})
+		return
+	}
+
+	qp := CloseContractQP{}
+	err := ctx.ShouldBindQuery(&qp)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = c.validator.StructCtx(ctx, qp)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = c.cm.CloseContract(ctx, contractID, contracts.CloseReason(qp.Reason))
+	if errors.Is(err, contractmanager.ErrContractNotFound) {
+		ctx.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"status": "ok"})
+}
+
 func (c *HTTPHandler) GetDeliveryLogsConsole(ctx *gin.Context) {
 	contractID := ctx.Param("ID")
 	if contractID == "" {
@@ -271,12 +312,15 @@ func (c *HTTPHandler) GetDeliveryLogs(ctx *gin.Context) {
 		return
 	}
 
-	sellerContract, ok := contract.(*hrcontract.ControllerSeller)
+	// seller contracts log the allocation, buyer contracts log the verdicts of the validation policy
+	loggedContract, ok := contract.(interface {
+		GetDeliveryLogs() ([]hrcontract.DeliveryLogEntry, error)
+	})
 	if !ok {
-		ctx.JSON(400, gin.H{"error": "contract is not seller contract"})
+		ctx.JSON(400, gin.H{"error": "contract has no delivery logs"})
 		return
 	}
-	logs, err := sellerContract.GetDeliveryLogs()
+	logs, err := loggedContract.GetDeliveryLogs()
 	if err != nil {
 		ctx.JSON(500, gin.H{"error": err.Error()})
 		return
@@ -292,6 +336,18 @@ func (c *HTTPHandler) GetDeliveryLogs(ctx *gin.Context) {
 }
 
 func (p *HTTPHandler) mapContract(ctx context.Context, item resources.Contract) (*Contract, error) {
+	var hrInterval *HashrateInterval
+	if buyer, ok := item.(interface {
+		HashrateInterval() hrcontract.HashrateInterval
+	}); ok && item.Role() != resources.ContractRoleSeller {
+		hrInterval = mapHashrateInterval(buyer.HashrateInterval())
+	}
+	var destRoutes []hrcontract.DestRouteStats
+	if buyer, ok := item.(interface {
+		DestRoutes() []hrcontract.DestRouteStats
+	}); ok {
+		destRoutes = buyer.DestRoutes()
+	}
 
 	return &Contract{
 		Resource: Resource{
@@ -309,6 +365,7 @@ func (p *HTTPHandler) mapContract(ctx context.Context, item resources.Contract)
 		ResourceEstimatesTarget: roundResourceEstimates(item.ResourceEstimates()),       // readonly
 		ResourceEstimatesActual: roundResourceEstimates(item.ResourceEstimatesActual()), // multiple atomics
 		StarvingGHS:             item.StarvingGHS(),                                     // atomic
+		HashrateInterval:        hrInterval,                                             // atomic
 		PriceLMR:                LMRWithDecimalsToLMR(item.Price()),                     // readonly
 		ProfitTarget:            item.ProfitTarget(),                                    // readonly
 		Duration:                formatDuration(item.Duration()),                        // readonly
@@ -326,10 +383,24 @@ func (p *HTTPHandler) mapContract(ctx context.Context, item resources.Contract)
 		Error:             errString(item.Error()),         // atomic
 		Dest:              item.Dest(),                     // readonly
 		PoolDest:          item.PoolDest(),                 // readonly
+		DestRoutes:        destRoutes,                      // mutex
 		// Miners:            p.allocator.GetMinersFulfillingContract(item.ID(), p.cycleDuration),
 	}, nil
 }
 
+func mapHashrateInterval(i hrcontract.HashrateInterval) *HashrateInterval {
+	return &HashrateInterval{
+		Shares:            i.Shares,
+		ExpectedShares:    int(i.ExpectedShares),
+		EstimateGHS:       int(i.EstimateGHS),
+		LowerGHS:          int(i.LowerGHS),
+		UpperGHS:          int(i.UpperGHS),
+		MinAcceptedGHS:    int(i.MinAcceptedGHS),
+		Confidence:        i.Confidence,
+		IsUnderdelivering: i.IsUnderdelivering(),
+	}
+}
+
 func errString(s error) string {
 	if s != nil {
 		return s.Error()
@@ -352,6 +423,9 @@ func writeHTML(w io.Writer, logs []hrcontract.DeliveryLogEntry) error {
 		"GlobalUnderDeliveryGHS",
 		"GlobalError",
 		"NextCyclePartialDeliveryTargetGHS",
+		"ValidationPolicy",
+		"ValidationStatus",
+		"ValidationReason",
 	}
 
 	// header
@@ -399,6 +473,9 @@ func writeHTML(w io.Writer, logs []hrcontract.DeliveryLogEntry) error {
 			fmt.Sprint(entry.GlobalUnderDeliveryGHS),
 			fmt.Sprintf("%.2f", entry.GlobalError),
 			fmt.Sprint(entry.NextCyclePartialDeliveryTargetGHS),
+			entry.ValidationPolicy,
+			entry.ValidationStatus,
+			entry.ValidationReason,
 		)
 		if err != nil {
 			return err
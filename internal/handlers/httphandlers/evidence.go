@@ -0,0 +1,115 @@
+package httphandlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/evidence"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources"
+	hrcontract "github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/contract"
+	"github.com/gin-gonic/gin"
+)
+
+// GetContractEvidence exports the dispute evidence of the contract as the signed tar.gz archive, the evidence
+// of the futures positions is available for ClosedContractsRetention after the delivery close
+func (c *HTTPHandler) GetContractEvidence(ctx *gin.Context) {
+	contractID := ctx.Param("ID")
+	if contractID == "" {
+		ctx.JSON(400, gin.H{"error": "contract id is required"})
+		return
+	}
+	contract, ok := c.contractCollection.Load(contractID)
+	if !ok {
+		// the futures positions are removed on the delivery close, the evidence is still available
+		contract, ok = c.closedContracts.Load(contractID)
+	}
+	if !ok {
+		ctx.JSON(404, gin.H{"error": "contract not found"})
+		return
+	}
+
+	files, err := c.collectEvidence(ctx, contract)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	archive, err := evidence.Build(ctx, contract.ID(), files, c.signer)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=evidence-%s.tar.gz", contract.ID()))
+	ctx.Data(200, "application/gzip", archive)
+}
+
+// collectEvidence gathers the delivery state of the contract, the seller contracts have the per miner submit
+// statistics and the share samples, both seller and buyer contracts have the delivery log and destination errors
+func (c *HTTPHandler) collectEvidence(ctx context.Context, contract resources.Contract) ([]evidence.File, error) {
+	var files []evidence.File
+	addJSON := func(name string, v any) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		files = append(files, evidence.File{Name: name, Data: data})
+		return nil
+	}
+
+	contractData, err := c.mapContract(ctx, contract)
+	if err != nil {
+		return nil, err
+	}
+	err = addJSON("contract.json", contractData)
+	if err != nil {
+		return nil, err
+	}
+
+	if logged, ok := contract.(interface {
+		GetDeliveryLogs() ([]hrcontract.DeliveryLogEntry, error)
+	}); ok {
+		entries, err := logged.GetDeliveryLogs()
+		if err != nil {
+			return nil, err
+		}
+		err = addJSON("delivery-log.json", entries)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if seller, ok := contract.(interface {
+		GetMinerSubmitStats() []hrcontract.MinerSubmitStats
+		GetShareSamples() []hrcontract.ShareSample
+	}); ok {
+		err = addJSON("miners.json", seller.GetMinerSubmitStats())
+		if err != nil {
+			return nil, err
+		}
+		err = addJSON("shares.json", seller.GetShareSamples())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if dest, ok := contract.(interface {
+		GetDestErrors() []hrcontract.DestError
+	}); ok {
+		err = addJSON("dest-errors.json", dest.GetDestErrors())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if logStorage, ok := c.logStorage.Load(contract.ID()); ok {
+		data, err := io.ReadAll(logStorage.GetReader())
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, evidence.File{Name: "contract.log", Data: data})
+	}
+
+	return files, nil
+}
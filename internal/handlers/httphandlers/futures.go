@@ -0,0 +1,35 @@
+package httphandlers
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func (h *HTTPHandler) GetFuturesReadiness(ctx *gin.Context) {
+	if h.readiness == nil {
+		ctx.JSON(404, gin.H{"error": "readiness check is available for futures sellers only"})
+		return
+	}
+
+	last := h.readiness.GetLast()
+	if last == nil {
+		ctx.JSON(404, gin.H{"error": "readiness was not checked yet"})
+		return
+	}
+
+	ctx.JSON(200, last)
+}
+
+func (h *HTTPHandler) CheckFuturesReadiness(ctx *gin.Context) {
+	if h.readiness == nil {
+		ctx.JSON(404, gin.H{"error": "readiness check is available for futures sellers only"})
+		return
+	}
+
+	res, err := h.readiness.CheckNext(ctx)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, res)
+}
@@ -0,0 +1,72 @@
+package httphandlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/history"
+	"github.com/gin-gonic/gin"
+)
+
+type HistoryQP struct {
+	Range time.Duration `form:"range" validate:"omitempty,gt=0"`
+	Step  time.Duration `form:"step"  validate:"omitempty,gt=0"`
+}
+
+// GetHistory returns the hashrate time-series of a worker, miner or contract,
+// range and step are durations, for example ?range=24h&step=15m
+func (c *HTTPHandler) GetHistory(ctx *gin.Context) {
+	qp := HistoryQP{}
+	err := ctx.ShouldBindQuery(&qp)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = c.validator.StructCtx(ctx, qp)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if qp.Range == 0 {
+		qp.Range = time.Hour
+	}
+
+	kind, ID := history.Kind(ctx.Param("kind")), ctx.Param("ID")
+	points, resolution, err := c.history.Query(kind, ID, qp.Range, qp.Step)
+	if err != nil {
+		if errors.Is(err, history.ErrUnknownKind) {
+			ctx.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, history.ErrSeriesNotFound) {
+			ctx.JSON(404, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	step := qp.Step
+	if step < resolution {
+		step = resolution
+	}
+
+	res := HistoryResponse{
+		Kind:       string(kind),
+		ID:         ID,
+		Range:      formatDuration(qp.Range),
+		Step:       formatDuration(step),
+		Resolution: formatDuration(resolution),
+		Points:     make([]HistoryPoint, len(points)),
+	}
+	for i, p := range points {
+		res.Points[i] = HistoryPoint{
+			Timestamp:   formatTime(p.Timestamp),
+			HashrateGHS: int(p.Value),
+		}
+	}
+
+	ctx.JSON(200, res)
+}
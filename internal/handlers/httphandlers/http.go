@@ -15,10 +15,18 @@ import (
 	"github.com/Lumerin-protocol/proxy-router/internal/contractmanager"
 	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
 	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/attestation"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/ethpool"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/ledger"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/reputation"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/txmanager"
 	"github.com/Lumerin-protocol/proxy-router/internal/resources"
 	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate"
 	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/allocator"
 	hr "github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/hashrate"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/history"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/proxy"
 	"github.com/Lumerin-protocol/proxy-router/internal/system"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -35,12 +43,26 @@ type Sanitizable interface {
 
 type HTTPHandler struct {
 	globalHashrate         *hr.GlobalHashrate
+	history                *history.History
+	blockCandidates        *proxy.BlockCandidateLog
+	txManager              *txmanager.TxManager
+	registry               *contracts.ValidatorRegistryEthereum
+	signer                 interfaces.Signer
+	readiness              *contractmanager.FuturesReadiness
+	closedContracts        *contractmanager.ClosedContracts
+	autoLister             *contractmanager.SellerAutoLister
+	autoBuyer              *contractmanager.BuyerAutoBuyer
+	reputation             *reputation.Store
+	attestations           *attestation.Store
+	ledger                 *ledger.Ledger
+	ethPool                *ethpool.Pool
 	allocator              *allocator.Allocator
 	sysConfig              *system.SystemConfigurator
 	cfg                    Sanitizable
 	cycleDuration          time.Duration
 	hashrateCounterDefault string
 	publicUrl              *url.URL
+	apiToken               string
 	pubKey                 string
 	config                 Sanitizable
 	derivedConfig          *config.DerivedConfig
@@ -52,14 +74,28 @@ type HTTPHandler struct {
 	cm                     *contractmanager.ContractManager
 }
 
-func NewHTTPHandler(cm *contractmanager.ContractManager, contractCollection *lib.Collection[resources.Contract], allocator *allocator.Allocator, globalHashrate *hr.GlobalHashrate, sysConfig *system.SystemConfigurator, publicUrl *url.URL, hashrateCounter string, cycleDuration time.Duration, config Sanitizable, derivedConfig *config.DerivedConfig, appStartTime time.Time, logStorage *lib.Collection[*interfaces.LogStorage], log interfaces.ILogger) *gin.Engine {
+func NewHTTPHandler(cm *contractmanager.ContractManager, contractCollection *lib.Collection[resources.Contract], allocator *allocator.Allocator, globalHashrate *hr.GlobalHashrate, history *history.History, blockCandidates *proxy.BlockCandidateLog, txManager *txmanager.TxManager, registry *contracts.ValidatorRegistryEthereum, signer interfaces.Signer, readiness *contractmanager.FuturesReadiness, closedContracts *contractmanager.ClosedContracts, autoLister *contractmanager.SellerAutoLister, autoBuyer *contractmanager.BuyerAutoBuyer, reputation *reputation.Store, attestations *attestation.Store, ledger *ledger.Ledger, ethPool *ethpool.Pool, sysConfig *system.SystemConfigurator, publicUrl *url.URL, apiToken string, hashrateCounter string, cycleDuration time.Duration, config Sanitizable, derivedConfig *config.DerivedConfig, appStartTime time.Time, logStorage *lib.Collection[*interfaces.LogStorage], log interfaces.ILogger) *gin.Engine {
 	handl := &HTTPHandler{
 		cm:                     cm,
 		contractCollection:     contractCollection,
 		allocator:              allocator,
 		globalHashrate:         globalHashrate,
+		history:                history,
+		blockCandidates:        blockCandidates,
+		txManager:              txManager,
+		registry:               registry,
+		signer:                 signer,
+		readiness:              readiness,
+		closedContracts:        closedContracts,
+		autoLister:             autoLister,
+		autoBuyer:              autoBuyer,
+		reputation:             reputation,
+		attestations:           attestations,
+		ledger:                 ledger,
+		ethPool:                ethPool,
 		sysConfig:              sysConfig,
 		publicUrl:              publicUrl,
+		apiToken:               apiToken,
 		hashrateCounterDefault: hashrateCounter,
 		cycleDuration:          cycleDuration,
 		config:                 config,
@@ -84,11 +120,46 @@ func NewHTTPHandler(cm *contractmanager.ContractManager, contractCollection *lib
 	r.GET("/contracts/:ID", handl.GetContract)
 	r.GET("/contracts/:ID/logs", handl.GetDeliveryLogs)
 	r.GET("/contracts/:ID/logs-console", handl.GetDeliveryLogsConsole)
+	r.GET("/contracts/:ID/evidence", handl.GetContractEvidence)
 	r.POST("/contracts", handl.CreateContract)
+	r.POST("/contracts/:ID/close", handl.RequireAuth, handl.CloseContract)
 
 	r.GET("/workers", handl.GetWorkers)
+	r.GET("/workers/stats", handl.GetWorkersStats)
 	r.POST("/change-dest", handl.ChangeDest)
 
+	r.GET("/history/:kind/:ID", handl.GetHistory)
+	r.GET("/block-candidates", handl.GetBlockCandidates)
+	r.GET("/transactions", handl.GetTransactions)
+	r.GET("/eth-nodes", handl.GetEthNodes)
+
+	r.GET("/validator", handl.GetValidator)
+	r.GET("/validator/complaints", handl.GetValidatorComplaints)
+	r.POST("/validator/register", handl.RequireAuth, handl.RegisterValidator)
+	r.POST("/validator/host", handl.RequireAuth, handl.UpdateValidatorHost)
+	r.POST("/validator/stake", handl.RequireAuth, handl.AddValidatorStake)
+	r.POST("/validator/deregister", handl.RequireAuth, handl.DeregisterValidator)
+
+	r.GET("/futures/readiness", handl.GetFuturesReadiness)
+	r.POST("/futures/readiness", handl.RequireAuth, handl.CheckFuturesReadiness)
+
+	r.GET("/seller/autolist", handl.GetSellerAutoList)
+	r.POST("/seller/autolist", handl.RequireAuth, handl.RunSellerAutoList)
+
+	r.GET("/buyer/autobuy", handl.GetBuyerAutoBuy)
+	r.POST("/buyer/autobuy", handl.RequireAuth, handl.RunBuyerAutoBuy)
+
+	r.GET("/sellers/reputation", handl.GetSellersReputation)
+	r.GET("/sellers/:ID/reputation", handl.GetSellerReputation)
+	r.POST("/sellers/reputation/sync", handl.SyncSellersReputation)
+
+	r.GET("/attestations", handl.GetAttestations)
+	r.GET("/attestations/:ID", handl.GetAttestation)
+	r.POST("/attestations/verify", handl.VerifyAttestation)
+
+	r.GET("/ledger", handl.GetLedger)
+	r.GET("/ledger/summary", handl.GetLedgerSummary)
+
 	r.Any("/debug/pprof/*action", gin.WrapF(pprof.Index))
 
 	err := r.SetTrustedProxies(nil)
@@ -107,6 +178,20 @@ func (h *HTTPHandler) HealthCheck(ctx *gin.Context) {
 	})
 }
 
+func (h *HTTPHandler) GetBlockCandidates(ctx *gin.Context) {
+	ctx.JSON(200, h.blockCandidates.GetAll())
+}
+
+// GetTransactions returns the pending and recently finished on-chain transactions, starting from the most recent one
+func (h *HTTPHandler) GetTransactions(ctx *gin.Context) {
+	ctx.JSON(200, h.txManager.GetAll())
+}
+
+// GetEthNodes returns the health of the ethereum node endpoints in the order they are used
+func (h *HTTPHandler) GetEthNodes(ctx *gin.Context) {
+	ctx.JSON(200, h.ethPool.GetStatus())
+}
+
 func (h *HTTPHandler) GetFiles(ctx *gin.Context) {
 	files, err := h.sysConfig.GetFileDescriptors(ctx, os.Getpid())
 	if err != nil {
@@ -142,7 +227,7 @@ func writeFiles(writer io.Writer, files []system.FD) error {
 	text += "\n"
 	text += "fd\tpath\n"
 
-	_, err := fmt.Fprintf(writer, text)
+	_, err := fmt.Fprint(writer, text)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,89 @@
+package httphandlers
+
+import (
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/ledger"
+	"github.com/gin-gonic/gin"
+)
+
+type LedgerQP struct {
+	From   time.Time `form:"from" time_format:"2006-01-02" time_utc:"1"`
+	To     time.Time `form:"to"   time_format:"2006-01-02" time_utc:"1"`
+	Format string    `form:"format" validate:"omitempty,oneof=json csv"`
+}
+
+type LedgerSummaryQP struct {
+	LedgerQP
+	Period string `form:"period" validate:"omitempty,oneof=day week month"`
+}
+
+// GetLedger returns the ledger entries in the [from, to) date range, for example ?from=2025-01-01&to=2025-02-01&format=csv
+func (h *HTTPHandler) GetLedger(ctx *gin.Context) {
+	qp := LedgerQP{}
+	if !h.bindLedgerQP(ctx, &qp) {
+		return
+	}
+
+	entries := h.ledger.GetEntries(qp.From, qp.To)
+	if qp.Format == "csv" {
+		ctx.Header("Content-Disposition", "attachment; filename=ledger.csv")
+		ctx.Header("Content-Type", "text/csv")
+		err := ledger.WriteEntriesCSV(ctx.Writer, entries)
+		if err != nil {
+			_ = ctx.Error(err)
+		}
+		return
+	}
+
+	if entries == nil {
+		entries = []ledger.Entry{}
+	}
+	ctx.JSON(200, entries)
+}
+
+// GetLedgerSummary returns the ledger totals per day, week or month
+func (h *HTTPHandler) GetLedgerSummary(ctx *gin.Context) {
+	qp := LedgerSummaryQP{}
+	if !h.bindLedgerQP(ctx, &qp) {
+		return
+	}
+
+	period := ledger.PeriodMonth
+	if qp.Period != "" {
+		period = ledger.Period(qp.Period)
+	}
+
+	summaries := ledger.Summarize(h.ledger.GetEntries(qp.From, qp.To), period)
+	if qp.Format == "csv" {
+		ctx.Header("Content-Disposition", "attachment; filename=ledger-summary.csv")
+		ctx.Header("Content-Type", "text/csv")
+		err := ledger.WriteSummaryCSV(ctx.Writer, summaries)
+		if err != nil {
+			_ = ctx.Error(err)
+		}
+		return
+	}
+
+	ctx.JSON(200, summaries)
+}
+
+func (h *HTTPHandler) bindLedgerQP(ctx *gin.Context, qp any) bool {
+	if h.ledger == nil {
+		ctx.JSON(404, gin.H{"error": "ledger is disabled"})
+		return false
+	}
+
+	err := ctx.ShouldBindQuery(qp)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return false
+	}
+
+	err = h.validator.StructCtx(ctx, qp)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
@@ -85,6 +85,7 @@ func (c *HTTPHandler) MapMiner(m *allocator.Scheduler) *Miner {
 		},
 		ID:                    m.ID(),                                  // readonly
 		WorkerName:            m.GetWorkerName(),                       // readonly
+		Algorithm:             m.Algorithm().String(),                  // readonly
 		Status:                m.GetStatus(c.cycleDuration).String(),   // atomic
 		CurrentDifficulty:     int(m.GetCurrentDifficulty()),           // atomic
 		HashrateAvgGHS:        mapHRToInt(m),                           // atomic or single lock
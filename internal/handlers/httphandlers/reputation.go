@@ -0,0 +1,51 @@
+package httphandlers
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *HTTPHandler) GetSellersReputation(ctx *gin.Context) {
+	if h.reputation == nil {
+		ctx.JSON(404, gin.H{"error": "seller reputation is disabled"})
+		return
+	}
+
+	ctx.JSON(200, h.reputation.GetSellers())
+}
+
+func (h *HTTPHandler) GetSellerReputation(ctx *gin.Context) {
+	if h.reputation == nil {
+		ctx.JSON(404, gin.H{"error": "seller reputation is disabled"})
+		return
+	}
+
+	sellerAddr := ctx.Param("ID")
+	if !common.IsHexAddress(sellerAddr) {
+		ctx.JSON(400, gin.H{"error": "invalid seller address"})
+		return
+	}
+
+	seller, ok := h.reputation.GetSeller(common.HexToAddress(sellerAddr))
+	if !ok {
+		ctx.JSON(404, gin.H{"error": "seller not found"})
+		return
+	}
+
+	ctx.JSON(200, seller)
+}
+
+func (h *HTTPHandler) SyncSellersReputation(ctx *gin.Context) {
+	if h.reputation == nil {
+		ctx.JSON(404, gin.H{"error": "seller reputation is disabled"})
+		return
+	}
+
+	err := h.reputation.Sync(ctx)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, h.reputation.GetSellers())
+}
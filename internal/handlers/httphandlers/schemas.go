@@ -2,6 +2,7 @@ package httphandlers
 
 import (
 	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/allocator"
+	hrcontract "github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/contract"
 )
 
 type MinersResponse struct {
@@ -61,6 +62,7 @@ type Miner struct {
 
 	ID                    string
 	WorkerName            string
+	Algorithm             string
 	Status                string
 	HashrateAvgGHS        map[string]int
 	CurrentDestination    string
@@ -86,6 +88,7 @@ type Contract struct {
 	ResourceEstimatesTarget map[string]int
 	ResourceEstimatesActual map[string]int
 	StarvingGHS             int
+	HashrateInterval        *HashrateInterval `json:",omitempty"`
 
 	BalanceLMR     float64
 	IsDeleted      bool
@@ -104,15 +107,58 @@ type Contract struct {
 	Error             string
 	Dest              string
 	PoolDest          string
+	DestRoutes        []hrcontract.DestRouteStats `json:",omitempty"`
 	Miners            []*allocator.MinerItemJobScheduled
 }
 
+type HashrateInterval struct {
+	Shares            int
+	ExpectedShares    int
+	EstimateGHS       int
+	LowerGHS          int
+	UpperGHS          int
+	MinAcceptedGHS    int
+	Confidence        float64
+	IsUnderdelivering bool
+}
+
+type HistoryResponse struct {
+	Kind       string
+	ID         string
+	Range      string
+	Step       string
+	Resolution string
+	Points     []HistoryPoint
+}
+
+type HistoryPoint struct {
+	Timestamp   string
+	HashrateGHS int
+}
+
 type Resource struct {
 	Self string
 }
 
 type Worker struct {
-	WorkerName string
-	Hashrate   map[string]float64
-	Reconnects int
+	WorkerName   string
+	State        string
+	LastActivity string
+	Hashrate     map[string]float64
+	Reconnects   int
+}
+
+type WorkersStats struct {
+	Total   int
+	Active  int
+	Idle    int
+	Gone    int
+	Evicted uint64
+
+	HeapAllocBytes uint64
+	HeapInuseBytes uint64
+	HeapObjects    uint64
+	SysBytes       uint64
+	NumGC          uint32
+	Goroutines     int
 }
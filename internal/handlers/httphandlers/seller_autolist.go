@@ -0,0 +1,46 @@
+package httphandlers
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type SellerAutoListQP struct {
+	DryRun bool `form:"dryRun"`
+}
+
+func (h *HTTPHandler) GetSellerAutoList(ctx *gin.Context) {
+	if h.autoLister == nil {
+		ctx.JSON(404, gin.H{"error": "seller auto-listing is disabled"})
+		return
+	}
+
+	plan := h.autoLister.GetLastPlan()
+	if plan == nil {
+		ctx.JSON(404, gin.H{"error": "auto-listing did not run yet"})
+		return
+	}
+
+	ctx.JSON(200, plan)
+}
+
+func (h *HTTPHandler) RunSellerAutoList(ctx *gin.Context) {
+	if h.autoLister == nil {
+		ctx.JSON(404, gin.H{"error": "seller auto-listing is disabled"})
+		return
+	}
+
+	var qp SellerAutoListQP
+	err := ctx.ShouldBindQuery(&qp)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := h.autoLister.RunOnce(ctx, qp.DryRun)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, plan)
+}
@@ -0,0 +1,167 @@
+package httphandlers
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
+	"github.com/gin-gonic/gin"
+)
+
+type ValidatorResponse struct {
+	Validator *contracts.Validator
+	Params    *contracts.RegistryParams
+}
+
+type ValidatorTxResponse struct {
+	DryRun bool
+	Txs    []contracts.TxResult
+}
+
+type ValidatorRegisterQP struct {
+	Stake  string `form:"stake" validate:"required"`
+	Host   string `form:"host" validate:"required,hostname_port"`
+	DryRun bool   `form:"dryRun"`
+}
+
+type ValidatorHostQP struct {
+	Host   string `form:"host" validate:"required,hostname_port"`
+	DryRun bool   `form:"dryRun"`
+}
+
+type ValidatorStakeQP struct {
+	Amount string `form:"amount" validate:"required"`
+	DryRun bool   `form:"dryRun"`
+}
+
+type ValidatorDeregisterQP struct {
+	DryRun bool `form:"dryRun"`
+}
+
+type ValidatorComplaintsQP struct {
+	FromBlock *uint64 `form:"fromBlock"`
+}
+
+func (h *HTTPHandler) GetValidator(ctx *gin.Context) {
+	if h.registry == nil {
+		ctx.JSON(404, gin.H{"error": contracts.ErrRegistryDisabled.Error()})
+		return
+	}
+
+	validator, err := h.registry.GetValidator(ctx, h.signer.Address())
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	params, err := h.registry.GetParams(ctx)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, ValidatorResponse{Validator: validator, Params: params})
+}
+
+func (h *HTTPHandler) GetValidatorComplaints(ctx *gin.Context) {
+	var qp ValidatorComplaintsQP
+	if !h.bindValidatorQuery(ctx, &qp) {
+		return
+	}
+
+	var fromBlock *big.Int
+	if qp.FromBlock != nil {
+		fromBlock = new(big.Int).SetUint64(*qp.FromBlock)
+	}
+
+	complaints, err := h.registry.GetComplaints(ctx, h.signer.Address(), fromBlock)
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, complaints)
+}
+
+func (h *HTTPHandler) RegisterValidator(ctx *gin.Context) {
+	var qp ValidatorRegisterQP
+	if !h.bindValidatorQuery(ctx, &qp) {
+		return
+	}
+	stake, err := lib.ParseUnits(qp.Stake, contracts.LMRDecimals)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	txs, err := h.registry.Register(ctx, h.signer, stake, qp.Host, qp.DryRun)
+	h.respondValidatorTx(ctx, qp.DryRun, txs, err)
+}
+
+func (h *HTTPHandler) UpdateValidatorHost(ctx *gin.Context) {
+	var qp ValidatorHostQP
+	if !h.bindValidatorQuery(ctx, &qp) {
+		return
+	}
+
+	txs, err := h.registry.UpdateHost(ctx, h.signer, qp.Host, qp.DryRun)
+	h.respondValidatorTx(ctx, qp.DryRun, txs, err)
+}
+
+func (h *HTTPHandler) AddValidatorStake(ctx *gin.Context) {
+	var qp ValidatorStakeQP
+	if !h.bindValidatorQuery(ctx, &qp) {
+		return
+	}
+	amount, err := lib.ParseUnits(qp.Amount, contracts.LMRDecimals)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	txs, err := h.registry.AddStake(ctx, h.signer, amount, qp.DryRun)
+	h.respondValidatorTx(ctx, qp.DryRun, txs, err)
+}
+
+func (h *HTTPHandler) DeregisterValidator(ctx *gin.Context) {
+	var qp ValidatorDeregisterQP
+	if !h.bindValidatorQuery(ctx, &qp) {
+		return
+	}
+
+	txs, err := h.registry.Deregister(ctx, h.signer, qp.DryRun)
+	h.respondValidatorTx(ctx, qp.DryRun, txs, err)
+}
+
+// bindValidatorQuery binds and validates the query params, returns false if the response is already written
+func (h *HTTPHandler) bindValidatorQuery(ctx *gin.Context, qp any) bool {
+	if h.registry == nil {
+		ctx.JSON(404, gin.H{"error": contracts.ErrRegistryDisabled.Error()})
+		return false
+	}
+
+	err := ctx.ShouldBindQuery(qp)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return false
+	}
+
+	err = h.validator.StructCtx(ctx, qp)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+func (h *HTTPHandler) respondValidatorTx(ctx *gin.Context, dryRun bool, txs []contracts.TxResult, err error) {
+	if errors.Is(err, contracts.ErrNotRegistered) || errors.Is(err, contracts.ErrInsufficientLMR) {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(200, ValidatorTxResponse{DryRun: dryRun, Txs: txs})
+}
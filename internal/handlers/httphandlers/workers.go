@@ -1,6 +1,8 @@
 package httphandlers
 
 import (
+	"runtime"
+
 	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/hashrate"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/exp/slices"
@@ -11,9 +13,11 @@ func (c *HTTPHandler) GetWorkers(ctx *gin.Context) {
 
 	c.globalHashrate.Range(func(w *hashrate.WorkerHashrateModel) bool {
 		Workers = append(Workers, &Worker{
-			WorkerName: w.ID(),
-			Hashrate:   w.GetHashrateAvgGHSAll(),
-			Reconnects: w.Reconnects(),
+			WorkerName:   w.ID(),
+			State:        string(c.globalHashrate.GetWorkerState(w)),
+			LastActivity: formatTime(w.LastActivity()),
+			Hashrate:     w.GetHashrateAvgGHSAll(),
+			Reconnects:   w.Reconnects(),
 		})
 		return true
 	})
@@ -24,3 +28,25 @@ func (c *HTTPHandler) GetWorkers(ctx *gin.Context) {
 
 	ctx.JSON(200, Workers)
 }
+
+func (c *HTTPHandler) GetWorkersStats(ctx *gin.Context) {
+	stats := c.globalHashrate.GetStats()
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	ctx.JSON(200, WorkersStats{
+		Total:   stats.Active + stats.Idle + stats.Gone,
+		Active:  stats.Active,
+		Idle:    stats.Idle,
+		Gone:    stats.Gone,
+		Evicted: stats.Evicted,
+
+		HeapAllocBytes: mem.HeapAlloc,
+		HeapInuseBytes: mem.HeapInuse,
+		HeapObjects:    mem.HeapObjects,
+		SysBytes:       mem.Sys,
+		NumGC:          mem.NumGC,
+		Goroutines:     runtime.NumGoroutine(),
+	})
+}
@@ -24,12 +24,14 @@ func NewTCPHandler(
 	minerVettingShares, maxCachedDests int,
 	defaultDestUrl *url.URL,
 	destFactory proxy.DestConnFactory,
+	algorithm hashrate.Algorithm,
 	hashrateFactory proxy.HashrateFactory,
 	globalHashrate *hashrate.GlobalHashrate,
 	hashrateCounterDefault string,
 	alloc *allocator.Allocator,
 	setErrorFn SetErrorFn,
 	getContractFromStoreFn proxy.GetContractFromStoreFn,
+	onBlockCandidate proxy.BlockCandidateHandler,
 ) transport.Handler {
 	return func(ctx context.Context, conn net.Conn) {
 		addr := conn.RemoteAddr().String()
@@ -60,10 +62,12 @@ func NewTCPHandler(
 			minerVettingShares, maxCachedDests,
 			proxyLog.Named("PRX").With("SrcAddr", addr),
 			getContractFromStoreFn,
+			onBlockCandidate,
 		)
 		scheduler := allocator.NewScheduler(
 			prx,
 			hashrateCounterDefault,
+			algorithm,
 			url,
 			minerVettingShares,
 			hashrateFactory,
@@ -0,0 +1,21 @@
+package interfaces
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Signer holds the wallet key, so the raw private key is not passed around the app
+type Signer interface {
+	// Address is the wallet address
+	Address() common.Address
+	// SignTx signs the transaction for the given chain
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+	// SignMessage signs the message prefixed as the EIP-191 personal message, the signature is [R || S || V] with V 27 or 28
+	SignMessage(ctx context.Context, msg []byte) ([]byte, error)
+	// Decrypt decrypts the ECIES ciphertext encrypted with the wallet public key, such as contract destination
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
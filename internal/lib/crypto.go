@@ -5,7 +5,9 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"strings"
 
+	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/crypto/ecies"
@@ -22,12 +24,19 @@ func DecryptString(str string, privateKey string) (string, error) {
 	}
 
 	pkECIES := ecies.ImportECDSA(pkECDSA)
+	return DecryptStringWith(str, func(ciphertext []byte) ([]byte, error) {
+		return pkECIES.Decrypt(ciphertext, nil, nil)
+	})
+}
+
+// DecryptStringWith decrypts the hex encoded ciphertext with the provided function, e.g. the wallet signer
+func DecryptStringWith(str string, decrypt func(ciphertext []byte) ([]byte, error)) (string, error) {
 	strDecodedBytes, err := hex.DecodeString(str)
 	if err != nil {
 		return "", err
 	}
 
-	strDecryptedBytes, err := pkECIES.Decrypt(strDecodedBytes, nil, nil)
+	strDecryptedBytes, err := decrypt(strDecodedBytes)
 	if err != nil {
 		return "", err
 	}
@@ -59,6 +68,23 @@ func EncryptString(str string, publicKeyHex string) (string, error) {
 	return hex.EncodeToString(ciphertext), nil
 }
 
+// NormalizePubKey converts the hex public key, compressed or uncompressed, with or without 0x prefix,
+// to uncompressed hex without prefix as expected by EncryptString
+func NormalizePubKey(pubKey string) (string, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(pubKey, "0x"))
+	if err != nil {
+		return "", err
+	}
+	if len(data) == 33 {
+		key, err := crypto.DecompressPubkey(data)
+		if err != nil {
+			return "", err
+		}
+		data = crypto.FromECDSAPub(key)
+	}
+	return hex.EncodeToString(data), nil
+}
+
 func PrivKeyToAddr(privateKey *ecdsa.PrivateKey) (common.Address, error) {
 	publicKey := privateKey.Public()
 	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
@@ -125,3 +151,17 @@ func PrKeyToCompressedPubKey(prkey *ecdsa.PrivateKey) (yParity bool, x common.Ha
 	x.SetBytes(pub.X.Bytes())
 	return pub.Y.Bit(0) == 1, x, nil
 }
+
+// RecoverMessageSigner returns the public key that signed the message prefixed as the EIP-191 personal message,
+// V of the signature can be either 27/28 or 0/1
+func RecoverMessageSigner(msg []byte, sig []byte) (*ecdsa.PublicKey, error) {
+	if len(sig) != crypto.SignatureLength {
+		return nil, fmt.Errorf("invalid signature length %d", len(sig))
+	}
+	rsv := make([]byte, len(sig))
+	copy(rsv, sig)
+	if rsv[crypto.RecoveryIDOffset] >= 27 {
+		rsv[crypto.RecoveryIDOffset] -= 27
+	}
+	return crypto.SigToPub(accounts.TextHash(msg), rsv)
+}
@@ -31,3 +31,17 @@ func TestEncryptDecrypt(t *testing.T) {
 
 	require.Equal(t, msg, decoded)
 }
+
+func TestNormalizePubKey(t *testing.T) {
+	privateKey, err := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	require.NoError(t, err)
+	uncompressed := hex.EncodeToString(crypto.FromECDSAPub(&privateKey.PublicKey))
+
+	res, err := NormalizePubKey("0x" + hex.EncodeToString(crypto.CompressPubkey(&privateKey.PublicKey)))
+	require.NoError(t, err)
+	require.Equal(t, uncompressed, res)
+
+	res, err = NormalizePubKey(uncompressed)
+	require.NoError(t, err)
+	require.Equal(t, uncompressed, res)
+}
@@ -1,6 +1,7 @@
 package lib
 
 import (
+	"fmt"
 	"math/big"
 
 	"golang.org/x/exp/constraints"
@@ -32,3 +33,16 @@ func NewRat(numerator, denominator *big.Int) *big.Rat {
 	bFloat := new(big.Rat).SetInt(denominator)
 	return new(big.Rat).Quo(aFloat, bFloat)
 }
+
+// ParseUnits parses the decimal string into the integer amount with the given number of decimals, e.g. "1.5" with 8 decimals is 150000000
+func ParseUnits(s string, decimals int) (*big.Int, error) {
+	value, ok := new(big.Rat).SetString(s)
+	if !ok || value.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %s", s)
+	}
+	value.Mul(value, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)))
+	if !value.IsInt() {
+		return nil, fmt.Errorf("amount %s has more than %d decimals", s, decimals)
+	}
+	return value.Num(), nil
+}
@@ -21,3 +21,19 @@ func TestNewRat(t *testing.T) {
 	require.True(t, ok)
 	require.Equal(t, numInt/denInt, rat)
 }
+
+func TestParseUnits(t *testing.T) {
+	v, err := ParseUnits("1.5", 8)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(150_000_000), v)
+
+	v, err = ParseUnits("100", 8)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(10_000_000_000), v)
+
+	_, err = ParseUnits("0.000000001", 8)
+	require.Error(t, err)
+
+	_, err = ParseUnits("abc", 8)
+	require.Error(t, err)
+}
@@ -0,0 +1,188 @@
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ReportVersion is increased on the incompatible changes of the report format
+const ReportVersion = 1
+
+const (
+	ResultDelivered = "delivered" // position was delivered till the end
+	ResultClosed    = "closed"    // position delivery was closed by another party before the end
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid attestation signature")
+	ErrKeyMismatch      = errors.New("attestation is not signed with the registered validator key")
+)
+
+// Report is the delivery report of the futures position produced by the validator
+type Report struct {
+	Version       int
+	PositionID    string
+	Validator     common.Address
+	Seller        common.Address
+	Buyer         common.Address
+	StartTime     time.Time // delivery start by the position terms
+	EndTime       time.Time // delivery end by the position terms
+	ValidatedFrom time.Time
+	ValidatedTo   time.Time
+	TargetGHS     float64
+	Policy        string // validation policy of the position
+	Samples       []Sample
+	Shares        ShareStats
+	Errors        ErrorStats
+	Verdict       Verdict
+	CreatedAt     time.Time
+}
+
+// Sample is the result of the single validation check
+type Sample struct {
+	Time      time.Time
+	ActualGHS int
+	Error     float64 // relative error of the hashrate since the delivery start
+	Status    string  // status of the validation policy
+}
+
+// ShareStats are the shares submitted by the seller during the delivery
+type ShareStats struct {
+	Count         int
+	Work          float64
+	AvgDifficulty float64
+	LastShareTime time.Time
+}
+
+// ErrorStats summarize the validation checks
+type ErrorStats struct {
+	Checks       int
+	FailedChecks int // checks where the policy found underdelivery or share timeout
+	MeanError    float64
+	MaxError     float64
+	AvgGHS       float64
+	MinGHS       int
+}
+
+// Verdict is the outcome of the validation
+type Verdict struct {
+	Result      string // delivered, closed or the close reason
+	BlameSeller bool
+	Reason      string `json:",omitempty"`
+}
+
+// SignedReport is the report with the validator signature. The signature covers the compact json of the report
+// prefixed as the EIP-191 personal message, so it can be checked with the common wallet tools as well
+type SignedReport struct {
+	Report    json.RawMessage
+	Signature hexutil.Bytes
+}
+
+// Sign signs the report on behalf of the signer, the validator address of the report is set to the signer address
+func Sign(ctx context.Context, report Report, signer interfaces.Signer) (*SignedReport, error) {
+	report.Version = ReportVersion
+	report.Validator = signer.Address()
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signer.SignMessage(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedReport{Report: data, Signature: sig}, nil
+}
+
+// Verify checks the report is signed by its validator and returns the decoded report with the signer public key
+func (s *SignedReport) Verify() (*Report, *ecdsa.PublicKey, error) {
+	var data bytes.Buffer
+	err := json.Compact(&data, s.Report)
+	if err != nil {
+		return nil, nil, lib.WrapError(ErrInvalidSignature, err)
+	}
+	pubKey, err := lib.RecoverMessageSigner(data.Bytes(), s.Signature)
+	if err != nil {
+		return nil, nil, lib.WrapError(ErrInvalidSignature, err)
+	}
+
+	var report Report
+	err = json.Unmarshal(data.Bytes(), &report)
+	if err != nil {
+		return nil, nil, lib.WrapError(ErrInvalidSignature, err)
+	}
+	if signer := crypto.PubkeyToAddress(*pubKey); signer != report.Validator {
+		return nil, nil, lib.WrapError(ErrInvalidSignature, fmt.Errorf("signed by %s, report validator %s", signer.Hex(), report.Validator.Hex()))
+	}
+	return &report, pubKey, nil
+}
+
+// VerifyRegisteredKey checks the public key matches the compressed key of the validator registry
+func VerifyRegisteredKey(pubKey *ecdsa.PublicKey, yParity bool, x common.Hash) error {
+	if common.BigToHash(pubKey.X) != x || (pubKey.Y.Bit(0) == 1) != yParity {
+		return ErrKeyMismatch
+	}
+	return nil
+}
+
+// ValidatorRegistry provides the registered keys of the validators
+type ValidatorRegistry interface {
+	GetValidator(ctx context.Context, addr common.Address) (*contracts.Validator, error)
+}
+
+// VerifyRegistered checks the report signature and that the signer is the validator registered in the registry
+func VerifyRegistered(ctx context.Context, s *SignedReport, registry ValidatorRegistry) (*Report, error) {
+	report, pubKey, err := s.Verify()
+	if err != nil {
+		return nil, err
+	}
+	validator, err := registry.GetValidator(ctx, report.Validator)
+	if err != nil {
+		return report, err
+	}
+	if !validator.IsRegistered {
+		return report, lib.WrapError(ErrKeyMismatch, fmt.Errorf("validator %s is not registered", report.Validator.Hex()))
+	}
+	return report, VerifyRegisteredKey(pubKey, validator.PubKeyYparity, validator.PubKeyX)
+}
+
+// Verification is the result of the report check
+type Verification struct {
+	Valid      bool    // report is signed by its validator
+	Registered bool    // signing key is the key of the validator in the registry
+	Error      string  `json:",omitempty"`
+	Report     *Report `json:",omitempty"`
+}
+
+// Check verifies the report and reports the result, nil registry checks the signature only
+func Check(ctx context.Context, s *SignedReport, registry ValidatorRegistry) Verification {
+	report, _, err := s.Verify()
+	if err != nil {
+		return Verification{Error: err.Error()}
+	}
+	res := Verification{Valid: true, Report: report}
+	if registry == nil {
+		res.Error = contracts.ErrRegistryDisabled.Error()
+		return res
+	}
+
+	_, err = VerifyRegistered(ctx, s, registry)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Registered = true
+	return res
+}
@@ -0,0 +1,159 @@
+package attestation
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/wallet"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testPrivKey  = "b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291"
+	testPrivKey2 = "8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a"
+)
+
+var testPosition = common.HexToHash("0xabcd").Hex()
+
+func testReport() Report {
+	now := time.Now().UTC()
+	return Report{
+		PositionID:    testPosition,
+		Seller:        common.HexToAddress("0x1111111111111111111111111111111111111111"),
+		Buyer:         common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		StartTime:     now.Add(-time.Hour),
+		EndTime:       now,
+		ValidatedFrom: now.Add(-time.Hour),
+		ValidatedTo:   now,
+		TargetGHS:     100_000,
+		Policy:        "flatness",
+		Samples: []Sample{
+			{Time: now.Add(-30 * time.Minute), ActualGHS: 98_000, Error: 0.02, Status: "ok"},
+			{Time: now, ActualGHS: 60_000, Error: 0.4, Status: "underdelivery"},
+		},
+		Shares:  ShareStats{Count: 1200, Work: 1.5e9, AvgDifficulty: 1.25e6, LastShareTime: now},
+		Errors:  ErrorStats{Checks: 2, FailedChecks: 1, MeanError: 0.21, MaxError: 0.4, AvgGHS: 79_000, MinGHS: 60_000},
+		Verdict: Verdict{Result: "underdelivery", BlameSeller: true, Reason: "underdelivering: <5%"},
+	}
+}
+
+type fakeRegistry struct {
+	validator *contracts.Validator
+}
+
+func (r *fakeRegistry) GetValidator(ctx context.Context, addr common.Address) (*contracts.Validator, error) {
+	return r.validator, nil
+}
+
+func registeredValidator(signer *wallet.KeySigner) *contracts.Validator {
+	pubKey := signer.PublicKey()
+	return &contracts.Validator{
+		Address:       signer.Address(),
+		PubKeyYparity: pubKey.Y.Bit(0) == 1,
+		PubKeyX:       common.BigToHash(pubKey.X),
+		IsRegistered:  true,
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	signer, err := wallet.NewKeySigner(testPrivKey)
+	require.NoError(t, err)
+
+	signed, err := Sign(context.Background(), testReport(), signer)
+	require.NoError(t, err)
+
+	// the signature survives the reformatting of the downloaded file
+	data, err := json.MarshalIndent(signed, "", "    ")
+	require.NoError(t, err)
+	var downloaded SignedReport
+	require.NoError(t, json.Unmarshal(data, &downloaded))
+
+	report, pubKey, err := downloaded.Verify()
+	require.NoError(t, err)
+	require.Equal(t, signer.Address(), report.Validator)
+	require.Equal(t, ReportVersion, report.Version)
+	require.Equal(t, "underdelivery", report.Verdict.Result)
+	require.Len(t, report.Samples, 2)
+	require.NoError(t, VerifyRegisteredKey(pubKey, registeredValidator(signer).PubKeyYparity, registeredValidator(signer).PubKeyX))
+
+	// changed verdict
+	var tampered Report
+	require.NoError(t, json.Unmarshal(signed.Report, &tampered))
+	tampered.Verdict.BlameSeller = false
+	downloaded.Report, err = json.Marshal(tampered)
+	require.NoError(t, err)
+	_, _, err = downloaded.Verify()
+	require.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestCheckAgainstRegistry(t *testing.T) {
+	signer, err := wallet.NewKeySigner(testPrivKey)
+	require.NoError(t, err)
+	other, err := wallet.NewKeySigner(testPrivKey2)
+	require.NoError(t, err)
+
+	signed, err := Sign(context.Background(), testReport(), signer)
+	require.NoError(t, err)
+
+	res := Check(context.Background(), signed, &fakeRegistry{validator: registeredValidator(signer)})
+	require.True(t, res.Valid)
+	require.True(t, res.Registered)
+	require.Empty(t, res.Error)
+
+	// signature is valid, but the key registered for the validator is different
+	res = Check(context.Background(), signed, &fakeRegistry{validator: registeredValidator(other)})
+	require.True(t, res.Valid)
+	require.False(t, res.Registered)
+
+	notRegistered := registeredValidator(signer)
+	notRegistered.IsRegistered = false
+	_, err = VerifyRegistered(context.Background(), signed, &fakeRegistry{validator: notRegistered})
+	require.ErrorIs(t, err, ErrKeyMismatch)
+
+	res = Check(context.Background(), signed, nil)
+	require.True(t, res.Valid)
+	require.False(t, res.Registered)
+	require.Equal(t, contracts.ErrRegistryDisabled.Error(), res.Error)
+}
+
+func TestStore(t *testing.T) {
+	signer, err := wallet.NewKeySigner(testPrivKey)
+	require.NoError(t, err)
+	s := NewStore(t.TempDir(), signer, lib.NewTestLogger())
+
+	reports, err := s.GetReports()
+	require.NoError(t, err)
+	require.Empty(t, reports)
+
+	first := testReport()
+	first.CreatedAt = time.Now().Add(-time.Minute)
+	_, err = s.Attest(context.Background(), first)
+	require.NoError(t, err)
+
+	second := testReport()
+	second.PositionID = common.HexToHash("0x01").Hex()
+	second.CreatedAt = time.Now()
+	second.Verdict = Verdict{Result: ResultDelivered}
+	_, err = s.Attest(context.Background(), second)
+	require.NoError(t, err)
+
+	reports, err = s.GetReports()
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	require.Equal(t, testPosition, reports[0].PositionID)
+	require.Equal(t, ResultDelivered, reports[1].Verdict.Result)
+
+	signed, err := s.Get(testPosition)
+	require.NoError(t, err)
+	report, _, err := signed.Verify()
+	require.NoError(t, err)
+	require.Equal(t, signer.Address(), report.Validator)
+
+	_, err = s.Get(common.HexToHash("0x02").Hex())
+	require.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,141 @@
+package attestation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	ErrNotFound = errors.New("attestation not found")
+	ErrSave     = errors.New("can't save attestation")
+)
+
+// Store signs the delivery reports of the validated positions and keeps them as json files in the directory,
+// one file per position
+type Store struct {
+	// config
+	dir string
+
+	// state
+	mutex sync.Mutex
+
+	// deps
+	signer interfaces.Signer
+	log    interfaces.ILogger
+}
+
+func NewStore(dir string, signer interfaces.Signer, log interfaces.ILogger) *Store {
+	return &Store{
+		dir:    dir,
+		signer: signer,
+		log:    log,
+	}
+}
+
+// Attest signs the report and saves it, the previous report of the position is replaced
+func (s *Store) Attest(ctx context.Context, report Report) (*SignedReport, error) {
+	signed, err := Sign(ctx, report, s.signer)
+	if err != nil {
+		return nil, lib.WrapError(ErrSave, err)
+	}
+
+	data, err := json.MarshalIndent(signed, "", "  ")
+	if err != nil {
+		return nil, lib.WrapError(ErrSave, err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err = os.MkdirAll(s.dir, 0o755)
+	if err != nil {
+		return nil, lib.WrapError(ErrSave, err)
+	}
+	path := s.path(report.PositionID)
+	tmpPath := path + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0o644)
+	if err != nil {
+		return nil, lib.WrapError(ErrSave, err)
+	}
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		return nil, lib.WrapError(ErrSave, err)
+	}
+
+	s.log.Infof("delivery of position %s attested, verdict %s", lib.AddrShort(report.PositionID), report.Verdict.Result)
+	return signed, nil
+}
+
+// Get returns the signed report of the position
+func (s *Store) Get(positionID string) (*SignedReport, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, err := os.ReadFile(s.path(positionID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, lib.WrapError(ErrNotFound, fmt.Errorf("position %s", positionID))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var signed SignedReport
+	err = json.Unmarshal(data, &signed)
+	if err != nil {
+		return nil, err
+	}
+	return &signed, nil
+}
+
+// GetReports returns the reports of all attested positions sorted by the creation time, the signatures are
+// not checked, use Get to download the signed report
+func (s *Store) GetReports() ([]Report, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Report{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]Report, 0, len(entries))
+	for _, entry := range entries {
+		positionID, ok := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !ok {
+			continue
+		}
+		signed, err := s.Get(positionID)
+		if err != nil {
+			s.log.Warnf("can't read attestation %s: %s", entry.Name(), err)
+			continue
+		}
+		var report Report
+		err = json.Unmarshal(signed.Report, &report)
+		if err != nil {
+			s.log.Warnf("can't decode attestation %s: %s", entry.Name(), err)
+			continue
+		}
+		reports = append(reports, report)
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].CreatedAt.Before(reports[j].CreatedAt)
+	})
+	return reports, nil
+}
+
+// path returns the file of the position, the id is normalized so it can't point outside the directory
+func (s *Store) path(positionID string) string {
+	return filepath.Join(s.dir, common.HexToHash(positionID).Hex()+".json")
+}
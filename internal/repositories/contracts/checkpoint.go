@@ -0,0 +1,169 @@
+package contracts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	i "github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const CheckpointFlushInterval = 30 * time.Second
+
+// Checkpoint is the last block which logs were fully delivered to the subscriber
+type Checkpoint struct {
+	Block uint64      `json:"block"`
+	Hash  common.Hash `json:"hash"`
+}
+
+// CheckpointStore keeps the last processed block of each watched contract, so the log watchers
+// resume from it after restart instead of skipping or re-reading the events
+type CheckpointStore interface {
+	GetCheckpoint(key string) (Checkpoint, bool)
+	SetCheckpoint(key string, checkpoint Checkpoint)
+}
+
+// FileCheckpointStore keeps checkpoints in memory and periodically flushes them to a json file
+type FileCheckpointStore struct {
+	// config
+	path          string
+	flushInterval time.Duration
+
+	// state
+	checkpoints map[string]Checkpoint
+	dirty       bool
+	mutex       sync.Mutex
+
+	// deps
+	log i.ILogger
+}
+
+func NewFileCheckpointStore(path string, flushInterval time.Duration, log i.ILogger) *FileCheckpointStore {
+	return &FileCheckpointStore{
+		path:          path,
+		flushInterval: flushInterval,
+		checkpoints:   make(map[string]Checkpoint),
+		log:           log,
+	}
+}
+
+func (s *FileCheckpointStore) Load() error {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	checkpoints := make(map[string]Checkpoint)
+	err = json.Unmarshal(data, &checkpoints)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.checkpoints = checkpoints
+	return nil
+}
+
+// Run flushes the checkpoints periodically and on exit
+func (s *FileCheckpointStore) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			err := s.Flush()
+			if err != nil {
+				s.log.Errorf("failed to save log checkpoints: %s", err)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+			err := s.Flush()
+			if err != nil {
+				s.log.Errorf("failed to save log checkpoints: %s", err)
+			}
+		}
+	}
+}
+
+func (s *FileCheckpointStore) GetCheckpoint(key string) (Checkpoint, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	checkpoint, ok := s.checkpoints[key]
+	return checkpoint, ok
+}
+
+func (s *FileCheckpointStore) SetCheckpoint(key string, checkpoint Checkpoint) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.checkpoints[key] == checkpoint {
+		return
+	}
+	s.checkpoints[key] = checkpoint
+	s.dirty = true
+}
+
+// Flush writes the checkpoints to a temporary file and renames it, so the file is never left partially written
+func (s *FileCheckpointStore) Flush() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.checkpoints, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(s.path), 0o755)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := s.path + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0o600)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmpPath, s.path)
+	if err != nil {
+		return err
+	}
+
+	s.dirty = false
+	return nil
+}
+
+// MemoryCheckpointStore keeps checkpoints only in memory, watchers start from the latest block after restart
+type MemoryCheckpointStore struct {
+	checkpoints sync.Map
+}
+
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{}
+}
+
+func (s *MemoryCheckpointStore) GetCheckpoint(key string) (Checkpoint, bool) {
+	checkpoint, ok := s.checkpoints.Load(key)
+	if !ok {
+		return Checkpoint{}, false
+	}
+	return checkpoint.(Checkpoint), true
+}
+
+func (s *MemoryCheckpointStore) SetCheckpoint(key string, checkpoint Checkpoint) {
+	s.checkpoints.Store(key, checkpoint)
+}
@@ -1,5 +1,7 @@
 package contracts
 
+import "github.com/Lumerin-protocol/proxy-router/internal/repositories/ledger"
+
 type CloseoutType uint8
 
 const (
@@ -17,3 +19,32 @@ const (
 	CloseReasonDestinationUnavailable CloseReason = 2
 	CloseReasonShareTimeout           CloseReason = 3
 )
+
+const (
+	LMRDecimals = 8
+)
+
+func (r CloseReason) String() string {
+	switch r {
+	case CloseReasonUnderdelivery:
+		return "underdelivery"
+	case CloseReasonDestinationUnavailable:
+		return "destination unavailable"
+	case CloseReasonShareTimeout:
+		return "share timeout"
+	default:
+		return "unspecified"
+	}
+}
+
+// Blame returns the party responsible for the early closeout with this reason, empty if unknown
+func (r CloseReason) Blame() string {
+	switch r {
+	case CloseReasonUnderdelivery, CloseReasonShareTimeout:
+		return ledger.RoleSeller
+	case CloseReasonDestinationUnavailable:
+		return ledger.RoleBuyer
+	default:
+		return ""
+	}
+}
@@ -11,33 +11,36 @@ import (
 	"github.com/Lumerin-protocol/contracts-go/v3/futures"
 	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
 	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/ethpool"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/ledger"
 	"github.com/Lumerin-protocol/proxy-router/internal/repositories/multicall"
 	mc "github.com/Lumerin-protocol/proxy-router/internal/repositories/multicall"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/txmanager"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
 type FuturesEthereum struct {
 	// config
-	legacyTx    bool // use legacy transaction fee, for local node testing
 	futuresAddr common.Address
 
 	// state
-	nonce      uint64
-	mutex      lib.Mutex
 	futuresABI *abi.ABI
 
 	// deps
 	futures    *futures.Futures
 	multicall  *multicall.Multicall3Custom
 	client     EthereumClient
+	txManager  *txmanager.TxManager
 	logWatcher LogWatcher
+	ledger     *ledger.Ledger
 	log        interfaces.ILogger
 }
 
-func NewFuturesEthereum(futuresAddr common.Address, multicalladdr common.Address, client EthereumClient, logWatcher LogWatcher, log interfaces.ILogger) *FuturesEthereum {
+func NewFuturesEthereum(futuresAddr common.Address, multicalladdr common.Address, client EthereumClient, txManager *txmanager.TxManager, logWatcher LogWatcher, ledger *ledger.Ledger, log interfaces.ILogger) *FuturesEthereum {
 	ft, err := futures.NewFutures(futuresAddr, client)
 	if err != nil {
 		panic("invalid clonefactory ABI")
@@ -54,23 +57,22 @@ func NewFuturesEthereum(futuresAddr common.Address, multicalladdr common.Address
 		multicall:   multicall,
 		futuresAddr: futuresAddr,
 		client:      client,
+		txManager:   txManager,
 		futuresABI:  ftABI,
-		mutex:       lib.NewMutex(),
 		logWatcher:  logWatcher,
+		ledger:      ledger,
 		log:         log,
 	}
 }
 
-func (g *FuturesEthereum) SetLegacyTx(legacyTx bool) {
-	g.legacyTx = legacyTx
-}
-
 func (g *FuturesEthereum) GetToken(ctx context.Context) (common.Address, error) {
 	return g.futures.Token(&bind.CallOpts{Context: ctx})
 }
 
 // GetOngoingDeliveryRange returns the start and end of the ongoing delivery range
 func (g *FuturesEthereum) GetOngoingDeliveryRange(ctx context.Context) (start time.Time, end time.Time, duration time.Duration, err error) {
+	ctx = ethpool.WithQuorum(ctx)
+
 	_firstDeliveryDate, err := g.futures.FirstFutureDeliveryDate(&bind.CallOpts{Context: ctx})
 	if err != nil {
 		return time.Time{}, time.Time{}, 0, err
@@ -89,6 +91,8 @@ func (g *FuturesEthereum) GetOngoingDeliveryRange(ctx context.Context) (start ti
 }
 
 func (g *FuturesEthereum) GetMatchedContracts(ctx context.Context, userAddress common.Address, deliveryDate time.Time) ([]FuturesContract, error) {
+	ctx = ethpool.WithQuorum(ctx)
+
 	posIDs, err := g.futures.GetPositionsByParticipantDeliveryDate(&bind.CallOpts{Context: ctx}, userAddress, big.NewInt(deliveryDate.Unix()))
 	if err != nil {
 		return nil, err
@@ -119,22 +123,126 @@ func (g *FuturesEthereum) GetMatchedContracts(ctx context.Context, userAddress c
 	return contracts, nil
 }
 
-func (g *FuturesEthereum) CloseDelivery(ctx context.Context, positionID common.Hash, blameSeller bool, privKey string) error {
-	timeout := 2 * time.Minute
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
+// GetDeliveryContracts returns the open positions of all participants for the delivery date. The contract doesn't index
+// positions by date only, so they are collected from the position events since the delivery date could be traded
+func (g *FuturesEthereum) GetDeliveryContracts(ctx context.Context, deliveryDate time.Time) ([]FuturesContract, error) {
+	opts := &bind.CallOpts{Context: ctx}
+	datesCount, err := g.futures.FutureDeliveryDatesCount(opts)
+	if err != nil {
+		return nil, err
+	}
+	intervalDays, err := g.futures.DeliveryIntervalDays(opts)
+	if err != nil {
+		return nil, err
+	}
+	tradedFrom := deliveryDate.Add(-time.Duration(int(datesCount)+1) * time.Duration(intervalDays) * 24 * time.Hour)
+
+	head, err := g.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	from, err := g.findBlockByTime(ctx, tradedFrom, head)
+	if err != nil {
+		return nil, err
+	}
+	to := head.Number.Uint64()
+	g.log.Infof("loading positions for delivery %s from blocks %d-%d", deliveryDate.Format(time.RFC3339), from, to)
+
+	createdTopic, closedTopic := g.futuresABI.Events["PositionCreated"].ID, g.futuresABI.Events["PositionClosed"].ID
+
+	var posIDs []common.Hash
+	closed := make(map[common.Hash]bool)
+	for start := from; start <= to; start += maxLogRange {
+		end := min(start+maxLogRange-1, to)
+		logs, err := g.client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(start),
+			ToBlock:   new(big.Int).SetUint64(end),
+			Addresses: []common.Address{g.futuresAddr},
+			Topics:    [][]common.Hash{{createdTopic, closedTopic}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range logs {
+			switch l.Topics[0] {
+			case createdTopic:
+				e, err := g.futures.ParsePositionCreated(l)
+				if err != nil {
+					return nil, err
+				}
+				if e.DeliveryAt.Int64() == deliveryDate.Unix() {
+					posIDs = append(posIDs, e.PositionId)
+				}
+			case closedTopic:
+				e, err := g.futures.ParsePositionClosed(l)
+				if err != nil {
+					return nil, err
+				}
+				closed[e.PositionId] = true
+			}
+		}
+	}
 
-	l := lib.NewMutex()
+	var args [][]any
+	var openIDs []common.Hash
+	for _, id := range posIDs {
+		if !closed[id] {
+			openIDs = append(openIDs, id)
+			args = append(args, []any{id})
+		}
+	}
 
-	err := l.LockCtx(ctx)
+	pos, err := mc.Batch[futures.FuturesPosition](ethpool.WithQuorum(ctx), g.multicall, g.futuresABI, g.futuresAddr, "getPositionById", args)
 	if err != nil {
-		err = lib.WrapError(err, fmt.Errorf("close contract lock error %s", timeout))
-		g.log.Error(err)
-		return err
+		return nil, err
+	}
+
+	contracts := make([]FuturesContract, len(pos))
+	for i, position := range pos {
+		contracts[i] = FuturesContract{
+			ContractID: openIDs[i],
+			Seller:     position.Seller,
+			Buyer:      position.Buyer,
+			DestURL:    position.DestURL,
+			DeliveryAt: time.Unix(position.DeliveryAt.Int64(), 0),
+			Paid:       position.Paid,
+		}
 	}
-	defer l.Unlock()
+	return contracts, nil
+}
 
-	err = g.closeDelivery(ctx, positionID, privKey, blameSeller)
+// findBlockByTime returns the first block with the timestamp not earlier than t
+func (g *FuturesEthereum) findBlockByTime(ctx context.Context, t time.Time, head *types.Header) (uint64, error) {
+	lo, hi := uint64(0), head.Number.Uint64()
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		header, err := g.client.HeaderByNumber(ctx, new(big.Int).SetUint64(mid))
+		if err != nil {
+			return 0, err
+		}
+		if header.Time < uint64(t.Unix()) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, nil
+}
+
+// GetLatestBlockTime returns the timestamp of the latest block
+func (g *FuturesEthereum) GetLatestBlockTime(ctx context.Context) (time.Time, error) {
+	head, err := g.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(head.Time), 0), nil
+}
+
+func (g *FuturesEthereum) CloseDelivery(ctx context.Context, positionID common.Hash, blameSeller bool, signer interfaces.Signer) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	err := g.closeDelivery(ctx, positionID, signer, blameSeller)
 	if err != nil {
 		if strings.Contains(err.Error(), "the contract is not in the running state") {
 			return ErrNotRunning
@@ -142,7 +250,7 @@ func (g *FuturesEthereum) CloseDelivery(ctx context.Context, positionID common.H
 		return lib.WrapError(fmt.Errorf("close contract error"), err)
 	}
 
-	return err
+	return nil
 }
 
 type CloseDeliveryReq struct {
@@ -150,17 +258,12 @@ type CloseDeliveryReq struct {
 	BlameSeller bool
 }
 
-func (g *FuturesEthereum) BatchCloseDelivery(ctx context.Context, reqs []CloseDeliveryReq, privKey string) error {
-	transactOpts, err := g.getTransactOpts(ctx, privKey)
-	if err != nil {
-		g.log.Error(err)
-		return err
-	}
-
+func (g *FuturesEthereum) BatchCloseDelivery(ctx context.Context, reqs []CloseDeliveryReq, signer interfaces.Signer) error {
 	g.log.Debugf("batch closing deliveries %+v", reqs)
 
 	calls := make([][]byte, len(reqs))
 	for i, req := range reqs {
+		var err error
 		calls[i], err = g.futuresABI.Pack("closeDelivery", req.PositionID, req.BlameSeller)
 		if err != nil {
 			g.log.Error(err)
@@ -168,14 +271,11 @@ func (g *FuturesEthereum) BatchCloseDelivery(ctx context.Context, reqs []CloseDe
 		}
 	}
 
-	tx, err := g.futures.Multicall(transactOpts, calls)
-	if err != nil {
-		err = lib.TryConvertGethError(err, AllContractsMeta)
-		g.log.Error(err)
-		return err
-	}
-
-	_, err = bind.WaitMined(ctx, g.client, tx)
+	label := fmt.Sprintf("batch close %d deliveries", len(reqs))
+	_, err := g.txManager.Transact(ctx, signer, label, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		tx, err := g.futures.Multicall(opts, calls)
+		return tx, lib.TryConvertGethError(err, AllContractsMeta)
+	})
 	if err != nil {
 		g.log.Error(err)
 		return err
@@ -185,6 +285,8 @@ func (g *FuturesEthereum) BatchCloseDelivery(ctx context.Context, reqs []CloseDe
 }
 
 func (g *FuturesEthereum) GetPosition(ctx context.Context, positionID common.Hash) (*FuturesContract, error) {
+	ctx = ethpool.WithQuorum(ctx)
+
 	pos, err := g.futures.GetPositionById(&bind.CallOpts{Context: ctx}, positionID)
 	if err != nil {
 		return nil, err
@@ -200,26 +302,18 @@ func (g *FuturesEthereum) GetPosition(ctx context.Context, positionID common.Has
 	}, nil
 }
 
-func (g *FuturesEthereum) closeDelivery(ctx context.Context, positionID common.Hash, privKey string, blameSeller bool) error {
-	transactOpts, err := g.getTransactOpts(ctx, privKey)
+func (g *FuturesEthereum) closeDelivery(ctx context.Context, positionID common.Hash, signer interfaces.Signer, blameSeller bool) error {
+	label := fmt.Sprintf("close delivery %s, blame seller %t", positionID.Hex(), blameSeller)
+	_, err := g.txManager.Transact(ctx, signer, label, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		tx, err := g.futures.CloseDelivery(opts, positionID, blameSeller)
+		return tx, lib.TryConvertGethError(err, AllContractsMeta)
+	})
 	if err != nil {
 		g.log.Error(err)
 		return err
 	}
 
-	tx, err := g.futures.CloseDelivery(transactOpts, (positionID), blameSeller)
-	if err != nil {
-		err = lib.TryConvertGethError(err, AllContractsMeta)
-
-	}
-
-	g.log.Debugf("closed delivery, position id %s, blame seller %b nonce %d", positionID, blameSeller, tx.Nonce())
-
-	_, err = bind.WaitMined(ctx, g.client, tx)
-	if err != nil {
-		g.log.Error(err)
-		return err
-	}
+	g.log.Debugf("closed delivery, position id %s, blame seller %t", positionID, blameSeller)
 
 	return nil
 }
@@ -272,27 +366,31 @@ func (g *FuturesEthereum) GetContractSpecs(ctx context.Context) (*ContractSpecs,
 	}, nil
 }
 
-func (g *FuturesEthereum) ClaimReward(ctx context.Context, deliveryDate time.Time, privKey string) error {
-	transactOpts, err := g.getTransactOpts(ctx, privKey)
+func (g *FuturesEthereum) ClaimReward(ctx context.Context, deliveryDate time.Time, signer interfaces.Signer) error {
+	label := fmt.Sprintf("claim reward, delivery date %s", deliveryDate.Format(time.RFC3339))
+	receipt, err := g.txManager.Transact(ctx, signer, label, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		tx, err := g.futures.WithdrawDeliveryPayment(opts, big.NewInt(deliveryDate.Unix()))
+		return tx, lib.TryConvertGethError(err, AllContractsMeta)
+	})
 	if err != nil {
 		g.log.Error(err)
 		return err
 	}
 
-	tx, err := g.futures.WithdrawDeliveryPayment(transactOpts, big.NewInt(deliveryDate.Unix()))
-	if err != nil {
-		err = lib.TryConvertGethError(err, AllContractsMeta)
-
-	}
+	g.log.Debugf("claimed reward, delivery date %s", deliveryDate.Format(time.RFC3339))
 
-	_, err = bind.WaitMined(ctx, g.client, tx)
-	if err != nil {
-		g.log.Error(err)
-		return err
+	if g.ledger != nil {
+		err = g.ledger.AddFromReceipt(ledger.Entry{
+			Time:       time.Now(),
+			Type:       ledger.EntryRewardClaim,
+			Role:       ledger.RoleSeller,
+			ContractID: deliveryDate.UTC().Format(time.RFC3339),
+		}, receipt, signer.Address())
+		if err != nil {
+			g.log.Errorf("can't write ledger entry: %s", err)
+		}
 	}
 
-	g.log.Debugf("claimed reward, position id %s, nonce %d", deliveryDate.Format(time.RFC3339), tx.Nonce())
-
 	return nil
 }
 
@@ -300,37 +398,6 @@ func (g *FuturesEthereum) CreateFuturesSubscription(ctx context.Context, futures
 	return g.logWatcher.Watch(ctx, futuresAddr, CreateEventMapper(futuresEventFactory, g.futuresABI), nil)
 }
 
-func (g *FuturesEthereum) getTransactOpts(ctx context.Context, privKey string) (*bind.TransactOpts, error) {
-	privateKey, err := crypto.HexToECDSA(privKey)
-	if err != nil {
-		return nil, err
-	}
-
-	chainId, err := g.client.ChainID(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	transactOpts, err := bind.NewKeyedTransactorWithChainID(privateKey, chainId)
-	if err != nil {
-		return nil, err
-	}
-
-	// TODO: deal with likely gasPrice issue so our transaction processes before another pending nonce.
-	if g.legacyTx {
-		gasPrice, err := g.client.SuggestGasPrice(ctx)
-		if err != nil {
-			return nil, err
-		}
-		transactOpts.GasPrice = gasPrice
-	}
-
-	transactOpts.Value = big.NewInt(0)
-	transactOpts.Context = ctx
-
-	return transactOpts, nil
-}
-
 func GetOngoingDeliveryRange(firstDeliveryDate time.Time, deliveryInterval time.Duration, now time.Time) (start time.Time, end time.Time) {
 	duration := deliveryInterval
 	elapsed := now.Sub(firstDeliveryDate)
@@ -2,6 +2,7 @@ package contracts
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"strings"
@@ -11,12 +12,16 @@ import (
 	"github.com/Lumerin-protocol/contracts-go/v2/implementation"
 	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
 	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/ethpool"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/ledger"
+	mc "github.com/Lumerin-protocol/proxy-router/internal/repositories/multicall"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/txmanager"
 	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate"
 	hr "github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/hashrate"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
 var (
@@ -25,23 +30,25 @@ var (
 
 type HashrateEthereum struct {
 	// config
-	legacyTx         bool // use legacy transaction fee, for local node testing
-	clonefactoryAddr common.Address
+	clonefactoryAddr     common.Address
+	multicallBatchSize   int
+	multicallConcurrency int
 
 	// state
-	nonce   uint64
-	mutex   lib.Mutex
 	cfABI   *abi.ABI
 	implABI *abi.ABI
 
 	// deps
 	cloneFactory *clonefactory.Clonefactory
+	multicall    mc.MulticallBackend
 	client       EthereumClient
+	txManager    *txmanager.TxManager
 	logWatcher   LogWatcher
+	ledger       *ledger.Ledger
 	log          interfaces.ILogger
 }
 
-func NewHashrateEthereum(clonefactoryAddr common.Address, client EthereumClient, logWatcher LogWatcher, log interfaces.ILogger) *HashrateEthereum {
+func NewHashrateEthereum(clonefactoryAddr common.Address, multicallAddr common.Address, multicallBatchSize int, multicallConcurrency int, client EthereumClient, txManager *txmanager.TxManager, logWatcher LogWatcher, ledger *ledger.Ledger, log interfaces.ILogger) *HashrateEthereum {
 	cf, err := clonefactory.NewClonefactory(clonefactoryAddr, client)
 	if err != nil {
 		panic("invalid clonefactory ABI")
@@ -55,21 +62,21 @@ func NewHashrateEthereum(clonefactoryAddr common.Address, client EthereumClient,
 		panic("invalid implementation ABI: " + err.Error())
 	}
 	return &HashrateEthereum{
-		cloneFactory:     cf,
-		clonefactoryAddr: clonefactoryAddr,
-		client:           client,
-		cfABI:            cfABI,
-		implABI:          implABI,
-		mutex:            lib.NewMutex(),
-		logWatcher:       logWatcher,
-		log:              log,
+		cloneFactory:         cf,
+		clonefactoryAddr:     clonefactoryAddr,
+		multicallBatchSize:   multicallBatchSize,
+		multicallConcurrency: multicallConcurrency,
+		multicall:            mc.NewMulticall3Custom(client, multicallAddr),
+		client:               client,
+		txManager:            txManager,
+		cfABI:                cfABI,
+		implABI:              implABI,
+		logWatcher:           logWatcher,
+		ledger:               ledger,
+		log:                  log,
 	}
 }
 
-func (g *HashrateEthereum) SetLegacyTx(legacyTx bool) {
-	g.legacyTx = legacyTx
-}
-
 func (g *HashrateEthereum) GetPaymentToken(ctx context.Context) (common.Address, error) {
 	return g.cloneFactory.PaymentToken(&bind.CallOpts{Context: ctx})
 }
@@ -93,6 +100,9 @@ func (g *HashrateEthereum) GetContractsIDs(ctx context.Context) ([]string, error
 }
 
 func (g *HashrateEthereum) GetContract(ctx context.Context, contractID string) (*hashrate.EncryptedTerms, error) {
+	// contract terms define the delivery, so they are confirmed by the quorum of nodes
+	ctx = ethpool.WithQuorum(ctx)
+
 	instance, err := implementation.NewImplementation(common.HexToAddress(contractID), g.client)
 	if err != nil {
 		return nil, err
@@ -103,36 +113,138 @@ func (g *HashrateEthereum) GetContract(ctx context.Context, contractID string) (
 		return nil, lib.WrapError(fmt.Errorf("can't get public variables"), err)
 	}
 
-	var (
-		startsAt              time.Time
-		buyer                 string
-		encryptedValidatorURL string
-
-		encryptedDestURL string
-		validatorAddr    string
-	)
+	var validatorAddr, encryptedDestURL string
 
 	if data.State == 1 { // running
-		startsAt = time.Unix(data.StartingBlockTimestamp.Int64(), 0)
-		buyer = data.Buyer.Hex()
-		encryptedValidatorURL = data.EncryptedPoolData
-
 		validator, err := instance.Validator(&bind.CallOpts{Context: ctx})
 		if err != nil {
 			return nil, err
 		}
 		validatorAddr = validator.Hex()
 
-		destUrl, err := instance.EncrDestURL(&bind.CallOpts{Context: ctx})
+		encryptedDestURL, err = instance.EncrDestURL(&bind.CallOpts{Context: ctx})
 		if err != nil {
 			return nil, err
 		}
-		if destUrl != "" {
-			encryptedDestURL = destUrl
+	}
+
+	return newEncryptedTerms(contractID, publicVariablesV2(data), validatorAddr, encryptedDestURL), nil
+}
+
+// GetContracts loads the terms of multiple contracts using chunked multicall requests, which is much faster
+// than calling GetContract for each of them. Contracts which calls failed in the batch are loaded one by one
+func (g *HashrateEthereum) GetContracts(ctx context.Context, contractIDs []string) ([]*hashrate.EncryptedTerms, error) {
+	ctx = ethpool.WithQuorum(ctx)
+
+	calls := make([]mc.Call, len(contractIDs))
+	for i, id := range contractIDs {
+		calls[i] = mc.Call{Target: common.HexToAddress(id), ABI: g.implABI, Method: "getPublicVariablesV2"}
+	}
+	results, err := mc.BatchChunked(ctx, g.multicall, calls, g.multicallBatchSize, g.multicallConcurrency, func(done, total int) {
+		g.log.Infof("loaded terms of %d/%d contracts", done, total)
+	})
+	if err != nil {
+		return nil, lib.WrapError(fmt.Errorf("can't get public variables"), err)
+	}
+
+	// validator and destination are loaded only for the running contracts
+	var runningCalls []mc.Call
+	var runningIdx []int
+	data := make([]publicVariablesV2, len(contractIDs))
+
+	for i, res := range results {
+		if res.Err != nil {
+			continue
+		}
+		data[i] = unpackPublicVariables(res.Values)
+		if data[i].State == 1 {
+			addr := common.HexToAddress(contractIDs[i])
+			runningCalls = append(runningCalls,
+				mc.Call{Target: addr, ABI: g.implABI, Method: "validator"},
+				mc.Call{Target: addr, ABI: g.implABI, Method: "encrDestURL"},
+			)
+			runningIdx = append(runningIdx, i)
+		}
+	}
+
+	runningResults, err := mc.BatchChunked(ctx, g.multicall, runningCalls, g.multicallBatchSize, g.multicallConcurrency, nil)
+	if err != nil {
+		return nil, lib.WrapError(fmt.Errorf("can't get running contracts data"), err)
+	}
+
+	terms := make([]*hashrate.EncryptedTerms, len(contractIDs))
+	for j, i := range runningIdx {
+		validatorRes, destRes := runningResults[2*j], runningResults[2*j+1]
+		if validatorRes.Err != nil || destRes.Err != nil {
+			results[i].Err = errors.Join(validatorRes.Err, destRes.Err)
+			continue
+		}
+		validator := *abi.ConvertType(validatorRes.Values[0], new(common.Address)).(*common.Address)
+		destURL := *abi.ConvertType(destRes.Values[0], new(string)).(*string)
+		terms[i] = newEncryptedTerms(contractIDs[i], data[i], validator.Hex(), destURL)
+	}
+
+	for i, res := range results {
+		if terms[i] != nil {
+			continue
+		}
+		if res.Err == nil && data[i].State != 1 {
+			terms[i] = newEncryptedTerms(contractIDs[i], data[i], "", "")
+			continue
 		}
+
+		g.log.Warnf("failed to load contract %s in batch, loading separately: %s", contractIDs[i], res.Err)
+		terms[i], err = g.GetContract(ctx, contractIDs[i])
+		if err != nil {
+			return nil, lib.WrapError(fmt.Errorf("can't get contract %s", contractIDs[i]), err)
+		}
+	}
+
+	return terms, nil
+}
+
+// publicVariablesV2 is the output of the getPublicVariablesV2 contract method
+type publicVariablesV2 struct {
+	State                  uint8
+	Terms                  implementation.ImplementationTerms
+	StartingBlockTimestamp *big.Int
+	Buyer                  common.Address
+	Seller                 common.Address
+	EncryptedPoolData      string
+	IsDeleted              bool
+	Balance                *big.Int
+	HasFutureTerms         bool
+}
+
+// unpackPublicVariables converts unpacked getPublicVariablesV2 output the same way the generated binding does
+func unpackPublicVariables(out []interface{}) publicVariablesV2 {
+	return publicVariablesV2{
+		State:                  *abi.ConvertType(out[0], new(uint8)).(*uint8),
+		Terms:                  *abi.ConvertType(out[1], new(implementation.ImplementationTerms)).(*implementation.ImplementationTerms),
+		StartingBlockTimestamp: *abi.ConvertType(out[2], new(*big.Int)).(**big.Int),
+		Buyer:                  *abi.ConvertType(out[3], new(common.Address)).(*common.Address),
+		Seller:                 *abi.ConvertType(out[4], new(common.Address)).(*common.Address),
+		EncryptedPoolData:      *abi.ConvertType(out[5], new(string)).(*string),
+		IsDeleted:              *abi.ConvertType(out[6], new(bool)).(*bool),
+		Balance:                *abi.ConvertType(out[7], new(*big.Int)).(**big.Int),
+		HasFutureTerms:         *abi.ConvertType(out[8], new(bool)).(*bool),
 	}
+}
 
-	terms := &hashrate.EncryptedTerms{
+func newEncryptedTerms(contractID string, data publicVariablesV2, validatorAddr string, encryptedDestURL string) *hashrate.EncryptedTerms {
+	var (
+		startsAt              time.Time
+		buyer                 string
+		encryptedValidatorURL string
+	)
+
+	if data.State == 1 { // running
+		startsAt = time.Unix(data.StartingBlockTimestamp.Int64(), 0)
+		buyer = data.Buyer.Hex()
+		encryptedValidatorURL = data.EncryptedPoolData
+	}
+
+	return &hashrate.EncryptedTerms{
 		BaseTerms: *hashrate.NewBaseTerms(
 			contractID,
 			data.Seller.Hex(),
@@ -151,26 +263,13 @@ func (g *HashrateEthereum) GetContract(ctx context.Context, contractID string) (
 		ValidatorUrlEncrypted: encryptedValidatorURL,
 		DestEncrypted:         encryptedDestURL,
 	}
-
-	return terms, nil
 }
 
-func (g *HashrateEthereum) EarlyClose(ctx context.Context, contractID string, reason CloseReason, privKey string) error {
-	timeout := 2 * time.Minute
-	ctx, cancel := context.WithTimeout(ctx, timeout)
+func (g *HashrateEthereum) EarlyClose(ctx context.Context, contractID string, reason CloseReason, signer interfaces.Signer) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 	defer cancel()
 
-	l := lib.NewMutex()
-
-	err := l.LockCtx(ctx)
-	if err != nil {
-		err = lib.WrapError(err, fmt.Errorf("close contract lock error %s", timeout))
-		g.log.Error(err)
-		return err
-	}
-	defer l.Unlock()
-
-	err = g.earlyClose(ctx, contractID, reason, privKey)
+	err := g.earlyClose(ctx, contractID, reason, signer)
 	if err != nil {
 		if strings.Contains(err.Error(), "the contract is not in the running state") {
 			return ErrNotRunning
@@ -178,123 +277,194 @@ func (g *HashrateEthereum) EarlyClose(ctx context.Context, contractID string, re
 		return lib.WrapError(fmt.Errorf("close contract error"), err)
 	}
 
-	return err
+	return nil
 }
 
-func (g *HashrateEthereum) earlyClose(ctx context.Context, contractID string, reason CloseReason, privKey string) error {
+func (g *HashrateEthereum) earlyClose(ctx context.Context, contractID string, reason CloseReason, signer interfaces.Signer) error {
 	instance, err := implementation.NewImplementation(common.HexToAddress(contractID), g.client)
 	if err != nil {
 		g.log.Error(err)
 		return err
 	}
 
-	transactOpts, err := g.getTransactOpts(ctx, privKey)
-	if err != nil {
-		g.log.Error(err)
-		return err
-	}
-
-	tx, err := instance.CloseEarly(transactOpts, uint8(reason))
+	// the closeout is initiated by the buyer or the validator of the contract, the buyer is reset by the closeout
+	role := ledger.RoleValidator
+	buyer, err := instance.Buyer(&bind.CallOpts{Context: ctx})
 	if err != nil {
-		return err
+		g.log.Warnf("can't get contract buyer for the ledger: %s", err)
+		role = ""
+	} else if buyer == signer.Address() {
+		role = ledger.RoleBuyer
 	}
-	g.log.Debugf("closed contract id %s, reason %d nonce %d", contractID, reason, tx.Nonce())
 
-	_, err = bind.WaitMined(ctx, g.client, tx)
+	label := fmt.Sprintf("close contract %s, reason %d", contractID, reason)
+	receipt, err := g.txManager.Transact(ctx, signer, label, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return instance.CloseEarly(opts, uint8(reason))
+	})
 	if err != nil {
 		g.log.Error(err)
 		return err
 	}
+	g.log.Debugf("closed contract id %s, reason %d", contractID, reason)
+
+	g.record(ledger.Entry{
+		Time:       time.Now(),
+		Type:       ledger.EntryCloseout,
+		Role:       role,
+		ContractID: contractID,
+		Reason:     reason.String(),
+		Blame:      reason.Blame(),
+	}, receipt, signer.Address())
 
 	return nil
 }
 
-func (g *HashrateEthereum) ClaimValidatorReward(ctx context.Context, contractID string, privKey string) error {
-	timeout := 2 * time.Minute
-	ctx, cancel := context.WithTimeout(ctx, timeout)
+func (g *HashrateEthereum) ClaimValidatorReward(ctx context.Context, contractID string, signer interfaces.Signer) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 	defer cancel()
 
-	l := lib.NewMutex()
-
-	err := l.LockCtx(ctx)
-	if err != nil {
-		err = lib.WrapError(err, fmt.Errorf("claim validator reward lock error %s", timeout))
-		g.log.Error(err)
-		return err
-	}
-	defer l.Unlock()
-
-	err = g.claimValidatorReward(ctx, contractID, privKey)
+	err := g.claimValidatorReward(ctx, contractID, signer)
 	if err != nil {
 		return lib.WrapError(fmt.Errorf("claim validator reward error"), err)
 	}
 
-	return err
+	return nil
 }
 
-func (g *HashrateEthereum) claimValidatorReward(ctx context.Context, contractID string, privKey string) error {
+func (g *HashrateEthereum) claimValidatorReward(ctx context.Context, contractID string, signer interfaces.Signer) error {
 	instance, err := implementation.NewImplementation(common.HexToAddress(contractID), g.client)
 	if err != nil {
 		g.log.Error(err)
 		return err
 	}
 
-	transactOpts, err := g.getTransactOpts(ctx, privKey)
+	label := fmt.Sprintf("claim validator reward, contract %s", contractID)
+	receipt, err := g.txManager.Transact(ctx, signer, label, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return instance.ClaimFundsValidator(opts)
+	})
 	if err != nil {
 		g.log.Error(err)
 		return err
 	}
+	g.log.Debugf("claimed validator reward, contract id %s", contractID)
 
-	tx, err := instance.ClaimFundsValidator(transactOpts)
-	if err != nil {
-		return err
+	g.record(ledger.Entry{
+		Time:       time.Now(),
+		Type:       ledger.EntryValidatorFee,
+		Role:       ledger.RoleValidator,
+		ContractID: contractID,
+	}, receipt, signer.Address())
+
+	return nil
+}
+
+// RecordContractStart records the purchase of the seller contract to the ledger
+func (g *HashrateEthereum) RecordContractStart(ctx context.Context, contractID string, buyer common.Address, price *big.Int, txHash common.Hash) {
+	if g.ledger == nil {
+		return
 	}
-	g.log.Debugf("claim reward contract id %s, nonce %d", contractID, tx.Nonce())
 
-	_, err = bind.WaitMined(ctx, g.client, tx)
+	entry := ledger.Entry{
+		Time:         time.Now(),
+		Type:         ledger.EntryContractStart,
+		Role:         ledger.RoleSeller,
+		ContractID:   contractID,
+		Counterparty: &buyer,
+		Price:        price,
+		TxHash:       txHash,
+	}
+	token, err := g.GetPaymentToken(ctx)
 	if err != nil {
-		g.log.Error(err)
-		return err
+		g.log.Warnf("can't get payment token for the ledger: %s", err)
+	} else {
+		entry.Token = &token
 	}
 
-	return nil
+	err = g.ledger.Add(entry)
+	if err != nil {
+		g.log.Errorf("can't write ledger entry: %s", err)
+	}
 }
 
-func (s *HashrateEthereum) CreateCloneFactorySubscription(ctx context.Context, clonefactoryAddr common.Address) (*lib.Subscription, error) {
-	return s.logWatcher.Watch(ctx, clonefactoryAddr, CreateEventMapper(clonefactoryEventFactory, s.cfABI), nil)
-}
+// RecordClosedEarly records the closeout of the seller contract, initiated by the other party, to the ledger
+// with the amount the seller received in the closeout transaction
+func (g *HashrateEthereum) RecordClosedEarly(ctx context.Context, contractID string, reason CloseReason, txHash common.Hash, seller common.Address) {
+	if g.ledger == nil {
+		return
+	}
 
-func (s *HashrateEthereum) CreateImplementationSubscription(ctx context.Context, contractAddr common.Address) (*lib.Subscription, error) {
-	return s.logWatcher.Watch(ctx, contractAddr, CreateEventMapper(implementationEventFactory, s.implABI), nil)
+	receipt, err := g.client.TransactionReceipt(ctx, txHash)
+	if err != nil {
+		g.log.Warnf("can't get closeout receipt for the ledger: %s", err)
+		receipt = &types.Receipt{TxHash: txHash}
+	}
+
+	g.record(ledger.Entry{
+		Time:       time.Now(),
+		Type:       ledger.EntryCloseout,
+		Role:       ledger.RoleSeller,
+		ContractID: contractID,
+		Reason:     reason.String(),
+		Blame:      reason.Blame(),
+	}, receipt, seller)
 }
 
-func (g *HashrateEthereum) getTransactOpts(ctx context.Context, privKey string) (*bind.TransactOpts, error) {
-	privateKey, err := crypto.HexToECDSA(privKey)
-	if err != nil {
-		return nil, err
+// RecordPayout records the seller payment of the contract completed in full to the ledger. The payment of the
+// early closeout is recorded with the closeout
+func (g *HashrateEthereum) RecordPayout(ctx context.Context, contractID string, txHash common.Hash, seller common.Address) {
+	if g.ledger == nil {
+		return
 	}
 
-	chainId, err := g.client.ChainID(ctx)
+	receipt, err := g.client.TransactionReceipt(ctx, txHash)
 	if err != nil {
-		return nil, err
+		g.log.Warnf("can't get payout receipt for the ledger: %s", err)
+		receipt = &types.Receipt{TxHash: txHash}
 	}
+	closedEarlyTopic := g.implABI.Events["closedEarly"].ID
+	for _, log := range receipt.Logs {
+		if log.Address == common.HexToAddress(contractID) && len(log.Topics) > 0 && log.Topics[0] == closedEarlyTopic {
+			return
+		}
+	}
+
+	g.record(ledger.Entry{
+		Time:       time.Now(),
+		Type:       ledger.EntryPayout,
+		Role:       ledger.RoleSeller,
+		ContractID: contractID,
+	}, receipt, seller)
+}
 
-	transactOpts, err := bind.NewKeyedTransactorWithChainID(privateKey, chainId)
+// RevertLedgerEntries removes the ledger entries of the transaction which event was reverted by the chain reorg
+func (g *HashrateEthereum) RevertLedgerEntries(txHash common.Hash) {
+	if g.ledger == nil {
+		return
+	}
+	removed, err := g.ledger.RemoveTx(txHash)
 	if err != nil {
-		return nil, err
+		g.log.Errorf("can't remove ledger entries: %s", err)
+		return
 	}
+	if removed > 0 {
+		g.log.Warnf("removed %d ledger entries of the reverted tx %s", removed, txHash.Hex())
+	}
+}
 
-	// TODO: deal with likely gasPrice issue so our transaction processes before another pending nonce.
-	if g.legacyTx {
-		gasPrice, err := g.client.SuggestGasPrice(ctx)
-		if err != nil {
-			return nil, err
-		}
-		transactOpts.GasPrice = gasPrice
+func (g *HashrateEthereum) record(entry ledger.Entry, receipt *types.Receipt, wallet common.Address) {
+	if g.ledger == nil {
+		return
 	}
+	err := g.ledger.AddFromReceipt(entry, receipt, wallet)
+	if err != nil {
+		g.log.Errorf("can't write ledger entry: %s", err)
+	}
+}
 
-	transactOpts.Value = big.NewInt(0)
-	transactOpts.Context = ctx
+func (s *HashrateEthereum) CreateCloneFactorySubscription(ctx context.Context, clonefactoryAddr common.Address) (*lib.Subscription, error) {
+	return s.logWatcher.Watch(ctx, clonefactoryAddr, CreateEventMapper(clonefactoryEventFactory, s.cfABI), nil)
+}
 
-	return transactOpts, nil
+func (s *HashrateEthereum) CreateImplementationSubscription(ctx context.Context, contractAddr common.Address) (*lib.Subscription, error) {
+	return s.logWatcher.Watch(ctx, contractAddr, CreateEventMapper(implementationEventFactory, s.implABI), nil)
 }
@@ -0,0 +1,96 @@
+package contracts
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/Lumerin-protocol/contracts-go/v2/hashrateoracle"
+	"github.com/Lumerin-protocol/contracts-go/v2/implementation"
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// HashpriceOracle is the on-chain hashprice the contract price is calculated from
+type HashpriceOracle struct {
+	HashesForToken *big.Int // number of hashes worth the base unit of the payment token
+	TokenDecimals  int64
+}
+
+// BasePrice returns the price of the contract with zero profit target in the payment token base units,
+// the contract price is the base price adjusted by the profit target percent
+func (o *HashpriceOracle) BasePrice(speedHps *big.Int, length time.Duration) *big.Int {
+	hashes := new(big.Int).Mul(speedHps, big.NewInt(int64(length.Seconds())))
+	if o.HashesForToken.Sign() == 0 {
+		return new(big.Int)
+	}
+	return hashes.Div(hashes, o.HashesForToken)
+}
+
+func (g *HashrateEthereum) GetHashpriceOracle(ctx context.Context) (*HashpriceOracle, error) {
+	oracleAddr, err := g.cloneFactory.HashrateOracle(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, lib.TryConvertGethError(err, AllContractsMeta)
+	}
+	oracle, err := hashrateoracle.NewHashrateoracle(oracleAddr, g.client)
+	if err != nil {
+		return nil, err
+	}
+
+	hashesForToken, err := oracle.GetHashesforToken(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, lib.TryConvertGethError(err, AllContractsMeta)
+	}
+	decimals, err := oracle.TokenDecimals(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, lib.TryConvertGethError(err, AllContractsMeta)
+	}
+
+	return &HashpriceOracle{
+		HashesForToken: hashesForToken,
+		TokenDecimals:  decimals.Int64(),
+	}, nil
+}
+
+// GetContractPubKey returns the public key the buyers encrypt the destination of the contract with
+func (g *HashrateEthereum) GetContractPubKey(ctx context.Context, contractID string) (string, error) {
+	instance, err := implementation.NewImplementation(common.HexToAddress(contractID), g.client)
+	if err != nil {
+		return "", err
+	}
+	return instance.PubKey(&bind.CallOpts{Context: ctx})
+}
+
+// CreateContract lists the new contract on the clonefactory, the price is set by the oracle and the profit target
+func (g *HashrateEthereum) CreateContract(ctx context.Context, signer interfaces.Signer, speedHps *big.Int, length time.Duration, profitTarget int8, pubKey string, dryRun bool) (*TxResult, error) {
+	label := fmt.Sprintf("create contract, speed %s H/s, length %s, profit target %d%%", speedHps, length, profitTarget)
+	return transact(ctx, g.txManager, signer, label, dryRun, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return g.cloneFactory.SetCreateNewRentalContractV2(opts, big.NewInt(0), big.NewInt(0), speedHps, big.NewInt(int64(length.Seconds())), profitTarget, common.Address{}, pubKey)
+	}, g.log)
+}
+
+// UpdateContractTerms updates the terms of the contract, for the running contract the terms apply after it ends
+func (g *HashrateEthereum) UpdateContractTerms(ctx context.Context, signer interfaces.Signer, contractID string, speedHps *big.Int, length time.Duration, profitTarget int8, dryRun bool) (*TxResult, error) {
+	label := fmt.Sprintf("update contract %s, speed %s H/s, length %s, profit target %d%%", contractID, speedHps, length, profitTarget)
+	return transact(ctx, g.txManager, signer, label, dryRun, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return g.cloneFactory.SetUpdateContractInformationV2(opts, common.HexToAddress(contractID), big.NewInt(0), big.NewInt(0), speedHps, big.NewInt(int64(length.Seconds())), profitTarget)
+	}, g.log)
+}
+
+// SetContractsDeleted delists or relists the contracts, the deleted contracts can't be purchased
+func (g *HashrateEthereum) SetContractsDeleted(ctx context.Context, signer interfaces.Signer, contractIDs []string, deleted bool, dryRun bool) (*TxResult, error) {
+	addrs := make([]common.Address, len(contractIDs))
+	for i, id := range contractIDs {
+		addrs[i] = common.HexToAddress(id)
+	}
+
+	label := fmt.Sprintf("set deleted %t, contracts %s", deleted, strings.Join(contractIDs, ","))
+	return transact(ctx, g.txManager, signer, label, dryRun, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return g.cloneFactory.SetContractsDeleted(opts, addrs, deleted)
+	}, g.log)
+}
@@ -0,0 +1,143 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/Lumerin-protocol/contracts-go/v2/implementation"
+	"github.com/Lumerin-protocol/contracts-go/v3/ierc20"
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/ledger"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	ErrInsufficientBalance = errors.New("insufficient token balance")
+)
+
+// Purchase is the purchase of the contract on the clonefactory, the urls are encrypted by the caller
+type Purchase struct {
+	ContractID       string
+	Seller           common.Address
+	Validator        common.Address
+	EncrValidatorURL string   // encrypted with the public key of the contract, the seller directs the hashrate there
+	EncrDestURL      string   // encrypted with the public key of the validator, the validator forwards the hashrate there
+	TermsVersion     uint32   // purchase fails if the seller updated the terms after they were evaluated
+	Price            *big.Int // in the payment token
+	Fee              *big.Int // validator fee in the fee token
+}
+
+// GetPriceAndFee returns the current price of the contract in the payment token and the validator fee in the fee token
+func (g *HashrateEthereum) GetPriceAndFee(ctx context.Context, contractID string) (price *big.Int, fee *big.Int, err error) {
+	instance, err := implementation.NewImplementation(common.HexToAddress(contractID), g.client)
+	if err != nil {
+		return nil, nil, err
+	}
+	price, fee, err = instance.PriceAndFee(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, nil, lib.TryConvertGethError(err, AllContractsMeta)
+	}
+	return price, fee, nil
+}
+
+// PurchaseContract approves the clonefactory to transfer the price and the fee if needed and purchases the contract.
+// The purchase is recorded to the ledger, so the spent amount can be tracked
+func (g *HashrateEthereum) PurchaseContract(ctx context.Context, signer interfaces.Signer, p *Purchase, dryRun bool) ([]TxResult, error) {
+	paymentToken, err := g.GetPaymentToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	feeToken, err := g.GetFeeToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	amounts := map[common.Address]*big.Int{paymentToken: new(big.Int).Set(p.Price)}
+	if p.Fee != nil && p.Fee.Sign() > 0 {
+		if amounts[feeToken] == nil {
+			amounts[feeToken] = new(big.Int)
+		}
+		amounts[feeToken].Add(amounts[feeToken], p.Fee)
+	}
+
+	var results []TxResult
+	for _, token := range []common.Address{paymentToken, feeToken} {
+		amount, ok := amounts[token]
+		if !ok {
+			continue
+		}
+		delete(amounts, token)
+
+		res, err := g.approveToken(ctx, signer, token, amount, dryRun)
+		if err != nil {
+			return nil, err
+		}
+		if res != nil {
+			results = append(results, *res)
+		}
+	}
+
+	label := fmt.Sprintf("purchase contract %s, price %s", p.ContractID, p.Price)
+	res, err := transact(ctx, g.txManager, signer, label, dryRun, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return g.cloneFactory.SetPurchaseRentalContractV2(opts, common.HexToAddress(p.ContractID), p.Validator, p.EncrValidatorURL, p.EncrDestURL, p.TermsVersion)
+	}, g.log)
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, *res)
+
+	if !dryRun && g.ledger != nil {
+		err = g.ledger.Add(ledger.Entry{
+			Time:         time.Now(),
+			Type:         ledger.EntryContractStart,
+			Role:         ledger.RoleBuyer,
+			ContractID:   p.ContractID,
+			Counterparty: &p.Seller,
+			Price:        p.Price,
+			Fee:          p.Fee,
+			FeeToken:     &feeToken,
+			Token:        &paymentToken,
+			TxHash:       *res.TxHash,
+		})
+		if err != nil {
+			g.log.Errorf("can't write ledger entry: %s", err)
+		}
+	}
+
+	return results, nil
+}
+
+// approveToken approves the clonefactory to transfer the amount if the current allowance is not enough
+func (g *HashrateEthereum) approveToken(ctx context.Context, signer interfaces.Signer, tokenAddr common.Address, amount *big.Int, dryRun bool) (*TxResult, error) {
+	token, err := ierc20.NewIerc20(tokenAddr, g.client)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := token.BalanceOf(&bind.CallOpts{Context: ctx}, signer.Address())
+	if err != nil {
+		return nil, err
+	}
+	if balance.Cmp(amount) < 0 {
+		return nil, lib.WrapError(ErrInsufficientBalance, fmt.Errorf("token %s, balance %s, required %s", tokenAddr.Hex(), balance, amount))
+	}
+
+	allowance, err := token.Allowance(&bind.CallOpts{Context: ctx}, signer.Address(), g.clonefactoryAddr)
+	if err != nil {
+		return nil, err
+	}
+	if allowance.Cmp(amount) >= 0 {
+		return nil, nil
+	}
+
+	label := fmt.Sprintf("approve clonefactory to spend %s of token %s", amount, tokenAddr.Hex())
+	return transact(ctx, g.txManager, signer, label, dryRun, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return token.Approve(opts, g.clonefactoryAddr, amount)
+	}, g.log)
+}
@@ -0,0 +1,121 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/Lumerin-protocol/contracts-go/v2/implementation"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	mc "github.com/Lumerin-protocol/proxy-router/internal/repositories/multicall"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ContractStats is the number of the successful and early closed purchases of the contract kept on chain
+type ContractStats struct {
+	ContractID   string
+	Seller       common.Address
+	SuccessCount uint64
+	FailCount    uint64
+}
+
+// ClosedEarlyEvent is the early closeout of the contract found in the logs
+type ClosedEarlyEvent struct {
+	ContractID  string
+	Reason      CloseReason
+	BlockNumber uint64
+	TxHash      common.Hash
+}
+
+// GetContractsStats loads the sellers and the delivery statistics of the contracts using multicall,
+// contracts which calls failed are skipped
+func (g *HashrateEthereum) GetContractsStats(ctx context.Context, contractIDs []string) ([]ContractStats, error) {
+	calls := make([]mc.Call, 0, 2*len(contractIDs))
+	for _, id := range contractIDs {
+		addr := common.HexToAddress(id)
+		calls = append(calls,
+			mc.Call{Target: addr, ABI: g.implABI, Method: "seller"},
+			mc.Call{Target: addr, ABI: g.implABI, Method: "getStats"},
+		)
+	}
+	results, err := mc.BatchChunked(ctx, g.multicall, calls, g.multicallBatchSize, g.multicallConcurrency, nil)
+	if err != nil {
+		return nil, lib.WrapError(fmt.Errorf("can't get contract stats"), err)
+	}
+
+	stats := make([]ContractStats, 0, len(contractIDs))
+	for i, id := range contractIDs {
+		sellerRes, statsRes := results[2*i], results[2*i+1]
+		if sellerRes.Err != nil || statsRes.Err != nil {
+			g.log.Warnf("failed to load stats of contract %s: %s", id, errors.Join(sellerRes.Err, statsRes.Err))
+			continue
+		}
+		stats = append(stats, ContractStats{
+			ContractID:   id,
+			Seller:       *abi.ConvertType(sellerRes.Values[0], new(common.Address)).(*common.Address),
+			SuccessCount: (*abi.ConvertType(statsRes.Values[0], new(*big.Int)).(**big.Int)).Uint64(),
+			FailCount:    (*abi.ConvertType(statsRes.Values[1], new(*big.Int)).(**big.Int)).Uint64(),
+		})
+	}
+	return stats, nil
+}
+
+// GetClosedEarlyEvents returns the early closeouts of the contracts in the [from, to] block range. The logs are
+// filtered by the event topic only and matched with the contracts locally, as the nodes limit the number of
+// the addresses in the query
+func (g *HashrateEthereum) GetClosedEarlyEvents(ctx context.Context, contractIDs []string, from, to uint64) ([]ClosedEarlyEvent, error) {
+	if len(contractIDs) == 0 {
+		return nil, nil
+	}
+	addrs := make(map[common.Address]struct{}, len(contractIDs))
+	for _, id := range contractIDs {
+		addrs[common.HexToAddress(id)] = struct{}{}
+	}
+	topic := g.implABI.Events["closedEarly"].ID
+
+	var events []ClosedEarlyEvent
+	for start := from; start <= to; start += maxLogRange {
+		end := min(start+maxLogRange-1, to)
+		logs, err := g.client.FilterLogs(ctx, ethereum.FilterQuery{
+			Topics:    [][]common.Hash{{topic}},
+			FromBlock: new(big.Int).SetUint64(start),
+			ToBlock:   new(big.Int).SetUint64(end),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, log := range logs {
+			if log.Removed {
+				continue
+			}
+			if _, ok := addrs[log.Address]; !ok {
+				continue // other contract with the same event
+			}
+			var event implementation.ImplementationClosedEarly
+			err := UnpackLog(&event, "closedEarly", log, g.implABI)
+			if err != nil {
+				g.log.Warnf("can't unpack closedEarly event, tx %s: %s", log.TxHash.Hex(), err)
+				continue
+			}
+			events = append(events, ClosedEarlyEvent{
+				ContractID:  log.Address.Hex(),
+				Reason:      CloseReason(event.Reason),
+				BlockNumber: log.BlockNumber,
+				TxHash:      log.TxHash,
+			})
+		}
+	}
+	return events, nil
+}
+
+// GetLatestBlock returns the number of the latest block
+func (g *HashrateEthereum) GetLatestBlock(ctx context.Context) (uint64, error) {
+	head, err := g.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	return head.Number.Uint64(), nil
+}
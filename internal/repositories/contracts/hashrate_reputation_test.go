@@ -0,0 +1,59 @@
+package contracts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Lumerin-protocol/contracts-go/v2/implementation"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+type filterLogsClient struct {
+	EthereumClient
+	logs    []types.Log
+	queries []ethereum.FilterQuery
+}
+
+func (c *filterLogsClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	c.queries = append(c.queries, q)
+	var res []types.Log
+	for _, log := range c.logs {
+		if log.BlockNumber >= q.FromBlock.Uint64() && log.BlockNumber <= q.ToBlock.Uint64() {
+			res = append(res, log)
+		}
+	}
+	return res, nil
+}
+
+func TestGetClosedEarlyEventsMatchesContractsLocally(t *testing.T) {
+	implABI, err := implementation.ImplementationMetaData.GetAbi()
+	require.NoError(t, err)
+	closedEarly := implABI.Events["closedEarly"]
+	data, err := closedEarly.Inputs.NonIndexed().Pack(uint8(CloseReasonUnderdelivery))
+	require.NoError(t, err)
+
+	own := common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	other := common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	client := &filterLogsClient{
+		logs: []types.Log{
+			{Address: own, Topics: []common.Hash{closedEarly.ID}, Data: data, BlockNumber: 10, TxHash: common.HexToHash("0x01")},
+			{Address: other, Topics: []common.Hash{closedEarly.ID}, Data: data, BlockNumber: 11, TxHash: common.HexToHash("0x02")},
+		},
+	}
+	g := &HashrateEthereum{client: client, implABI: implABI, log: lib.NewTestLogger()}
+
+	events, err := g.GetClosedEarlyEvents(context.Background(), []string{own.Hex()}, 1, 20)
+	require.NoError(t, err)
+	require.Equal(t, []ClosedEarlyEvent{
+		{ContractID: own.Hex(), Reason: CloseReasonUnderdelivery, BlockNumber: 10, TxHash: common.HexToHash("0x01")},
+	}, events)
+
+	// the query is not limited by the contract addresses
+	require.Len(t, client.queries, 1)
+	require.Empty(t, client.queries[0].Addresses)
+	require.Equal(t, [][]common.Hash{{closedEarly.ID}}, client.queries[0].Topics)
+}
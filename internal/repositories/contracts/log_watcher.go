@@ -3,11 +3,212 @@ package contracts
 import (
 	"context"
 	"math/big"
+	"sort"
 
+	i "github.com/Lumerin-protocol/proxy-router/internal/interfaces"
 	"github.com/Lumerin-protocol/proxy-router/internal/lib"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	reorgHistorySize = 128   // number of processed blocks remembered to detect reorgs and duplicated logs
+	maxLogRange      = 10000 // maximum number of blocks requested in a single filter logs call
 )
 
 type LogWatcher interface {
 	Watch(ctx context.Context, contractAddr common.Address, mapper EventMapper, fromBlock *big.Int) (*lib.Subscription, error)
 }
+
+// RemovedEvent is emitted when the log of the already delivered event was removed from the canonical chain by a reorg.
+// The subscriber should revert the effect of the event or resync the state from the blockchain
+type RemovedEvent struct {
+	Event interface{}
+	Log   types.Log
+}
+
+type processedBlock struct {
+	number uint64
+	hash   common.Hash
+	logs   []types.Log // delivered logs of the block
+}
+
+// watchState tracks the recently delivered logs of a single watched contract,
+// so the logs can be compensated on reorg and the progress can be checkpointed
+type watchState struct {
+	key         string
+	mapper      EventMapper
+	history     []processedBlock // sorted by block number
+	checkpoints CheckpointStore
+	log         i.ILogger
+}
+
+func newWatchState(contractAddr common.Address, mapper EventMapper, checkpoints CheckpointStore, log i.ILogger) *watchState {
+	return &watchState{
+		key:         contractAddr.Hex(),
+		mapper:      mapper,
+		checkpoints: checkpoints,
+		log:         log,
+	}
+}
+
+// startBlock returns the block to start watching from. If fromBlock is not set the watching
+// is resumed after the checkpoint, or from the latest block if there is no checkpoint (nil is returned)
+func (s *watchState) startBlock(fromBlock *big.Int) *big.Int {
+	if fromBlock != nil {
+		return new(big.Int).Set(fromBlock)
+	}
+	checkpoint, ok := s.checkpoints.GetCheckpoint(s.key)
+	if !ok {
+		return nil
+	}
+	s.log.Infof("resuming logs of %s from checkpoint at block %d", s.key, checkpoint.Block)
+	s.record(checkpoint.Block, checkpoint.Hash, nil)
+	return new(big.Int).SetUint64(checkpoint.Block + 1)
+}
+
+// deliver maps the log and sends it to the sink, skipping the logs that were already delivered
+func (s *watchState) deliver(ctx context.Context, quit <-chan struct{}, sink chan interface{}, log types.Log) error {
+	if s.isDelivered(log) {
+		return nil
+	}
+
+	event, err := s.mapper(log)
+	if err != nil {
+		// mapper error, retry won't help, but we can continue
+		s.log.Debugf("error mapping event, skipping: %s", err)
+		return nil
+	}
+
+	err = send(ctx, quit, sink, event)
+	if err != nil {
+		return err
+	}
+
+	s.record(log.BlockNumber, log.BlockHash, &log)
+	return nil
+}
+
+// remove emits compensating event if the removed log was delivered before
+func (s *watchState) remove(ctx context.Context, quit <-chan struct{}, sink chan interface{}, log types.Log) error {
+	for i := range s.history {
+		block := &s.history[i]
+		if block.hash != log.BlockHash {
+			continue
+		}
+		for j, delivered := range block.logs {
+			if delivered.Index != log.Index {
+				continue
+			}
+			block.logs = append(block.logs[:j], block.logs[j+1:]...)
+			return s.compensate(ctx, quit, sink, delivered)
+		}
+	}
+	return nil
+}
+
+// markProcessed records that all logs up to the block were delivered and saves the checkpoint
+func (s *watchState) markProcessed(number uint64, hash common.Hash) {
+	s.record(number, hash, nil)
+	s.checkpoints.SetCheckpoint(s.key, Checkpoint{Block: number, Hash: hash})
+}
+
+// rollback compares recorded blocks with the canonical chain and emits compensating events for the logs of the
+// blocks that are not canonical anymore. Returns the block from which the logs should be requested again
+func (s *watchState) rollback(ctx context.Context, quit <-chan struct{}, sink chan interface{}, client i.EthClient) (resumeFrom *big.Int, err error) {
+	var lowestRemoved *processedBlock
+
+	for len(s.history) > 0 {
+		last := s.history[len(s.history)-1]
+		header, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(last.number))
+		if err != nil {
+			return nil, err
+		}
+		if header.Hash() == last.hash {
+			break
+		}
+
+		if lowestRemoved == nil {
+			s.log.Warnf("chain reorg detected for %s at block %d", s.key, last.number)
+		}
+		for j := len(last.logs) - 1; j >= 0; j-- {
+			err := s.compensate(ctx, quit, sink, last.logs[j])
+			if err != nil {
+				return nil, err
+			}
+		}
+		s.history = s.history[:len(s.history)-1]
+		lowestRemoved = &last
+	}
+
+	if lowestRemoved == nil {
+		return nil, nil
+	}
+
+	if len(s.history) == 0 {
+		s.log.Errorf("chain reorg for %s is deeper than the tracked history, some events may be missing", s.key)
+		return new(big.Int).SetUint64(lowestRemoved.number), nil
+	}
+
+	forkBlock := s.history[len(s.history)-1]
+	s.checkpoints.SetCheckpoint(s.key, Checkpoint{Block: forkBlock.number, Hash: forkBlock.hash})
+	return new(big.Int).SetUint64(forkBlock.number + 1), nil
+}
+
+func (s *watchState) compensate(ctx context.Context, quit <-chan struct{}, sink chan interface{}, log types.Log) error {
+	event, err := s.mapper(log)
+	if err != nil {
+		return nil
+	}
+	log.Removed = true
+	s.log.Warnf("event %T of %s removed by reorg, block %d, tx %s", event, s.key, log.BlockNumber, log.TxHash.Hex())
+	return send(ctx, quit, sink, &RemovedEvent{Event: event, Log: log})
+}
+
+func (s *watchState) isDelivered(log types.Log) bool {
+	for _, block := range s.history {
+		if block.hash != log.BlockHash {
+			continue
+		}
+		for _, delivered := range block.logs {
+			if delivered.Index == log.Index {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *watchState) record(number uint64, hash common.Hash, log *types.Log) {
+	idx := sort.Search(len(s.history), func(i int) bool {
+		return s.history[i].number >= number
+	})
+
+	if idx == len(s.history) || s.history[idx].number != number {
+		s.history = append(s.history, processedBlock{})
+		copy(s.history[idx+1:], s.history[idx:])
+		s.history[idx] = processedBlock{number: number, hash: hash}
+	} else if s.history[idx].hash != hash {
+		// the block was replaced without the reorg being noticed
+		s.history[idx] = processedBlock{number: number, hash: hash}
+	}
+
+	if log != nil {
+		s.history[idx].logs = append(s.history[idx].logs, *log)
+	}
+
+	if len(s.history) > reorgHistorySize {
+		s.history = s.history[len(s.history)-reorgHistorySize:]
+	}
+}
+
+func send(ctx context.Context, quit <-chan struct{}, sink chan interface{}, event interface{}) error {
+	select {
+	case <-quit:
+		return SubClosedError
+	case <-ctx.Done():
+		return ctx.Err()
+	case sink <- event:
+		return nil
+	}
+}
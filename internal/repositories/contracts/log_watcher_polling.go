@@ -21,34 +21,37 @@ type LogWatcherPolling struct {
 	// config
 	maxReconnects int
 	pollInterval  time.Duration
+	confirmations uint64
 
 	// deps
-	client i.EthClient
-	log    i.ILogger
+	client      i.EthClient
+	checkpoints CheckpointStore
+	log         i.ILogger
 }
 
-func NewLogWatcherPolling(client i.EthClient, pollInterval time.Duration, maxReconnects int, log i.ILogger) *LogWatcherPolling {
+// NewLogWatcherPolling creates a new log watcher that polls the logs. Logs are delivered only after the
+// specified number of confirmations, logs removed by reorg are compensated with RemovedEvent
+func NewLogWatcherPolling(client i.EthClient, pollInterval time.Duration, maxReconnects int, confirmations uint64, checkpoints CheckpointStore, log i.ILogger) *LogWatcherPolling {
 	return &LogWatcherPolling{
 		client:        client,
 		pollInterval:  pollInterval,
 		maxReconnects: maxReconnects,
+		confirmations: confirmations,
+		checkpoints:   checkpoints,
 		log:           log.Named("POL"),
 	}
 }
 
 func (w *LogWatcherPolling) Watch(ctx context.Context, contractAddr common.Address, mapper EventMapper, fromBlock *big.Int) (*lib.Subscription, error) {
-	var nextFromBlock *big.Int
-
-	if fromBlock != nil {
-		nextFromBlock = new(big.Int).Set(fromBlock)
-	}
+	state := newWatchState(contractAddr, mapper, w.checkpoints, w.log)
+	nextFromBlock := state.startBlock(fromBlock)
 
 	sink := make(chan interface{})
 	return lib.NewSubscription(func(quit <-chan struct{}) error {
 		defer close(sink)
 
 		for { // infinite polling loop
-			nextFrom, err := w.pollReconnect(ctx, quit, nextFromBlock, contractAddr, mapper, sink)
+			nextFrom, err := w.pollReconnect(ctx, quit, nextFromBlock, contractAddr, state, sink)
 			if err != nil {
 				return err
 			}
@@ -57,7 +60,7 @@ func (w *LogWatcherPolling) Watch(ctx context.Context, contractAddr common.Addre
 	}, sink), nil
 }
 
-func (w *LogWatcherPolling) pollReconnect(ctx context.Context, quit <-chan struct{}, nextFromBlock *big.Int, contractAddr common.Address, mapper EventMapper, sink chan interface{}) (*big.Int, error) {
+func (w *LogWatcherPolling) pollReconnect(ctx context.Context, quit <-chan struct{}, nextFromBlock *big.Int, contractAddr common.Address, state *watchState, sink chan interface{}) (*big.Int, error) {
 	var lastErr error
 	for i := 0; i < w.maxReconnects || w.maxReconnects == -1; i++ {
 		// for any of those cases, we should stop retrying
@@ -68,10 +71,21 @@ func (w *LogWatcherPolling) pollReconnect(ctx context.Context, quit <-chan struc
 			return nextFromBlock, ctx.Err()
 		default:
 		}
-		newNextFromBlock, err := w.pollChanges(ctx, nextFromBlock, quit, contractAddr, mapper, sink)
+		newNextFromBlock, caughtUp, err := w.pollChanges(ctx, nextFromBlock, quit, contractAddr, state, sink)
 		if err == nil {
-			return newNextFromBlock, nil
+			if !caughtUp {
+				// more blocks to read, no need to wait
+				return newNextFromBlock, nil
+			}
+			return newNextFromBlock, wait(ctx, quit, w.pollInterval)
+		}
+		if errors.Is(err, SubClosedError) || errors.Is(err, context.Canceled) {
+			return nextFromBlock, err
+		}
+		if newNextFromBlock != nil {
+			nextFromBlock = newNextFromBlock
 		}
+
 		maxReconnects := fmt.Sprintf("%d", w.maxReconnects)
 		if w.maxReconnects == -1 {
 			maxReconnects = "∞"
@@ -80,12 +94,9 @@ func (w *LogWatcherPolling) pollReconnect(ctx context.Context, quit <-chan struc
 		lastErr = err
 
 		// retry delay
-		select {
-		case <-quit:
-			return nextFromBlock, SubClosedError
-		case <-ctx.Done():
-			return nextFromBlock, ctx.Err()
-		case <-time.After(w.pollInterval):
+		err = wait(ctx, quit, w.pollInterval)
+		if err != nil {
+			return nextFromBlock, err
 		}
 	}
 
@@ -94,69 +105,80 @@ func (w *LogWatcherPolling) pollReconnect(ctx context.Context, quit <-chan struc
 	return nextFromBlock, err
 }
 
-func (w *LogWatcherPolling) pollChanges(ctx context.Context, nextFromBlock *big.Int, quit <-chan struct{}, contractAddr common.Address, mapper EventMapper, sink chan interface{}) (*big.Int, error) {
+// pollChanges delivers the confirmed logs since nextFromBlock, caughtUp is false if there are more blocks to read
+func (w *LogWatcherPolling) pollChanges(ctx context.Context, nextFromBlock *big.Int, quit <-chan struct{}, contractAddr common.Address, state *watchState, sink chan interface{}) (next *big.Int, caughtUp bool, err error) {
+	resumeFrom, err := state.rollback(ctx, quit, sink, w.client)
+	if err != nil {
+		return nil, false, err
+	}
+	if resumeFrom != nil {
+		nextFromBlock = resumeFrom
+	}
+
 	currentBlock, err := w.client.HeaderByNumber(ctx, nil)
 	if err != nil {
-		return nil, err
+		return nextFromBlock, false, err
 	}
 
+	safeBlock := new(big.Int).Sub(currentBlock.Number, new(big.Int).SetUint64(w.confirmations))
+
 	if nextFromBlock == nil {
-		nextFromBlock = new(big.Int).Set(currentBlock.Number)
+		nextFromBlock = new(big.Int).Set(safeBlock)
 	}
 
 	// if we poll too often, we might be behind the chain, so we wait for the next block
-	// mapper error, retry won't help, but we can continue
-	if currentBlock.Number.Cmp(nextFromBlock) < 0 {
-		select {
-		case <-quit:
-			return nextFromBlock, SubClosedError
-		case <-ctx.Done():
-			return nextFromBlock, ctx.Err()
-		case <-time.After(w.pollInterval):
+	if safeBlock.Cmp(nextFromBlock) < 0 {
+		return nextFromBlock, true, nil
+	}
+
+	toBlock := safeBlock
+	caughtUp = true
+	if new(big.Int).Sub(toBlock, nextFromBlock).Cmp(big.NewInt(maxLogRange)) >= 0 {
+		toBlock = new(big.Int).Add(nextFromBlock, big.NewInt(maxLogRange-1))
+		caughtUp = false
+	}
+
+	toHeader := currentBlock
+	if toBlock.Cmp(currentBlock.Number) != 0 {
+		toHeader, err = w.client.HeaderByNumber(ctx, toBlock)
+		if err != nil {
+			return nextFromBlock, false, err
 		}
-		return nextFromBlock, nil
 	}
 
 	query := ethereum.FilterQuery{
 		Addresses: []common.Address{contractAddr},
 		FromBlock: nextFromBlock,
-		ToBlock:   currentBlock.Number,
+		ToBlock:   toBlock,
 	}
 
 	w.log.Debugf("requesting changes from %s to %s block", query.FromBlock.String(), query.ToBlock.String())
-	sub, err := w.client.FilterLogs(ctx, query)
-	nextFromBlock.Add(currentBlock.Number, big.NewInt(1))
-
+	logs, err := w.client.FilterLogs(ctx, query)
 	if err != nil {
-		return nextFromBlock, err
+		return nextFromBlock, false, err
 	}
 
-	for _, log := range sub {
+	for _, log := range logs {
 		if log.Removed {
 			continue
 		}
-		event, err := mapper(log)
+		err := state.deliver(ctx, quit, sink, log)
 		if err != nil {
-			w.log.Debugf("error mapping event, skipping: %s", err)
-			continue
-		}
-
-		select {
-		case <-quit:
-			return nextFromBlock, SubClosedError
-		case <-ctx.Done():
-			return nextFromBlock, ctx.Err()
-		case sink <- event:
+			return nextFromBlock, false, err
 		}
 	}
 
+	state.markProcessed(toBlock.Uint64(), toHeader.Hash())
+	return new(big.Int).Add(toBlock, big.NewInt(1)), caughtUp, nil
+}
+
+func wait(ctx context.Context, quit <-chan struct{}, d time.Duration) error {
 	select {
 	case <-quit:
-		return nextFromBlock, SubClosedError
+		return SubClosedError
 	case <-ctx.Done():
-		return nextFromBlock, ctx.Err()
-	case <-time.After(w.pollInterval):
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
 	}
-
-	return nextFromBlock, nil
 }
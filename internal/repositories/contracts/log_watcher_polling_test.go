@@ -43,7 +43,7 @@ func TestWatchDoesntReturnEventsTwice(t *testing.T) {
 	_ = ethClientMock.EXPECT().FilterLogs(mock.Anything, matchBlockNumber(2)).Return([]types.Log{event2}, nil)
 	_ = ethClientMock.EXPECT().FilterLogs(mock.Anything, mock.Anything).Return([]types.Log{}, nil)
 
-	logWatcherPolling := NewLogWatcherPolling(ethClientMock, 0, 10, lib.NewTestLogger())
+	logWatcherPolling := NewLogWatcherPolling(ethClientMock, 0, 10, 0, NewMemoryCheckpointStore(), lib.NewTestLogger())
 	sub, err := logWatcherPolling.Watch(context.Background(), common.Address{}, eventMapper, big.NewInt(1))
 	require.NoError(t, err)
 	defer sub.Unsubscribe()
@@ -77,7 +77,7 @@ func TestWatchShouldErrorAfterMaxReconnects(t *testing.T) {
 
 	_ = ethClientMock.EXPECT().FilterLogs(mock.Anything, mock.Anything).Return([]types.Log{}, TEST_ERR)
 
-	logWatcherPolling := NewLogWatcherPolling(ethClientMock, 0, maxRetries, lib.NewTestLogger())
+	logWatcherPolling := NewLogWatcherPolling(ethClientMock, 0, maxRetries, 0, NewMemoryCheckpointStore(), lib.NewTestLogger())
 	sub, err := logWatcherPolling.Watch(context.Background(), common.Address{}, eventMapper, big.NewInt(1))
 	require.NoError(t, err)
 	defer sub.Unsubscribe()
@@ -104,7 +104,7 @@ func TestShouldHandleContextCancellation(t *testing.T) {
 
 	_ = ethClientMock.EXPECT().FilterLogs(mock.Anything, mock.Anything).Return([]types.Log{}, nil)
 
-	logWatcherPolling := NewLogWatcherPolling(ethClientMock, 0, 10, lib.NewTestLogger())
+	logWatcherPolling := NewLogWatcherPolling(ethClientMock, 0, 10, 0, NewMemoryCheckpointStore(), lib.NewTestLogger())
 	sub, err := logWatcherPolling.Watch(ctx, common.Address{}, eventMapper, big.NewInt(1))
 	require.NoError(t, err)
 	defer sub.Unsubscribe()
@@ -130,7 +130,7 @@ func TestShouldUnsubscribe(t *testing.T) {
 	ethClientMock := mocks.NewEthClientMock(t)
 	_ = ethClientMock.EXPECT().FilterLogs(mock.Anything, mock.Anything).Return([]types.Log{}, nil)
 
-	logWatcherPolling := NewLogWatcherPolling(ethClientMock, 0, 10, lib.NewTestLogger())
+	logWatcherPolling := NewLogWatcherPolling(ethClientMock, 0, 10, 0, NewMemoryCheckpointStore(), lib.NewTestLogger())
 	sub, err := logWatcherPolling.Watch(context.Background(), common.Address{}, eventMapper, big.NewInt(1))
 	require.NoError(t, err)
 
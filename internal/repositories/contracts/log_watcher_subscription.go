@@ -2,8 +2,10 @@ package contracts
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
+	"time"
 
 	i "github.com/Lumerin-protocol/proxy-router/internal/interfaces"
 	"github.com/Lumerin-protocol/proxy-router/internal/lib"
@@ -12,26 +14,38 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
+const subscriptionHeadInterval = 5 * time.Second // how often the head is checked to release confirmed logs
+
 type LogWatcherSubscription struct {
 	// config
 	maxReconnects int
+	confirmations uint64
+	headInterval  time.Duration
 
 	// deps
-	client i.EthClient
-	log    i.ILogger
+	client      i.EthClient
+	checkpoints CheckpointStore
+	log         i.ILogger
 }
 
-// NewLogWatcherSubscription creates a new log subscription using websocket
-// TODO: if it is going to be primary implementation we should rewrite it so it doesn't skip events in case of temporary downtime
-func NewLogWatcherSubscription(client i.EthClient, maxReconnects int, log i.ILogger) *LogWatcherSubscription {
+// NewLogWatcherSubscription creates a new log subscription using websocket. Logs missed during the reconnect
+// are read starting from the last processed block. Logs are delivered only after the specified number of
+// confirmations, logs removed by reorg are compensated with RemovedEvent
+func NewLogWatcherSubscription(client i.EthClient, maxReconnects int, confirmations uint64, checkpoints CheckpointStore, log i.ILogger) *LogWatcherSubscription {
 	return &LogWatcherSubscription{
 		maxReconnects: maxReconnects,
+		confirmations: confirmations,
+		headInterval:  subscriptionHeadInterval,
 		client:        client,
+		checkpoints:   checkpoints,
 		log:           log.Named("SUB"),
 	}
 }
 
 func (w *LogWatcherSubscription) Watch(ctx context.Context, contractAddr common.Address, mapper EventMapper, fromBlock *big.Int) (*lib.Subscription, error) {
+	state := newWatchState(contractAddr, mapper, w.checkpoints, w.log)
+	nextFromBlock := state.startBlock(fromBlock)
+
 	sink := make(chan interface{})
 
 	return lib.NewSubscription(func(quit <-chan struct{}) error {
@@ -41,52 +55,185 @@ func (w *LogWatcherSubscription) Watch(ctx context.Context, contractAddr common.
 			Addresses: []common.Address{contractAddr},
 		}
 		in := make(chan types.Log)
-		defer close(in)
 
 		for {
-
 			sub, err := w.subscribeFilterLogsRetry(ctx, query, in)
 			if err != nil {
 				w.log.Errorf("failed to subscribe to logs: %s", err)
 				return err
 			}
 
-			defer sub.Unsubscribe()
-
-		EVENTS_LOOP:
-			for {
-				select {
-				case log := <-in:
-					event, err := mapper(log)
-					if err != nil {
-						w.log.Debugf("failed to map event: %s", err)
-						// mapper error, retry won't help, continue to next event
-						continue
-					}
-
-					select {
-					case sink <- event:
-					case err := <-sub.Err():
-						w.log.Debugf("subscription error: %s", err)
-						break EVENTS_LOOP
-					case <-quit:
-						return nil
-					case <-ctx.Done():
-						return ctx.Err()
-					}
-				case err := <-sub.Err():
-					w.log.Debugf("subscription error: %s", err)
-					break EVENTS_LOOP
-				case <-quit:
-					return nil
-				case <-ctx.Done():
-					return ctx.Err()
-				}
+			// subscription is created before reading the missed logs, so nothing is lost in between,
+			// logs received twice are skipped by the watch state
+			nextFromBlock, err = w.backfill(ctx, quit, nextFromBlock, contractAddr, state, sink)
+			if err == nil {
+				nextFromBlock, err = w.watchEvents(ctx, quit, nextFromBlock, contractAddr, sub, in, state, sink)
+			}
+			sub.Unsubscribe()
+
+			if errors.Is(err, SubClosedError) {
+				return nil
+			}
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return err
+			}
+			w.log.Warnf("subscription error, reconnecting: %s", err)
+
+			err = wait(ctx, quit, w.headInterval)
+			if errors.Is(err, SubClosedError) {
+				return nil
+			}
+			if err != nil {
+				return err
 			}
 		}
 	}, sink), nil
 }
 
+// backfill delivers confirmed logs from nextFromBlock up to the safe head
+func (w *LogWatcherSubscription) backfill(ctx context.Context, quit <-chan struct{}, nextFromBlock *big.Int, contractAddr common.Address, state *watchState, sink chan interface{}) (*big.Int, error) {
+	resumeFrom, err := state.rollback(ctx, quit, sink, w.client)
+	if err != nil {
+		return nextFromBlock, err
+	}
+	if resumeFrom != nil {
+		nextFromBlock = resumeFrom
+	}
+
+	safeHeader, err := w.safeHeader(ctx)
+	if err != nil {
+		return nextFromBlock, err
+	}
+	if nextFromBlock == nil {
+		state.markProcessed(safeHeader.Number.Uint64(), safeHeader.Hash())
+		return new(big.Int).Add(safeHeader.Number, big.NewInt(1)), nil
+	}
+
+	return w.deliverRange(ctx, quit, nextFromBlock, safeHeader, contractAddr, state, sink)
+}
+
+// deliverRange reads the logs from nextFromBlock up to the safe header, delivers them and checkpoints
+// the blocks, so a block is marked processed only after its logs were actually received
+func (w *LogWatcherSubscription) deliverRange(ctx context.Context, quit <-chan struct{}, nextFromBlock *big.Int, safeHeader *types.Header, contractAddr common.Address, state *watchState, sink chan interface{}) (*big.Int, error) {
+	for nextFromBlock.Cmp(safeHeader.Number) <= 0 {
+		toBlock := new(big.Int).Add(nextFromBlock, big.NewInt(maxLogRange-1))
+		if toBlock.Cmp(safeHeader.Number) > 0 {
+			toBlock = safeHeader.Number
+		}
+
+		w.log.Debugf("requesting logs from %s to %s block", nextFromBlock.String(), toBlock.String())
+		logs, err := w.client.FilterLogs(ctx, ethereum.FilterQuery{
+			Addresses: []common.Address{contractAddr},
+			FromBlock: nextFromBlock,
+			ToBlock:   toBlock,
+		})
+		if err != nil {
+			return nextFromBlock, err
+		}
+		for _, log := range logs {
+			err := state.deliver(ctx, quit, sink, log)
+			if err != nil {
+				return nextFromBlock, err
+			}
+		}
+
+		toHeader := safeHeader
+		if toBlock.Cmp(safeHeader.Number) != 0 {
+			toHeader, err = w.client.HeaderByNumber(ctx, toBlock)
+			if err != nil {
+				return nextFromBlock, err
+			}
+		}
+		state.markProcessed(toBlock.Uint64(), toHeader.Hash())
+		nextFromBlock = new(big.Int).Add(toBlock, big.NewInt(1))
+	}
+
+	return nextFromBlock, nil
+}
+
+// watchEvents delivers subscription logs once they are confirmed, returns on subscription error
+func (w *LogWatcherSubscription) watchEvents(ctx context.Context, quit <-chan struct{}, nextFromBlock *big.Int, contractAddr common.Address, sub ethereum.Subscription, in <-chan types.Log, state *watchState, sink chan interface{}) (*big.Int, error) {
+	var pending []types.Log // logs waiting for confirmations
+
+	ticker := time.NewTicker(w.headInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case log := <-in:
+			if log.Removed {
+				pending = removePending(pending, log)
+				err := state.remove(ctx, quit, sink, log)
+				if err != nil {
+					return nextFromBlock, err
+				}
+				continue
+			}
+			if w.confirmations > 0 {
+				pending = append(pending, log)
+				continue
+			}
+			err := state.deliver(ctx, quit, sink, log)
+			if err != nil {
+				return nextFromBlock, err
+			}
+		case <-ticker.C:
+			safeHeader, err := w.safeHeader(ctx)
+			if err != nil {
+				return nextFromBlock, err
+			}
+			safeBlock := safeHeader.Number.Uint64()
+
+			var stillPending []types.Log
+			for _, log := range pending {
+				if log.BlockNumber > safeBlock {
+					stillPending = append(stillPending, log)
+					continue
+				}
+				err := state.deliver(ctx, quit, sink, log)
+				if err != nil {
+					return nextFromBlock, err
+				}
+			}
+			pending = stillPending
+
+			// the subscription may silently drop logs, so the confirmed range is read again
+			// before it is checkpointed, the logs that were already delivered are skipped
+			nextFromBlock, err = w.deliverRange(ctx, quit, nextFromBlock, safeHeader, contractAddr, state, sink)
+			if err != nil {
+				return nextFromBlock, err
+			}
+		case err := <-sub.Err():
+			return nextFromBlock, err
+		case <-quit:
+			return nextFromBlock, SubClosedError
+		case <-ctx.Done():
+			return nextFromBlock, ctx.Err()
+		}
+	}
+}
+
+// safeHeader returns the header of the latest block with enough confirmations
+func (w *LogWatcherSubscription) safeHeader(ctx context.Context) (*types.Header, error) {
+	head, err := w.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if w.confirmations == 0 || head.Number.Uint64() < w.confirmations {
+		return head, nil
+	}
+	return w.client.HeaderByNumber(ctx, new(big.Int).SetUint64(head.Number.Uint64()-w.confirmations))
+}
+
+func removePending(pending []types.Log, removed types.Log) []types.Log {
+	for i, log := range pending {
+		if log.BlockHash == removed.BlockHash && log.Index == removed.Index {
+			return append(pending[:i], pending[i+1:]...)
+		}
+	}
+	return pending
+}
+
 func (w *LogWatcherSubscription) subscribeFilterLogsRetry(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
 	var lastErr error
 
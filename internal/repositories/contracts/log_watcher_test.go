@@ -0,0 +1,239 @@
+package contracts
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	i "github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChain implements only the methods used by the polling log watcher
+type fakeChain struct {
+	i.EthClient
+	headers map[uint64]*types.Header
+	logs    []types.Log
+	head    uint64
+	mutex   sync.Mutex
+}
+
+func newFakeChain(head uint64) *fakeChain {
+	c := &fakeChain{headers: make(map[uint64]*types.Header)}
+	c.mine(head)
+	return c
+}
+
+// mine appends the blocks up to the new head, logs are added to the new blocks at once
+func (c *fakeChain) mine(head uint64, logs ...types.Log) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	defer c.addLogsLocked(logs)
+	for n := c.head; n <= head; n++ {
+		if _, ok := c.headers[n]; !ok {
+			c.headers[n] = &types.Header{Number: new(big.Int).SetUint64(n)}
+		}
+	}
+	c.head = head
+}
+
+// reorg replaces the blocks starting from the given one, fork distinguishes the new block hashes
+func (c *fakeChain) reorg(fromBlock uint64, fork byte, logs []types.Log) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for n := fromBlock; n <= c.head; n++ {
+		c.headers[n] = &types.Header{Number: new(big.Int).SetUint64(n), Extra: []byte{fork}}
+	}
+	var kept []types.Log
+	for _, log := range c.logs {
+		if log.BlockNumber < fromBlock {
+			kept = append(kept, log)
+		}
+	}
+	c.logs = kept
+	c.addLogsLocked(logs)
+}
+
+func (c *fakeChain) addLogs(logs ...types.Log) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.addLogsLocked(logs)
+}
+
+func (c *fakeChain) addLogsLocked(logs []types.Log) {
+	for _, log := range logs {
+		log.BlockHash = c.headers[log.BlockNumber].Hash()
+		c.logs = append(c.logs, log)
+	}
+}
+
+func (c *fakeChain) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if number == nil {
+		return c.headers[c.head], nil
+	}
+	return c.headers[number.Uint64()], nil
+}
+
+func (c *fakeChain) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var res []types.Log
+	for _, log := range c.logs {
+		if log.BlockNumber >= q.FromBlock.Uint64() && log.BlockNumber <= q.ToBlock.Uint64() {
+			res = append(res, log)
+		}
+	}
+	return res, nil
+}
+
+func receiveEvent(t *testing.T, sub *lib.Subscription) interface{} {
+	select {
+	case e := <-sub.Events():
+		return e
+	case err := <-sub.Err():
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		require.FailNow(t, "no event received")
+	}
+	return nil
+}
+
+func requireNoEvent(t *testing.T, sub *lib.Subscription) {
+	select {
+	case e := <-sub.Events():
+		require.FailNow(t, "unexpected event", "%v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestPollingWaitsForConfirmations(t *testing.T) {
+	chain := newFakeChain(10)
+	chain.addLogs(types.Log{BlockNumber: 9, Index: 1})
+
+	watcher := NewLogWatcherPolling(chain, 5*time.Millisecond, 10, 3, NewMemoryCheckpointStore(), lib.NewTestLogger())
+	sub, err := watcher.Watch(context.Background(), common.Address{}, eventMapper, big.NewInt(5))
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	requireNoEvent(t, sub)
+
+	chain.mine(12)
+	event := receiveEvent(t, sub).(types.Log)
+	require.Equal(t, uint64(9), event.BlockNumber)
+}
+
+func TestPollingCompensatesReorgedLogs(t *testing.T) {
+	chain := newFakeChain(10)
+	chain.addLogs(types.Log{BlockNumber: 8, Index: 1, Data: []byte{1}})
+
+	checkpoints := NewMemoryCheckpointStore()
+	watcher := NewLogWatcherPolling(chain, 5*time.Millisecond, 10, 0, checkpoints, lib.NewTestLogger())
+	sub, err := watcher.Watch(context.Background(), common.Address{}, eventMapper, big.NewInt(5))
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	original := receiveEvent(t, sub).(types.Log)
+	require.Equal(t, uint64(8), original.BlockNumber)
+
+	// the log is moved to another block by reorg
+	chain.reorg(7, 1, []types.Log{{BlockNumber: 9, Index: 1, Data: []byte{1}}})
+
+	removed := receiveEvent(t, sub).(*RemovedEvent)
+	require.Equal(t, original.BlockHash, removed.Log.BlockHash)
+	require.True(t, removed.Log.Removed)
+	require.Equal(t, original, removed.Event)
+
+	replayed := receiveEvent(t, sub).(types.Log)
+	require.Equal(t, uint64(9), replayed.BlockNumber)
+
+	checkpoint, ok := checkpoints.GetCheckpoint(common.Address{}.Hex())
+	require.True(t, ok)
+	require.Equal(t, uint64(10), checkpoint.Block)
+}
+
+func TestPollingResumesFromCheckpoint(t *testing.T) {
+	chain := newFakeChain(10)
+	chain.addLogs(types.Log{BlockNumber: 3, Index: 1}, types.Log{BlockNumber: 6, Index: 1})
+
+	header, _ := chain.HeaderByNumber(context.Background(), big.NewInt(4))
+	checkpoints := NewMemoryCheckpointStore()
+	checkpoints.SetCheckpoint(common.Address{}.Hex(), Checkpoint{Block: 4, Hash: header.Hash()})
+
+	watcher := NewLogWatcherPolling(chain, 5*time.Millisecond, 10, 0, checkpoints, lib.NewTestLogger())
+	sub, err := watcher.Watch(context.Background(), common.Address{}, eventMapper, nil)
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	event := receiveEvent(t, sub).(types.Log)
+	require.Equal(t, uint64(6), event.BlockNumber)
+	requireNoEvent(t, sub)
+}
+
+func TestFileCheckpointStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "checkpoints.json")
+	checkpoint := Checkpoint{Block: 42, Hash: common.HexToHash("0x01")}
+
+	store := NewFileCheckpointStore(path, time.Minute, lib.NewTestLogger())
+	require.NoError(t, store.Load())
+	store.SetCheckpoint("key", checkpoint)
+	require.NoError(t, store.Flush())
+
+	store = NewFileCheckpointStore(path, time.Minute, lib.NewTestLogger())
+	require.NoError(t, store.Load())
+	loaded, ok := store.GetCheckpoint("key")
+	require.True(t, ok)
+	require.Equal(t, checkpoint, loaded)
+}
+
+// silentSubscription never delivers logs, like a websocket subscription that dropped them
+type silentSubscription struct {
+	err chan error
+}
+
+func (s *silentSubscription) Unsubscribe()      {}
+func (s *silentSubscription) Err() <-chan error { return s.err }
+
+type silentSubChain struct {
+	*fakeChain
+}
+
+func (c *silentSubChain) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return &silentSubscription{err: make(chan error)}, nil
+}
+
+func TestSubscriptionReadsLogsMissedBySubscription(t *testing.T) {
+	chain := &silentSubChain{newFakeChain(10)}
+
+	checkpoints := NewMemoryCheckpointStore()
+	watcher := NewLogWatcherSubscription(chain, 1, 0, checkpoints, lib.NewTestLogger())
+	watcher.headInterval = 5 * time.Millisecond
+	sub, err := watcher.Watch(context.Background(), common.Address{}, eventMapper, big.NewInt(5))
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	requireNoEvent(t, sub)
+
+	// the log is never received from the subscription
+	chain.mine(15, types.Log{BlockNumber: 13, Index: 1})
+
+	event := receiveEvent(t, sub).(types.Log)
+	require.Equal(t, uint64(13), event.BlockNumber)
+	requireNoEvent(t, sub)
+
+	checkpoint, ok := checkpoints.GetCheckpoint(common.Address{}.Hex())
+	require.True(t, ok)
+	require.Equal(t, uint64(15), checkpoint.Block)
+}
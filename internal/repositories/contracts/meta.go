@@ -1,6 +1,7 @@
 package contracts
 
 import (
+	"github.com/Lumerin-protocol/contracts-go/v2/validatorregistry"
 	"github.com/Lumerin-protocol/contracts-go/v3/aggregatorv3interface"
 	"github.com/Lumerin-protocol/contracts-go/v3/futures"
 	"github.com/Lumerin-protocol/contracts-go/v3/hashrateoracle"
@@ -16,4 +17,5 @@ var AllContractsMeta = []lib.Meta{
 	hashrateoracle.HashrateoracleMetaData,
 	ierc20.Ierc20MetaData,
 	aggregatorv3interface.Aggregatorv3interfaceMetaData,
+	validatorregistry.ValidatorregistryMetaData,
 }
@@ -0,0 +1,42 @@
+package contracts
+
+import (
+	"context"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/txmanager"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TxResult is the result of the contract operation step, with dry run only the estimation is set
+type TxResult struct {
+	Estimate *txmanager.Estimate
+	TxHash   *common.Hash `json:",omitempty"`
+	GasUsed  uint64       `json:",omitempty"`
+	Error    string       `json:",omitempty"` // estimation error of the dry run, expected if the previous step is not executed yet
+}
+
+// transact estimates the transaction and sends it unless it is a dry run
+func transact(ctx context.Context, txManager *txmanager.TxManager, signer interfaces.Signer, label string, dryRun bool, fn txmanager.TxFunc, log interfaces.ILogger) (*TxResult, error) {
+	estimate, err := txManager.Estimate(ctx, signer.Address(), label, fn)
+	if err != nil {
+		err = lib.TryConvertGethError(err, AllContractsMeta)
+		// the transaction depending on the not yet sent approval can't be estimated, so the dry run continues
+		if dryRun {
+			return &TxResult{Estimate: &txmanager.Estimate{Label: label}, Error: err.Error()}, nil
+		}
+		return nil, err
+	}
+	if dryRun {
+		return &TxResult{Estimate: estimate}, nil
+	}
+
+	receipt, err := txManager.Transact(ctx, signer, label, fn)
+	if err != nil {
+		return nil, lib.TryConvertGethError(err, AllContractsMeta)
+	}
+	log.Infof("%s, tx %s", label, receipt.TxHash.Hex())
+
+	return &TxResult{Estimate: estimate, TxHash: &receipt.TxHash, GasUsed: receipt.GasUsed}, nil
+}
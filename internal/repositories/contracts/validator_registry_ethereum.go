@@ -0,0 +1,311 @@
+package contracts
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	vr "github.com/Lumerin-protocol/contracts-go/v2/validatorregistry"
+	"github.com/Lumerin-protocol/contracts-go/v3/ierc20"
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/txmanager"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	DefaultComplaintsBlockRange = 100_000 // number of recent blocks searched for complaints if the start block is not set
+)
+
+var (
+	ErrNotRegistered    = errors.New("validator is not registered")
+	ErrNoPublicKey      = errors.New("signer doesn't expose the public key required for registration")
+	ErrInsufficientLMR  = errors.New("insufficient LMR balance for the stake")
+	ErrRegistryDisabled = errors.New("validator registry address is not configured")
+)
+
+// publicKeySigner is implemented by the signers holding the key locally
+type publicKeySigner interface {
+	PublicKey() *ecdsa.PublicKey
+}
+
+// Validator is the registration record of the validator
+type Validator struct {
+	Address        common.Address
+	Host           string
+	Stake          *big.Int
+	PubKeyYparity  bool
+	PubKeyX        common.Hash
+	Complains      uint8
+	LastComplainer common.Address
+	IsActive       bool
+	IsRegistered   bool
+}
+
+// RegistryParams are the staking parameters of the validator registry
+type RegistryParams struct {
+	Token           common.Address
+	StakeMinimum    *big.Int // stake required to stay active
+	StakeRegister   *big.Int // stake required to register
+	PunishAmount    *big.Int
+	PunishThreshold uint8 // number of complaints after which the validator is punished
+}
+
+// Complaint is the complaint event on the validator
+type Complaint struct {
+	Complainer  common.Address
+	BlockNumber uint64
+	TxHash      common.Hash
+}
+
+type ValidatorRegistryEthereum struct {
+	// config
+	registryAddr common.Address
+
+	// deps
+	registry  *vr.Validatorregistry
+	client    EthereumClient
+	txManager *txmanager.TxManager
+	log       interfaces.ILogger
+}
+
+func NewValidatorRegistryEthereum(registryAddr common.Address, client EthereumClient, txManager *txmanager.TxManager, log interfaces.ILogger) *ValidatorRegistryEthereum {
+	registry, err := vr.NewValidatorregistry(registryAddr, client)
+	if err != nil {
+		panic("invalid validator registry ABI")
+	}
+	return &ValidatorRegistryEthereum{
+		registryAddr: registryAddr,
+		registry:     registry,
+		client:       client,
+		txManager:    txManager,
+		log:          log,
+	}
+}
+
+func (g *ValidatorRegistryEthereum) GetValidator(ctx context.Context, addr common.Address) (*Validator, error) {
+	res, err := g.registry.GetValidatorV2(&bind.CallOpts{Context: ctx}, addr)
+	if err != nil {
+		return nil, lib.TryConvertGethError(err, AllContractsMeta)
+	}
+	return &Validator{
+		Address:        addr,
+		Host:           res.Validator.Host,
+		Stake:          res.Validator.Stake,
+		PubKeyYparity:  res.Validator.PubKeyYparity,
+		PubKeyX:        res.Validator.PubKeyX,
+		Complains:      res.Validator.Complains,
+		LastComplainer: res.Validator.LastComplainer,
+		IsActive:       res.IsActive,
+		IsRegistered:   res.IsRegistered,
+	}, nil
+}
+
+func (g *ValidatorRegistryEthereum) GetParams(ctx context.Context) (*RegistryParams, error) {
+	opts := &bind.CallOpts{Context: ctx}
+	token, err := g.registry.Token(opts)
+	if err != nil {
+		return nil, err
+	}
+	stakeMinimum, err := g.registry.StakeMinimum(opts)
+	if err != nil {
+		return nil, err
+	}
+	stakeRegister, err := g.registry.StakeRegister(opts)
+	if err != nil {
+		return nil, err
+	}
+	punishAmount, err := g.registry.PunishAmount(opts)
+	if err != nil {
+		return nil, err
+	}
+	punishThreshold, err := g.registry.PunishThreshold(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &RegistryParams{
+		Token:           token,
+		StakeMinimum:    stakeMinimum,
+		StakeRegister:   stakeRegister,
+		PunishAmount:    punishAmount,
+		PunishThreshold: punishThreshold,
+	}, nil
+}
+
+// GetComplaints returns the complaints on the validator starting from the block, if fromBlock is nil
+// the last DefaultComplaintsBlockRange blocks are searched
+func (g *ValidatorRegistryEthereum) GetComplaints(ctx context.Context, addr common.Address, fromBlock *big.Int) ([]Complaint, error) {
+	head, err := g.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	to := head.Number.Uint64()
+
+	var from uint64
+	if fromBlock != nil {
+		from = fromBlock.Uint64()
+	} else if to > DefaultComplaintsBlockRange {
+		from = to - DefaultComplaintsBlockRange
+	}
+
+	var complaints []Complaint
+	for start := from; start <= to; start += maxLogRange {
+		end := min(start+maxLogRange-1, to)
+		it, err := g.registry.FilterValidatorComplain(&bind.FilterOpts{Start: start, End: &end, Context: ctx}, []common.Address{addr}, nil)
+		if err != nil {
+			return nil, err
+		}
+		for it.Next() {
+			complaints = append(complaints, Complaint{
+				Complainer:  it.Event.Complainer,
+				BlockNumber: it.Event.Raw.BlockNumber,
+				TxHash:      it.Event.Raw.TxHash,
+			})
+		}
+		err = it.Error()
+		_ = it.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return complaints, nil
+}
+
+// Register registers the validator or adds the stake and updates the host of the registered one.
+// The stake token allowance is approved first if needed. With dryRun the transactions are estimated only
+func (g *ValidatorRegistryEthereum) Register(ctx context.Context, signer interfaces.Signer, stake *big.Int, host string, dryRun bool) ([]TxResult, error) {
+	validator, err := g.GetValidator(ctx, signer.Address())
+	if err != nil {
+		return nil, err
+	}
+
+	yParity, pubKeyX := validator.PubKeyYparity, validator.PubKeyX
+	if !validator.IsRegistered {
+		params, err := g.GetParams(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if host == "" {
+			return nil, fmt.Errorf("host is required for registration")
+		}
+		if stake.Cmp(params.StakeRegister) < 0 {
+			return nil, fmt.Errorf("stake %s is less than required for registration %s", stake, params.StakeRegister)
+		}
+		pkSigner, ok := signer.(publicKeySigner)
+		if !ok {
+			return nil, ErrNoPublicKey
+		}
+		pubKey := pkSigner.PublicKey()
+		yParity = pubKey.Y.Bit(0) == 1
+		pubKeyX = common.BigToHash(pubKey.X)
+	}
+	if host == "" {
+		host = validator.Host
+	}
+
+	var results []TxResult
+	if stake.Sign() > 0 {
+		res, err := g.approveStake(ctx, signer, stake, dryRun)
+		if err != nil {
+			return nil, err
+		}
+		if res != nil {
+			results = append(results, *res)
+		}
+	}
+
+	label := fmt.Sprintf("register validator %s, host %s, stake %s", signer.Address().Hex(), host, stake.String())
+	res, err := g.transact(ctx, signer, label, dryRun, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return g.registry.ValidatorRegister(opts, stake, yParity, pubKeyX, host)
+	})
+	if err != nil {
+		return results, err
+	}
+	return append(results, *res), nil
+}
+
+// UpdateHost changes the host of the registered validator keeping the stake
+func (g *ValidatorRegistryEthereum) UpdateHost(ctx context.Context, signer interfaces.Signer, host string, dryRun bool) ([]TxResult, error) {
+	validator, err := g.GetValidator(ctx, signer.Address())
+	if err != nil {
+		return nil, err
+	}
+	if !validator.IsRegistered {
+		return nil, ErrNotRegistered
+	}
+	return g.Register(ctx, signer, big.NewInt(0), host, dryRun)
+}
+
+// AddStake increases the stake of the registered validator
+func (g *ValidatorRegistryEthereum) AddStake(ctx context.Context, signer interfaces.Signer, amount *big.Int, dryRun bool) ([]TxResult, error) {
+	validator, err := g.GetValidator(ctx, signer.Address())
+	if err != nil {
+		return nil, err
+	}
+	if !validator.IsRegistered {
+		return nil, ErrNotRegistered
+	}
+	return g.Register(ctx, signer, amount, "", dryRun)
+}
+
+// Deregister removes the validator from the registry returning the whole stake, the registry doesn't support partial unstaking
+func (g *ValidatorRegistryEthereum) Deregister(ctx context.Context, signer interfaces.Signer, dryRun bool) ([]TxResult, error) {
+	validator, err := g.GetValidator(ctx, signer.Address())
+	if err != nil {
+		return nil, err
+	}
+	if !validator.IsRegistered {
+		return nil, ErrNotRegistered
+	}
+
+	label := fmt.Sprintf("deregister validator %s", signer.Address().Hex())
+	res, err := g.transact(ctx, signer, label, dryRun, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return g.registry.ValidatorDeregister(opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []TxResult{*res}, nil
+}
+
+// approveStake approves the registry to transfer the stake if the current allowance is not enough
+func (g *ValidatorRegistryEthereum) approveStake(ctx context.Context, signer interfaces.Signer, stake *big.Int, dryRun bool) (*TxResult, error) {
+	tokenAddr, err := g.registry.Token(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, err
+	}
+	token, err := ierc20.NewIerc20(tokenAddr, g.client)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := token.BalanceOf(&bind.CallOpts{Context: ctx}, signer.Address())
+	if err != nil {
+		return nil, err
+	}
+	if balance.Cmp(stake) < 0 {
+		return nil, lib.WrapError(ErrInsufficientLMR, fmt.Errorf("balance %s, stake %s", balance, stake))
+	}
+
+	allowance, err := token.Allowance(&bind.CallOpts{Context: ctx}, signer.Address(), g.registryAddr)
+	if err != nil {
+		return nil, err
+	}
+	if allowance.Cmp(stake) >= 0 {
+		return nil, nil
+	}
+
+	label := fmt.Sprintf("approve validator stake %s", stake.String())
+	return g.transact(ctx, signer, label, dryRun, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return token.Approve(opts, g.registryAddr, stake)
+	})
+}
+
+func (g *ValidatorRegistryEthereum) transact(ctx context.Context, signer interfaces.Signer, label string, dryRun bool, fn txmanager.TxFunc) (*TxResult, error) {
+	return transact(ctx, g.txManager, signer, label, dryRun, fn, g.log)
+}
@@ -0,0 +1,191 @@
+package ethpool
+
+import (
+	"context"
+	"math/big"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	ewmaAlpha        = 0.2 // weight of the latest sample in latency and error rate averages
+	errorRatePenalty = 10  // how much the error rate degrades the endpoint score
+)
+
+// RPCClient is the subset of ethclient.Client methods used by the pool
+type RPCClient interface {
+	interfaces.EthClient
+	StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	Close()
+}
+
+type DialFunc func(ctx context.Context, rawURL string) (RPCClient, error)
+
+func DialEthClient(ctx context.Context, rawURL string) (RPCClient, error) {
+	return ethclient.DialContext(ctx, rawURL)
+}
+
+// EndpointStatus is the health of the endpoint exposed over the api
+type EndpointStatus struct {
+	URL       string
+	Healthy   bool
+	Head      uint64
+	LatencyMs float64
+	ErrorRate float64
+	Score     float64
+	LastError string
+	LastCheck time.Time
+}
+
+// Endpoint is a single ethereum node with its health statistics
+type Endpoint struct {
+	// config
+	rawURL string
+	url    string // redacted url for logs and api
+
+	// state
+	client    RPCClient
+	healthy   bool
+	head      uint64
+	latencyMs float64
+	errorRate float64
+	lastError string
+	lastCheck time.Time
+	mutex     sync.RWMutex
+
+	// deps
+	dial DialFunc
+}
+
+func NewEndpoint(rawURL string, dial DialFunc) (*Endpoint, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &Endpoint{
+		rawURL:  rawURL,
+		url:     redactURL(u),
+		healthy: true, // optimistic until the first check
+		dial:    dial,
+	}, nil
+}
+
+func (e *Endpoint) URL() string {
+	return e.url
+}
+
+// getClient returns the connected client, dialing it if the previous attempts failed
+func (e *Endpoint) getClient(ctx context.Context) (RPCClient, error) {
+	e.mutex.RLock()
+	client := e.client
+	e.mutex.RUnlock()
+	if client != nil {
+		return client, nil
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.client != nil {
+		return e.client, nil
+	}
+
+	client, err := e.dial(ctx, e.rawURL)
+	if err != nil {
+		return nil, err
+	}
+	e.client = client
+	return client, nil
+}
+
+func (e *Endpoint) recordSuccess(latency time.Duration) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	ms := float64(latency.Microseconds()) / 1000
+	if e.latencyMs == 0 {
+		e.latencyMs = ms
+	} else {
+		e.latencyMs = ewmaAlpha*ms + (1-ewmaAlpha)*e.latencyMs
+	}
+	e.errorRate = (1 - ewmaAlpha) * e.errorRate
+}
+
+func (e *Endpoint) recordFailure(err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.errorRate = ewmaAlpha + (1-ewmaAlpha)*e.errorRate
+	e.lastError = err.Error()
+}
+
+func (e *Endpoint) setHealth(healthy bool, head uint64, now time.Time) (changed bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	changed = e.healthy != healthy
+	e.healthy = healthy
+	if head > 0 {
+		e.head = head
+	}
+	e.lastCheck = now
+	return changed
+}
+
+func (e *Endpoint) isHealthy() bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.healthy
+}
+
+func (e *Endpoint) getHead() uint64 {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.head
+}
+
+// score is lower for the better endpoints
+func (e *Endpoint) score() float64 {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.scoreLocked()
+}
+
+func (e *Endpoint) scoreLocked() float64 {
+	return (e.latencyMs + 1) * (1 + errorRatePenalty*e.errorRate)
+}
+
+func (e *Endpoint) Status() EndpointStatus {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	return EndpointStatus{
+		URL:       e.url,
+		Healthy:   e.healthy,
+		Head:      e.head,
+		LatencyMs: e.latencyMs,
+		ErrorRate: e.errorRate,
+		Score:     e.scoreLocked(),
+		LastError: e.lastError,
+		LastCheck: e.lastCheck,
+	}
+}
+
+func (e *Endpoint) close() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.client != nil {
+		e.client.Close()
+		e.client = nil
+	}
+}
+
+// redactURL removes the credentials and the path, which often contains the api key of the provider
+func redactURL(u *url.URL) string {
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host}).String()
+}
@@ -0,0 +1,494 @@
+package ethpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	healthCheckTimeout = 5 * time.Second
+)
+
+var (
+	ErrNoEndpoints        = errors.New("no ethereum node endpoints configured")
+	ErrAllEndpointsFailed = errors.New("all ethereum node endpoints failed")
+	ErrQuorumNotReached   = errors.New("ethereum node endpoints returned different results")
+	ErrTxPossiblySent     = errors.New("transaction may have been accepted by the failed endpoint")
+)
+
+type quorumKey struct{}
+
+// WithQuorum marks the contract reads made with the context as critical, so they are
+// confirmed by the quorum of the endpoints
+func WithQuorum(ctx context.Context) context.Context {
+	return context.WithValue(ctx, quorumKey{}, true)
+}
+
+func isQuorum(ctx context.Context) bool {
+	v, _ := ctx.Value(quorumKey{}).(bool)
+	return v
+}
+
+// Pool implements the ethereum client over several endpoints. Requests are sent to the endpoint
+// with the best score and fail over to the next ones on network errors. Slow reads are hedged
+// by sending the same request to the next endpoint, critical reads may require a quorum
+type Pool struct {
+	// config
+	quorum              int
+	hedgeDelay          time.Duration
+	healthCheckInterval time.Duration
+	maxBlockLag         uint64
+
+	// state
+	endpoints []*Endpoint
+
+	// deps
+	log interfaces.ILogger
+}
+
+func NewPool(urls []string, dial DialFunc, quorum int, hedgeDelay time.Duration, healthCheckInterval time.Duration, maxBlockLag uint64, log interfaces.ILogger) (*Pool, error) {
+	if len(urls) == 0 {
+		return nil, ErrNoEndpoints
+	}
+
+	endpoints := make([]*Endpoint, len(urls))
+	for i, u := range urls {
+		ep, err := NewEndpoint(u, dial)
+		if err != nil {
+			return nil, err
+		}
+		endpoints[i] = ep
+	}
+
+	return &Pool{
+		quorum:              quorum,
+		hedgeDelay:          hedgeDelay,
+		healthCheckInterval: healthCheckInterval,
+		maxBlockLag:         maxBlockLag,
+		endpoints:           endpoints,
+		log:                 log,
+	}, nil
+}
+
+// Run periodically checks the health of the endpoints
+func (p *Pool) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.healthCheckInterval)
+	defer ticker.Stop()
+	defer p.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			p.CheckHealth(ctx, now)
+		}
+	}
+}
+
+// CheckHealth requests the latest block from every endpoint, the endpoint is healthy
+// if it responded and is not lagging behind the others by more than maxBlockLag blocks
+func (p *Pool) CheckHealth(ctx context.Context, now time.Time) {
+	heads := make([]uint64, len(p.endpoints))
+	errs := make([]error, len(p.endpoints))
+
+	var wg sync.WaitGroup
+	for i, ep := range p.endpoints {
+		wg.Add(1)
+		go func(i int, ep *Endpoint) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			heads[i], errs[i] = callEndpoint(ctx, ep, func(ctx context.Context, c RPCClient) (uint64, error) {
+				return c.BlockNumber(ctx)
+			})
+		}(i, ep)
+	}
+	wg.Wait()
+
+	var maxHead uint64
+	for _, head := range heads {
+		if head > maxHead {
+			maxHead = head
+		}
+	}
+
+	for i, ep := range p.endpoints {
+		healthy := errs[i] == nil && heads[i]+p.maxBlockLag >= maxHead
+		if !ep.setHealth(healthy, heads[i], now) {
+			continue
+		}
+		if healthy {
+			p.log.Infof("ethereum node %s is healthy, head %d", ep.URL(), heads[i])
+		} else if errs[i] != nil {
+			p.log.Warnf("ethereum node %s is unhealthy: %s", ep.URL(), errs[i])
+		} else {
+			p.log.Warnf("ethereum node %s is unhealthy: head %d is behind %d", ep.URL(), heads[i], maxHead)
+		}
+	}
+}
+
+// GetStatus returns the health of the endpoints in the order they are used
+func (p *Pool) GetStatus() []EndpointStatus {
+	eps := p.ordered()
+	res := make([]EndpointStatus, len(eps))
+	for i, ep := range eps {
+		res[i] = ep.Status()
+	}
+	return res
+}
+
+func (p *Pool) Close() {
+	for _, ep := range p.endpoints {
+		ep.close()
+	}
+}
+
+// ordered returns healthy endpoints sorted by score followed by unhealthy ones
+func (p *Pool) ordered() []*Endpoint {
+	eps := make([]*Endpoint, len(p.endpoints))
+	copy(eps, p.endpoints)
+
+	healthy := make(map[*Endpoint]bool, len(eps))
+	scores := make(map[*Endpoint]float64, len(eps))
+	for _, ep := range eps {
+		healthy[ep] = ep.isHealthy()
+		scores[ep] = ep.score()
+	}
+
+	sort.SliceStable(eps, func(i, j int) bool {
+		if healthy[eps[i]] != healthy[eps[j]] {
+			return healthy[eps[i]]
+		}
+		return scores[eps[i]] < scores[eps[j]]
+	})
+	return eps
+}
+
+// safeHead returns the lowest head among the healthy endpoints, so all of them are able to serve it
+func (p *Pool) safeHead() uint64 {
+	var head uint64
+	for _, ep := range p.endpoints {
+		if !ep.isHealthy() {
+			continue
+		}
+		h := ep.getHead()
+		if h > 0 && (head == 0 || h < head) {
+			head = h
+		}
+	}
+	return head
+}
+
+func (p *Pool) BlockNumber(ctx context.Context) (uint64, error) {
+	return do(ctx, p, true, func(ctx context.Context, c RPCClient) (uint64, error) {
+		return c.BlockNumber(ctx)
+	})
+}
+
+func (p *Pool) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return do(ctx, p, true, func(ctx context.Context, c RPCClient) (*big.Int, error) {
+		return c.BalanceAt(ctx, account, blockNumber)
+	})
+}
+
+func (p *Pool) ChainID(ctx context.Context) (*big.Int, error) {
+	return do(ctx, p, true, func(ctx context.Context, c RPCClient) (*big.Int, error) {
+		return c.ChainID(ctx)
+	})
+}
+
+func (p *Pool) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return do(ctx, p, true, func(ctx context.Context, c RPCClient) ([]byte, error) {
+		return c.CodeAt(ctx, contract, blockNumber)
+	})
+}
+
+func (p *Pool) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if p.quorum > 1 && isQuorum(ctx) {
+		return p.callContractQuorum(ctx, call, blockNumber)
+	}
+	return do(ctx, p, true, func(ctx context.Context, c RPCClient) ([]byte, error) {
+		return c.CallContract(ctx, call, blockNumber)
+	})
+}
+
+func (p *Pool) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return do(ctx, p, true, func(ctx context.Context, c RPCClient) (*types.Header, error) {
+		return c.HeaderByNumber(ctx, number)
+	})
+}
+
+func (p *Pool) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return do(ctx, p, true, func(ctx context.Context, c RPCClient) ([]byte, error) {
+		return c.PendingCodeAt(ctx, account)
+	})
+}
+
+func (p *Pool) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return do(ctx, p, true, func(ctx context.Context, c RPCClient) (uint64, error) {
+		return c.PendingNonceAt(ctx, account)
+	})
+}
+
+func (p *Pool) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return do(ctx, p, true, func(ctx context.Context, c RPCClient) (uint64, error) {
+		return c.NonceAt(ctx, account, blockNumber)
+	})
+}
+
+func (p *Pool) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	return do(ctx, p, true, func(ctx context.Context, c RPCClient) ([]byte, error) {
+		return c.StorageAt(ctx, account, key, blockNumber)
+	})
+}
+
+func (p *Pool) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return do(ctx, p, true, func(ctx context.Context, c RPCClient) (*big.Int, error) {
+		return c.SuggestGasPrice(ctx)
+	})
+}
+
+func (p *Pool) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return do(ctx, p, true, func(ctx context.Context, c RPCClient) (*big.Int, error) {
+		return c.SuggestGasTipCap(ctx)
+	})
+}
+
+func (p *Pool) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	return do(ctx, p, true, func(ctx context.Context, c RPCClient) (uint64, error) {
+		return c.EstimateGas(ctx, call)
+	})
+}
+
+func (p *Pool) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return do(ctx, p, true, func(ctx context.Context, c RPCClient) (*types.Receipt, error) {
+		return c.TransactionReceipt(ctx, txHash)
+	})
+}
+
+func (p *Pool) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	return do(ctx, p, true, func(ctx context.Context, c RPCClient) ([]types.Log, error) {
+		return c.FilterLogs(ctx, q)
+	})
+}
+
+// SendTransaction is not hedged, the next endpoint is tried only if the current one is unreachable. If the request
+// reached the endpoint that failed, e.g. timed out, the transaction may have been accepted by it, so the error is
+// wrapped with ErrTxPossiblySent, as the answer of the next endpoint such as "nonce too low" can't be trusted
+func (p *Pool) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	var attempts atomic.Int32 // endpoints the request was sent to
+	_, err := do(ctx, p, false, func(ctx context.Context, c RPCClient) (struct{}, error) {
+		attempts.Add(1)
+		return struct{}{}, c.SendTransaction(ctx, tx)
+	})
+	if err == nil {
+		return nil
+	}
+	if n := attempts.Load(); n > 1 || (n == 1 && isRetryable(err)) {
+		return lib.WrapError(ErrTxPossiblySent, err)
+	}
+	return err
+}
+
+// SubscribeFilterLogs subscribes using the first endpoint that supports subscriptions
+func (p *Pool) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return do(ctx, p, false, func(ctx context.Context, c RPCClient) (ethereum.Subscription, error) {
+		return c.SubscribeFilterLogs(ctx, q, ch)
+	})
+}
+
+// callContractQuorum sends the call to the endpoints in parallel and returns the result
+// as soon as the quorum of them agreed on it. The call is pinned to the block available on all of the
+// healthy endpoints, otherwise the endpoints with different heads may legitimately disagree
+func (p *Pool) callContractQuorum(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if blockNumber == nil {
+		if head := p.safeHead(); head > 0 {
+			blockNumber = new(big.Int).SetUint64(head)
+		}
+	}
+
+	eps := p.ordered()
+	quorum := min(p.quorum, len(eps))
+
+	type result struct {
+		data []byte
+		err  error
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan result, len(eps))
+	for _, ep := range eps {
+		go func(ep *Endpoint) {
+			data, err := callEndpoint(ctx, ep, func(ctx context.Context, c RPCClient) ([]byte, error) {
+				return c.CallContract(ctx, call, blockNumber)
+			})
+			results <- result{data, err}
+		}(ep)
+	}
+
+	votes := make(map[string]int)
+	var lastErr error
+	for range eps {
+		var r result
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case r = <-results:
+		}
+
+		if r.err != nil && isRetryable(r.err) {
+			lastErr = r.err
+			continue
+		}
+
+		// the answered error, such as the revert, is a valid result and has to be agreed upon as well
+		key := "data:" + string(r.data)
+		if r.err != nil {
+			key = "err:" + r.err.Error()
+		}
+		votes[key]++
+		if votes[key] >= quorum {
+			return r.data, r.err
+		}
+	}
+
+	if lastErr != nil {
+		return nil, lib.WrapError(ErrQuorumNotReached, lastErr)
+	}
+	return nil, lib.WrapError(ErrQuorumNotReached, fmt.Errorf("%d different results, quorum %d", len(votes), quorum))
+}
+
+// do calls the endpoints in the order of their score until one of them responds. If hedge is set and
+// the endpoint did not respond within hedgeDelay the request is also sent to the next endpoint
+func do[T any](ctx context.Context, p *Pool, hedge bool, fn func(ctx context.Context, c RPCClient) (T, error)) (T, error) {
+	var zero T
+
+	type result struct {
+		value T
+		err   error
+	}
+
+	eps := p.ordered()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan result, len(eps))
+	launched, inflight := 0, 0
+	launch := func() {
+		ep := eps[launched]
+		launched++
+		inflight++
+		go func() {
+			value, err := callEndpoint(ctx, ep, fn)
+			results <- result{value, err}
+		}()
+	}
+
+	var hedgeCh <-chan time.Time
+	resetHedge := func() {
+		if hedge && p.hedgeDelay > 0 && launched < len(eps) {
+			hedgeCh = time.After(p.hedgeDelay)
+		} else {
+			hedgeCh = nil
+		}
+	}
+
+	launch()
+	resetHedge()
+
+	var lastErr error
+	for inflight > 0 {
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case <-hedgeCh:
+			launch()
+			resetHedge()
+		case r := <-results:
+			inflight--
+			if r.err == nil || !isRetryable(r.err) {
+				return r.value, r.err
+			}
+			lastErr = r.err
+			if launched < len(eps) {
+				launch()
+				resetHedge()
+			}
+		}
+	}
+
+	return zero, lib.WrapError(ErrAllEndpointsFailed, lastErr)
+}
+
+// callEndpoint calls the endpoint and records the outcome in its statistics
+func callEndpoint[T any](ctx context.Context, ep *Endpoint, fn func(ctx context.Context, c RPCClient) (T, error)) (T, error) {
+	var zero T
+
+	client, err := ep.getClient(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			ep.recordFailure(err)
+		}
+		return zero, lib.WrapError(fmt.Errorf("%s", ep.URL()), err)
+	}
+
+	start := time.Now()
+	value, err := fn(ctx, client)
+	if err != nil && isRetryable(err) {
+		// requests cancelled because another endpoint responded first are not the endpoint failures
+		if ctx.Err() == nil {
+			ep.recordFailure(err)
+		}
+		return zero, lib.WrapError(fmt.Errorf("%s", ep.URL()), err)
+	}
+
+	ep.recordSuccess(time.Since(start))
+	return value, err
+}
+
+// isRetryable returns true if the error is caused by the endpoint failure rather than
+// the request itself, so the request may succeed on the other endpoint
+func isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ethereum.NotFound) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, rpc.ErrNotificationsUnsupported) {
+		return true
+	}
+
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return true
+	}
+
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		// limit exceeded and internal errors are the provider issues, others are the answers to the request
+		return rpcErr.ErrorCode() == -32005 || rpcErr.ErrorCode() == -32603 ||
+			strings.Contains(strings.ToLower(rpcErr.Error()), "rate limit")
+	}
+
+	// network and transport errors, such as timeout, EOF or closed connection
+	return true
+}
@@ -0,0 +1,214 @@
+package ethpool
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+type rpcError struct{}
+
+func (e rpcError) Error() string  { return "execution reverted" }
+func (e rpcError) ErrorCode() int { return 3 }
+
+// fakeClient implements only the methods used in tests
+type fakeClient struct {
+	RPCClient
+	head   uint64
+	data   []byte
+	err    error
+	delay  time.Duration
+	calls  atomic.Int32
+	closed atomic.Bool
+}
+
+func (c *fakeClient) wait(ctx context.Context) error {
+	c.calls.Add(1)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(c.delay):
+		return nil
+	}
+}
+
+func (c *fakeClient) BlockNumber(ctx context.Context) (uint64, error) {
+	if err := c.wait(ctx); err != nil {
+		return 0, err
+	}
+	return c.head, c.err
+}
+
+func (c *fakeClient) ChainID(ctx context.Context) (*big.Int, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return big.NewInt(int64(c.head)), nil
+}
+
+func (c *fakeClient) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.data, c.err
+}
+
+func (c *fakeClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if err := c.wait(ctx); err != nil {
+		return err
+	}
+	return c.err
+}
+
+func (c *fakeClient) Close() {
+	c.closed.Store(true)
+}
+
+func newTestPool(t *testing.T, quorum int, hedgeDelay time.Duration, clients ...*fakeClient) *Pool {
+	urls := make([]string, len(clients))
+	byURL := make(map[string]*fakeClient, len(clients))
+	for i, c := range clients {
+		urls[i] = "http://node" + string(rune('a'+i)) + "/key"
+		byURL[urls[i]] = c
+	}
+
+	dial := func(ctx context.Context, rawURL string) (RPCClient, error) {
+		c := byURL[rawURL]
+		if c == nil {
+			return nil, errors.New("dial failed")
+		}
+		return c, nil
+	}
+
+	pool, err := NewPool(urls, dial, quorum, hedgeDelay, time.Minute, 5, lib.NewTestLogger())
+	require.NoError(t, err)
+	return pool
+}
+
+func TestPoolFailover(t *testing.T) {
+	bad := &fakeClient{err: errors.New("connection refused"), head: 1}
+	good := &fakeClient{head: 2}
+	pool := newTestPool(t, 1, 0, bad, good)
+
+	id, err := pool.ChainID(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(2), id.Int64())
+
+	// failed endpoint is scored lower and not tried first anymore
+	_, err = pool.ChainID(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int32(1), bad.calls.Load())
+	require.Equal(t, "http://nodeb", pool.GetStatus()[0].URL)
+}
+
+func TestPoolDialFailure(t *testing.T) {
+	good := &fakeClient{head: 2}
+	pool := newTestPool(t, 1, 0, nil, good)
+
+	id, err := pool.ChainID(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(2), id.Int64())
+}
+
+func TestPoolAllFailed(t *testing.T) {
+	pool := newTestPool(t, 1, 0, &fakeClient{err: errors.New("EOF")}, &fakeClient{err: errors.New("EOF")})
+
+	_, err := pool.ChainID(context.Background())
+	require.ErrorIs(t, err, ErrAllEndpointsFailed)
+}
+
+func TestPoolNoFailoverOnRequestError(t *testing.T) {
+	first := &fakeClient{err: rpcError{}}
+	second := &fakeClient{data: []byte{1}}
+	pool := newTestPool(t, 1, 0, first, second)
+
+	_, err := pool.CallContract(context.Background(), ethereum.CallMsg{}, nil)
+	require.ErrorIs(t, err, rpcError{})
+	require.Zero(t, second.calls.Load())
+}
+
+type nonceTooLowError struct{}
+
+func (e nonceTooLowError) Error() string  { return "nonce too low" }
+func (e nonceTooLowError) ErrorCode() int { return -32000 }
+
+func TestPoolSendTransactionFailover(t *testing.T) {
+	tx := types.NewTx(&types.LegacyTx{})
+
+	// the first endpoint may have accepted the transaction before timing out, so the rejection
+	// of the next one is not final
+	timedOut := &fakeClient{err: errors.New("i/o timeout")}
+	rejecting := &fakeClient{err: nonceTooLowError{}}
+	pool := newTestPool(t, 1, 0, timedOut, rejecting)
+	err := pool.SendTransaction(context.Background(), tx)
+	require.ErrorIs(t, err, ErrTxPossiblySent)
+	require.ErrorIs(t, err, nonceTooLowError{})
+
+	// the rejection of the only endpoint called is final
+	pool = newTestPool(t, 1, 0, &fakeClient{err: nonceTooLowError{}})
+	err = pool.SendTransaction(context.Background(), tx)
+	require.NotErrorIs(t, err, ErrTxPossiblySent)
+}
+
+func TestPoolHedging(t *testing.T) {
+	slow := &fakeClient{head: 1, delay: time.Second}
+	fast := &fakeClient{head: 2}
+	pool := newTestPool(t, 1, 10*time.Millisecond, slow, fast)
+
+	start := time.Now()
+	id, err := pool.ChainID(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(2), id.Int64())
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestPoolQuorum(t *testing.T) {
+	a1 := &fakeClient{data: []byte("a")}
+	b := &fakeClient{data: []byte("b")}
+	a2 := &fakeClient{data: []byte("a"), delay: 10 * time.Millisecond}
+
+	pool := newTestPool(t, 2, 0, b, a1, a2)
+
+	// regular reads are served by the first endpoint
+	data, err := pool.CallContract(context.Background(), ethereum.CallMsg{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("b"), data)
+
+	data, err = pool.CallContract(WithQuorum(context.Background()), ethereum.CallMsg{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("a"), data)
+
+	pool = newTestPool(t, 3, 0, b, a1, a2)
+	_, err = pool.CallContract(WithQuorum(context.Background()), ethereum.CallMsg{}, nil)
+	require.ErrorIs(t, err, ErrQuorumNotReached)
+}
+
+func TestPoolHealthCheck(t *testing.T) {
+	lagging := &fakeClient{head: 100}
+	synced := &fakeClient{head: 110}
+	down := &fakeClient{err: errors.New("connection refused")}
+	pool := newTestPool(t, 1, 0, lagging, down, synced)
+
+	pool.CheckHealth(context.Background(), time.Now())
+
+	status := pool.GetStatus()
+	require.Equal(t, "http://nodec", status[0].URL)
+	require.True(t, status[0].Healthy)
+	require.False(t, status[1].Healthy)
+	require.False(t, status[2].Healthy)
+	require.Equal(t, uint64(110), pool.safeHead())
+
+	pool.Close()
+	require.True(t, synced.closed.Load())
+}
@@ -0,0 +1,204 @@
+package evidence
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// BundleVersion is increased on the incompatible changes of the bundle format
+const BundleVersion = 1
+
+const (
+	ManifestFile  = "manifest.json"
+	SignatureFile = "manifest.sig"
+
+	maxFileSize = 64 << 20 // files of the verified bundle are read into memory
+)
+
+var (
+	ErrInvalidBundle = errors.New("invalid evidence bundle")
+)
+
+// File is the named file of the bundle
+type File struct {
+	Name string
+	Data []byte
+}
+
+// FileHash is the checksum of the bundle file
+type FileHash struct {
+	Name   string
+	Size   int
+	SHA256 string
+}
+
+// Manifest lists the files of the bundle with their checksums, so the signature of the manifest covers all files
+type Manifest struct {
+	Version    int
+	ContractID string
+	Signer     common.Address
+	CreatedAt  time.Time
+	Files      []FileHash
+}
+
+// Build packs the files to the gzipped tar archive together with the manifest and its signature. The signature
+// file contains the hex EIP-191 signature of the manifest file bytes
+func Build(ctx context.Context, contractID string, files []File, signer interfaces.Signer) ([]byte, error) {
+	manifest := Manifest{
+		Version:    BundleVersion,
+		ContractID: contractID,
+		Signer:     signer.Address(),
+		CreatedAt:  time.Now().UTC(),
+		Files:      make([]FileHash, 0, len(files)),
+	}
+	for _, f := range files {
+		sum := sha256.Sum256(f.Data)
+		manifest.Files = append(manifest.Files, FileHash{Name: f.Name, Size: len(f.Data), SHA256: hex.EncodeToString(sum[:])})
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signer.SignMessage(ctx, manifestData)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	entries := append([]File{
+		{Name: ManifestFile, Data: manifestData},
+		{Name: SignatureFile, Data: []byte(hexutil.Encode(sig))},
+	}, files...)
+	for _, f := range entries {
+		err = tw.WriteHeader(&tar.Header{
+			Name:    f.Name,
+			Mode:    0o644,
+			Size:    int64(len(f.Data)),
+			ModTime: manifest.CreatedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		_, err = tw.Write(f.Data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return nil, err
+	}
+	err = gz.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Verify checks the manifest is signed by its signer and the files match the manifest, returns the manifest
+// and the files in the manifest order
+func Verify(archive io.Reader) (*Manifest, []File, error) {
+	entries, err := readArchive(archive)
+	if err != nil {
+		return nil, nil, lib.WrapError(ErrInvalidBundle, err)
+	}
+
+	manifestData, ok := entries[ManifestFile]
+	if !ok {
+		return nil, nil, lib.WrapError(ErrInvalidBundle, fmt.Errorf("%s is missing", ManifestFile))
+	}
+	sigData, ok := entries[SignatureFile]
+	if !ok {
+		return nil, nil, lib.WrapError(ErrInvalidBundle, fmt.Errorf("%s is missing", SignatureFile))
+	}
+
+	var manifest Manifest
+	err = json.Unmarshal(manifestData, &manifest)
+	if err != nil {
+		return nil, nil, lib.WrapError(ErrInvalidBundle, err)
+	}
+	sig, err := hexutil.Decode(strings.TrimSpace(string(sigData)))
+	if err != nil {
+		return nil, nil, lib.WrapError(ErrInvalidBundle, err)
+	}
+	pubKey, err := lib.RecoverMessageSigner(manifestData, sig)
+	if err != nil {
+		return nil, nil, lib.WrapError(ErrInvalidBundle, err)
+	}
+	if signer := crypto.PubkeyToAddress(*pubKey); signer != manifest.Signer {
+		return nil, nil, lib.WrapError(ErrInvalidBundle, fmt.Errorf("manifest signed by %s, manifest signer %s", signer.Hex(), manifest.Signer.Hex()))
+	}
+
+	files := make([]File, 0, len(manifest.Files))
+	for _, fh := range manifest.Files {
+		data, ok := entries[fh.Name]
+		if !ok {
+			return nil, nil, lib.WrapError(ErrInvalidBundle, fmt.Errorf("%s is missing", fh.Name))
+		}
+		sum := sha256.Sum256(data)
+		if len(data) != fh.Size || hex.EncodeToString(sum[:]) != fh.SHA256 {
+			return nil, nil, lib.WrapError(ErrInvalidBundle, fmt.Errorf("%s doesn't match the manifest", fh.Name))
+		}
+		files = append(files, File{Name: fh.Name, Data: data})
+		delete(entries, fh.Name)
+	}
+	for name := range entries {
+		if name != ManifestFile && name != SignatureFile {
+			return nil, nil, lib.WrapError(ErrInvalidBundle, fmt.Errorf("%s is not listed in the manifest", name))
+		}
+	}
+
+	return &manifest, files, nil
+}
+
+func readArchive(archive io.Reader) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	entries := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Size > maxFileSize {
+			return nil, fmt.Errorf("%s is too large", hdr.Name)
+		}
+		if _, ok := entries[hdr.Name]; ok {
+			return nil, fmt.Errorf("%s is duplicated", hdr.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		entries[hdr.Name] = data
+	}
+}
@@ -0,0 +1,127 @@
+package evidence
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testPrivKey  = "b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291"
+	testPrivKey2 = "8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a"
+)
+
+var testFiles = []File{
+	{Name: "delivery-log.json", Data: []byte(`[{"Timestamp":1}]`)},
+	{Name: "contract.log", Data: []byte("contract started\n")},
+}
+
+func unpack(t *testing.T, archive []byte) []File {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	var entries []File
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		entries = append(entries, File{Name: hdr.Name, Data: data})
+	}
+}
+
+func pack(t *testing.T, entries []File) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range entries {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: f.Name, Mode: 0o644, Size: int64(len(f.Data))}))
+		_, err := tw.Write(f.Data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+// replace returns the archive with the entry data replaced
+func replace(t *testing.T, archive []byte, name string, data []byte) []byte {
+	entries := unpack(t, archive)
+	for i := range entries {
+		if entries[i].Name == name {
+			entries[i].Data = data
+		}
+	}
+	return pack(t, entries)
+}
+
+func TestBuildVerify(t *testing.T) {
+	signer, err := wallet.NewKeySigner(testPrivKey)
+	require.NoError(t, err)
+
+	archive, err := Build(context.Background(), "0x01", testFiles, signer)
+	require.NoError(t, err)
+
+	manifest, files, err := Verify(bytes.NewReader(archive))
+	require.NoError(t, err)
+	require.Equal(t, BundleVersion, manifest.Version)
+	require.Equal(t, "0x01", manifest.ContractID)
+	require.Equal(t, signer.Address(), manifest.Signer)
+	require.Equal(t, testFiles, files)
+}
+
+func TestVerifyTampered(t *testing.T) {
+	signer, err := wallet.NewKeySigner(testPrivKey)
+	require.NoError(t, err)
+	other, err := wallet.NewKeySigner(testPrivKey2)
+	require.NoError(t, err)
+
+	archive, err := Build(context.Background(), "0x01", testFiles, signer)
+	require.NoError(t, err)
+
+	// repacked archive is still valid
+	_, _, err = Verify(bytes.NewReader(pack(t, unpack(t, archive))))
+	require.NoError(t, err)
+
+	// changed file
+	changed := replace(t, archive, "contract.log", []byte("contract closed\n"))
+	_, _, err = Verify(bytes.NewReader(changed))
+	require.ErrorIs(t, err, ErrInvalidBundle)
+
+	// file not listed in the manifest
+	added := pack(t, append(unpack(t, archive), File{Name: "extra.json", Data: []byte("{}")}))
+	_, _, err = Verify(bytes.NewReader(added))
+	require.ErrorIs(t, err, ErrInvalidBundle)
+
+	// signature of the other key
+	otherArchive, err := Build(context.Background(), "0x01", testFiles, other)
+	require.NoError(t, err)
+	var otherSig []byte
+	for _, f := range unpack(t, otherArchive) {
+		if f.Name == SignatureFile {
+			otherSig = f.Data
+		}
+	}
+	resigned := replace(t, archive, SignatureFile, otherSig)
+	_, _, err = Verify(bytes.NewReader(resigned))
+	require.ErrorIs(t, err, ErrInvalidBundle)
+
+	// missing signature
+	var unsigned []File
+	for _, f := range unpack(t, archive) {
+		if f.Name != SignatureFile {
+			unsigned = append(unsigned, f)
+		}
+	}
+	_, _, err = Verify(bytes.NewReader(pack(t, unsigned)))
+	require.ErrorIs(t, err, ErrInvalidBundle)
+}
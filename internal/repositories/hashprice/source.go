@@ -0,0 +1,105 @@
+package hashprice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+)
+
+const (
+	HTTPTimeout = 10 * time.Second
+)
+
+var (
+	ErrInvalidHashprice = errors.New("invalid hashprice")
+)
+
+// Hashprice is the market price of the hashrate, the feed is a json object, for example
+// {"pricePerPHDay": 52.5, "updatedAt": "2025-01-01T00:00:00Z"}
+type Hashprice struct {
+	PricePerPHDay float64   `json:"pricePerPHDay"` // price of 1 PH/s for a day in the payment token
+	UpdatedAt     time.Time `json:"updatedAt"`     // optional, the time the price was calculated at
+}
+
+type Source interface {
+	GetHashprice(ctx context.Context) (*Hashprice, error)
+}
+
+// NewSource returns the http source if src is an http(s) url, otherwise the file source
+func NewSource(src string) Source {
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		return NewHTTPSource(src)
+	}
+	return NewFileSource(src)
+}
+
+// FileSource reads the hashprice from the local json file, which is updated by the external script
+type FileSource struct {
+	path string
+}
+
+func NewFileSource(path string) *FileSource {
+	return &FileSource{path: path}
+}
+
+func (s *FileSource) GetHashprice(ctx context.Context) (*Hashprice, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	return parse(data)
+}
+
+// HTTPSource fetches the hashprice from the http feed
+type HTTPSource struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPSource(url string) *HTTPSource {
+	return &HTTPSource{
+		url:    url,
+		client: &http.Client{Timeout: HTTPTimeout},
+	}
+}
+
+func (s *HTTPSource) GetHashprice(ctx context.Context) (*Hashprice, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("hashprice feed responded with status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parse(data)
+}
+
+func parse(data []byte) (*Hashprice, error) {
+	var res Hashprice
+	err := json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, lib.WrapError(ErrInvalidHashprice, err)
+	}
+	if res.PricePerPHDay <= 0 {
+		return nil, lib.WrapError(ErrInvalidHashprice, fmt.Errorf("price must be positive, got %f", res.PricePerPHDay))
+	}
+	return &res, nil
+}
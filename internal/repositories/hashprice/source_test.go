@@ -0,0 +1,48 @@
+package hashprice
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hashprice.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"pricePerPHDay": 52.5, "updatedAt": "2025-01-01T00:00:00Z"}`), 0o644))
+
+	price, err := NewSource(path).GetHashprice(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 52.5, price.PricePerPHDay)
+	require.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), price.UpdatedAt)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"pricePerPHDay": 0}`), 0o644))
+	_, err = NewSource(path).GetHashprice(context.Background())
+	require.ErrorIs(t, err, ErrInvalidHashprice)
+}
+
+func TestHTTPSource(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"pricePerPHDay": 48}`))
+	}))
+	defer srv.Close()
+
+	src := NewSource(srv.URL)
+	require.IsType(t, &HTTPSource{}, src)
+
+	price, err := src.GetHashprice(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 48.0, price.PricePerPHDay)
+	require.True(t, price.UpdatedAt.IsZero())
+
+	status = http.StatusServiceUnavailable
+	_, err = src.GetHashprice(context.Background())
+	require.Error(t, err)
+}
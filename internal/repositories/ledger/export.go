@@ -0,0 +1,101 @@
+package ledger
+
+import (
+	"encoding/csv"
+	"io"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	entriesCSVHeader = []string{"time", "type", "role", "contract", "counterparty", "price", "token", "amount", "reason", "blame", "tx", "fee", "fee_token"}
+	summaryCSVHeader = []string{"period", "contract_starts", "closeouts", "reward_claims", "validator_fees", "token", "sales", "spent", "received"}
+)
+
+// WriteEntriesCSV writes the entries as csv, token amounts are in the token base units
+func WriteEntriesCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write(entriesCSVHeader)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		err := cw.Write([]string{
+			e.Time.UTC().Format(time.RFC3339),
+			string(e.Type),
+			e.Role,
+			e.ContractID,
+			addrOrEmpty(e.Counterparty),
+			bigOrEmpty(e.Price),
+			addrOrEmpty(e.Token),
+			bigOrEmpty(e.Amount),
+			e.Reason,
+			e.Blame,
+			e.TxHash.Hex(),
+			bigOrEmpty(e.Fee),
+			addrOrEmpty(e.FeeToken),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteSummaryCSV writes the summaries as csv with a row per period and token
+func WriteSummaryCSV(w io.Writer, summaries []*Summary) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write(summaryCSVHeader)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range summaries {
+		counts := []string{
+			s.PeriodStart.Format(time.DateOnly),
+			strconv.Itoa(s.ContractStarts),
+			strconv.Itoa(s.Closeouts),
+			strconv.Itoa(s.RewardClaims),
+			strconv.Itoa(s.ValidatorFees),
+		}
+
+		tokens := s.Tokens()
+		if len(tokens) == 0 {
+			err := cw.Write(append(counts, "", "0", "0", "0"))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, token := range tokens {
+			err := cw.Write(append(counts, token.Hex(), s.GetSales(token).String(), s.GetSpent(token).String(), s.GetReceived(token).String()))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func addrOrEmpty(addr *common.Address) string {
+	if addr == nil {
+		return ""
+	}
+	return addr.Hex()
+}
+
+func bigOrEmpty(v *big.Int) string {
+	if v == nil {
+		return ""
+	}
+	return v.String()
+}
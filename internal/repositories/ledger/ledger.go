@@ -0,0 +1,324 @@
+package ledger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gofrs/flock"
+)
+
+type EntryType string
+
+const (
+	EntryContractStart EntryType = "contract-start"
+	EntryCloseout      EntryType = "closeout"
+	EntryRewardClaim   EntryType = "reward-claim"  // futures delivery payment
+	EntryValidatorFee  EntryType = "validator-fee" // validator reward of the hashrate contract
+	EntryPayout        EntryType = "payout"        // seller payment of the hashrate contract completed in full
+)
+
+const (
+	RoleSeller    = "seller"
+	RoleBuyer     = "buyer"
+	RoleValidator = "validator"
+)
+
+var (
+	ErrInvalidPeriod = errors.New("invalid period, expected day, week or month")
+)
+
+// Entry is a single settlement event of the wallet. Amount is the number of tokens received by the wallet in the
+// transaction, decoded from its receipt. If the transaction transferred several tokens, there is an entry per token
+type Entry struct {
+	Time         time.Time
+	Type         EntryType
+	Role         string
+	ContractID   string          // contract address, or delivery date for the futures
+	Counterparty *common.Address `json:",omitempty"`
+	Price        *big.Int        `json:",omitempty"`
+	Fee          *big.Int        `json:",omitempty"` // validator fee paid by the buyer with the price
+	FeeToken     *common.Address `json:",omitempty"`
+	Token        *common.Address `json:",omitempty"`
+	Amount       *big.Int        `json:",omitempty"`
+	Reason       string          `json:",omitempty"` // close reason
+	Blame        string          `json:",omitempty"` // party responsible for the closeout
+	TxHash       common.Hash
+}
+
+func (e *Entry) key() string {
+	token := ""
+	if e.Token != nil {
+		token = e.Token.Hex()
+	}
+	return fmt.Sprintf("%s:%s:%s:%s", e.Type, e.ContractID, e.TxHash.Hex(), token)
+}
+
+// Ledger keeps the settlement events of the wallet in memory and appends them to the json lines file, if the path is set
+type Ledger struct {
+	// config
+	path string
+
+	// state
+	entries []Entry
+	keys    map[string]struct{}
+	mutex   sync.RWMutex
+}
+
+func NewLedger(path string) *Ledger {
+	return &Ledger{
+		path: path,
+		keys: make(map[string]struct{}),
+	}
+}
+
+// Load reads the entries from the file, missing file is treated as empty ledger
+func (l *Ledger) Load() error {
+	if l.path == "" {
+		return nil
+	}
+
+	entries, err := l.readFile()
+	if err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for _, entry := range entries {
+		l.entries = append(l.entries, entry)
+		l.keys[entry.key()] = struct{}{}
+	}
+	return nil
+}
+
+// Add records the entry, the entry of the same transaction is ignored, so the replayed events are not counted twice
+func (l *Ledger) Add(entry Entry) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if entry.TxHash != (common.Hash{}) {
+		if _, ok := l.keys[entry.key()]; ok {
+			return nil
+		}
+		l.keys[entry.key()] = struct{}{}
+	}
+	l.entries = append(l.entries, entry)
+
+	if l.path == "" {
+		return nil
+	}
+	return l.append(entry)
+}
+
+// RemoveTx removes the entries of the transaction, e.g. reverted by the chain reorg, and rewrites the file.
+// The file may be appended by the other process, such as the offline cli, so it is re-read under the file lock
+// and the entries are reloaded from it
+func (l *Ledger) RemoveTx(txHash common.Hash) (removed int, err error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.path == "" {
+		l.entries, removed = removeTx(l.entries, txHash)
+		l.reindex()
+		return removed, nil
+	}
+
+	unlock, err := l.lockFile()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	entries, err := l.readFile()
+	if err != nil {
+		return 0, err
+	}
+	entries, removed = removeTx(entries, txHash)
+	if removed > 0 {
+		err = l.rewrite(entries)
+		if err != nil {
+			return 0, err
+		}
+	}
+	l.entries = entries
+	l.reindex()
+	return removed, nil
+}
+
+// GetEntries returns the entries in the [from, to) range sorted by time, zero time means no bound
+func (l *Ledger) GetEntries(from, to time.Time) []Entry {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	var res []Entry
+	for _, e := range l.entries {
+		if !from.IsZero() && e.Time.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !e.Time.Before(to) {
+			continue
+		}
+		res = append(res, e)
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Time.Before(res[j].Time)
+	})
+	return res
+}
+
+// GetSpent returns the sum of the prices of the contracts purchased by the wallet since the time, by payment token
+func (l *Ledger) GetSpent(since time.Time) map[common.Address]*big.Int {
+	res := make(map[common.Address]*big.Int)
+	for _, e := range l.GetEntries(since, time.Time{}) {
+		if e.Type != EntryContractStart || e.Role != RoleBuyer {
+			continue
+		}
+		if e.Price != nil {
+			addTo(res, e.Token, e.Price)
+		}
+		if e.Fee != nil && e.FeeToken != nil {
+			addTo(res, e.FeeToken, e.Fee)
+		}
+	}
+	return res
+}
+
+// GetSellerFailureRate returns the share of the contracts purchased from the seller that were closed early
+// with the seller to blame, and the number of the purchases the rate is based on
+func (l *Ledger) GetSellerFailureRate(seller common.Address) (rate float64, purchases int) {
+	purchased := make(map[string]struct{})
+	failures := make(map[common.Hash]struct{}) // closeout receiving several tokens has an entry per token
+	for _, e := range l.GetEntries(time.Time{}, time.Time{}) {
+		switch {
+		case e.Type == EntryContractStart && e.Role == RoleBuyer && e.Counterparty != nil && *e.Counterparty == seller:
+			purchased[e.ContractID] = struct{}{}
+			purchases++
+		case e.Type == EntryCloseout && e.Blame == RoleSeller:
+			if _, ok := purchased[e.ContractID]; ok {
+				failures[e.TxHash] = struct{}{}
+			}
+		}
+	}
+	if purchases == 0 {
+		return 0, 0
+	}
+	return float64(min(len(failures), purchases)) / float64(purchases), purchases
+}
+
+// rewrite replaces the file with the entries, the temporary file is renamed so the file is never partial
+func (l *Ledger) rewrite(entries []Entry) error {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buf.Write(append(data, '\n'))
+	}
+
+	tmpPath := l.path + ".tmp"
+	err := os.WriteFile(tmpPath, buf.Bytes(), 0o644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, l.path)
+}
+
+func (l *Ledger) append(entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(l.path), 0o755)
+	if err != nil {
+		return err
+	}
+
+	// the rewrite renames the file, so the entry appended meanwhile to the old file would be lost
+	unlock, err := l.lockFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// lockFile takes the lock shared with the other processes writing to the same ledger file
+func (l *Ledger) lockFile() (unlock func(), err error) {
+	err = os.MkdirAll(filepath.Dir(l.path), 0o755)
+	if err != nil {
+		return nil, err
+	}
+	lock := flock.New(l.path + ".lock")
+	err = lock.Lock()
+	if err != nil {
+		return nil, err
+	}
+	return func() { _ = lock.Unlock() }, nil
+}
+
+// readFile reads the entries from the file, missing file is treated as empty
+func (l *Ledger) readFile() ([]Entry, error) {
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		err := json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// reindex rebuilds the keys of the entries used to skip the replayed events
+func (l *Ledger) reindex() {
+	l.keys = make(map[string]struct{}, len(l.entries))
+	for _, e := range l.entries {
+		l.keys[e.key()] = struct{}{}
+	}
+}
+
+func removeTx(entries []Entry, txHash common.Hash) ([]Entry, int) {
+	kept := entries[:0:0]
+	for _, e := range entries {
+		if e.TxHash != txHash {
+			kept = append(kept, e)
+		}
+	}
+	return kept, len(entries) - len(kept)
+}
@@ -0,0 +1,186 @@
+package ledger
+
+import (
+	"bytes"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testWallet = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	testOther  = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	testToken  = common.HexToAddress("0x3333333333333333333333333333333333333333")
+)
+
+func transferLog(token, from, to common.Address, amount int64) *types.Log {
+	return &types.Log{
+		Address: token,
+		Topics:  []common.Hash{transferTopic, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+		Data:    common.BigToHash(big.NewInt(amount)).Bytes(),
+	}
+}
+
+func TestAddFromReceiptPersistsAndDeduplicates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	l := NewLedger(path)
+
+	receipt := &types.Receipt{
+		TxHash: common.HexToHash("0x01"),
+		Logs: []*types.Log{
+			transferLog(testToken, testOther, testWallet, 70),
+			transferLog(testToken, testOther, testWallet, 30),
+			transferLog(testToken, testOther, testOther, 1000),
+		},
+	}
+	entry := Entry{Time: time.Unix(1700000000, 0).UTC(), Type: EntryValidatorFee, Role: RoleValidator, ContractID: "0xc0"}
+	require.NoError(t, l.AddFromReceipt(entry, receipt, testWallet))
+	// replayed transaction is not counted twice
+	require.NoError(t, l.AddFromReceipt(entry, receipt, testWallet))
+
+	entries := l.GetEntries(time.Time{}, time.Time{})
+	require.Len(t, entries, 1)
+	require.Equal(t, int64(100), entries[0].Amount.Int64())
+	require.Equal(t, testToken, *entries[0].Token)
+
+	loaded := NewLedger(path)
+	require.NoError(t, loaded.Load())
+	require.Equal(t, entries, loaded.GetEntries(time.Time{}, time.Time{}))
+	require.NoError(t, loaded.AddFromReceipt(entry, receipt, testWallet))
+	require.Len(t, loaded.GetEntries(time.Time{}, time.Time{}), 1)
+}
+
+func TestSummarizeAndExport(t *testing.T) {
+	l := NewLedger("")
+	sept := time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC)
+	oct := time.Date(2025, 10, 2, 10, 0, 0, 0, time.UTC)
+
+	require.NoError(t, l.Add(Entry{Time: sept, Type: EntryContractStart, Role: RoleSeller, ContractID: "0xc1", Token: &testToken, Price: big.NewInt(500)}))
+	require.NoError(t, l.Add(Entry{Time: sept.Add(time.Hour), Type: EntryCloseout, Role: RoleSeller, ContractID: "0xc1", Token: &testToken, Amount: big.NewInt(200), Reason: "underdelivery", Blame: RoleSeller}))
+	require.NoError(t, l.Add(Entry{Time: oct, Type: EntryRewardClaim, Role: RoleSeller, ContractID: "2025-10-01T00:00:00Z", Token: &testToken, Amount: big.NewInt(300)}))
+	// purchase is spent, not sold
+	require.NoError(t, l.Add(Entry{Time: oct.Add(time.Hour), Type: EntryContractStart, Role: RoleBuyer, ContractID: "0xc2", Token: &testToken, Price: big.NewInt(100), Fee: big.NewInt(5), FeeToken: &testWallet}))
+
+	require.Len(t, l.GetEntries(time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC), time.Time{}), 2)
+
+	summaries := Summarize(l.GetEntries(time.Time{}, time.Time{}), PeriodMonth)
+	require.Len(t, summaries, 2)
+	require.Equal(t, time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC), summaries[0].PeriodStart)
+	require.Equal(t, 1, summaries[0].ContractStarts)
+	require.Equal(t, 1, summaries[0].Closeouts)
+	require.Equal(t, int64(500), summaries[0].GetSales(testToken).Int64())
+	require.Equal(t, int64(200), summaries[0].GetReceived(testToken).Int64())
+	require.Equal(t, 1, summaries[1].RewardClaims)
+	require.Equal(t, int64(300), summaries[1].GetReceived(testToken).Int64())
+	require.Equal(t, int64(0), summaries[1].GetSales(testToken).Int64())
+	require.Equal(t, int64(100), summaries[1].GetSpent(testToken).Int64())
+	require.Equal(t, int64(5), summaries[1].GetSpent(testWallet).Int64())
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteSummaryCSV(&buf, summaries))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	require.Equal(t, "2025-09-01,1,1,0,0,"+testToken.Hex()+",500,0,200", lines[1])
+
+	buf.Reset()
+	require.NoError(t, WriteEntriesCSV(&buf, l.GetEntries(time.Time{}, time.Time{})))
+	require.Len(t, strings.Split(strings.TrimSpace(buf.String()), "\n"), 5)
+	require.Contains(t, buf.String(), "underdelivery,seller")
+}
+
+func TestPeriodStart(t *testing.T) {
+	wed := time.Date(2025, 10, 15, 13, 0, 0, 0, time.UTC)
+	require.Equal(t, time.Date(2025, 10, 15, 0, 0, 0, 0, time.UTC), PeriodDay.Start(wed))
+	require.Equal(t, time.Date(2025, 10, 13, 0, 0, 0, 0, time.UTC), PeriodWeek.Start(wed))
+	require.Equal(t, time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC), PeriodMonth.Start(wed))
+
+	_, err := ParsePeriod("year")
+	require.ErrorIs(t, err, ErrInvalidPeriod)
+}
+
+func TestBuyerSpentAndSellerFailureRate(t *testing.T) {
+	l := NewLedger("")
+	now := time.Now()
+
+	require.NoError(t, l.Add(Entry{Time: now.Add(-48 * time.Hour), Type: EntryContractStart, Role: RoleBuyer, ContractID: "0xc1", Counterparty: &testOther, Token: &testToken, Price: big.NewInt(100), TxHash: common.HexToHash("0x01")}))
+	require.NoError(t, l.Add(Entry{Time: now.Add(-time.Hour), Type: EntryContractStart, Role: RoleBuyer, ContractID: "0xc2", Counterparty: &testOther, Token: &testToken, Price: big.NewInt(200), Fee: big.NewInt(20), FeeToken: &testToken, TxHash: common.HexToHash("0x02")}))
+	// sales of the wallet are not spent
+	require.NoError(t, l.Add(Entry{Time: now.Add(-time.Hour), Type: EntryContractStart, Role: RoleSeller, ContractID: "0xc3", Counterparty: &testOther, Token: &testToken, Price: big.NewInt(400), TxHash: common.HexToHash("0x03")}))
+
+	// the validator fee is spent too
+	require.Equal(t, int64(220), l.GetSpent(now.Add(-24 * time.Hour))[testToken].Int64())
+	require.Equal(t, int64(320), l.GetSpent(time.Time{})[testToken].Int64())
+
+	rate, purchases := l.GetSellerFailureRate(testOther)
+	require.Equal(t, 0.0, rate)
+	require.Equal(t, 2, purchases)
+
+	// closeout refunding several tokens is counted once
+	closeout := Entry{Time: now, Type: EntryCloseout, ContractID: "0xc2", Reason: "underdelivery", Blame: RoleSeller, TxHash: common.HexToHash("0x04")}
+	for _, token := range []common.Address{testToken, testWallet} {
+		e := closeout
+		e.Token = &token
+		e.Amount = big.NewInt(10)
+		require.NoError(t, l.Add(e))
+	}
+	rate, purchases = l.GetSellerFailureRate(testOther)
+	require.Equal(t, 0.5, rate)
+	require.Equal(t, 2, purchases)
+
+	_, purchases = l.GetSellerFailureRate(testWallet)
+	require.Equal(t, 0, purchases)
+}
+
+func TestRemoveTx(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	l := NewLedger(path)
+	now := time.Unix(1700000000, 0).UTC()
+
+	start := Entry{Time: now, Type: EntryContractStart, Role: RoleSeller, ContractID: "0xc1", Price: big.NewInt(100), TxHash: common.HexToHash("0x01")}
+	payout := Entry{Time: now.Add(time.Hour), Type: EntryPayout, Role: RoleSeller, ContractID: "0xc1", Token: &testToken, Amount: big.NewInt(100), TxHash: common.HexToHash("0x02")}
+	require.NoError(t, l.Add(start))
+	require.NoError(t, l.Add(payout))
+
+	removed, err := l.RemoveTx(common.HexToHash("0x02"))
+	require.NoError(t, err)
+	require.Equal(t, 1, removed)
+	require.Equal(t, []Entry{start}, l.GetEntries(time.Time{}, time.Time{}))
+
+	loaded := NewLedger(path)
+	require.NoError(t, loaded.Load())
+	require.Equal(t, []Entry{start}, loaded.GetEntries(time.Time{}, time.Time{}))
+
+	// the transaction included again after the reorg is recorded again
+	require.NoError(t, l.Add(payout))
+	require.Len(t, l.GetEntries(time.Time{}, time.Time{}), 2)
+}
+
+func TestRemoveTxKeepsOtherProcessEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	router := NewLedger(path)
+	now := time.Unix(1700000000, 0).UTC()
+
+	start := Entry{Time: now, Type: EntryContractStart, Role: RoleSeller, ContractID: "0xc1", Price: big.NewInt(100), TxHash: common.HexToHash("0x01")}
+	require.NoError(t, router.Add(start))
+
+	// the offline cli appends to the same file after the router loaded it
+	cli := NewLedger(path)
+	require.NoError(t, cli.Load())
+	closeout := Entry{Time: now.Add(time.Hour), Type: EntryCloseout, Role: RoleBuyer, ContractID: "0xc2", TxHash: common.HexToHash("0x03")}
+	require.NoError(t, cli.Add(closeout))
+
+	removed, err := router.RemoveTx(common.HexToHash("0x01"))
+	require.NoError(t, err)
+	require.Equal(t, 1, removed)
+	require.Equal(t, []Entry{closeout}, router.GetEntries(time.Time{}, time.Time{}))
+
+	loaded := NewLedger(path)
+	require.NoError(t, loaded.Load())
+	require.Equal(t, []Entry{closeout}, loaded.GetEntries(time.Time{}, time.Time{}))
+}
@@ -0,0 +1,80 @@
+package ledger
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// Transfer is the ERC20 token transfer decoded from the transaction receipt
+type Transfer struct {
+	Token  common.Address
+	From   common.Address
+	To     common.Address
+	Amount *big.Int
+}
+
+// GetTransfersTo returns the ERC20 transfers to the address in the receipt, summed by token
+func GetTransfersTo(receipt *types.Receipt, to common.Address) []Transfer {
+	if receipt == nil {
+		return nil
+	}
+
+	var res []Transfer
+	for _, log := range receipt.Logs {
+		if len(log.Topics) != 3 || log.Topics[0] != transferTopic || len(log.Data) != 32 {
+			continue
+		}
+		recipient := common.BytesToAddress(log.Topics[2].Bytes())
+		if recipient != to {
+			continue
+		}
+
+		amount := new(big.Int).SetBytes(log.Data)
+		found := false
+		for i := range res {
+			if res[i].Token == log.Address {
+				res[i].Amount.Add(res[i].Amount, amount)
+				found = true
+				break
+			}
+		}
+		if !found {
+			res = append(res, Transfer{
+				Token:  log.Address,
+				From:   common.BytesToAddress(log.Topics[1].Bytes()),
+				To:     recipient,
+				Amount: amount,
+			})
+		}
+	}
+	return res
+}
+
+// AddFromReceipt records the entry for each token received by the wallet in the transaction,
+// or a single entry without amount if nothing was received
+func (l *Ledger) AddFromReceipt(entry Entry, receipt *types.Receipt, wallet common.Address) error {
+	if receipt != nil {
+		entry.TxHash = receipt.TxHash
+	}
+
+	transfers := GetTransfersTo(receipt, wallet)
+	if len(transfers) == 0 {
+		return l.Add(entry)
+	}
+
+	for _, t := range transfers {
+		e := entry
+		e.Token = &t.Token
+		e.Amount = t.Amount
+		err := l.Add(e)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package ledger
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type Period string
+
+const (
+	PeriodDay   Period = "day"
+	PeriodWeek  Period = "week"
+	PeriodMonth Period = "month"
+)
+
+func ParsePeriod(s string) (Period, error) {
+	switch Period(s) {
+	case PeriodDay, PeriodWeek, PeriodMonth:
+		return Period(s), nil
+	}
+	return "", ErrInvalidPeriod
+}
+
+// Start returns the start of the period containing t in UTC, weeks start on Monday
+func (p Period) Start(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch p {
+	case PeriodWeek:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case PeriodMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// Summary is the totals of the entries of a single period
+type Summary struct {
+	PeriodStart    time.Time
+	ContractStarts int
+	Closeouts      int
+	RewardClaims   int
+	ValidatorFees  int
+	Sales          map[common.Address]*big.Int // sum of the prices of the contracts sold by the wallet, by payment token
+	Spent          map[common.Address]*big.Int // prices and validator fees of the contracts purchased by the wallet, by token
+	Received       map[common.Address]*big.Int // tokens received by the wallet, by token
+}
+
+// Tokens returns the tokens of the sales, spent and received amounts
+func (s *Summary) Tokens() []common.Address {
+	seen := make(map[common.Address]struct{})
+	var tokens []common.Address
+	for _, totals := range []map[common.Address]*big.Int{s.Sales, s.Spent, s.Received} {
+		for token := range totals {
+			if _, ok := seen[token]; !ok {
+				seen[token] = struct{}{}
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].Cmp(tokens[j]) < 0
+	})
+	return tokens
+}
+
+func (s *Summary) GetSales(token common.Address) *big.Int {
+	return orZero(s.Sales[token])
+}
+
+func (s *Summary) GetSpent(token common.Address) *big.Int {
+	return orZero(s.Spent[token])
+}
+
+func (s *Summary) GetReceived(token common.Address) *big.Int {
+	return orZero(s.Received[token])
+}
+
+// Summarize groups the entries by period, oldest period first
+func Summarize(entries []Entry, period Period) []*Summary {
+	byStart := make(map[time.Time]*Summary)
+	for _, e := range entries {
+		start := period.Start(e.Time)
+		s, ok := byStart[start]
+		if !ok {
+			s = &Summary{
+				PeriodStart: start,
+				Sales:       make(map[common.Address]*big.Int),
+				Spent:       make(map[common.Address]*big.Int),
+				Received:    make(map[common.Address]*big.Int),
+			}
+			byStart[start] = s
+		}
+
+		switch e.Type {
+		case EntryContractStart:
+			s.ContractStarts++
+			switch {
+			case e.Role == RoleSeller && e.Price != nil:
+				addTo(s.Sales, e.Token, e.Price)
+			case e.Role == RoleBuyer:
+				if e.Price != nil {
+					addTo(s.Spent, e.Token, e.Price)
+				}
+				if e.Fee != nil && e.FeeToken != nil {
+					addTo(s.Spent, e.FeeToken, e.Fee)
+				}
+			}
+		case EntryCloseout:
+			s.Closeouts++
+		case EntryRewardClaim:
+			s.RewardClaims++
+		case EntryValidatorFee:
+			s.ValidatorFees++
+		}
+		if e.Amount != nil && e.Token != nil {
+			addTo(s.Received, e.Token, e.Amount)
+		}
+	}
+
+	res := make([]*Summary, 0, len(byStart))
+	for _, s := range byStart {
+		res = append(res, s)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].PeriodStart.Before(res[j].PeriodStart)
+	})
+	return res
+}
+
+func addTo(totals map[common.Address]*big.Int, token *common.Address, amount *big.Int) {
+	var key common.Address
+	if token != nil {
+		key = *token
+	}
+	if totals[key] == nil {
+		totals[key] = new(big.Int)
+	}
+	totals[key].Add(totals[key], amount)
+}
+
+func orZero(v *big.Int) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v
+}
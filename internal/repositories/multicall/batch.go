@@ -2,10 +2,13 @@ package multicall
 
 import (
 	"context"
+	"fmt"
+	"sync/atomic"
 
 	"github.com/Lumerin-protocol/contracts-go/v3/multicall3"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"golang.org/x/sync/errgroup"
 )
 
 // Batch executes multiple calls to the same method on the same contract in a single multicall and converts the results to the specified type
@@ -42,3 +45,77 @@ func Batch[T any](ctx context.Context, mc MulticallBackend, contractABI *abi.ABI
 
 	return sessions, nil
 }
+
+// Call is a single contract call of the chunked batch
+type Call struct {
+	Target common.Address
+	ABI    *abi.ABI
+	Method string
+	Args   []interface{}
+}
+
+// Result is the unpacked output of the call, Err is set if the call reverted or its output couldn't be unpacked
+type Result struct {
+	Values []interface{}
+	Err    error
+}
+
+// BatchChunked executes calls to arbitrary contracts and methods splitting them into multicalls of at most
+// chunkSize calls, up to concurrency multicalls are executed in parallel. A failed call doesn't fail the batch,
+// its error is returned in the corresponding result. onProgress is called after each multicall, can be nil
+func BatchChunked(ctx context.Context, mc MulticallBackend, calls []Call, chunkSize int, concurrency int, onProgress func(done, total int)) ([]Result, error) {
+	results := make([]Result, len(calls))
+	done := atomic.Int32{}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+
+	for start := 0; start < len(calls); start += chunkSize {
+		end := min(start+chunkSize, len(calls))
+
+		g.Go(func() error {
+			chunk := make([]multicall3.Multicall3Call3, end-start)
+			for i, call := range calls[start:end] {
+				calldata, err := call.ABI.Pack(call.Method, call.Args...)
+				if err != nil {
+					return err
+				}
+				chunk[i] = multicall3.Multicall3Call3{
+					Target:       call.Target,
+					AllowFailure: true,
+					CallData:     calldata,
+				}
+			}
+
+			res, err := mc.Aggregate3(ctx, chunk)
+			if err != nil {
+				return err
+			}
+			if len(res) != len(chunk) {
+				return fmt.Errorf("multicall returned %d results for %d calls", len(res), len(chunk))
+			}
+
+			for i, r := range res {
+				call := calls[start+i]
+				if !r.Success {
+					results[start+i] = Result{Err: fmt.Errorf("call %s to %s reverted", call.Method, call.Target.Hex())}
+					continue
+				}
+				values, err := call.ABI.Unpack(call.Method, r.ReturnData)
+				results[start+i] = Result{Values: values, Err: err}
+			}
+
+			total := done.Add(int32(len(chunk)))
+			if onProgress != nil {
+				onProgress(int(total), len(calls))
+			}
+			return nil
+		})
+	}
+
+	err := g.Wait()
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,75 @@
+package multicall
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Lumerin-protocol/contracts-go/v3/multicall3"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+const testABI = `[{"type":"function","name":"value","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]}]`
+
+// fakeMulticall returns the last byte of the target address as the value, zero address call reverts
+type fakeMulticall struct {
+	MulticallBackend
+	abi       *abi.ABI
+	chunkLens []int
+	mutex     sync.Mutex
+}
+
+func (m *fakeMulticall) Aggregate3(ctx context.Context, calls []multicall3.Multicall3Call3) ([]multicall3.Multicall3Result, error) {
+	m.mutex.Lock()
+	m.chunkLens = append(m.chunkLens, len(calls))
+	m.mutex.Unlock()
+
+	res := make([]multicall3.Multicall3Result, len(calls))
+	for i, call := range calls {
+		if call.Target == (common.Address{}) {
+			continue
+		}
+		data, err := m.abi.Methods["value"].Outputs.Pack(big.NewInt(int64(call.Target[19])))
+		if err != nil {
+			return nil, err
+		}
+		res[i] = multicall3.Multicall3Result{Success: true, ReturnData: data}
+	}
+	return res, nil
+}
+
+func TestBatchChunked(t *testing.T) {
+	contractABI, err := abi.JSON(strings.NewReader(testABI))
+	require.NoError(t, err)
+
+	mc := &fakeMulticall{abi: &contractABI}
+
+	calls := make([]Call, 7)
+	for i := range calls {
+		calls[i] = Call{Target: common.BytesToAddress([]byte{byte(i)}), ABI: &contractABI, Method: "value"}
+	}
+
+	var progress []int
+	var progressMutex sync.Mutex
+	results, err := BatchChunked(context.Background(), mc, calls, 3, 2, func(done, total int) {
+		progressMutex.Lock()
+		defer progressMutex.Unlock()
+		require.Equal(t, 7, total)
+		progress = append(progress, done)
+	})
+	require.NoError(t, err)
+
+	require.ElementsMatch(t, []int{3, 3, 1}, mc.chunkLens)
+	require.Len(t, progress, 3)
+	require.Contains(t, progress, 7)
+
+	require.Error(t, results[0].Err)
+	for i := 1; i < len(results); i++ {
+		require.NoError(t, results[i].Err)
+		require.Equal(t, int64(i), results[i].Values[0].(*big.Int).Int64())
+	}
+}
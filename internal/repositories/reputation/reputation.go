@@ -0,0 +1,370 @@
+package reputation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/ledger"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	ErrSync = errors.New("reputation sync error")
+)
+
+// ChainHistory is the source of the on-chain delivery history of the hashrate contracts
+type ChainHistory interface {
+	GetContractsIDs(ctx context.Context) ([]string, error)
+	GetContractsStats(ctx context.Context, contractIDs []string) ([]contracts.ContractStats, error)
+	GetClosedEarlyEvents(ctx context.Context, contractIDs []string, from, to uint64) ([]contracts.ClosedEarlyEvent, error)
+	GetLatestBlock(ctx context.Context) (uint64, error)
+}
+
+// Closeout is the early closeout of the seller contract found in the logs
+type Closeout struct {
+	ContractID  string
+	Seller      common.Address
+	Reason      string
+	Blame       string `json:",omitempty"` // party responsible for the closeout
+	BlockNumber uint64
+	TxHash      common.Hash
+}
+
+// Validation is the verdict of the buyer validation run by this node
+type Validation struct {
+	Time       time.Time
+	ContractID string
+	Seller     common.Address
+	OK         bool    // contract was delivered till the end
+	Reason     string  `json:",omitempty"` // close reason if not ok
+	Blame      string  `json:",omitempty"`
+	Accuracy   float64 // share of the purchased hashrate delivered during the last check, capped at 1
+}
+
+// Seller is the reputation of the seller address
+type Seller struct {
+	Address            common.Address
+	Contracts          int            // contracts listed by the seller
+	SuccessCount       uint64         // purchases delivered till the end, on chain counter
+	FailCount          uint64         // purchases closed early for any reason, on chain counter
+	SuccessRate        float64        // SuccessCount / (SuccessCount + FailCount)
+	CloseReasons       map[string]int // early closeouts found in the logs by reason
+	SellerBlamedCloses int            // early closeouts found in the logs with the seller to blame
+	Validations        int            // contracts of the seller validated by this node
+	ValidationFailures int            // validations that ended with the closeout with the seller to blame
+	AvgAccuracy        float64        // average accuracy of the validated contracts
+
+	buyerBlamedCloses int // early closeouts found in the logs with the buyer to blame
+}
+
+// snapshot is the persisted state of the store
+type snapshot struct {
+	LastBlock   uint64
+	Stats       []contracts.ContractStats
+	Closeouts   []Closeout
+	Validations []Validation
+}
+
+// Store keeps the delivery history of the sellers collected from the chain and from the own buyer validation,
+// the state is saved to the json file if the path is set
+type Store struct {
+	// config
+	path          string
+	syncInterval  time.Duration
+	historyBlocks uint64 // number of the blocks searched for the closeouts on the first sync
+	confirmations uint64 // number of the blocks the closeouts are read behind the head, so they are not reverted by reorg
+
+	// state
+	lastBlock   uint64
+	stats       map[string]contracts.ContractStats // by contract id
+	closeouts   map[common.Hash]Closeout           // by tx hash
+	validations []Validation
+	mutex       sync.RWMutex
+	fileMutex   sync.Mutex
+
+	// deps
+	chain ChainHistory
+	log   interfaces.ILogger
+}
+
+func NewStore(path string, syncInterval time.Duration, historyBlocks uint64, confirmations uint64, chain ChainHistory, log interfaces.ILogger) *Store {
+	return &Store{
+		path:          path,
+		syncInterval:  syncInterval,
+		historyBlocks: historyBlocks,
+		confirmations: confirmations,
+		stats:         make(map[string]contracts.ContractStats),
+		closeouts:     make(map[common.Hash]Closeout),
+		chain:         chain,
+		log:           log,
+	}
+}
+
+// Load reads the state from the file, missing file is treated as empty store
+func (s *Store) Load() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var snap snapshot
+	err = json.Unmarshal(data, &snap)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.lastBlock = snap.LastBlock
+	for _, st := range snap.Stats {
+		s.stats[st.ContractID] = st
+	}
+	for _, c := range snap.Closeouts {
+		s.closeouts[c.TxHash] = c
+	}
+	s.validations = snap.Validations
+	return nil
+}
+
+// Run syncs the on-chain history periodically, the sync errors are logged and retried on the next tick
+func (s *Store) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		err := s.Sync(ctx)
+		if err != nil {
+			s.log.Warnf("%s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync refreshes the on-chain counters of all contracts and reads the closeouts since the last synced block
+func (s *Store) Sync(ctx context.Context) error {
+	ids, err := s.chain.GetContractsIDs(ctx)
+	if err != nil {
+		return lib.WrapError(ErrSync, err)
+	}
+	stats, err := s.chain.GetContractsStats(ctx, ids)
+	if err != nil {
+		return lib.WrapError(ErrSync, err)
+	}
+	head, err := s.chain.GetLatestBlock(ctx)
+	if err != nil {
+		return lib.WrapError(ErrSync, err)
+	}
+	var latest uint64 // latest block with enough confirmations
+	if head > s.confirmations {
+		latest = head - s.confirmations
+	}
+
+	s.mutex.RLock()
+	from := s.lastBlock + 1
+	s.mutex.RUnlock()
+	if from == 1 && latest > s.historyBlocks {
+		from = latest - s.historyBlocks
+	}
+
+	var events []contracts.ClosedEarlyEvent
+	if from <= latest {
+		events, err = s.chain.GetClosedEarlyEvents(ctx, ids, from, latest)
+		if err != nil {
+			return lib.WrapError(ErrSync, err)
+		}
+	}
+
+	s.mutex.Lock()
+	for _, st := range stats {
+		s.stats[st.ContractID] = st
+	}
+	for _, e := range events {
+		if _, ok := s.stats[e.ContractID]; !ok {
+			continue // seller is unknown
+		}
+		s.closeouts[e.TxHash] = Closeout{
+			ContractID:  e.ContractID,
+			Seller:      s.stats[e.ContractID].Seller,
+			Reason:      e.Reason.String(),
+			Blame:       e.Reason.Blame(),
+			BlockNumber: e.BlockNumber,
+			TxHash:      e.TxHash,
+		}
+	}
+	if from <= latest {
+		s.lastBlock = latest
+	}
+	s.mutex.Unlock()
+
+	s.log.Infof("synced reputation of %d contracts, %d new closeouts up to block %d", len(stats), len(events), latest)
+	return s.save()
+}
+
+// RecordValidation records the verdict of the buyer validation of the contract. The reason is ignored if ok
+func (s *Store) RecordValidation(contractID string, seller common.Address, ok bool, reason contracts.CloseReason, accuracy float64) {
+	v := Validation{
+		Time:       time.Now(),
+		ContractID: contractID,
+		Seller:     seller,
+		OK:         ok,
+		Accuracy:   min(max(accuracy, 0), 1),
+	}
+	if !ok {
+		v.Reason, v.Blame = reason.String(), reason.Blame()
+	}
+
+	s.mutex.Lock()
+	s.validations = append(s.validations, v)
+	s.mutex.Unlock()
+
+	err := s.save()
+	if err != nil {
+		s.log.Errorf("can't save reputation: %s", err)
+	}
+}
+
+// GetSellers returns the reputation of all known sellers sorted by the address
+func (s *Store) GetSellers() []Seller {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	sellers := make(map[common.Address]*Seller)
+	get := func(addr common.Address) *Seller {
+		seller, ok := sellers[addr]
+		if !ok {
+			seller = &Seller{Address: addr, CloseReasons: make(map[string]int)}
+			sellers[addr] = seller
+		}
+		return seller
+	}
+
+	for _, st := range s.stats {
+		seller := get(st.Seller)
+		seller.Contracts++
+		seller.SuccessCount += st.SuccessCount
+		seller.FailCount += st.FailCount
+	}
+	for _, c := range s.closeouts {
+		seller := get(c.Seller)
+		seller.CloseReasons[c.Reason]++
+		switch c.Blame {
+		case ledger.RoleSeller:
+			seller.SellerBlamedCloses++
+		case ledger.RoleBuyer:
+			seller.buyerBlamedCloses++
+		}
+	}
+	accuracySum := make(map[common.Address]float64)
+	for _, v := range s.validations {
+		seller := get(v.Seller)
+		seller.Validations++
+		if !v.OK && v.Blame == ledger.RoleSeller {
+			seller.ValidationFailures++
+		}
+		accuracySum[v.Seller] += v.Accuracy
+	}
+
+	res := make([]Seller, 0, len(sellers))
+	for _, seller := range sellers {
+		if total := seller.SuccessCount + seller.FailCount; total > 0 {
+			seller.SuccessRate = float64(seller.SuccessCount) / float64(total)
+		}
+		if seller.Validations > 0 {
+			seller.AvgAccuracy = accuracySum[seller.Address] / float64(seller.Validations)
+		}
+		res = append(res, *seller)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Address.Cmp(res[j].Address) < 0
+	})
+	return res
+}
+
+// GetSeller returns the reputation of the seller, false if nothing is known about it
+func (s *Store) GetSeller(addr common.Address) (Seller, bool) {
+	for _, seller := range s.GetSellers() {
+		if seller.Address == addr {
+			return seller, true
+		}
+	}
+	return Seller{}, false
+}
+
+// GetSellerFailureRate returns the share of the purchases of the seller contracts closed early and the number of
+// the purchases it is based on. The on-chain counters don't tell who is to blame, so the closeouts found in the logs
+// with the buyer to blame are not counted as failures. If there are no on-chain purchases, own validations are used
+func (s *Store) GetSellerFailureRate(addr common.Address) (rate float64, samples int) {
+	seller, ok := s.GetSeller(addr)
+	if !ok {
+		return 0, 0
+	}
+
+	if total := seller.SuccessCount + seller.FailCount; total > 0 {
+		failures := seller.FailCount - min(uint64(seller.buyerBlamedCloses), seller.FailCount)
+		return float64(failures) / float64(total), int(total)
+	}
+	if seller.Validations > 0 {
+		return float64(seller.ValidationFailures) / float64(seller.Validations), seller.Validations
+	}
+	return 0, 0
+}
+
+// save writes the state to a temporary file and renames it, so the file is never left partially written
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	s.fileMutex.Lock()
+	defer s.fileMutex.Unlock()
+
+	s.mutex.RLock()
+	snap := snapshot{
+		LastBlock:   s.lastBlock,
+		Validations: s.validations,
+	}
+	for _, st := range s.stats {
+		snap.Stats = append(snap.Stats, st)
+	}
+	for _, c := range s.closeouts {
+		snap.Closeouts = append(snap.Closeouts, c)
+	}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	s.mutex.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(s.path), 0o755)
+	if err != nil {
+		return err
+	}
+	tmpPath := s.path + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0o644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
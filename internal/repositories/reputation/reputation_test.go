@@ -0,0 +1,161 @@
+package reputation
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testSeller1   = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	testSeller2   = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	testContract1 = common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Hex()
+	testContract2 = common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb").Hex()
+	testContract3 = common.HexToAddress("0xcccccccccccccccccccccccccccccccccccccccc").Hex()
+)
+
+type fakeChain struct {
+	stats     []contracts.ContractStats
+	events    []contracts.ClosedEarlyEvent
+	latest    uint64
+	requested [][2]uint64
+}
+
+func (c *fakeChain) GetContractsIDs(ctx context.Context) ([]string, error) {
+	ids := make([]string, len(c.stats))
+	for i, st := range c.stats {
+		ids[i] = st.ContractID
+	}
+	return ids, nil
+}
+
+func (c *fakeChain) GetContractsStats(ctx context.Context, contractIDs []string) ([]contracts.ContractStats, error) {
+	return c.stats, nil
+}
+
+func (c *fakeChain) GetClosedEarlyEvents(ctx context.Context, contractIDs []string, from, to uint64) ([]contracts.ClosedEarlyEvent, error) {
+	c.requested = append(c.requested, [2]uint64{from, to})
+	var res []contracts.ClosedEarlyEvent
+	for _, e := range c.events {
+		if e.BlockNumber >= from && e.BlockNumber <= to {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+func (c *fakeChain) GetLatestBlock(ctx context.Context) (uint64, error) {
+	return c.latest, nil
+}
+
+func TestSyncAggregatesSellers(t *testing.T) {
+	chain := &fakeChain{
+		stats: []contracts.ContractStats{
+			{ContractID: testContract1, Seller: testSeller1, SuccessCount: 5, FailCount: 3},
+			{ContractID: testContract2, Seller: testSeller1, SuccessCount: 2, FailCount: 0},
+			{ContractID: testContract3, Seller: testSeller2, SuccessCount: 1, FailCount: 1},
+		},
+		events: []contracts.ClosedEarlyEvent{
+			{ContractID: testContract1, Reason: contracts.CloseReasonUnderdelivery, BlockNumber: 950, TxHash: common.HexToHash("0x01")},
+			{ContractID: testContract1, Reason: contracts.CloseReasonDestinationUnavailable, BlockNumber: 960, TxHash: common.HexToHash("0x02")},
+			{ContractID: testContract3, Reason: contracts.CloseReasonShareTimeout, BlockNumber: 1050, TxHash: common.HexToHash("0x03")},
+			{ContractID: testContract1, Reason: contracts.CloseReasonUnderdelivery, BlockNumber: 100, TxHash: common.HexToHash("0x04")}, // before history
+		},
+		latest: 1000,
+	}
+	s := NewStore("", 0, 100, 0, chain, lib.NewTestLogger())
+
+	require.NoError(t, s.Sync(context.Background()))
+	require.Equal(t, [2]uint64{900, 1000}, chain.requested[0])
+
+	seller, ok := s.GetSeller(testSeller1)
+	require.True(t, ok)
+	require.Equal(t, 2, seller.Contracts)
+	require.Equal(t, uint64(7), seller.SuccessCount)
+	require.Equal(t, uint64(3), seller.FailCount)
+	require.InDelta(t, 0.7, seller.SuccessRate, 1e-9)
+	require.Equal(t, map[string]int{"underdelivery": 1, "destination unavailable": 1}, seller.CloseReasons)
+	require.Equal(t, 1, seller.SellerBlamedCloses)
+
+	// the closeout blamed on the buyer is not a failure of the seller
+	rate, samples := s.GetSellerFailureRate(testSeller1)
+	require.Equal(t, 10, samples)
+	require.InDelta(t, 0.2, rate, 1e-9)
+
+	// next sync continues from the last synced block
+	chain.latest = 1100
+	require.NoError(t, s.Sync(context.Background()))
+	require.Equal(t, [2]uint64{1001, 1100}, chain.requested[1])
+
+	seller, ok = s.GetSeller(testSeller2)
+	require.True(t, ok)
+	require.Equal(t, map[string]int{"share timeout": 1}, seller.CloseReasons)
+}
+
+func TestRecordValidationPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reputation.json")
+	chain := &fakeChain{
+		stats:  []contracts.ContractStats{{ContractID: testContract1, Seller: testSeller1, SuccessCount: 1}},
+		latest: 10,
+	}
+	s := NewStore(path, 0, 100, 0, chain, lib.NewTestLogger())
+	require.NoError(t, s.Sync(context.Background()))
+
+	s.RecordValidation(testContract1, testSeller2, true, contracts.CloseReasonUnspecified, 1.2)
+	s.RecordValidation(testContract2, testSeller2, false, contracts.CloseReasonUnderdelivery, 0.6)
+	s.RecordValidation(testContract3, testSeller2, false, contracts.CloseReasonDestinationUnavailable, 0.7)
+
+	loaded := NewStore(path, 0, 100, 0, chain, lib.NewTestLogger())
+	require.NoError(t, loaded.Load())
+
+	seller, ok := loaded.GetSeller(testSeller2)
+	require.True(t, ok)
+	require.Equal(t, 3, seller.Validations)
+	require.Equal(t, 1, seller.ValidationFailures)
+	require.InDelta(t, (1+0.6+0.7)/3, seller.AvgAccuracy, 1e-9)
+
+	// no on-chain purchases, own validations are used
+	rate, samples := loaded.GetSellerFailureRate(testSeller2)
+	require.Equal(t, 3, samples)
+	require.InDelta(t, 1.0/3, rate, 1e-9)
+
+	_, ok = loaded.GetSeller(testSeller1)
+	require.True(t, ok)
+
+	// the synced block is kept, only the new blocks are searched
+	chain.latest = 20
+	require.NoError(t, loaded.Sync(context.Background()))
+	require.Equal(t, [2]uint64{11, 20}, chain.requested[len(chain.requested)-1])
+}
+
+func TestSyncLagsBehindHead(t *testing.T) {
+	chain := &fakeChain{
+		stats: []contracts.ContractStats{{ContractID: testContract1, Seller: testSeller1, SuccessCount: 1, FailCount: 1}},
+		events: []contracts.ClosedEarlyEvent{
+			{ContractID: testContract1, Reason: contracts.CloseReasonUnderdelivery, BlockNumber: 995, TxHash: common.HexToHash("0x01")},
+		},
+		latest: 1000,
+	}
+	s := NewStore("", 0, 100, 10, chain, lib.NewTestLogger())
+
+	// the closeout in the unconfirmed blocks is not read yet
+	require.NoError(t, s.Sync(context.Background()))
+	require.Equal(t, [2]uint64{890, 990}, chain.requested[0])
+	seller, _ := s.GetSeller(testSeller1)
+	require.Empty(t, seller.CloseReasons)
+
+	// no new confirmed blocks, the synced block is kept
+	require.NoError(t, s.Sync(context.Background()))
+	require.Len(t, chain.requested, 1)
+
+	chain.latest = 1010
+	require.NoError(t, s.Sync(context.Background()))
+	require.Equal(t, [2]uint64{991, 1000}, chain.requested[1])
+	seller, _ = s.GetSeller(testSeller1)
+	require.Equal(t, map[string]int{"underdelivery": 1}, seller.CloseReasons)
+}
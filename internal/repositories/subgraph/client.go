@@ -2,58 +2,165 @@ package subgraph
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strconv"
 	"time"
 
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
 	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/shurcooL/graphql"
 )
 
+const (
+	PageSize            = 1000 // maximum number of entities the subgraph returns in a single query
+	firstPageCursor     = "0x" // lower than any id, the ids are hex encoded
+	DefaultMaxRetries   = 3
+	DefaultRetryBackoff = time.Second // delay before the first retry, doubled for each next one
+)
+
+var (
+	ErrSubgraph       = errors.New("subgraph request failed")
+	ErrIndexingErrors = errors.New("subgraph has indexing errors")
+)
+
+// Positions is the result of the positions query with the block it was consistently read at
+type Positions struct {
+	Contracts      []contracts.FuturesContract
+	BlockNumber    uint64
+	BlockTimestamp time.Time
+}
+
 type SubgraphClient struct {
+	// config
+	maxRetries   int
+	retryBackoff time.Duration
+
+	// deps
 	client *graphql.Client
+	log    interfaces.ILogger
 }
 
-func NewClient(subgraphURL string) *SubgraphClient {
+func NewClient(subgraphURL string, maxRetries int, retryBackoff time.Duration, log interfaces.ILogger) *SubgraphClient {
 	client := graphql.NewClient(subgraphURL, nil)
 	return &SubgraphClient{
-		client: client,
+		maxRetries:   maxRetries,
+		retryBackoff: retryBackoff,
+		client:       client,
+		log:          log,
 	}
 }
 
-func (c *SubgraphClient) GetAllPositions(ctx context.Context, deliveryAt time.Time) ([]contracts.FuturesContract, error) {
-	var query struct {
-		Positions []Position `graphql:"positions(where: {deliveryAt: $deliveryAt, closedAt: null})"`
-	}
+func (c *SubgraphClient) GetAllPositions(ctx context.Context, deliveryAt time.Time) (*Positions, error) {
+	return c.getPositions(ctx, func(ctx context.Context, block graphql.Int, lastID graphql.String) ([]Position, error) {
+		var query struct {
+			Positions []Position `graphql:"positions(block: {number: $block}, first: $first, orderBy: id, where: {id_gt: $lastID, deliveryAt: $deliveryAt, closedAt: null})"`
+		}
 
-	var variables = map[string]any{
-		"deliveryAt": graphql.Int(deliveryAt.Unix()),
-	}
+		var variables = map[string]any{
+			"block":      block,
+			"first":      graphql.Int(PageSize),
+			"lastID":     lastID,
+			"deliveryAt": graphql.Int(deliveryAt.Unix()),
+		}
 
-	err := c.client.Query(ctx, &query, variables)
-	if err != nil {
-		return nil, err
-	}
+		err := c.query(ctx, &query, variables)
+		return query.Positions, err
+	})
+}
 
-	return mapPositionsToContracts(query.Positions), nil
+func (c *SubgraphClient) GetPositionsBySeller(ctx context.Context, sellerAddr common.Address, deliveryAt time.Time) (*Positions, error) {
+	return c.getPositions(ctx, func(ctx context.Context, block graphql.Int, lastID graphql.String) ([]Position, error) {
+		var query struct {
+			Positions []Position `graphql:"positions(block: {number: $block}, first: $first, orderBy: id, where: {id_gt: $lastID, seller_: {address: $sellerAddr}, deliveryAt: $deliveryAt, closedAt: null})"`
+		}
+
+		var variables = map[string]any{
+			"block":      block,
+			"first":      graphql.Int(PageSize),
+			"lastID":     lastID,
+			"deliveryAt": graphql.Int(deliveryAt.Unix()),
+			"sellerAddr": graphql.String(sellerAddr.Hex()),
+		}
+
+		err := c.query(ctx, &query, variables)
+		return query.Positions, err
+	})
 }
 
-func (c *SubgraphClient) GetPositionsBySeller(ctx context.Context, sellerAddr common.Address, deliveryAt time.Time) ([]contracts.FuturesContract, error) {
+// GetMeta returns the last indexed block of the subgraph
+func (c *SubgraphClient) GetMeta(ctx context.Context) (*Meta, error) {
 	var query struct {
-		Positions []Position `graphql:"positions(where: {seller_: {address: $sellerAddr}, deliveryAt: $deliveryAt, closedAt: null})"`
+		Meta Meta `graphql:"_meta"`
 	}
-
-	var variables = map[string]any{
-		"deliveryAt": graphql.Int(deliveryAt.Unix()),
-		"sellerAddr": graphql.String(sellerAddr.Hex()),
+	err := c.query(ctx, &query, nil)
+	if err != nil {
+		return nil, err
+	}
+	if query.Meta.HasIndexingErrors {
+		return nil, ErrIndexingErrors
 	}
+	return &query.Meta, nil
+}
 
-	err := c.client.Query(ctx, &query, variables)
+type pageFn func(ctx context.Context, block graphql.Int, lastID graphql.String) ([]Position, error)
+
+// getPositions reads all pages at the last indexed block, so the positions are not shifted between the pages.
+// The pages are read by the id cursor, the subgraph rejects skip above 5000
+func (c *SubgraphClient) getPositions(ctx context.Context, page pageFn) (*Positions, error) {
+	meta, err := c.GetMeta(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return mapPositionsToContracts(query.Positions), nil
+	var positions []Position
+	lastID := firstPageCursor
+	for {
+		res, err := page(ctx, graphql.Int(meta.Block.Number), graphql.String(lastID))
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, res...)
+		if len(res) < PageSize {
+			break
+		}
+		lastID = res[len(res)-1].ID
+	}
+
+	return &Positions{
+		Contracts:      mapPositionsToContracts(positions),
+		BlockNumber:    uint64(meta.Block.Number),
+		BlockTimestamp: time.Unix(int64(meta.Block.Timestamp), 0),
+	}, nil
+}
+
+// query runs the query retrying with exponential backoff
+func (c *SubgraphClient) query(ctx context.Context, q any, variables map[string]any) error {
+	backoff := c.retryBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = c.client.Query(ctx, q, variables)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if attempt >= c.maxRetries {
+			break
+		}
+		c.log.Warnf("subgraph request failed, retrying in %s: %s", backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return lib.WrapError(ErrSubgraph, fmt.Errorf("%d attempts: %w", c.maxRetries+1, err))
 }
 
 func mapPositionsToContracts(positions []Position) []contracts.FuturesContract {
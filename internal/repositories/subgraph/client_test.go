@@ -2,16 +2,23 @@ package subgraph
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/require"
 )
 
 func TestGetAllPositions(t *testing.T) {
-	client := NewClient("https://api.studio.thegraph.com/query/1724245/lumerin-dev-futures/version/latest")
+	client := NewClient("https://api.studio.thegraph.com/query/1724245/lumerin-dev-futures/version/latest", DefaultMaxRetries, DefaultRetryBackoff, lib.NewTestLogger())
 	deliveryAt := time.Unix(1763679600, 0)
 	positions, err := client.GetAllPositions(context.Background(), deliveryAt)
 	fmt.Printf("%+v\n", positions)
@@ -20,7 +27,7 @@ func TestGetAllPositions(t *testing.T) {
 }
 
 func TestGetPositionsBySeller(t *testing.T) {
-	client := NewClient("https://api.studio.thegraph.com/query/1724245/lumerin-dev-futures/version/latest")
+	client := NewClient("https://api.studio.thegraph.com/query/1724245/lumerin-dev-futures/version/latest", DefaultMaxRetries, DefaultRetryBackoff, lib.NewTestLogger())
 	participantAddress := common.HexToAddress("0xb4b12a69fdbb70b31214d4d3c063752c186ff8de")
 	deliveryAt := time.Unix(1763679600, 0)
 	positions, err := client.GetPositionsBySeller(context.Background(), participantAddress, deliveryAt)
@@ -28,3 +35,79 @@ func TestGetPositionsBySeller(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, positions)
 }
+
+type graphqlRequest struct {
+	Query     string
+	Variables map[string]any
+}
+
+func TestGetAllPositionsPaginatesAtIndexedBlock(t *testing.T) {
+	total := 6*PageSize + 5 // more than the subgraph allows to skip
+	var failures atomic.Int32
+	failures.Store(1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphqlRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		// the first request fails to check the retry
+		if failures.Add(-1) >= 0 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		if strings.Contains(req.Query, "_meta") {
+			_, _ = w.Write([]byte(`{"data":{"_meta":{"block":{"number":123,"timestamp":1700000000},"hasIndexingErrors":false}}}`))
+			return
+		}
+
+		require.EqualValues(t, 123, req.Variables["block"])
+		require.NotContains(t, req.Query, "skip")
+		lastID := req.Variables["lastID"].(string)
+		positions := make([]map[string]any, 0)
+		for i := 1; i <= total && len(positions) < PageSize; i++ {
+			id := common.BigToHash(big.NewInt(int64(i))).Hex()
+			if id <= lastID {
+				continue
+			}
+			positions = append(positions, map[string]any{
+				"id":         id,
+				"deliveryAt": "1763679600",
+				"destURL":    "",
+				"isPaid":     i%2 == 0,
+				"seller":     map[string]string{"address": "0x01"},
+				"buyer":      map[string]string{"address": "0x02"},
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"positions": positions}})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 1, time.Millisecond, lib.NewTestLogger())
+	res, err := client.GetAllPositions(context.Background(), time.Unix(1763679600, 0))
+	require.NoError(t, err)
+	require.Len(t, res.Contracts, total)
+	require.Equal(t, common.BigToHash(big.NewInt(int64(total))), res.Contracts[total-1].ContractID)
+	require.Equal(t, uint64(123), res.BlockNumber)
+	require.Equal(t, time.Unix(1700000000, 0), res.BlockTimestamp)
+}
+
+func TestGetMetaIndexingErrorsAndRetries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"_meta":{"block":{"number":1,"timestamp":1},"hasIndexingErrors":true}}}`))
+	}))
+	_, err := NewClient(server.URL, 0, time.Millisecond, lib.NewTestLogger()).GetMeta(context.Background())
+	require.ErrorIs(t, err, ErrIndexingErrors)
+	server.Close()
+
+	var requests atomic.Int32
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	_, err = NewClient(server.URL, 2, time.Millisecond, lib.NewTestLogger()).GetMeta(context.Background())
+	require.ErrorIs(t, err, ErrSubgraph)
+	require.EqualValues(t, 3, requests.Load())
+}
@@ -12,3 +12,11 @@ type Position struct {
 		Address string
 	}
 }
+
+type Meta struct {
+	Block struct {
+		Number    int
+		Timestamp int
+	}
+	HasIndexingErrors bool
+}
@@ -0,0 +1,75 @@
+package txmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// TxStore persists the tracked transactions, so the pending ones are monitored after restart
+type TxStore interface {
+	Load() ([]*Tx, error)
+	Save(txs []*Tx) error
+}
+
+// FileTxStore keeps transactions in a json file
+type FileTxStore struct {
+	path string
+}
+
+func NewFileTxStore(path string) *FileTxStore {
+	return &FileTxStore{path: path}
+}
+
+func (s *FileTxStore) Load() ([]*Tx, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var txs []*Tx
+	err = json.Unmarshal(data, &txs)
+	if err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
+// Save writes the transactions to a temporary file and renames it, so the file is never left partially written
+func (s *FileTxStore) Save(txs []*Tx) error {
+	data, err := json.MarshalIndent(txs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(s.path), 0o755)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := s.path + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0o600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+// MemoryTxStore is a no-op store, transactions are lost on restart
+type MemoryTxStore struct{}
+
+func NewMemoryTxStore() *MemoryTxStore {
+	return &MemoryTxStore{}
+}
+
+func (s *MemoryTxStore) Load() ([]*Tx, error) {
+	return nil, nil
+}
+
+func (s *MemoryTxStore) Save(txs []*Tx) error {
+	return nil
+}
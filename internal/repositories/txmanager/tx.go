@@ -0,0 +1,50 @@
+package txmanager
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+type TxStatus string
+
+const (
+	TxStatusPending   TxStatus = "pending"   // broadcasted, waiting to be mined
+	TxStatusConfirmed TxStatus = "confirmed" // mined successfully
+	TxStatusReverted  TxStatus = "reverted"  // mined, but the execution reverted
+	TxStatusDropped   TxStatus = "dropped"   // nonce was used by another transaction
+)
+
+// Tx is a transaction tracked by the manager. Every gas bump produces a new transaction
+// with the same nonce, so all of the broadcasted hashes are kept to look up the receipt
+type Tx struct {
+	ID          string         `json:"id"` // hash of the first broadcasted version
+	Label       string         `json:"label"`
+	From        common.Address `json:"from"`
+	Nonce       uint64         `json:"nonce"`
+	Status      TxStatus       `json:"status"`
+	Hashes      []common.Hash  `json:"hashes"`
+	RawTx       hexutil.Bytes  `json:"rawTx"` // latest signed version of the transaction
+	Bumps       int            `json:"bumps"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	SentAt      time.Time      `json:"sentAt"`
+	MinedAt     time.Time      `json:"minedAt,omitempty"`
+	BlockNumber uint64         `json:"blockNumber,omitempty"`
+	Error       string         `json:"error,omitempty"`
+}
+
+func (t *Tx) IsPending() bool {
+	return t.Status == TxStatusPending
+}
+
+// LastHash returns the hash of the latest broadcasted version
+func (t *Tx) LastHash() common.Hash {
+	return t.Hashes[len(t.Hashes)-1]
+}
+
+func (t *Tx) copy() Tx {
+	c := *t
+	c.Hashes = append([]common.Hash(nil), t.Hashes...)
+	return c
+}
@@ -0,0 +1,674 @@
+package txmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/ethpool"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	ReceiptPollInterval = 3 * time.Second
+	HistorySize         = 100 // number of finished transactions kept for the api
+	minBumpPercent      = 10  // nodes reject replacement transactions with lower fee increase
+)
+
+var (
+	ErrTxReverted = errors.New("transaction reverted")
+	ErrTxDropped  = errors.New("transaction dropped, nonce was used by another transaction")
+	ErrSendTx     = errors.New("failed to send transaction")
+	ErrLoadTxs    = errors.New("failed to load transactions")
+)
+
+type EthClient interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+}
+
+// TxFunc builds and signs the transaction with provided options without sending it,
+// typically it is a call of the abigen generated contract method
+type TxFunc func(opts *bind.TransactOpts) (*types.Transaction, error)
+
+type txResult struct {
+	receipt *types.Receipt
+	err     error
+}
+
+// TxManager sends all of the on-chain transactions. It assigns nonces, persists the pending
+// transactions, waits for the receipts, replaces stuck transactions with higher fee and retries
+// the transactions which nonce was taken by another transaction
+type TxManager struct {
+	// config
+	legacyTx     bool // use legacy transaction fee, for local node testing
+	pollInterval time.Duration
+	bumpInterval time.Duration
+	bumpPercent  int
+	maxBumps     int
+	maxRetries   int
+
+	// state
+	chainID   *big.Int
+	nonces    map[common.Address]uint64
+	signers   map[common.Address]interfaces.Signer
+	txs       []*Tx
+	waiters   map[string]chan txResult
+	mutex     sync.Mutex // guards state
+	sendMutex sync.Mutex // serializes nonce assignment and broadcasting
+
+	// deps
+	client EthClient
+	store  TxStore
+	log    interfaces.ILogger
+}
+
+func NewTxManager(client EthClient, store TxStore, legacyTx bool, pollInterval time.Duration, bumpInterval time.Duration, bumpPercent int, maxBumps int, maxRetries int, log interfaces.ILogger) *TxManager {
+	if bumpPercent < minBumpPercent {
+		bumpPercent = minBumpPercent
+	}
+	return &TxManager{
+		legacyTx:     legacyTx,
+		pollInterval: pollInterval,
+		bumpInterval: bumpInterval,
+		bumpPercent:  bumpPercent,
+		maxBumps:     maxBumps,
+		maxRetries:   maxRetries,
+		nonces:       make(map[common.Address]uint64),
+		signers:      make(map[common.Address]interfaces.Signer),
+		waiters:      make(map[string]chan txResult),
+		client:       client,
+		store:        store,
+		log:          log,
+	}
+}
+
+// Load restores the transactions persisted by the previous run, should be called before Run
+func (m *TxManager) Load() error {
+	txs, err := m.store.Load()
+	if err != nil {
+		return lib.WrapError(ErrLoadTxs, err)
+	}
+
+	m.mutex.Lock()
+	m.txs = append(txs, m.txs...)
+	m.mutex.Unlock()
+
+	m.log.Infof("loaded %d transactions, %d pending", len(txs), len(m.GetPending()))
+	return nil
+}
+
+// AddSigner registers the signer, so the pending transactions of its address loaded from the store can be replaced
+func (m *TxManager) AddSigner(signer interfaces.Signer) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.signers[signer.Address()] = signer
+}
+
+// Run monitors the pending transactions until the context is cancelled
+func (m *TxManager) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			m.CheckPending(ctx, now)
+		}
+	}
+}
+
+// Transact builds the transaction with the next nonce, sends it and waits for the receipt. If the context
+// is cancelled before the transaction is mined, the transaction is still monitored by the manager
+func (m *TxManager) Transact(ctx context.Context, signer interfaces.Signer, label string, fn TxFunc) (*types.Receipt, error) {
+	m.AddSigner(signer)
+
+	var lastErr error
+	for i := 0; i <= m.maxRetries; i++ {
+		if i > 0 {
+			m.log.Warnf("retrying transaction %s (%d/%d): %s", label, i, m.maxRetries, lastErr)
+			select {
+			case <-ctx.Done():
+				return nil, lib.WrapError(lastErr, ctx.Err())
+			case <-time.After(m.pollInterval):
+			}
+		}
+
+		tx, err := m.send(ctx, signer, label, fn)
+		if errors.Is(err, ErrSendTx) {
+			lastErr = err
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		receipt, err := m.wait(ctx, tx.ID)
+		if errors.Is(err, ErrTxDropped) {
+			lastErr = err
+			continue
+		}
+		return receipt, err
+	}
+
+	return nil, lastErr
+}
+
+// Estimate is the gas estimation of the transaction which is not sent
+type Estimate struct {
+	Label   string
+	Gas     uint64
+	GasFee  *big.Int // max fee per gas, or gas price for the legacy transaction
+	MaxCost *big.Int // gas * fee, the upper bound of the transaction fee in wei
+}
+
+// Estimate builds the transaction with gas estimation but doesn't sign or send it, used for dry runs
+func (m *TxManager) Estimate(ctx context.Context, from common.Address, label string, fn TxFunc) (*Estimate, error) {
+	nonce, err := m.nextNonce(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &bind.TransactOpts{
+		From: from,
+		Signer: func(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			return tx, nil
+		},
+		Context: ctx,
+		Nonce:   new(big.Int).SetUint64(nonce),
+		Value:   big.NewInt(0),
+		NoSend:  true,
+	}
+
+	if m.legacyTx {
+		gasPrice, err := m.client.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, err
+		}
+		opts.GasPrice = gasPrice
+	}
+
+	tx, err := fn(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Estimate{
+		Label:   label,
+		Gas:     tx.Gas(),
+		GasFee:  tx.GasFeeCap(),
+		MaxCost: new(big.Int).Mul(new(big.Int).SetUint64(tx.Gas()), tx.GasFeeCap()),
+	}, nil
+}
+
+// GetAll returns the tracked transactions starting from the most recent one
+func (m *TxManager) GetAll() []Tx {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	res := make([]Tx, len(m.txs))
+	for i, tx := range m.txs {
+		res[len(m.txs)-1-i] = tx.copy()
+	}
+	return res
+}
+
+func (m *TxManager) GetPending() []Tx {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var res []Tx
+	for _, tx := range m.txs {
+		if tx.IsPending() {
+			res = append(res, tx.copy())
+		}
+	}
+	return res
+}
+
+// CheckPending looks up the receipts of the pending transactions and replaces the ones
+// that were not mined within the bump interval
+func (m *TxManager) CheckPending(ctx context.Context, now time.Time) {
+	for _, tx := range m.GetPending() {
+		err := m.checkTx(ctx, tx, now)
+		if err != nil {
+			m.log.Warnf("failed to check transaction %s %s: %s", tx.Label, tx.ID, err)
+		}
+	}
+}
+
+func (m *TxManager) checkTx(ctx context.Context, tx Tx, now time.Time) error {
+	// nonce is fetched before the receipts, so if it is taken and none of the receipts
+	// are found, the transaction was replaced by another one
+	minedNonce, err := m.client.NonceAt(ctx, tx.From, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range tx.Hashes {
+		receipt, err := m.client.TransactionReceipt(ctx, hash)
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		m.finish(tx.ID, receipt, nil)
+		return nil
+	}
+
+	if minedNonce > tx.Nonce {
+		m.finish(tx.ID, nil, ErrTxDropped)
+		return nil
+	}
+
+	if now.Sub(tx.SentAt) < m.bumpInterval {
+		return nil
+	}
+	return m.bump(ctx, tx, now)
+}
+
+func (m *TxManager) send(ctx context.Context, signer interfaces.Signer, label string, fn TxFunc) (*Tx, error) {
+	m.sendMutex.Lock()
+	defer m.sendMutex.Unlock()
+
+	from := signer.Address()
+
+	chainID, err := m.getChainID(ctx)
+	if err != nil {
+		return nil, lib.WrapError(ErrSendTx, err)
+	}
+
+	nonce, err := m.nextNonce(ctx, from)
+	if err != nil {
+		return nil, lib.WrapError(ErrSendTx, err)
+	}
+
+	opts := &bind.TransactOpts{
+		From: from,
+		Signer: func(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if addr != from {
+				return nil, bind.ErrNotAuthorized
+			}
+			return signer.SignTx(ctx, tx, chainID)
+		},
+		Context: ctx,
+		Nonce:   new(big.Int).SetUint64(nonce),
+		Value:   big.NewInt(0),
+		NoSend:  true,
+	}
+
+	if m.legacyTx {
+		gasPrice, err := m.client.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, lib.WrapError(ErrSendTx, err)
+		}
+		opts.GasPrice = gasPrice
+	}
+
+	signed, err := fn(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tx := &Tx{
+		ID:        signed.Hash().Hex(),
+		Label:     label,
+		From:      from,
+		Nonce:     nonce,
+		Status:    TxStatusPending,
+		Hashes:    []common.Hash{signed.Hash()},
+		RawTx:     raw,
+		CreatedAt: now,
+		SentAt:    now,
+	}
+
+	// persisted before broadcasting, so the transaction is tracked even if the process stops right after
+	m.add(tx)
+
+	err = m.client.SendTransaction(ctx, signed)
+	if err != nil && !isAlreadyKnown(err) {
+		if !isPossiblySent(err) {
+			m.remove(tx.ID)
+			if isNonceTooLow(err) {
+				m.resetNonce(from)
+			}
+			return nil, lib.WrapError(ErrSendTx, err)
+		}
+		// the node may have accepted the transaction, so it is kept tracked and its receipt is checked
+		// before it is rebroadcasted, the nonce is not given to another transaction
+		m.log.Warnf("transaction %s %s may have been sent, tracking it: %s", label, tx.ID, err)
+	}
+
+	m.setNonce(from, nonce+1)
+	m.log.Infof("sent transaction %s %s, nonce %d", label, tx.ID, nonce)
+
+	return tx, nil
+}
+
+func (m *TxManager) wait(ctx context.Context, ID string) (*types.Receipt, error) {
+	m.mutex.Lock()
+	waiter, ok := m.waiters[ID]
+	m.mutex.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("transaction %s is not awaited", ID)
+	}
+
+	select {
+	case <-ctx.Done():
+		m.mutex.Lock()
+		delete(m.waiters, ID)
+		m.mutex.Unlock()
+		return nil, ctx.Err()
+	case res := <-waiter:
+		m.mutex.Lock()
+		delete(m.waiters, ID)
+		m.mutex.Unlock()
+		return res.receipt, res.err
+	}
+}
+
+// bump replaces the transaction with the same nonce and higher fee. If the signer is not available
+// or the bumps are exhausted the transaction is rebroadcasted in case it was evicted from the mempool
+func (m *TxManager) bump(ctx context.Context, tx Tx, now time.Time) error {
+	old := new(types.Transaction)
+	err := old.UnmarshalBinary(tx.RawTx)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	signer, hasSigner := m.signers[tx.From]
+	m.mutex.Unlock()
+
+	if !hasSigner || tx.Bumps >= m.maxBumps {
+		err := m.client.SendTransaction(ctx, old)
+		if err != nil && !isAlreadyKnown(err) {
+			return err
+		}
+		m.update(tx.ID, func(t *Tx) {
+			t.SentAt = now
+		})
+		return nil
+	}
+
+	chainID, err := m.getChainID(ctx)
+	if err != nil {
+		return err
+	}
+
+	newTx, err := signer.SignTx(ctx, types.NewTx(m.bumpTxData(ctx, old, chainID)), chainID)
+	if err != nil {
+		return err
+	}
+
+	err = m.client.SendTransaction(ctx, newTx)
+	if err != nil && !isAlreadyKnown(err) && !isPossiblySent(err) {
+		return err
+	}
+
+	raw, err := newTx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	m.update(tx.ID, func(t *Tx) {
+		t.Hashes = append(t.Hashes, newTx.Hash())
+		t.RawTx = raw
+		t.Bumps++
+		t.SentAt = now
+	})
+	m.log.Infof("replaced transaction %s %s with higher fee (%d/%d), new hash %s", tx.Label, tx.ID, tx.Bumps+1, m.maxBumps, newTx.Hash().Hex())
+
+	return nil
+}
+
+// bumpTxData returns the copy of the transaction with the fee increased by bumpPercent
+// or to the currently suggested value, whichever is higher. The fee model is taken from the type of the signed
+// transaction, so it is kept after restart
+func (m *TxManager) bumpTxData(ctx context.Context, old *types.Transaction, chainID *big.Int) types.TxData {
+	if old.Type() == types.LegacyTxType {
+		gasPrice := bumpValue(old.GasPrice(), m.bumpPercent)
+		suggested, err := m.client.SuggestGasPrice(ctx)
+		if err == nil && suggested.Cmp(gasPrice) > 0 {
+			gasPrice = suggested
+		}
+		return &types.LegacyTx{
+			Nonce:    old.Nonce(),
+			GasPrice: gasPrice,
+			Gas:      old.Gas(),
+			To:       old.To(),
+			Value:    old.Value(),
+			Data:     old.Data(),
+		}
+	}
+
+	tipCap := bumpValue(old.GasTipCap(), m.bumpPercent)
+	suggested, err := m.client.SuggestGasTipCap(ctx)
+	if err == nil && suggested.Cmp(tipCap) > 0 {
+		tipCap = suggested
+	}
+	feeCap := bumpValue(old.GasFeeCap(), m.bumpPercent)
+	if feeCap.Cmp(tipCap) < 0 {
+		feeCap = tipCap
+	}
+
+	return &types.DynamicFeeTx{
+		ChainID:    chainID,
+		Nonce:      old.Nonce(),
+		GasTipCap:  tipCap,
+		GasFeeCap:  feeCap,
+		Gas:        old.Gas(),
+		To:         old.To(),
+		Value:      old.Value(),
+		Data:       old.Data(),
+		AccessList: old.AccessList(),
+	}
+}
+
+func (m *TxManager) finish(ID string, receipt *types.Receipt, err error) {
+	m.mutex.Lock()
+
+	tx := m.find(ID)
+	if tx == nil || !tx.IsPending() {
+		m.mutex.Unlock()
+		return
+	}
+
+	switch {
+	case err != nil:
+		tx.Status = TxStatusDropped
+		// nonce is fetched from the node next time
+		delete(m.nonces, tx.From)
+	case receipt.Status == types.ReceiptStatusSuccessful:
+		tx.Status = TxStatusConfirmed
+	default:
+		tx.Status = TxStatusReverted
+		err = lib.WrapError(ErrTxReverted, fmt.Errorf("tx %s", receipt.TxHash.Hex()))
+	}
+	if err != nil {
+		tx.Error = err.Error()
+	}
+	if receipt != nil {
+		tx.MinedAt = time.Now()
+		tx.BlockNumber = receipt.BlockNumber.Uint64()
+	}
+	label, status := tx.Label, tx.Status
+
+	// the waiter is removed by wait, as the result may come before it is awaited
+	waiter, ok := m.waiters[ID]
+	m.trim()
+	m.save()
+	m.mutex.Unlock()
+
+	if ok {
+		waiter <- txResult{receipt: receipt, err: err}
+	}
+	m.log.Infof("transaction %s %s is %s", label, ID, status)
+}
+
+func (m *TxManager) add(tx *Tx) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.txs = append(m.txs, tx)
+	m.waiters[tx.ID] = make(chan txResult, 1)
+	m.save()
+}
+
+func (m *TxManager) remove(ID string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i, tx := range m.txs {
+		if tx.ID == ID {
+			m.txs = append(m.txs[:i], m.txs[i+1:]...)
+			break
+		}
+	}
+	delete(m.waiters, ID)
+	m.save()
+}
+
+func (m *TxManager) update(ID string, fn func(tx *Tx)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	tx := m.find(ID)
+	if tx == nil {
+		return
+	}
+	fn(tx)
+	m.save()
+}
+
+func (m *TxManager) find(ID string) *Tx {
+	for _, tx := range m.txs {
+		if tx.ID == ID {
+			return tx
+		}
+	}
+	return nil
+}
+
+// trim removes the oldest finished transactions keeping at most HistorySize of them
+func (m *TxManager) trim() {
+	finished := 0
+	res := make([]*Tx, 0, len(m.txs))
+	for i := len(m.txs) - 1; i >= 0; i-- {
+		tx := m.txs[i]
+		if !tx.IsPending() {
+			finished++
+			if finished > HistorySize {
+				continue
+			}
+		}
+		res = append(res, tx)
+	}
+	for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+		res[i], res[j] = res[j], res[i]
+	}
+	m.txs = res
+}
+
+// save should be called with the mutex locked
+func (m *TxManager) save() {
+	err := m.store.Save(m.txs)
+	if err != nil {
+		m.log.Errorf("failed to persist transactions: %s", err)
+	}
+}
+
+func (m *TxManager) nextNonce(ctx context.Context, from common.Address) (uint64, error) {
+	pending, err := m.client.PendingNonceAt(ctx, from)
+	if err != nil {
+		return 0, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	local, ok := m.nonces[from]
+	if ok && local > pending {
+		return local, nil
+	}
+	return pending, nil
+}
+
+func (m *TxManager) setNonce(from common.Address, nonce uint64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.nonces[from] = nonce
+}
+
+func (m *TxManager) resetNonce(from common.Address) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.nonces, from)
+}
+
+func (m *TxManager) getChainID(ctx context.Context) (*big.Int, error) {
+	m.mutex.Lock()
+	chainID := m.chainID
+	m.mutex.Unlock()
+	if chainID != nil {
+		return chainID, nil
+	}
+
+	chainID, err := m.client.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	m.chainID = chainID
+	m.mutex.Unlock()
+	return chainID, nil
+}
+
+func bumpValue(value *big.Int, percent int) *big.Int {
+	res := new(big.Int).Mul(value, big.NewInt(int64(100+percent)))
+	res.Div(res, big.NewInt(100))
+	return res.Add(res, big.NewInt(1))
+}
+
+func isAlreadyKnown(err error) bool {
+	return strings.Contains(err.Error(), "already known")
+}
+
+// isPossiblySent returns true if the send failed after the transaction could have reached the node,
+// e.g. it timed out or failed over to another endpoint, so the transaction may still be mined
+func isPossiblySent(err error) bool {
+	if errors.Is(err, ethpool.ErrTxPossiblySent) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func isNonceTooLow(err error) bool {
+	return strings.Contains(err.Error(), "nonce too low")
+}
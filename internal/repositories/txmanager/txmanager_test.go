@@ -0,0 +1,324 @@
+package txmanager
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/ethpool"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/wallet"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+const testPrivKey = "b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291"
+
+var testSigner, _ = wallet.NewKeySigner(testPrivKey)
+
+var testChainID = big.NewInt(1337)
+
+type fakeClient struct {
+	mineOnSend bool
+	nonces     map[common.Address]uint64
+	receipts   map[common.Hash]*types.Receipt
+	sent       []*types.Transaction
+	sendErr    error // returned by the next send after the transaction is accepted
+	mutex      sync.Mutex
+}
+
+func newFakeClient(mineOnSend bool) *fakeClient {
+	return &fakeClient{
+		mineOnSend: mineOnSend,
+		nonces:     make(map[common.Address]uint64),
+		receipts:   make(map[common.Hash]*types.Receipt),
+	}
+}
+
+func (c *fakeClient) ChainID(ctx context.Context) (*big.Int, error) {
+	return testChainID, nil
+}
+
+func (c *fakeClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.nonces[account], nil
+}
+
+func (c *fakeClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return c.NonceAt(ctx, account, nil)
+}
+
+func (c *fakeClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	c.mutex.Lock()
+	c.sent = append(c.sent, tx)
+	mineOnSend := c.mineOnSend
+	sendErr := c.sendErr
+	c.sendErr = nil
+	c.mutex.Unlock()
+	if mineOnSend {
+		c.mine(tx)
+	}
+	return sendErr
+}
+
+func (c *fakeClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	receipt, ok := c.receipts[txHash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return receipt, nil
+}
+
+func (c *fakeClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1e9), nil
+}
+
+func (c *fakeClient) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1e9), nil
+}
+
+func (c *fakeClient) mine(tx *types.Transaction) {
+	from, _ := types.Sender(types.LatestSignerForChainID(testChainID), tx)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.receipts[tx.Hash()] = &types.Receipt{Status: types.ReceiptStatusSuccessful, TxHash: tx.Hash(), BlockNumber: big.NewInt(1)}
+	c.nonces[from] = tx.Nonce() + 1
+}
+
+func (c *fakeClient) sentCount() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.sent)
+}
+
+func (c *fakeClient) lastSent() *types.Transaction {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.sent[len(c.sent)-1]
+}
+
+func testTxFunc(opts *bind.TransactOpts) (*types.Transaction, error) {
+	to := common.HexToAddress("0x1")
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   testChainID,
+		Nonce:     opts.Nonce.Uint64(),
+		GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(2e9),
+		Gas:       21000,
+		To:        &to,
+	})
+	return opts.Signer(opts.From, tx)
+}
+
+func newTestTxManager(client EthClient, store TxStore) *TxManager {
+	return NewTxManager(client, store, false, 10*time.Millisecond, time.Hour, 20, 3, 2, lib.NewTestLogger())
+}
+
+func TestTransactConfirmed(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := newFakeClient(true)
+	m := newTestTxManager(client, NewMemoryTxStore())
+	go func() { _ = m.Run(ctx) }()
+
+	for i := 0; i < 2; i++ {
+		receipt, err := m.Transact(ctx, testSigner, "test", testTxFunc)
+		require.NoError(t, err)
+		require.Equal(t, types.ReceiptStatusSuccessful, receipt.Status)
+	}
+
+	txs := m.GetAll()
+	require.Len(t, txs, 2)
+	require.Equal(t, uint64(1), txs[0].Nonce)
+	require.Equal(t, TxStatusConfirmed, txs[0].Status)
+}
+
+func TestEstimateDoesNotSend(t *testing.T) {
+	client := newFakeClient(true)
+	m := newTestTxManager(client, NewMemoryTxStore())
+
+	estimate, err := m.Estimate(context.Background(), testSigner.Address(), "test", testTxFunc)
+	require.NoError(t, err)
+	require.Equal(t, uint64(21000), estimate.Gas)
+	require.Equal(t, big.NewInt(21000*2e9), estimate.MaxCost)
+	require.Zero(t, client.sentCount())
+	require.Empty(t, m.GetAll())
+}
+
+func TestTransactBumpGas(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := newFakeClient(false)
+	m := newTestTxManager(client, NewMemoryTxStore())
+	m.bumpInterval = 0
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := m.Transact(ctx, testSigner, "test", testTxFunc)
+		errCh <- err
+	}()
+
+	require.Eventually(t, func() bool { return client.sentCount() == 1 }, time.Second, time.Millisecond)
+	first := client.lastSent()
+
+	m.CheckPending(ctx, time.Now())
+	m.CheckPending(ctx, time.Now())
+
+	tx := m.GetPending()[0]
+	require.Equal(t, 2, tx.Bumps)
+	require.Len(t, tx.Hashes, 3)
+
+	bumped := client.lastSent()
+	require.Equal(t, first.Nonce(), bumped.Nonce())
+	require.Equal(t, 1, bumped.GasTipCap().Cmp(first.GasTipCap()))
+	require.Equal(t, 1, bumped.GasFeeCap().Cmp(first.GasFeeCap()))
+
+	// the original transaction is mined after all
+	client.mine(first)
+	m.CheckPending(ctx, time.Now())
+	require.NoError(t, <-errCh)
+	require.Equal(t, TxStatusConfirmed, m.GetAll()[0].Status)
+}
+
+func TestTransactDroppedRetry(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := newFakeClient(false)
+	m := newTestTxManager(client, NewMemoryTxStore())
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := m.Transact(ctx, testSigner, "test", testTxFunc)
+		errCh <- err
+	}()
+
+	require.Eventually(t, func() bool { return client.sentCount() == 1 }, time.Second, time.Millisecond)
+
+	// nonce is taken by a transaction sent outside of the manager
+	client.mutex.Lock()
+	client.nonces[testSigner.Address()] = 1
+	client.mineOnSend = true
+	client.mutex.Unlock()
+
+	go func() { _ = m.Run(ctx) }()
+	require.NoError(t, <-errCh)
+
+	txs := m.GetAll()
+	require.Len(t, txs, 2)
+	require.Equal(t, TxStatusConfirmed, txs[0].Status)
+	require.Equal(t, uint64(1), txs[0].Nonce)
+	require.Equal(t, TxStatusDropped, txs[1].Status)
+}
+
+func TestTransactPossiblySent(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the first node accepted the transaction and timed out, the next one rejects it as already mined
+	client := newFakeClient(true)
+	client.sendErr = lib.WrapError(ethpool.ErrTxPossiblySent, errors.New("nonce too low"))
+	m := newTestTxManager(client, NewMemoryTxStore())
+	go func() { _ = m.Run(ctx) }()
+
+	receipt, err := m.Transact(ctx, testSigner, "test", testTxFunc)
+	require.NoError(t, err)
+	require.Equal(t, types.ReceiptStatusSuccessful, receipt.Status)
+	require.Equal(t, 1, client.sentCount())
+
+	// the rejection of the only node called is final, the transaction is not tracked
+	client.mutex.Lock()
+	client.sendErr = errors.New("insufficient funds")
+	client.mutex.Unlock()
+	_, err = m.send(ctx, testSigner, "test", testTxFunc)
+	require.ErrorIs(t, err, ErrSendTx)
+	require.Empty(t, m.GetPending())
+}
+
+func TestTransactResumePersisted(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store := NewFileTxStore(filepath.Join(t.TempDir(), "txs.json"))
+	client := newFakeClient(false)
+
+	m := newTestTxManager(client, store)
+	sendCtx, sendCancel := context.WithCancel(ctx)
+	go func() {
+		_, _ = m.Transact(sendCtx, testSigner, "test", testTxFunc)
+	}()
+	require.Eventually(t, func() bool { return client.sentCount() == 1 }, time.Second, time.Millisecond)
+	sendCancel()
+
+	// restarted manager picks up the pending transaction from the store
+	m2 := newTestTxManager(client, store)
+	require.NoError(t, m2.Load())
+	require.Len(t, m2.GetPending(), 1)
+
+	client.mine(client.lastSent())
+	m2.CheckPending(ctx, time.Now())
+	require.Empty(t, m2.GetPending())
+
+	txs, err := store.Load()
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	require.Equal(t, TxStatusConfirmed, txs[0].Status)
+}
+
+func TestTransactResumePersistedLegacy(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store := NewFileTxStore(filepath.Join(t.TempDir(), "txs.json"))
+	client := newFakeClient(false)
+
+	legacyTxFunc := func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		to := common.HexToAddress("0x1")
+		tx := types.NewTx(&types.LegacyTx{
+			Nonce:    opts.Nonce.Uint64(),
+			GasPrice: opts.GasPrice,
+			Gas:      21000,
+			To:       &to,
+		})
+		return opts.Signer(opts.From, tx)
+	}
+
+	m := NewTxManager(client, store, true, 10*time.Millisecond, time.Hour, 20, 3, 2, lib.NewTestLogger())
+	sendCtx, sendCancel := context.WithCancel(ctx)
+	go func() {
+		_, _ = m.Transact(sendCtx, testSigner, "test", legacyTxFunc)
+	}()
+	require.Eventually(t, func() bool { return client.sentCount() == 1 }, time.Second, time.Millisecond)
+	sendCancel()
+
+	txs, err := store.Load()
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	var persisted types.Transaction
+	require.NoError(t, persisted.UnmarshalBinary(txs[0].RawTx))
+	require.Equal(t, uint8(types.LegacyTxType), persisted.Type())
+
+	// restarted without the legacy flag, the persisted transaction is still bumped with the gas price
+	m2 := newTestTxManager(client, store)
+	m2.bumpInterval = 0
+	m2.AddSigner(testSigner)
+	require.NoError(t, m2.Load())
+	m2.CheckPending(ctx, time.Now())
+
+	bumped := client.lastSent()
+	require.Equal(t, 2, client.sentCount())
+	require.Equal(t, uint8(types.LegacyTxType), bumped.Type())
+}
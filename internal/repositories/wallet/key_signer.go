@@ -0,0 +1,76 @@
+package wallet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"os"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+)
+
+// KeySigner signs with the private key kept in memory
+type KeySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+func NewKeySigner(privKey string) (*KeySigner, error) {
+	key, err := crypto.HexToECDSA(privKey)
+	if err != nil {
+		return nil, lib.WrapError(lib.ErrInvalidPrivateKey, err)
+	}
+	return newKeySigner(key), nil
+}
+
+// NewKeystoreSigner decrypts the go-ethereum keystore json file with the passphrase
+func NewKeystoreSigner(path string, passphrase string) (*KeySigner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, lib.WrapError(ErrKeystore, err)
+	}
+	key, err := keystore.DecryptKey(data, passphrase)
+	if err != nil {
+		return nil, lib.WrapError(ErrKeystore, err)
+	}
+	return newKeySigner(key.PrivateKey), nil
+}
+
+func newKeySigner(key *ecdsa.PrivateKey) *KeySigner {
+	return &KeySigner{
+		key:     key,
+		address: crypto.PubkeyToAddress(key.PublicKey),
+	}
+}
+
+func (s *KeySigner) Address() common.Address {
+	return s.address
+}
+
+// PublicKey is used to register as a validator, remote signer doesn't expose it
+func (s *KeySigner) PublicKey() *ecdsa.PublicKey {
+	return &s.key.PublicKey
+}
+
+func (s *KeySigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+func (s *KeySigner) SignMessage(ctx context.Context, msg []byte) ([]byte, error) {
+	sig, err := crypto.Sign(accounts.TextHash(msg), s.key)
+	if err != nil {
+		return nil, err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return sig, nil
+}
+
+func (s *KeySigner) Decrypt(ciphertext []byte) ([]byte, error) {
+	return ecies.ImportECDSA(s.key).Decrypt(ciphertext, nil, nil)
+}
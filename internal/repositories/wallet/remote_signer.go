@@ -0,0 +1,208 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// RemoteSigner signs transactions with the external signer (e.g. clef or web3signer) over
+// the standard eth_signTransaction json-rpc method, the private key never leaves the signer.
+// There is no standard method for decryption, so the destinations are decrypted with the separate key
+type RemoteSigner struct {
+	address       common.Address
+	decryptionKey *ecdsa.PrivateKey // optional
+	client        *rpc.Client
+}
+
+// NewRemoteSigner connects to the signer. If the address is not set the first account of the signer is used.
+// The decryption key is optional, without it the contract destinations can't be decrypted
+func NewRemoteSigner(ctx context.Context, url string, address common.Address, decryptionKey string) (*RemoteSigner, error) {
+	var key *ecdsa.PrivateKey
+	if decryptionKey != "" {
+		var err error
+		key, err = crypto.HexToECDSA(decryptionKey)
+		if err != nil {
+			return nil, lib.WrapError(lib.ErrInvalidPrivateKey, err)
+		}
+	}
+
+	client, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, lib.WrapError(ErrRemoteSigner, err)
+	}
+
+	if address == (common.Address{}) {
+		var accounts []common.Address
+		err := client.CallContext(ctx, &accounts, "eth_accounts")
+		if err != nil {
+			client.Close()
+			return nil, lib.WrapError(ErrRemoteSigner, err)
+		}
+		if len(accounts) == 0 {
+			client.Close()
+			return nil, lib.WrapError(ErrRemoteSigner, fmt.Errorf("signer has no accounts"))
+		}
+		address = accounts[0]
+	}
+
+	return &RemoteSigner{
+		address:       address,
+		decryptionKey: key,
+		client:        client,
+	}, nil
+}
+
+// signTxArgs are the transaction fields of eth_signTransaction request
+type signTxArgs struct {
+	From                 common.Address  `json:"from"`
+	To                   *common.Address `json:"to,omitempty"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	GasPrice             *hexutil.Big    `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas,omitempty"`
+	Value                *hexutil.Big    `json:"value"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	Data                 hexutil.Bytes   `json:"data"`
+	ChainID              *hexutil.Big    `json:"chainId"`
+}
+
+// signTxResult is the response of geth and clef, web3signer responds with the raw transaction only
+type signTxResult struct {
+	Raw hexutil.Bytes `json:"raw"`
+}
+
+func (s *RemoteSigner) Address() common.Address {
+	return s.address
+}
+
+func (s *RemoteSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := signTxArgs{
+		From:    s.address,
+		To:      tx.To(),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   (*hexutil.Big)(tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    tx.Data(),
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	if tx.Type() == types.LegacyTxType {
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	} else {
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	}
+
+	var res json.RawMessage
+	err := s.client.CallContext(ctx, &res, "eth_signTransaction", args)
+	if err != nil {
+		return nil, lib.WrapError(ErrRemoteSigner, err)
+	}
+
+	var raw hexutil.Bytes
+	if bytes.HasPrefix(res, []byte(`"`)) {
+		err = json.Unmarshal(res, &raw)
+	} else {
+		var result signTxResult
+		err = json.Unmarshal(res, &result)
+		raw = result.Raw
+	}
+	if err != nil {
+		return nil, lib.WrapError(ErrRemoteSigner, err)
+	}
+
+	signed := new(types.Transaction)
+	err = signed.UnmarshalBinary(raw)
+	if err != nil {
+		return nil, lib.WrapError(ErrRemoteSigner, err)
+	}
+
+	// make sure the signer signed what was requested
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	if err != nil {
+		return nil, lib.WrapError(ErrRemoteSigner, err)
+	}
+	if sender != s.address || !sameTx(signed, tx, chainID) {
+		return nil, lib.WrapError(ErrRemoteSigner, fmt.Errorf("signed transaction doesn't match the request"))
+	}
+
+	return signed, nil
+}
+
+// SignMessage signs with eth_sign, the signer adds the personal message prefix itself
+func (s *RemoteSigner) SignMessage(ctx context.Context, msg []byte) ([]byte, error) {
+	var sig hexutil.Bytes
+	err := s.client.CallContext(ctx, &sig, "eth_sign", s.address, hexutil.Bytes(msg))
+	if err != nil {
+		return nil, lib.WrapError(ErrRemoteSigner, err)
+	}
+
+	// make sure the message is signed by the wallet account
+	pubKey, err := lib.RecoverMessageSigner(msg, sig)
+	if err != nil {
+		return nil, lib.WrapError(ErrRemoteSigner, err)
+	}
+	if crypto.PubkeyToAddress(*pubKey) != s.address {
+		return nil, lib.WrapError(ErrRemoteSigner, fmt.Errorf("message is signed by another account"))
+	}
+
+	// V is 27 or 28 as for the local key
+	if sig[crypto.RecoveryIDOffset] < 27 {
+		sig[crypto.RecoveryIDOffset] += 27
+	}
+	return sig, nil
+}
+
+// Decrypt decrypts with the decryption key, the remote signer itself can't decrypt
+func (s *RemoteSigner) Decrypt(ciphertext []byte) ([]byte, error) {
+	if s.decryptionKey == nil {
+		return nil, ErrDecryptNotSupported
+	}
+	return ecies.ImportECDSA(s.decryptionKey).Decrypt(ciphertext, nil, nil)
+}
+
+// CanDecrypt returns true if the decryption key is set
+func (s *RemoteSigner) CanDecrypt() bool {
+	return s.decryptionKey != nil
+}
+
+// DecryptionPublicKey returns the public key the destinations should be encrypted with, nil if the decryption key is not set
+func (s *RemoteSigner) DecryptionPublicKey() *ecdsa.PublicKey {
+	if s.decryptionKey == nil {
+		return nil
+	}
+	return &s.decryptionKey.PublicKey
+}
+
+func (s *RemoteSigner) Close() {
+	s.client.Close()
+}
+
+// sameTx checks that the signed transaction a has the fields of the requested transaction b and the chain id
+func sameTx(a, b *types.Transaction, chainID *big.Int) bool {
+	if a.Type() != b.Type() || a.Nonce() != b.Nonce() || a.Value().Cmp(b.Value()) != 0 || !bytes.Equal(a.Data(), b.Data()) {
+		return false
+	}
+	if a.Gas() != b.Gas() || a.GasPrice().Cmp(b.GasPrice()) != 0 || a.GasFeeCap().Cmp(b.GasFeeCap()) != 0 || a.GasTipCap().Cmp(b.GasTipCap()) != 0 {
+		return false
+	}
+	// unprotected legacy transaction has zero chain id
+	if a.ChainId().Cmp(chainID) != 0 {
+		return false
+	}
+	if a.To() == nil || b.To() == nil {
+		return a.To() == b.To()
+	}
+	return *a.To() == *b.To()
+}
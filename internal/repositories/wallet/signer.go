@@ -0,0 +1,72 @@
+package wallet
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/ethereum/go-ethereum/common"
+	"golang.org/x/term"
+)
+
+var (
+	ErrKeystore            = errors.New("can't load keystore")
+	ErrRemoteSigner        = errors.New("remote signer error")
+	ErrDecryptNotSupported = errors.New("decryption is not supported by the remote signer, set the decryption key")
+	ErrNoWallet            = errors.New("wallet is not configured, set private key, keystore or remote signer")
+)
+
+// NewSigner creates the signer from the first configured source: private key, keystore file or remote signer.
+// Keystore passphrase is read from the file, or prompted from the terminal if the file is not set.
+// The decryption key is used only by the remote signer
+func NewSigner(ctx context.Context, privKey, keystorePath, passphraseFile, remoteURL, remoteAddr, decryptionKey string) (interfaces.Signer, error) {
+	switch {
+	case privKey != "":
+		return NewKeySigner(privKey)
+	case keystorePath != "":
+		passphrase, err := ReadPassphrase(passphraseFile)
+		if err != nil {
+			return nil, lib.WrapError(ErrKeystore, err)
+		}
+		return NewKeystoreSigner(keystorePath, passphrase)
+	case remoteURL != "":
+		return NewRemoteSigner(ctx, remoteURL, common.HexToAddress(remoteAddr), strings.TrimPrefix(decryptionKey, "0x"))
+	}
+	return nil, ErrNoWallet
+}
+
+// ReadPassphrase reads the passphrase from the first line of the file, or prompts it if the file is not set
+func ReadPassphrase(file string) (string, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		passphrase, _, _ := strings.Cut(string(data), "\n")
+		return strings.TrimRight(passphrase, "\r"), nil
+	}
+
+	fmt.Fprint(os.Stderr, "Keystore passphrase: ")
+	defer fmt.Fprintln(os.Stderr)
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		passphrase, err := term.ReadPassword(fd)
+		if err != nil {
+			return "", fmt.Errorf("can't read passphrase: %w", err)
+		}
+		return string(passphrase), nil
+	}
+
+	// stdin is piped, the passphrase is not echoed
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("can't read passphrase: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
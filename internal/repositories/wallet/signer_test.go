@@ -0,0 +1,256 @@
+package wallet
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testPrivKey  = "b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291"
+	testPrivKey2 = "8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a"
+)
+
+var testChainID = big.NewInt(421614)
+
+func testTx() *types.Transaction {
+	to := common.HexToAddress("0x01")
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   testChainID,
+		Nonce:     7,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(100),
+		Gas:       21000,
+		To:        &to,
+		Value:     big.NewInt(5),
+		Data:      []byte{1, 2, 3},
+	})
+}
+
+func TestKeySignerSignAndDecrypt(t *testing.T) {
+	signer, err := NewKeySigner(testPrivKey)
+	require.NoError(t, err)
+
+	signed, err := signer.SignTx(context.Background(), testTx(), testChainID)
+	require.NoError(t, err)
+	sender, err := types.Sender(types.LatestSignerForChainID(testChainID), signed)
+	require.NoError(t, err)
+	require.Equal(t, signer.Address(), sender)
+
+	key, err := crypto.HexToECDSA(testPrivKey)
+	require.NoError(t, err)
+	pubKey := hex.EncodeToString(crypto.FromECDSAPub(&key.PublicKey))
+	encrypted, err := lib.EncryptString("stratum+tcp://worker:@pool.dev:3333", pubKey)
+	require.NoError(t, err)
+
+	decrypted, err := lib.DecryptStringWith(encrypted, signer.Decrypt)
+	require.NoError(t, err)
+	require.Equal(t, "stratum+tcp://worker:@pool.dev:3333", decrypted)
+}
+
+func TestKeystoreSigner(t *testing.T) {
+	key, err := crypto.HexToECDSA(testPrivKey)
+	require.NoError(t, err)
+
+	data, err := keystore.EncryptKey(&keystore.Key{
+		Address:    crypto.PubkeyToAddress(key.PublicKey),
+		PrivateKey: key,
+	}, "secret", keystore.LightScryptN, keystore.LightScryptP)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	keystorePath := filepath.Join(dir, "keystore.json")
+	passwordPath := filepath.Join(dir, "password")
+	require.NoError(t, os.WriteFile(keystorePath, data, 0600))
+	require.NoError(t, os.WriteFile(passwordPath, []byte("secret\n"), 0600))
+
+	signer, err := NewSigner(context.Background(), "", keystorePath, passwordPath, "", "", "")
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), signer.Address())
+
+	_, err = NewKeystoreSigner(keystorePath, "wrong")
+	require.ErrorIs(t, err, ErrKeystore)
+}
+
+func TestReadPassphraseFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(path, []byte("pass phrase\r\nsecond line"), 0600))
+
+	passphrase, err := ReadPassphrase(path)
+	require.NoError(t, err)
+	require.Equal(t, "pass phrase", passphrase)
+}
+
+func TestKeySignerSignMessage(t *testing.T) {
+	signer, err := NewKeySigner(testPrivKey)
+	require.NoError(t, err)
+
+	msg := []byte("delivery report")
+	sig, err := signer.SignMessage(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, sig, crypto.SignatureLength)
+	require.Contains(t, []byte{27, 28}, sig[crypto.RecoveryIDOffset])
+
+	sig[crypto.RecoveryIDOffset] -= 27
+	pubKey, err := crypto.SigToPub(accounts.TextHash(msg), sig)
+	require.NoError(t, err)
+	require.Equal(t, signer.Address(), crypto.PubkeyToAddress(*pubKey))
+}
+
+// signerService is a local stand-in of the external signer exposing eth_accounts, eth_signTransaction and eth_sign
+type signerService struct {
+	signer   *KeySigner
+	tamper   bool
+	tamperTx func(tx *types.DynamicFeeTx)
+}
+
+func (s *signerService) Accounts() []common.Address {
+	return []common.Address{s.signer.Address()}
+}
+
+func (s *signerService) SignTransaction(args signTxArgs) (*signTxResult, error) {
+	txData := &types.DynamicFeeTx{
+		ChainID:   args.ChainID.ToInt(),
+		Nonce:     uint64(args.Nonce),
+		GasTipCap: args.MaxPriorityFeePerGas.ToInt(),
+		GasFeeCap: args.MaxFeePerGas.ToInt(),
+		Gas:       uint64(args.Gas),
+		To:        args.To,
+		Value:     args.Value.ToInt(),
+		Data:      args.Data,
+	}
+	if s.tamper {
+		txData.Value = big.NewInt(1000)
+	}
+	if s.tamperTx != nil {
+		s.tamperTx(txData)
+	}
+
+	signed, err := s.signer.SignTx(context.Background(), types.NewTx(txData), txData.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &signTxResult{Raw: raw}, nil
+}
+
+func (s *signerService) Sign(addr common.Address, data hexutil.Bytes) (hexutil.Bytes, error) {
+	if s.tamper {
+		data = append(data, 0)
+	}
+	return s.signer.SignMessage(context.Background(), data)
+}
+
+func startSigner(t *testing.T, service *signerService) string {
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("eth", service))
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(func() {
+		httpServer.Close()
+		server.Stop()
+	})
+	return httpServer.URL
+}
+
+func TestRemoteSigner(t *testing.T) {
+	keySigner, err := NewKeySigner(testPrivKey)
+	require.NoError(t, err)
+	url := startSigner(t, &signerService{signer: keySigner})
+
+	signer, err := NewRemoteSigner(context.Background(), url, common.Address{}, "")
+	require.NoError(t, err)
+	defer signer.Close()
+	require.Equal(t, keySigner.Address(), signer.Address())
+
+	tx := testTx()
+	signed, err := signer.SignTx(context.Background(), tx, testChainID)
+	require.NoError(t, err)
+	require.Equal(t, tx.Nonce(), signed.Nonce())
+	require.Equal(t, tx.Data(), signed.Data())
+
+	sig, err := signer.SignMessage(context.Background(), []byte("delivery report"))
+	require.NoError(t, err)
+	expected, err := keySigner.SignMessage(context.Background(), []byte("delivery report"))
+	require.NoError(t, err)
+	require.Equal(t, expected, sig)
+
+	_, err = signer.Decrypt([]byte{1})
+	require.ErrorIs(t, err, ErrDecryptNotSupported)
+}
+
+func TestRemoteSignerRejectsTamperedTx(t *testing.T) {
+	keySigner, err := NewKeySigner(testPrivKey)
+	require.NoError(t, err)
+	url := startSigner(t, &signerService{signer: keySigner, tamper: true})
+
+	signer, err := NewRemoteSigner(context.Background(), url, keySigner.Address(), "")
+	require.NoError(t, err)
+	defer signer.Close()
+
+	_, err = signer.SignTx(context.Background(), testTx(), testChainID)
+	require.ErrorIs(t, err, ErrRemoteSigner)
+
+	_, err = signer.SignMessage(context.Background(), []byte("delivery report"))
+	require.ErrorIs(t, err, ErrRemoteSigner)
+}
+
+func TestRemoteSignerRejectsTamperedFees(t *testing.T) {
+	keySigner, err := NewKeySigner(testPrivKey)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		tamper func(tx *types.DynamicFeeTx)
+	}{
+		{"gas", func(tx *types.DynamicFeeTx) { tx.Gas *= 10 }},
+		{"fee cap", func(tx *types.DynamicFeeTx) { tx.GasFeeCap = big.NewInt(1000) }},
+		{"tip cap", func(tx *types.DynamicFeeTx) { tx.GasTipCap = big.NewInt(50) }},
+		{"chain id", func(tx *types.DynamicFeeTx) { tx.ChainID = big.NewInt(1) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := startSigner(t, &signerService{signer: keySigner, tamperTx: tt.tamper})
+			signer, err := NewRemoteSigner(context.Background(), url, keySigner.Address(), "")
+			require.NoError(t, err)
+			defer signer.Close()
+
+			_, err = signer.SignTx(context.Background(), testTx(), testChainID)
+			require.ErrorIs(t, err, ErrRemoteSigner)
+		})
+	}
+}
+
+func TestRemoteSignerDecryptionKey(t *testing.T) {
+	keySigner, err := NewKeySigner(testPrivKey)
+	require.NoError(t, err)
+	url := startSigner(t, &signerService{signer: keySigner})
+
+	signer, err := NewRemoteSigner(context.Background(), url, common.Address{}, testPrivKey2)
+	require.NoError(t, err)
+	defer signer.Close()
+	require.True(t, signer.CanDecrypt())
+
+	pubKey := hex.EncodeToString(crypto.FromECDSAPub(signer.DecryptionPublicKey()))
+	encrypted, err := lib.EncryptString("stratum+tcp://worker:@pool.dev:3333", pubKey)
+	require.NoError(t, err)
+	decrypted, err := lib.DecryptStringWith(encrypted, signer.Decrypt)
+	require.NoError(t, err)
+	require.Equal(t, "stratum+tcp://worker:@pool.dev:3333", decrypted)
+}
@@ -33,6 +33,14 @@ type MinerItem struct {
 	IsFullMiner   bool
 }
 
+// Capacity is the hashrate of the vetted miners
+type Capacity struct {
+	TotalGHS   float64
+	FreeGHS    float64 // hashrate of the miners not serving any contract
+	Miners     int
+	FreeMiners int
+}
+
 type ListenerHandle int
 
 type MinerItemJobScheduled struct {
@@ -66,16 +74,36 @@ func (p *Allocator) GetMiners() *lib.Collection[*Scheduler] {
 	return p.proxies
 }
 
+// GetCapacity returns the hashrate of the vetted miners, the miners that are vetting or disconnecting are not counted
+func (p *Allocator) GetCapacity() Capacity {
+	var res Capacity
+	p.proxies.Range(func(item *Scheduler) bool {
+		if item.IsVetting() || item.IsDisconnecting() {
+			return true
+		}
+		res.Miners++
+		res.TotalGHS += item.HashrateGHS()
+		return true
+	})
+
+	for _, m := range p.getMinersSnapshot(0, "").freeMiners {
+		res.FreeMiners++
+		res.FreeGHS += m.HrGHS
+	}
+	return res
+}
+
 func (p *Allocator) AllocateFullMinersForHR(
 	ID string,
 	hrGHS float64,
 	dest *url.URL,
+	algorithm hashrate.Algorithm,
 	duration time.Duration,
 	onSubmit OnSubmitCb,
 	onDisconnect OnDisconnectCb,
 	onEnd OnEndCb,
 ) (minerIDs []string, deltaGHS float64) {
-	miners := p.getMinersSnapshot(0)
+	miners := p.getMinersSnapshot(0, algorithm)
 	p.log.Infow(fmt.Sprintf("available free miners %v", miners.freeMiners), "CtrAddr", lib.AddrShort(ID))
 
 	for _, miner := range miners.freeMiners {
@@ -98,6 +126,7 @@ func (p *Allocator) AllocatePartialForJob(
 	ID string,
 	jobNeeded float64,
 	dest *url.URL,
+	algorithm hashrate.Algorithm,
 	cycleEndTimeout time.Duration,
 	onSubmit func(diff float64, ID string),
 	onDisconnect func(ID string, hrGHS float64, remainingJob float64),
@@ -105,7 +134,7 @@ func (p *Allocator) AllocatePartialForJob(
 ) (minerIDJob MinerIDJob, remainderGHS float64) {
 	p.log.Infof("attempting to partially allocate job %.f", jobNeeded)
 
-	miners := p.getMinersSnapshot(cycleEndTimeout)
+	miners := p.getMinersSnapshot(cycleEndTimeout, algorithm)
 	p.log.Infof("available partial miners %v", miners.partialMiners)
 
 	minerIDJob = MinerIDJob{}
@@ -233,10 +262,15 @@ func (p *Allocator) InvokeVettedListeners(minerID string) {
 	}
 }
 
-func (p *Allocator) getMinersSnapshot(remainingCycleDuration time.Duration) minerSnapshot {
+// getMinersSnapshot returns the miners available for the allocation, if the algorithm is set only the miners
+// of this algorithm are returned
+func (p *Allocator) getMinersSnapshot(remainingCycleDuration time.Duration, algorithm hashrate.Algorithm) minerSnapshot {
 	snap := minerSnapshot{}
 
 	p.proxies.Range(func(item *Scheduler) bool {
+		if algorithm != "" && item.Algorithm() != algorithm {
+			return true
+		}
 		if item.IsVetting() { // atomic
 			return true
 		}
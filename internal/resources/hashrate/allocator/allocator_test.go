@@ -0,0 +1,53 @@
+package allocator
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/hashrate"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/proxy"
+	"github.com/stretchr/testify/require"
+)
+
+type hashrateMock struct {
+	proxy.Hashrate
+	hrGHS float64
+}
+
+func (h *hashrateMock) GetHashrateAvgGHSCustom(ID string) (float64, bool) {
+	return h.hrGHS, true
+}
+
+type proxyMock struct {
+	StratumProxyInterface
+	id    string
+	hrGHS float64
+}
+
+func (p *proxyMock) GetID() string                  { return p.id }
+func (p *proxyMock) IsVetting() bool                { return false }
+func (p *proxyMock) GetMinerConnectedAt() time.Time { return time.Now() }
+func (p *proxyMock) GetHashrate() proxy.Hashrate    { return &hashrateMock{hrGHS: p.hrGHS} }
+func (p *proxyMock) GetDest() *url.URL              { return &url.URL{} }
+
+func newTestScheduler(id string, algorithm hashrate.Algorithm) *Scheduler {
+	hrFactory := func() *hashrate.Hashrate { return hashrate.NewHashrate(map[string]hashrate.Counter{}) }
+	return NewScheduler(&proxyMock{id: id, hrGHS: 100}, hashrate.MeanCounterKey, algorithm, &url.URL{}, 0, hrFactory, nil, nil, lib.NewTestLogger())
+}
+
+func TestAllocateByAlgorithm(t *testing.T) {
+	alloc := NewAllocator(lib.NewCollection[*Scheduler](), lib.NewTestLogger())
+	alloc.GetMiners().Store(newTestScheduler("sha256-miner", hashrate.AlgorithmSHA256))
+	alloc.GetMiners().Store(newTestScheduler("scrypt-miner", hashrate.AlgorithmScrypt))
+
+	minerIDs, _ := alloc.AllocateFullMinersForHR("0x01", 1000, &url.URL{}, hashrate.AlgorithmScrypt, time.Hour, nil, nil, nil)
+	require.Equal(t, []string{"scrypt-miner"}, minerIDs)
+
+	minerIDJob, _ := alloc.AllocatePartialForJob("0x02", hashrate.GHSToJobSubmittedV2(50, 10*time.Minute), &url.URL{}, hashrate.AlgorithmScrypt, 10*time.Minute, nil, nil, nil)
+	require.Empty(t, minerIDJob, "the only scrypt miner is busy")
+
+	minerIDJob, _ = alloc.AllocatePartialForJob("0x02", hashrate.GHSToJobSubmittedV2(50, 10*time.Minute), &url.URL{}, hashrate.AlgorithmSHA256, 10*time.Minute, nil, nil, nil)
+	require.Contains(t, minerIDJob, "sha256-miner")
+}
@@ -27,6 +27,7 @@ type Scheduler struct {
 	// config
 	minerVettingShares int
 	hashrateCounterID  string
+	algorithm          hashrate.Algorithm // algorithm of the listener the miner is connected to
 
 	// state
 	primaryDest     *url.URL
@@ -42,10 +43,11 @@ type Scheduler struct {
 	log       interfaces.ILogger
 }
 
-func NewScheduler(proxy StratumProxyInterface, hashrateCounterID string, defaultDest *url.URL, minerVettingShares int, hashrateFactory HashrateFactory, onVetted func(ID string), onDestErr func(contractID *string, err error), log interfaces.ILogger) *Scheduler {
+func NewScheduler(proxy StratumProxyInterface, hashrateCounterID string, algorithm hashrate.Algorithm, defaultDest *url.URL, minerVettingShares int, hashrateFactory HashrateFactory, onVetted func(ID string), onDestErr func(contractID *string, err error), log interfaces.ILogger) *Scheduler {
 	return &Scheduler{
 		primaryDest:        defaultDest,
 		hashrateCounterID:  hashrateCounterID,
+		algorithm:          algorithm,
 		minerVettingShares: minerVettingShares,
 		newTaskSignal:      make(chan struct{}, 1), // bufferized, so if at the moment of sending there is no one to receive, it will be received later
 		tasks:              NewTaskList(),
@@ -62,6 +64,11 @@ func (p *Scheduler) ID() string {
 	return p.proxy.GetID()
 }
 
+// Algorithm returns the proof of work algorithm of the miner, the miner is allocated only to the contracts of the same algorithm
+func (p *Scheduler) Algorithm() hashrate.Algorithm {
+	return p.algorithm
+}
+
 func (p *Scheduler) Run(ctx context.Context) error {
 	err := p.proxy.Connect(ctx)
 	if err != nil {
@@ -185,7 +192,7 @@ func (p *Scheduler) taskLoop(ctx context.Context, proxyTask *lib.Task) (proxyExi
 			continue
 		case <-deadlineCh:
 			err := lib.WrapError(ErrTaskDeadlineExceeded, fmt.Errorf("%s", lib.StrShort(task.ID)))
-			p.logDebugf(err.Error())
+			p.logDebugf("%s", err)
 			task.OnEnd(p.ID(), p.HashrateGHS(), float64(task.RemainingJobToSubmit.Load()), err)
 			p.tasks.UnlockAndRemove()
 			continue
@@ -223,7 +230,7 @@ func (p *Scheduler) taskLoop(ctx context.Context, proxyTask *lib.Task) (proxyExi
 			continue
 		case <-deadlineCh:
 			err := lib.WrapError(ErrTaskDeadlineExceeded, fmt.Errorf("%s", lib.StrShort(task.ID)))
-			p.logDebugf(err.Error())
+			p.logDebugf("%s", err)
 			task.OnEnd(p.ID(), p.HashrateGHS(), float64(task.RemainingJobToSubmit.Load()), err)
 			p.tasks.UnlockAndRemove()
 			continue
@@ -368,7 +375,7 @@ func (p *Scheduler) GetDestinations(cycleDuration time.Duration) []*DestItem {
 // HashrateGHS returns hashrate in GHS
 func (p *Scheduler) HashrateGHS() float64 {
 	if time.Since(p.proxy.GetMinerConnectedAt()) < 10*time.Minute {
-		hr, ok := p.proxy.GetHashrate().GetHashrateAvgGHSCustom("mean")
+		hr, ok := p.proxy.GetHashrate().GetHashrateAvgGHSCustom(hashrate.MeanCounterKey)
 		if !ok {
 			panic("hashrate counter not found")
 		}
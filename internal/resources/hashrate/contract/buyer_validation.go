@@ -1,6 +1,22 @@
 package contract
 
-import "time"
+import (
+	"math"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/hashrate"
+)
+
+type ValidationMode string
+
+const (
+	ValidationModeFlatness   ValidationMode = "flatness"   // relative error is compared against the flatness curve (GetMaxGlobalError)
+	ValidationModeConfidence ValidationMode = "confidence" // share count is compared against the poisson confidence interval
+)
+
+func (m ValidationMode) String() string {
+	return string(m)
+}
 
 func GetMaxGlobalError(elapsed time.Duration, minError float64, flatness, skipPeriod time.Duration) float64 {
 	maxErr := float64(flatness) / float64(elapsed+flatness-skipPeriod)
@@ -12,3 +28,69 @@ func GetMaxGlobalError(elapsed time.Duration, minError float64, flatness, skipPe
 	}
 	return maxErr
 }
+
+// HashrateInterval is the confidence interval of the hashrate estimated from the submitted shares
+type HashrateInterval struct {
+	Elapsed        time.Duration
+	Shares         int
+	AvgDiff        float64
+	ExpectedShares float64 // number of shares expected to be submitted with the target hashrate
+	EstimateGHS    float64
+	LowerGHS       float64 // lower bound of the one-sided interval with 1-falsePositive confidence
+	UpperGHS       float64 // upper bound of the one-sided interval with 1-falsePositive confidence
+	MinAcceptedGHS float64 // minimum hashrate that is considered as accurate delivery
+	Confidence     float64
+}
+
+// IsUnderdelivering returns true if the whole interval is below the minimum accepted hashrate, so the probability
+// of the false positive verdict doesn't exceed 1-Confidence. Returns false if there is not enough data.
+func (i HashrateInterval) IsUnderdelivering() bool {
+	if i.Shares == 0 {
+		// without shares the difficulty is unknown, this case is covered by the share timeout
+		return false
+	}
+	return i.UpperGHS < i.MinAcceptedGHS
+}
+
+// GetHashrateConfidenceInterval estimates the hashrate confidence interval from the number of shares submitted with the
+// average difficulty within elapsed time. Share arrivals are modeled as a poisson process, so the interval bounds of
+// the share count are approximated with the Byar's method, which is accurate for low share counts as well.
+func GetHashrateConfidenceInterval(targetGHS float64, shares int, avgDiff float64, elapsed time.Duration, errorThreshold float64, falsePositive float64) HashrateInterval {
+	interval := HashrateInterval{
+		Elapsed:        elapsed,
+		Shares:         shares,
+		AvgDiff:        avgDiff,
+		MinAcceptedGHS: targetGHS * (1 - errorThreshold),
+		Confidence:     1 - falsePositive,
+	}
+	if shares == 0 || avgDiff <= 0 || elapsed <= 0 {
+		return interval
+	}
+
+	z := normalQuantile(1 - falsePositive)
+	lowerShares, upperShares := PoissonConfidenceBounds(shares, z)
+
+	interval.ExpectedShares = hashrate.GHSToJobSubmittedV2(targetGHS, elapsed) / avgDiff
+	interval.EstimateGHS = hashrate.JobSubmittedToGHSV2(float64(shares)*avgDiff, elapsed)
+	interval.LowerGHS = hashrate.JobSubmittedToGHSV2(lowerShares*avgDiff, elapsed)
+	interval.UpperGHS = hashrate.JobSubmittedToGHSV2(upperShares*avgDiff, elapsed)
+
+	return interval
+}
+
+// PoissonConfidenceBounds returns one-sided lower and upper bounds of the poisson
+// mean for the observed count n, where z is the standard normal quantile
+func PoissonConfidenceBounds(n int, z float64) (lower, upper float64) {
+	if n > 0 {
+		fn := float64(n)
+		lower = fn * math.Pow(1-1/(9*fn)-z/(3*math.Sqrt(fn)), 3)
+	}
+	fn1 := float64(n + 1)
+	upper = fn1 * math.Pow(1-1/(9*fn1)+z/(3*math.Sqrt(fn1)), 3)
+	return math.Max(lower, 0), upper
+}
+
+// normalQuantile is the inverse of the standard normal cumulative distribution function
+func normalQuantile(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
@@ -1,11 +1,29 @@
 package contract
 
 import (
+	"context"
 	"fmt"
+	"math/big"
 	"testing"
 	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources"
+	hashrateContract "github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/hashrate"
+	"github.com/stretchr/testify/require"
 )
 
+type policySpy struct {
+	ValidationPolicy
+	calls int
+}
+
+func (p *policySpy) Validate(in ValidationInput) ValidationVerdict {
+	p.calls++
+	return p.ValidationPolicy.Validate(in)
+}
+
 func TestC1(t *testing.T) {
 	data := []time.Duration{}
 	for i := 0; i < 60; i++ {
@@ -17,3 +35,63 @@ func TestC1(t *testing.T) {
 		fmt.Printf("elapsed %s - error threshold %.2f\n", d, k)
 	}
 }
+
+func TestPoissonConfidenceBounds(t *testing.T) {
+	z := normalQuantile(0.99)
+	for _, n := range []int{0, 1, 5, 20, 100, 1000} {
+		lower, upper := PoissonConfidenceBounds(n, z)
+		require.LessOrEqual(t, lower, float64(n))
+		require.Greater(t, upper, float64(n))
+	}
+
+	// exact 99% one-sided bounds for n=10 derived from chi-square distribution
+	lower, upper := PoissonConfidenceBounds(10, z)
+	require.InDelta(t, 4.13, lower, 0.1)
+	require.InDelta(t, 20.14, upper, 0.2)
+}
+
+func TestGetHashrateConfidenceInterval(t *testing.T) {
+	targetGHS := 100_000.0
+	elapsed := 30 * time.Minute
+	avgDiff := 100_000.0
+	expectedShares := int(hashrate.GHSToJobSubmittedV2(targetGHS, elapsed) / avgDiff)
+
+	interval := GetHashrateConfidenceInterval(targetGHS, expectedShares, avgDiff, elapsed, 0.1, 0.01)
+	require.LessOrEqual(t, interval.LowerGHS, interval.EstimateGHS)
+	require.GreaterOrEqual(t, interval.UpperGHS, interval.EstimateGHS)
+	require.InDelta(t, targetGHS, interval.EstimateGHS, targetGHS*0.01)
+	require.False(t, interval.IsUnderdelivering())
+
+	interval = GetHashrateConfidenceInterval(targetGHS, expectedShares/2, avgDiff, elapsed, 0.1, 0.01)
+	require.True(t, interval.IsUnderdelivering())
+
+	// a few shares are not enough to conclude underdelivery
+	interval = GetHashrateConfidenceInterval(targetGHS, 1, avgDiff*10, time.Minute, 0.1, 0.01)
+	require.False(t, interval.IsUnderdelivering())
+
+	interval = GetHashrateConfidenceInterval(targetGHS, 0, 0, elapsed, 0.1, 0.01)
+	require.False(t, interval.IsUnderdelivering())
+}
+
+func TestBuyerValidationNotRunBeforeStart(t *testing.T) {
+	hrFactory := func() *hashrate.Hashrate { return hashrate.NewHashrate(map[string]hashrate.Counter{}) }
+	terms := &hashrateContract.Terms{
+		BaseTerms: *hashrateContract.NewBaseTerms("0x1", "", "", "", time.Now(), time.Hour, 100, big.NewInt(0), 0, false, big.NewInt(0), false, 0),
+	}
+	policy := &policySpy{ValidationPolicy: NewValidationPolicy(testPolicyConfig)}
+	globalHashrate := hashrate.NewGlobalHashrate(hrFactory, time.Minute, time.Hour)
+	watcher := NewContractWatcherBuyer(terms, hrFactory, nil, globalHashrate, lib.NewTestLogger(), time.Minute, hashrate.MeanCounterKey,
+		time.Now().Add(time.Hour), resources.ContractRoleBuyer, nil, nil, policy)
+
+	// the app start timeout, the stateful policy is not run, the delivery is still logged
+	require.NoError(t, watcher.checkIncomingHashrate(context.Background()))
+	require.Equal(t, 0, policy.calls)
+	entries, err := watcher.GetDeliveryLogs()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, ValidationStatusPending, entries[0].ValidationStatus)
+
+	watcher.validatorStartTime = time.Now().Add(-time.Second)
+	_ = watcher.checkIncomingHashrate(context.Background())
+	require.Equal(t, 1, policy.calls)
+}
@@ -21,10 +21,7 @@ import (
 type ContractWatcherBuyer struct {
 	// config
 	contractCycleDuration    time.Duration
-	shareTimeout             time.Duration // time to wait for the share to arrive, otherwise close contract
-	hrErrorThreshold         float64       // hashrate relative error threshold for the contract to be considered fulfilling accurately
 	hashrateCounterNameBuyer string
-	hrValidationFlatness     time.Duration
 	role                     resources.ContractRole
 	validatorStartTime       time.Time
 	defaultDest              *url.URL
@@ -34,6 +31,10 @@ type ContractWatcherBuyer struct {
 	validationStage      *lib.AtomicValue[hashrateContract.ValidationStage]
 	fulfillmentStartedAt *atomic.Time
 	starvingGHS          *atomic.Uint64
+	hrInterval           *lib.AtomicValue[HashrateInterval]
+	destChangedAt        *atomic.Time
+	deliveryLog          *DeliveryLog
+	destErrors           *DestErrorLog
 	contractErr          atomic.Error // keeps the last error that happened in the contract that prevents it from fulfilling correctly, like invalid destination
 	contractErrCh        chan struct{}
 	startedCh            chan struct{}
@@ -48,6 +49,8 @@ type ContractWatcherBuyer struct {
 	Terms
 	allocator      *allocator.Allocator
 	globalHashrate *hashrate.GlobalHashrate
+	destRouter     *DestRouter      // splits the incoming connections among several pools, nil to use the contract destination
+	policy         ValidationPolicy // decides whether the contract is delivered accurately
 	log            interfaces.ILogger
 }
 
@@ -65,19 +68,15 @@ func NewContractWatcherBuyer(
 	log interfaces.ILogger,
 
 	cycleDuration time.Duration,
-	shareTimeout time.Duration,
-	hrErrorThreshold float64,
 	hashrateCounterNameBuyer string,
-	hrValidationFlatness time.Duration,
 	validatorStartTime time.Time,
 	role resources.ContractRole,
 	defaultDest *url.URL,
+	destRouter *DestRouter,
+	policy ValidationPolicy,
 ) *ContractWatcherBuyer {
 	return &ContractWatcherBuyer{
 		contractCycleDuration:    cycleDuration,
-		shareTimeout:             shareTimeout,
-		hrErrorThreshold:         hrErrorThreshold,
-		hrValidationFlatness:     hrValidationFlatness,
 		hashrateCounterNameBuyer: hashrateCounterNameBuyer,
 		role:                     role,
 		validatorStartTime:       validatorStartTime,
@@ -87,6 +86,10 @@ func NewContractWatcherBuyer(
 		validationStage:      lib.NewAtomicValue(hashrateContract.ValidationStageValidating),
 		fulfillmentStartedAt: atomic.NewTime(time.Time{}),
 		starvingGHS:          atomic.NewUint64(0),
+		hrInterval:           lib.NewAtomicValue(HashrateInterval{}),
+		destChangedAt:        atomic.NewTime(time.Time{}),
+		deliveryLog:          NewDeliveryLog(),
+		destErrors:           NewDestErrorLog(),
 		contractErrCh:        make(chan struct{}),
 		startedCh:            make(chan struct{}),
 		doneCh:               make(chan struct{}),
@@ -94,6 +97,8 @@ func NewContractWatcherBuyer(
 		Terms:          terms,
 		allocator:      allocator,
 		globalHashrate: globalHashrate,
+		destRouter:     destRouter,
+		policy:         policy,
 		log:            log,
 	}
 }
@@ -213,7 +218,7 @@ func (p *ContractWatcherBuyer) proceedToNextStage() {
 func (p *ContractWatcherBuyer) checkIncomingHashrate(ctx context.Context) error {
 	p.proceedToNextStage()
 
-	isHashrateOK := p.isReceivingAcceptableHashrate()
+	verdict := p.validate()
 
 	if !p.isValidationStarted() {
 		return nil
@@ -221,19 +226,7 @@ func (p *ContractWatcherBuyer) checkIncomingHashrate(ctx context.Context) error
 
 	switch p.validationStage.Load() {
 	case hashrateContract.ValidationStageValidating:
-		lastShareTime, ok := p.globalHashrate.GetLastSubmitTime(p.getWorkerName())
-		if !ok {
-			lastShareTime = p.fulfillmentStartedAt.Load()
-		}
-		if time.Since(lastShareTime) > p.shareTimeout {
-			err := fmt.Errorf("no share submitted within shareTimeout (%s), lastShare at (%s)", p.shareTimeout, lastShareTime.Format(time.RFC3339))
-			return lib.WrapError(ErrShareTimeout, err)
-		}
-
-		if !isHashrateOK {
-			return ErrUnderdelivery
-		}
-		return nil
+		return verdict.Err
 	case hashrateContract.ValidationStageFinished:
 		return fmt.Errorf("contract is finished")
 	default:
@@ -241,7 +234,8 @@ func (p *ContractWatcherBuyer) checkIncomingHashrate(ctx context.Context) error
 	}
 }
 
-func (p *ContractWatcherBuyer) isReceivingAcceptableHashrate() bool {
+// validate collects the delivery state and runs the validation policy, the verdict is recorded to the delivery log
+func (p *ContractWatcherBuyer) validate() ValidationVerdict {
 	actualHashrate, ok := p.globalHashrate.GetHashRateGHS(p.getWorkerName(), p.hashrateCounterNameBuyer)
 	if !ok {
 		p.log.Warnf("no hashrate submitted yet")
@@ -252,33 +246,63 @@ func (p *ContractWatcherBuyer) isReceivingAcceptableHashrate() bool {
 	p.starvingGHS.Store(uint64(starvingGHS))
 	fulfilmentElapsed := time.Since(p.fulfillmentStartedAt.Load())
 
-	hrError := lib.RelativeError(targetHashrateGHS, actualHashrate)
-	maxHrError := GetMaxGlobalError(fulfilmentElapsed, p.hrErrorThreshold, p.hrValidationFlatness, 5*time.Minute)
-
 	totalShares := 0
 	worker := p.globalHashrate.GetWorker(p.getWorkerName())
 	if worker != nil {
 		totalShares = worker.GetTotalShares()
 	}
 
-	hrMsg := fmt.Sprintf(
-		"elapsed %s target GHS %.0f, actual GHS %.0f, error %.0f%%, threshold(%.0f%%) totalShares(%d)",
-		fulfilmentElapsed.Round(time.Second), targetHashrateGHS, actualHashrate, hrError*100, maxHrError*100, totalShares,
-	)
+	totalWork, _ := p.globalHashrate.GetTotalWork(p.getWorkerName())
+	avgDiff := 0.0
+	if totalShares > 0 {
+		avgDiff = totalWork / float64(totalShares)
+	}
+
+	policyCfg := p.policy.Config()
+	interval := GetHashrateConfidenceInterval(targetHashrateGHS, totalShares, avgDiff, fulfilmentElapsed, policyCfg.ErrorThreshold, policyCfg.FalsePositive)
+	p.hrInterval.Store(interval)
 
-	if hrError <= maxHrError {
-		p.log.Infof("contract is delivering accurately: %s", hrMsg)
-		return true
+	lastShareTime, ok := p.globalHashrate.GetLastSubmitTime(p.getWorkerName())
+	if !ok {
+		lastShareTime = p.fulfillmentStartedAt.Load()
 	}
 
-	if actualHashrate > targetHashrateGHS {
-		p.log.Infof("contract is overdelivering: %s", hrMsg)
-		// contract overdelivery is ok for buyer
-		return true
+	// the policy keeps the state between the checks, so it is not run until the validation starts,
+	// otherwise the outage tolerance would be spent during the app start timeout
+	verdict := ValidationVerdict{
+		Status: ValidationStatusPending,
+		Reason: fmt.Sprintf("validation starts at %s", p.validatorStartTime.Format(time.RFC3339)),
+	}
+	if p.isValidationStarted() {
+		verdict = p.policy.Validate(ValidationInput{
+			Now:           time.Now(),
+			Elapsed:       fulfilmentElapsed,
+			TargetGHS:     targetHashrateGHS,
+			ActualGHS:     actualHashrate,
+			TotalWork:     totalWork,
+			LastShareTime: lastShareTime,
+			DestChangedAt: p.destChangedAt.Load(),
+			Interval:      interval,
+		})
+
+		if verdict.Err != nil {
+			p.log.Warnf("contract validation (%s policy) failed: %s", p.policy.Name(), verdict.Reason)
+		} else {
+			p.log.Infof("contract validation (%s policy) %s: %s", p.policy.Name(), verdict.Status, verdict.Reason)
+		}
 	}
 
-	p.log.Warnf("contract is underdelivering: %s, %f", hrMsg, hrError-maxHrError)
-	return false
+	p.deliveryLog.AddEntry(DeliveryLogEntry{
+		Timestamp:        time.Now(),
+		ActualGHS:        int(actualHashrate),
+		UnderDeliveryGHS: int(starvingGHS),
+		GlobalError:      lib.RelativeError(targetHashrateGHS, actualHashrate),
+		ValidationPolicy: p.policy.Name(),
+		ValidationStatus: verdict.Status,
+		ValidationReason: verdict.Reason,
+	})
+
+	return verdict
 }
 
 func (p *ContractWatcherBuyer) getUntilContractEnd() time.Duration {
@@ -298,6 +322,7 @@ func (p *ContractWatcherBuyer) getWorkerName() string {
 }
 
 func (p *ContractWatcherBuyer) SetError(err error) {
+	p.destErrors.Add(err)
 	swapped := p.contractErr.CompareAndSwap(nil, err)
 	if swapped {
 		close(p.contractErrCh)
@@ -345,7 +370,12 @@ func (p *ContractWatcherBuyer) Dest() string {
 	return ""
 }
 
+// PoolDest returns the destination of the contract, if the destination routes are configured they override
+// the destination set in the contract, so the routes are returned instead
 func (p *ContractWatcherBuyer) PoolDest() string {
+	if p.destRouter != nil {
+		return p.destRouter.String()
+	}
 	url := p.Terms.PoolDest()
 	if url == nil {
 		return p.defaultDest.String()
@@ -353,10 +383,72 @@ func (p *ContractWatcherBuyer) PoolDest() string {
 	return url.String()
 }
 
+// PoolDests returns the destinations for the new incoming connection in the order they should be tried
+// and the callback to report the result of the connection to the n-th of them, nil if it is not tracked
+func (p *ContractWatcherBuyer) PoolDests() ([]*url.URL, func(n int, err error)) {
+	if p.destRouter == nil {
+		dest, err := url.Parse(p.PoolDest())
+		if err != nil {
+			return nil, nil
+		}
+		return []*url.URL{dest}, nil
+	}
+
+	dests, indexes := p.destRouter.Pick()
+	return dests, func(n int, err error) {
+		if err != nil {
+			p.log.Warnf("destination %s failed, trying next: %s", dests[n].Redacted(), err)
+		}
+		p.destRouter.OnResult(indexes[n], err)
+	}
+}
+
+// IsDestRouted returns true if the destination of the contract is overridden by the destination routes
+func (p *ContractWatcherBuyer) IsDestRouted() bool {
+	return p.destRouter != nil
+}
+
+// DestRoutes returns the state of the destination routes, nil if the contract has the single destination
+func (p *ContractWatcherBuyer) DestRoutes() []DestRouteStats {
+	if p.destRouter == nil {
+		return nil
+	}
+	return p.destRouter.GetStats()
+}
+
 func (p *ContractWatcherBuyer) StarvingGHS() int {
 	return int(p.starvingGHS.Load())
 }
 
+// DeliveryAccuracy returns the share of the contract hashrate received during the last check, capped at 1
+func (p *ContractWatcherBuyer) DeliveryAccuracy() float64 {
+	target := p.HashrateGHS()
+	if target <= 0 {
+		return 0
+	}
+	return math.Max(1-float64(p.starvingGHS.Load())/target, 0)
+}
+
+// OnDestinationChanged starts the grace period of the validation policy
+func (p *ContractWatcherBuyer) OnDestinationChanged() {
+	p.destChangedAt.Store(time.Now())
+}
+
+// GetDeliveryLogs returns the verdicts of the validation policy
+func (p *ContractWatcherBuyer) GetDeliveryLogs() ([]DeliveryLogEntry, error) {
+	return p.deliveryLog.GetEntries()
+}
+
+// GetDestErrors returns the last errors reported for the contract destination
+func (p *ContractWatcherBuyer) GetDestErrors() []DestError {
+	return p.destErrors.GetEntries()
+}
+
+// HashrateInterval returns the confidence interval of the incoming hashrate calculated during the last check
+func (p *ContractWatcherBuyer) HashrateInterval() HashrateInterval {
+	return p.hrInterval.Load()
+}
+
 func (p *ContractWatcherBuyer) Error() error {
 	return p.contractErr.Load()
 }
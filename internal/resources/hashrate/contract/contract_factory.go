@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/big"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
@@ -18,28 +19,34 @@ import (
 
 type ContractFactory struct {
 	// config
-	privateKey               string // private key of the user
 	cycleDuration            time.Duration
-	shareTimeout             time.Duration
-	hrErrorThreshold         float64
 	hashrateCounterNameBuyer string
-	validatorFlatness        time.Duration
+	hashrateCounterName      string                            // counter of the seller contracts delivery log
+	validationPolicy         ValidationPolicyConfig            // default validation rules of the buyer contracts
+	validationPolicies       map[string]ValidationPolicyConfig // validation rules by contract id, override the default
 	validatorStartTime       time.Time
 	defaultDest              *url.URL
 	validatorURL             *url.URL
 	contractDuration         time.Duration
 	contractHashrateGHPS     float64
+	destRoutes               []DestRoute // pools the hashrate of the purchased contracts is split among, overrides the contract destination
+	destRoutingMode          DestRoutingMode
+	algorithm                hashrate.Algorithm            // default algorithm of the sold contracts
+	algorithms               map[string]hashrate.Algorithm // algorithm by lowercase contract id, overrides the default
 
 	// state
-	address common.Address // derived from private key
+	address common.Address // address of the wallet
 
 	// deps
+	signer          interfaces.Signer
 	store           *contracts.HashrateEthereum
 	futuresStore    *contracts.FuturesEthereum
 	allocator       *allocator.Allocator
 	globalHashrate  *hashrate.GlobalHashrate
 	hashrateFactory func() *hashrate.Hashrate
 	logFactory      func(contractID string) (interfaces.ILogger, error)
+	validations     ValidationRecorder // records the verdicts of the buyer validation for the seller reputation, can be nil
+	attestor        DeliveryAttestor   // signs the delivery reports of the validated futures positions, can be nil
 }
 
 func NewContractFactory(
@@ -50,44 +57,56 @@ func NewContractFactory(
 	futuresStore *contracts.FuturesEthereum,
 	logFactory func(contractID string) (interfaces.ILogger, error),
 
-	privateKey string,
+	signer interfaces.Signer,
 	cycleDuration time.Duration,
-	shareTimeout time.Duration,
-	hrErrorThreshold float64,
 	hashrateCounterNameBuyer string,
-	validatorFlatness time.Duration,
+	hashrateCounterName string,
+	validationPolicy ValidationPolicyConfig,
+	validationPolicies map[string]ValidationPolicyConfig,
 	validatorStartTime time.Time,
 	defaultDest *url.URL,
 	validatorURL *url.URL,
 	contractDuration time.Duration,
 	contractHashrateGHPS float64,
+	destRoutes []DestRoute,
+	destRoutingMode DestRoutingMode,
+	algorithm hashrate.Algorithm,
+	algorithms map[string]hashrate.Algorithm,
+	validations ValidationRecorder,
+	attestor DeliveryAttestor,
 ) (*ContractFactory, error) {
-	address, err := lib.PrivKeyStringToAddr(privateKey)
-	if err != nil {
-		return nil, err
+	contractAlgorithms := make(map[string]hashrate.Algorithm, len(algorithms))
+	for contractID, algo := range algorithms {
+		contractAlgorithms[strings.ToLower(contractID)] = algo
 	}
 
 	return &ContractFactory{
+		signer:          signer,
 		allocator:       allocator,
 		hashrateFactory: hashrateFactory,
 		globalHashrate:  globalHashrate,
 		store:           store,
 		futuresStore:    futuresStore,
 		logFactory:      logFactory,
+		validations:     validations,
+		attestor:        attestor,
 
-		address: address,
+		address: signer.Address(),
 
-		privateKey:               privateKey,
 		cycleDuration:            cycleDuration,
-		shareTimeout:             shareTimeout,
-		hrErrorThreshold:         hrErrorThreshold,
 		hashrateCounterNameBuyer: hashrateCounterNameBuyer,
-		validatorFlatness:        validatorFlatness,
+		hashrateCounterName:      hashrateCounterName,
+		validationPolicy:         validationPolicy,
+		validationPolicies:       validationPolicies,
 		validatorStartTime:       validatorStartTime,
 		defaultDest:              defaultDest,
 		validatorURL:             validatorURL,
 		contractDuration:         contractDuration,
 		contractHashrateGHPS:     contractHashrateGHPS,
+		destRoutes:               destRoutes,
+		destRoutingMode:          destRoutingMode,
+		algorithm:                algorithm,
+		algorithms:               contractAlgorithms,
 	}, nil
 }
 
@@ -106,8 +125,8 @@ func (c *ContractFactory) CreateContract(contractData *hashrateContract.Encrypte
 			ValidatorURL: nil,
 		}
 
-		watcher := NewContractWatcherSellerV2(terms, c.cycleDuration, c.hashrateFactory, c.allocator, logNamed)
-		return NewControllerSeller(watcher, c.store, c.privateKey), nil
+		watcher := NewContractWatcherSellerV2(terms, c.cycleDuration, c.getAlgorithm(terms.ID()), c.hashrateCounterName, c.hashrateFactory, c.allocator, logNamed)
+		return NewControllerSeller(watcher, c.store, c.signer), nil
 	}
 
 	if contractData.Buyer() == c.address.String() || contractData.Validator() == c.address.String() {
@@ -131,6 +150,14 @@ func (c *ContractFactory) CreateContract(contractData *hashrateContract.Encrypte
 			DestURL:      destUrl,
 			ValidatorURL: nil,
 		}
+
+		// the routes apply only to the contracts purchased by this wallet, the validated contracts
+		// of other buyers are forwarded to their destination
+		var destRouter *DestRouter
+		if role == resources.ContractRoleBuyer && len(c.destRoutes) > 0 {
+			destRouter = NewDestRouter(c.destRoutingMode, c.destRoutes, DestRouteRetryAfter)
+		}
+
 		watcher := NewContractWatcherBuyer(
 			terms,
 			c.hashrateFactory,
@@ -139,20 +166,19 @@ func (c *ContractFactory) CreateContract(contractData *hashrateContract.Encrypte
 			logNamed,
 
 			c.cycleDuration,
-			c.shareTimeout,
-			c.hrErrorThreshold,
 			c.hashrateCounterNameBuyer,
-			c.validatorFlatness,
 			c.validatorStartTime,
 			role,
 			c.defaultDest,
+			destRouter,
+			c.createValidationPolicy(contractData.ID()),
 		)
 
 		if destErr != nil {
 			watcher.contractErr.Store(destErr)
 		}
 
-		return NewControllerBuyer(watcher, c.store, c.privateKey, false), nil
+		return NewControllerBuyer(watcher, c.store, c.signer, false, c.validations), nil
 	}
 	return nil, fmt.Errorf("invalid terms %+v", contractData)
 }
@@ -167,7 +193,7 @@ func (c *ContractFactory) CreateFuturesContractSeller(contractData *contracts.Fu
 	if err != nil {
 		return nil, err
 	}
-	watcher := NewContractWatcherSellerV2(terms, c.cycleDuration, c.hashrateFactory, c.allocator, logNamed)
+	watcher := NewContractWatcherSellerV2(terms, c.cycleDuration, c.getAlgorithm(terms.ID()), c.hashrateCounterName, c.hashrateFactory, c.allocator, logNamed)
 	return NewControllerFuturesSeller(watcher, contractData.DeliveryAt), nil
 }
 
@@ -188,15 +214,22 @@ func (c *ContractFactory) CreateFuturesContractBuyer(contractData *contracts.Fut
 		c.globalHashrate,
 		logNamed,
 		c.cycleDuration,
-		c.shareTimeout,
-		c.hrErrorThreshold,
 		c.hashrateCounterNameBuyer,
-		c.validatorFlatness,
 		c.validatorStartTime,
 		resources.ContractRoleBuyer,
 		c.defaultDest,
+		nil,
+		c.createValidationPolicy(contractData.ID()),
 	)
-	return NewControllerFuturesBuyer(watcher, c.futuresStore, contractData.DeliveryAt, c.privateKey, false), nil
+	return NewControllerFuturesBuyer(watcher, c.futuresStore, contractData.DeliveryAt, c.signer, false, c.attestor), nil
+}
+
+// getAlgorithm returns the algorithm of the contract, the miners of other algorithms are not allocated to it
+func (c *ContractFactory) getAlgorithm(contractID string) hashrate.Algorithm {
+	if algorithm, ok := c.algorithms[strings.ToLower(contractID)]; ok {
+		return algorithm
+	}
+	return c.algorithm
 }
 
 func (c *ContractFactory) getDestURL(destEncrypted string) (*url.URL, error) {
@@ -204,7 +237,7 @@ func (c *ContractFactory) getDestURL(destEncrypted string) (*url.URL, error) {
 		return nil, nil
 	}
 
-	dest, err := lib.DecryptString(destEncrypted, c.privateKey)
+	dest, err := lib.DecryptStringWith(destEncrypted, c.signer.Decrypt)
 	if err != nil {
 		return nil, err
 	}
@@ -212,6 +245,15 @@ func (c *ContractFactory) getDestURL(destEncrypted string) (*url.URL, error) {
 	return url.Parse(dest)
 }
 
+// createValidationPolicy creates the validation policy configured for the contract, or the default one
+func (c *ContractFactory) createValidationPolicy(contractID string) ValidationPolicy {
+	cfg, ok := c.validationPolicies[contractID]
+	if !ok {
+		cfg = c.validationPolicy
+	}
+	return NewValidationPolicy(cfg)
+}
+
 func (c *ContractFactory) GetType() resources.ResourceType {
 	return ResourceTypeHashrate
 }
@@ -31,6 +31,8 @@ var (
 type ContractWatcherSellerV2 struct {
 	// config
 	contractCycleDuration time.Duration
+	algorithm             hr.Algorithm // only the miners of this algorithm are allocated
+	hashrateCounterID     string       // counter of the delivered hashrate reported in the delivery log next to the mean
 
 	// state
 	stats             *stats
@@ -40,6 +42,8 @@ type ContractWatcherSellerV2 struct {
 	cycleEndsAt       time.Time
 	minerDisconnectCh *lib.ChanRecvStop[allocator.MinerItem]
 	deliveryLog       *DeliveryLog
+	submitLog         *SubmitLog    // shares of each miner of the current or the last delivery for the dispute evidence
+	destErrors        *DestErrorLog // errors reported with SetError
 
 	// shared state
 	fulfillmentStartedAt atomic.Value // time.Time
@@ -57,11 +61,15 @@ type ContractWatcherSellerV2 struct {
 	log       interfaces.ILogger
 }
 
-func NewContractWatcherSellerV2(terms Terms, cycleDuration time.Duration, hashrateFactory func() *hr.Hashrate, allocator *allocator.Allocator, log interfaces.ILogger) *ContractWatcherSellerV2 {
+func NewContractWatcherSellerV2(terms Terms, cycleDuration time.Duration, algorithm hr.Algorithm, hashrateCounterID string, hashrateFactory func() *hr.Hashrate, allocator *allocator.Allocator, log interfaces.ILogger) *ContractWatcherSellerV2 {
+	submitLog := NewSubmitLog()
 	return &ContractWatcherSellerV2{
 		contractCycleDuration: cycleDuration,
+		algorithm:             algorithm,
+		hashrateCounterID:     hashrateCounterID,
 		stats: &stats{
 			actualHRGHS: hashrateFactory(),
+			submits:     submitLog,
 		},
 		isRunning:   false,
 		startCh:     make(chan struct{}),
@@ -69,6 +77,8 @@ func NewContractWatcherSellerV2(terms Terms, cycleDuration time.Duration, hashra
 		doneCh:      make(chan struct{}),
 		err:         atomic.NewError(nil),
 		deliveryLog: NewDeliveryLog(),
+		submitLog:   submitLog,
+		destErrors:  NewDestErrorLog(),
 		Terms:       terms,
 		allocator:   allocator,
 		hrFactory:   hashrateFactory,
@@ -80,6 +90,8 @@ func (p *ContractWatcherSellerV2) StartFulfilling() error {
 	p.isRunningMutex.Lock()
 	defer p.isRunningMutex.Unlock()
 	p.Reset()
+	// the submits of the previous delivery are kept after the contract is closed for the dispute evidence
+	p.submitLog.Reset()
 
 	p.isRunning = true
 	close(p.startCh)
@@ -141,6 +153,7 @@ func (p *ContractWatcherSellerV2) Reset() {
 		partialMiners:          make([]string, 0),
 		actualHRGHS:            p.hrFactory(),
 		deliveryTargetGHS:      0,
+		submits:                p.submitLog,
 	}
 	p.isRunning = false
 	p.startCh = make(chan struct{})
@@ -170,6 +183,7 @@ func (p *ContractWatcherSellerV2) run() error {
 		partialMiners:          make([]string, 0),
 		actualHRGHS:            p.hrFactory(),
 		deliveryTargetGHS:      0,
+		submits:                p.submitLog,
 	}
 
 	p.minerDisconnectCh = lib.NewChanRecvStop[allocator.MinerItem]()
@@ -256,6 +270,7 @@ CONTRACT_CYCLE:
 func (p *ContractWatcherSellerV2) onCycleEnd(cycleDuration time.Duration) {
 	thisCycleActualGHS := hr.JobSubmittedToGHSV2(p.stats.totalJob(), cycleDuration)
 	thisCycleUnderDeliveryGHS := p.HashrateGHS() - thisCycleActualGHS
+	globalActualGHS := p.stats.actualHRGHS.GetHashrateAvgGHSAll()[hr.MeanCounterKey]
 	p.stats.globalUnderDeliveryGHS.Add(int64(thisCycleUnderDeliveryGHS))
 	p.stats.deliveryTargetGHS = p.HashrateGHS() - p.getFullMinersHR() + float64(p.stats.globalUnderDeliveryGHS.Load())
 
@@ -269,16 +284,26 @@ func (p *ContractWatcherSellerV2) onCycleEnd(cycleDuration time.Duration) {
 		PartialMiners:                     lib.CopySlice(p.stats.partialMiners),
 		PartialMinersShares:               int(p.stats.sharesPartialMiners.Load()),
 		UnderDeliveryGHS:                  int(thisCycleUnderDeliveryGHS),
-		GlobalHashrateGHS:                 int(p.stats.actualHRGHS.GetHashrateAvgGHSAll()["mean"]),
+		GlobalHashrateGHS:                 int(globalActualGHS),
 		GlobalUnderDeliveryGHS:            int(p.stats.globalUnderDeliveryGHS.Load()),
-		GlobalError:                       1 - p.stats.actualHRGHS.GetHashrateAvgGHSAll()["mean"]/p.HashrateGHS(),
+		GlobalError:                       1 - globalActualGHS/p.HashrateGHS(),
 		NextCyclePartialDeliveryTargetGHS: int(p.stats.deliveryTargetGHS),
+		DeliveredHashrateGHS:              int(p.deliveredGHS()),
 	}
 	p.deliveryLog.AddEntry(logEntry)
 
 	p.log.Infof("contract cycle ended %+v", logEntry)
 }
 
+// deliveredGHS returns the delivered hashrate by the configured counter, which may be a moving average unlike
+// the since-start mean of GlobalHashrateGHS. The mean counter is used if the counter is not found
+func (p *ContractWatcherSellerV2) deliveredGHS() float64 {
+	if hrGHS, ok := p.stats.actualHRGHS.GetHashrateAvgGHSCustom(p.hashrateCounterID); ok {
+		return hrGHS
+	}
+	return p.stats.actualHRGHS.GetHashrateAvgGHSAll()[hr.MeanCounterKey]
+}
+
 // adjustHashrate adjusts the hashrate of the contract by adding/removing full miners and allocating partial miners
 // if hashrateGHS > 0 the allocation increases, if hashrateGHS < 0 the allocation decreases
 // returns the amount of hashrateGHS that was added or removed (with negative sign)
@@ -330,6 +355,7 @@ func (p *ContractWatcherSellerV2) addFullMiners(hashrateGHS float64) (addedGHS f
 		p.ID(),
 		hashrateGHS,
 		p.getAdjustedDest(),
+		p.algorithm,
 		p.Duration(),
 		p.stats.onFullMinerShare,
 		func(ID string, hashrateGHS float64, remainingJob float64) {
@@ -386,6 +412,7 @@ func (p *ContractWatcherSellerV2) addPartialMiners(job float64, cycleEndTimeout
 		p.ID(),
 		job,
 		p.getAdjustedDest(),
+		p.algorithm,
 		cycleEndTimeout,
 		func(diff float64, ID string) {
 			p.stats.onPartialMinerShare(diff, ID)
@@ -651,9 +678,25 @@ func (p *ContractWatcherSellerV2) Error() error {
 
 func (p *ContractWatcherSellerV2) SetError(err error) {
 	p.contractErr.Store(err)
+	p.destErrors.Add(err)
 	p.log.Infof("contract error was set: %s", err)
 }
 
+// GetMinerSubmitStats returns the shares submitted by each miner during the current or the last delivery
+func (p *ContractWatcherSellerV2) GetMinerSubmitStats() []MinerSubmitStats {
+	return p.submitLog.GetMinerStats()
+}
+
+// GetShareSamples returns the last shares submitted during the current or the last delivery
+func (p *ContractWatcherSellerV2) GetShareSamples() []ShareSample {
+	return p.submitLog.GetShareSamples()
+}
+
+// GetDestErrors returns the last errors reported for the contract destination
+func (p *ContractWatcherSellerV2) GetDestErrors() []DestError {
+	return p.destErrors.GetEntries()
+}
+
 func (p *ContractWatcherSellerV2) logDeliveryTarget() {
 	p.log.Debugf("deliveryTarget %.0f GHS", p.stats.deliveryTargetGHS)
 }
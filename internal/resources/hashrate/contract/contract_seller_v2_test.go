@@ -0,0 +1,32 @@
+package contract
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	hr "github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/hashrate"
+	"github.com/stretchr/testify/require"
+)
+
+type counterMock struct {
+	hr.Counter
+	perSecond float64
+}
+
+func (c *counterMock) ValuePer(t time.Duration) float64 {
+	return c.perSecond
+}
+
+func TestSellerDeliveredGHSCounter(t *testing.T) {
+	hrFactory := func() *hr.Hashrate {
+		return hr.NewHashrate(map[string]hr.Counter{"ema-5m": &counterMock{perSecond: hr.GHSToJobSubmitted(500)}})
+	}
+
+	watcher := NewContractWatcherSellerV2(nil, time.Minute, hr.AlgorithmSHA256, "ema-5m", hrFactory, nil, lib.NewTestLogger())
+	require.InDelta(t, 500, watcher.deliveredGHS(), 1)
+
+	// unknown counter falls back to the mean
+	watcher = NewContractWatcherSellerV2(nil, time.Minute, hr.AlgorithmSHA256, "unknown", hrFactory, nil, lib.NewTestLogger())
+	require.Equal(t, watcher.stats.actualHRGHS.GetHashrateAvgGHSAll()[hr.MeanCounterKey], watcher.deliveredGHS())
+}
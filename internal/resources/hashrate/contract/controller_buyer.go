@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/Lumerin-protocol/contracts-go/v2/implementation"
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
 	"github.com/Lumerin-protocol/proxy-router/internal/lib"
 	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
 	"github.com/Lumerin-protocol/proxy-router/internal/resources"
@@ -13,20 +14,29 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 )
 
+// ValidationRecorder records the verdict of the buyer validation of the contract, the reason is set if not ok
+type ValidationRecorder interface {
+	RecordValidation(contractID string, seller common.Address, ok bool, reason contracts.CloseReason, accuracy float64)
+}
+
 type ControllerBuyer struct {
 	*ContractWatcherBuyer
 	store           *contracts.HashrateEthereum
 	tsk             *lib.Task
-	privKey         string
+	signer          interfaces.Signer
 	autoClaimReward bool
+	validations     ValidationRecorder
+
+	validationRecorded bool
 }
 
-func NewControllerBuyer(contract *ContractWatcherBuyer, store *contracts.HashrateEthereum, privKey string, autoClaimReward bool) *ControllerBuyer {
+func NewControllerBuyer(contract *ContractWatcherBuyer, store *contracts.HashrateEthereum, signer interfaces.Signer, autoClaimReward bool, validations ValidationRecorder) *ControllerBuyer {
 	return &ControllerBuyer{
 		ContractWatcherBuyer: contract,
 		store:                store,
-		privKey:              privKey,
+		signer:               signer,
 		autoClaimReward:      autoClaimReward,
+		validations:          validations,
 	}
 }
 
@@ -79,8 +89,9 @@ func (c *ControllerBuyer) Run(ctx context.Context) error {
 				} else if errors.Is(err, ErrUnderdelivery) {
 					reason = contracts.CloseReasonUnderdelivery
 				}
+				c.recordValidation(false, reason)
 
-				err = c.store.EarlyClose(ctx, c.ID(), reason, c.privKey)
+				err = c.store.EarlyClose(ctx, c.ID(), reason, c.signer)
 				if err != nil {
 					c.log.Errorf("error closing contract: %s", err)
 					c.log.Info("sleeping for 10 seconds")
@@ -99,10 +110,11 @@ func (c *ControllerBuyer) Run(ctx context.Context) error {
 			} else {
 				// delivery ok, seller will close the contract
 				c.log.Infof("buyer contract ended without an error")
+				c.recordValidation(true, contracts.CloseReasonUnspecified)
 				if c.isValidator() && c.autoClaimReward {
 					c.log.Infof("auto claiming reward")
 
-					err := c.store.ClaimValidatorReward(ctx, c.ID(), c.privKey)
+					err := c.store.ClaimValidatorReward(ctx, c.ID(), c.signer)
 					if err != nil {
 						c.log.Errorf("error during auto claiming reward: %s", err)
 					}
@@ -123,6 +135,8 @@ func (c *ControllerBuyer) controller(ctx context.Context, event interface{}) err
 		return c.handleDestinationUpdated(ctx, e)
 	case *implementation.ImplementationPurchaseInfoUpdated:
 		return c.handlePurchaseInfoUpdated(ctx, e)
+	case *contracts.RemovedEvent:
+		return c.handleRemovedEvent(ctx, e)
 	}
 	return nil
 }
@@ -148,7 +162,14 @@ func (c *ControllerBuyer) handleClosedEarly(_ context.Context, _ *implementation
 }
 
 func (c *ControllerBuyer) handleDestinationUpdated(_ context.Context, _ *implementation.ImplementationDestinationUpdated) error {
-	// ignoring, if destination cipher is changed then there is going to be a different destination
+	if c.IsDestRouted() {
+		// the updated destination is not used, the hashrate keeps going to the destination routes
+		c.log.Warnf("contract destination updated, ignored as it is overridden by the destination routes")
+		return nil
+	}
+	// if destination cipher is changed then there is going to be a different destination, the miners
+	// reconnect, so the validation policy may ignore the drop of the hashrate for a while
+	c.OnDestinationChanged()
 	return nil
 }
 
@@ -158,6 +179,23 @@ func (c *ControllerBuyer) handlePurchaseInfoUpdated(_ context.Context, _ *implem
 	return nil
 }
 
+// handleRemovedEvent reloads the terms if the event was reverted by the chain reorg,
+// and stops the delivery if the purchase itself was reverted
+func (c *ControllerBuyer) handleRemovedEvent(ctx context.Context, event *contracts.RemovedEvent) error {
+	c.log.Warnf("event %T reverted by chain reorg, reloading terms", event.Event)
+
+	err := c.LoadTermsFromBlockchain(ctx)
+	if err != nil {
+		return err
+	}
+
+	if c.State() == resources.ContractStateRunning && c.BlockchainState() != hashrate.BlockchainStateRunning {
+		c.log.Warnf("contract is not running on the blockchain anymore, stopping")
+		c.StopFulfilling()
+	}
+	return nil
+}
+
 // LoadTermsFromBlockchain loads terms from blockchain and decrypts destination pool if exists
 func (c *ControllerBuyer) LoadTermsFromBlockchain(ctx context.Context) error {
 	encryptedTerms, err := c.store.GetContract(ctx, c.ID())
@@ -166,7 +204,7 @@ func (c *ControllerBuyer) LoadTermsFromBlockchain(ctx context.Context) error {
 		return err
 	}
 
-	terms, err := encryptedTerms.DecryptPoolDest(c.privKey)
+	terms, err := encryptedTerms.DecryptPoolDest(c.signer)
 	c.SetData(terms)
 
 	return err
@@ -176,8 +214,17 @@ func (c *ControllerBuyer) SyncState(ctx context.Context) error {
 	return nil
 }
 
+// recordValidation records the verdict only once, the closeout may be retried
+func (c *ControllerBuyer) recordValidation(ok bool, reason contracts.CloseReason) {
+	if c.validations == nil || c.validationRecorded {
+		return
+	}
+	c.validationRecorded = true
+	c.validations.RecordValidation(c.ID(), common.HexToAddress(c.Seller()), ok, reason, c.DeliveryAccuracy())
+}
+
 func (c *ControllerBuyer) isValidator() bool {
-	return common.HexToAddress(c.Validator()) == lib.MustPrivKeyStringToAddr(c.privKey)
+	return common.HexToAddress(c.Validator()) == c.signer.Address()
 }
 
 func (c *ControllerBuyer) Stop(ctx context.Context) error {
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"time"
 
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/attestation"
 	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
 	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate"
 	"github.com/ethereum/go-ethereum/common"
@@ -16,16 +18,19 @@ type ControllerFuturesBuyer struct {
 	deliveryAt      time.Time
 	stopCh          chan struct{}
 	store           *contracts.FuturesEthereum
-	privKey         string
+	signer          interfaces.Signer
+	attestor        DeliveryAttestor // signs the delivery report, nil to skip
+	attested        bool
 }
 
-func NewControllerFuturesBuyer(contract *ContractWatcherBuyer, store *contracts.FuturesEthereum, deliveryAt time.Time, privKey string, autoClaimReward bool) *ControllerFuturesBuyer {
+func NewControllerFuturesBuyer(contract *ContractWatcherBuyer, store *contracts.FuturesEthereum, deliveryAt time.Time, signer interfaces.Signer, autoClaimReward bool, attestor DeliveryAttestor) *ControllerFuturesBuyer {
 	return &ControllerFuturesBuyer{
 		ContractWatcherBuyer: contract,
 		deliveryAt:           deliveryAt,
 		autoClaimReward:      autoClaimReward,
 		store:                store,
-		privKey:              privKey,
+		signer:               signer,
+		attestor:             attestor,
 		stopCh:               make(chan struct{}),
 	}
 }
@@ -45,6 +50,10 @@ func (c *ControllerFuturesBuyer) Run(ctx context.Context) error {
 			c.log.Infof("delivery time reached, starting fulfillment")
 			c.ContractWatcherBuyer.StartFulfilling(ctx)
 		case <-ctx.Done():
+			if c.isContractExpired() {
+				// the delivery range ended together with the position before the watcher, no failure was found
+				c.attest(context.WithoutCancel(ctx), deliveryVerdict(nil))
+			}
 			return ctx.Err()
 		case <-c.ContractWatcherBuyer.Done():
 			err := c.ContractWatcherBuyer.Err()
@@ -52,22 +61,18 @@ func (c *ControllerFuturesBuyer) Run(ctx context.Context) error {
 				// contract closed, no need to close it again
 				if errors.Is(err, ErrContractClosed) || c.ContractWatcherBuyer.BlockchainState() == hashrate.BlockchainStateAvailable {
 					c.log.Warnf("buyer contract ended due to closeout")
+					c.attest(ctx, attestation.Verdict{Result: attestation.ResultClosed, Reason: err.Error()})
 					return nil
 				}
 
 				// underdelivery or destination unreachable, buyer closes the contract
 				c.log.Warnf("buyer contract ended with error: %s", err)
 
-				blameSeller := true
-				if errors.Is(err, ErrContractDest) {
-					blameSeller = false
-				} else if errors.Is(err, ErrShareTimeout) {
-					blameSeller = true
-				} else if errors.Is(err, ErrUnderdelivery) {
-					blameSeller = true
-				}
+				verdict := deliveryVerdict(err)
+				blameSeller := verdict.BlameSeller
+				c.attest(ctx, verdict)
 
-				err = c.store.CloseDelivery(ctx, common.HexToHash(c.ID()), blameSeller, c.privKey)
+				err = c.store.CloseDelivery(ctx, common.HexToHash(c.ID()), blameSeller, c.signer)
 				if err != nil {
 					c.log.Errorf("error closing contract: %s", err)
 					c.log.Info("sleeping for 10 seconds")
@@ -86,6 +91,7 @@ func (c *ControllerFuturesBuyer) Run(ctx context.Context) error {
 			} else {
 				// delivery ok, seller will close the contract
 				c.log.Infof("buyer contract ended without an error")
+				c.attest(ctx, deliveryVerdict(nil))
 				return nil
 			}
 		}
@@ -101,3 +107,18 @@ func (c *ControllerFuturesBuyer) Stop(ctx context.Context) error {
 func (c *ControllerFuturesBuyer) SyncState(ctx context.Context) error {
 	return nil
 }
+
+// attest signs the delivery report of the position only once, the closeout may be retried
+func (c *ControllerFuturesBuyer) attest(ctx context.Context, verdict attestation.Verdict) {
+	if c.attestor == nil || c.attested || c.FulfillmentStartTime().IsZero() {
+		return
+	}
+	c.attested = true
+
+	report := c.DeliveryReport()
+	report.Verdict = verdict
+	_, err := c.attestor.Attest(ctx, report)
+	if err != nil {
+		c.log.Errorf("can't attest the delivery: %s", err)
+	}
+}
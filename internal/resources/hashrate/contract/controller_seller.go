@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/Lumerin-protocol/contracts-go/v2/implementation"
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
 	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
 	"github.com/Lumerin-protocol/proxy-router/internal/resources"
 	"github.com/ethereum/go-ethereum/common"
@@ -16,15 +17,15 @@ type ControllerSeller struct {
 
 	syncStateCh chan struct{}
 	store       *contracts.HashrateEthereum
-	privKey     string
+	signer      interfaces.Signer
 }
 
-func NewControllerSeller(contract *ContractWatcherSellerV2, store *contracts.HashrateEthereum, privKey string) *ControllerSeller {
+func NewControllerSeller(contract *ContractWatcherSellerV2, store *contracts.HashrateEthereum, signer interfaces.Signer) *ControllerSeller {
 	return &ControllerSeller{
 		syncStateCh:             make(chan struct{}, 1),
 		ContractWatcherSellerV2: contract,
 		store:                   store,
-		privKey:                 privKey,
+		signer:                  signer,
 	}
 }
 
@@ -113,11 +114,15 @@ func (c *ControllerSeller) controller(ctx context.Context, event interface{}) er
 		return c.handleDestinationUpdated(ctx, e)
 	case *implementation.ImplementationPurchaseInfoUpdated:
 		return c.handlePurchaseInfoUpdated(ctx, e)
+	case *implementation.ImplementationFundsClaimed:
+		return c.handleFundsClaimed(ctx, e)
+	case *contracts.RemovedEvent:
+		return c.handleRemovedEvent(ctx, e)
 	}
 	return nil
 }
 
-func (c *ControllerSeller) handleContractPurchased(ctx context.Context, _ *implementation.ImplementationContractPurchased) error {
+func (c *ControllerSeller) handleContractPurchased(ctx context.Context, e *implementation.ImplementationContractPurchased) error {
 	c.log.Debugf("got purchased event for contract")
 	if c.State() == resources.ContractStateRunning {
 		c.log.Infof("contract is running, ignore")
@@ -134,6 +139,11 @@ func (c *ControllerSeller) handleContractPurchased(ctx context.Context, _ *imple
 		return nil
 	}
 
+	// the event is emitted locally on startup for the already running contract, it has no transaction
+	if e.Raw.TxHash != (common.Hash{}) {
+		c.store.RecordContractStart(ctx, c.ID(), e.Buyer, c.Price(), e.Raw.TxHash)
+	}
+
 	c.ContractWatcherSellerV2.Reset()
 	err = c.StartFulfilling()
 	if err != nil {
@@ -143,8 +153,9 @@ func (c *ControllerSeller) handleContractPurchased(ctx context.Context, _ *imple
 	return nil
 }
 
-func (c *ControllerSeller) handleClosedEarly(ctx context.Context, _ *implementation.ImplementationClosedEarly) error {
+func (c *ControllerSeller) handleClosedEarly(ctx context.Context, e *implementation.ImplementationClosedEarly) error {
 	c.log.Warnf("got closed event for contract")
+	c.store.RecordClosedEarly(ctx, c.ID(), contracts.CloseReason(e.Reason), e.Raw.TxHash, c.signer.Address())
 
 	if c.IsRunning() {
 		c.log.Infof("contract is running, stopping")
@@ -160,6 +171,12 @@ func (c *ControllerSeller) handleClosedEarly(ctx context.Context, _ *implementat
 	return nil
 }
 
+func (c *ControllerSeller) handleFundsClaimed(ctx context.Context, e *implementation.ImplementationFundsClaimed) error {
+	c.log.Debugf("got fundsClaimed event for contract")
+	c.store.RecordPayout(ctx, c.ID(), e.Raw.TxHash, c.signer.Address())
+	return nil
+}
+
 func (c *ControllerSeller) handleDestinationUpdated(ctx context.Context, _ *implementation.ImplementationDestinationUpdated) error {
 	c.log.Debugf("got destinationUpdated event for contract")
 
@@ -214,7 +231,7 @@ func (c *ControllerSeller) LoadTermsFromBlockchain(ctx context.Context) error {
 		return err
 	}
 
-	terms, err := encryptedTerms.Decrypt(c.privKey)
+	terms, err := encryptedTerms.Decrypt(c.signer)
 	c.SetTerms(terms)
 
 	return err
@@ -227,7 +244,7 @@ func (c *ControllerSeller) GetTermsFromBlockchain(ctx context.Context) (*hashrat
 		return nil, err
 	}
 
-	terms, err := encryptedTerms.Decrypt(c.privKey)
+	terms, err := encryptedTerms.Decrypt(c.signer)
 
 	if err != nil {
 		c.log.Errorf("error decrypting terms: %s", err)
@@ -237,6 +254,30 @@ func (c *ControllerSeller) GetTermsFromBlockchain(ctx context.Context) (*hashrat
 	return terms, nil
 }
 
+// handleRemovedEvent reloads the terms if the event was reverted by the chain reorg,
+// and stops the delivery if the purchase itself was reverted
+func (c *ControllerSeller) handleRemovedEvent(ctx context.Context, event *contracts.RemovedEvent) error {
+	c.log.Warnf("event %T reverted by chain reorg, reloading terms", event.Event)
+
+	switch event.Event.(type) {
+	case *implementation.ImplementationContractPurchased,
+		*implementation.ImplementationClosedEarly,
+		*implementation.ImplementationFundsClaimed:
+		c.store.RevertLedgerEntries(event.Log.TxHash)
+	}
+
+	err := c.LoadTermsFromBlockchain(ctx)
+	if err != nil {
+		return err
+	}
+
+	if c.IsRunning() && !c.ShouldBeRunning() {
+		c.log.Warnf("contract is not running on the blockchain anymore, stopping")
+		c.ContractWatcherSellerV2.StopFulfilling()
+	}
+	return nil
+}
+
 func (c *ControllerSeller) SyncState(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
@@ -19,6 +19,10 @@ type DeliveryLogEntry struct {
 	GlobalUnderDeliveryGHS            int
 	GlobalError                       float64
 	NextCyclePartialDeliveryTargetGHS int
+	DeliveredHashrateGHS              int    `json:",omitempty"` // seller hashrate by the configured counter
+	ValidationPolicy                  string `json:",omitempty"` // buyer validation policy
+	ValidationStatus                  string `json:",omitempty"`
+	ValidationReason                  string `json:",omitempty"`
 }
 
 type DeliveryLog struct {
@@ -0,0 +1,97 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/attestation"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/contracts"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DeliveryAttestor signs and keeps the delivery report of the validated position
+type DeliveryAttestor interface {
+	Attest(ctx context.Context, report attestation.Report) (*attestation.SignedReport, error)
+}
+
+// DeliveryReport returns the report of the delivery collected so far from the validation checks, the verdict
+// is left for the caller
+func (p *ContractWatcherBuyer) DeliveryReport() attestation.Report {
+	entries, _ := p.deliveryLog.GetEntries()
+
+	report := attestation.Report{
+		PositionID:    p.ID(),
+		Seller:        common.HexToAddress(p.Seller()),
+		Buyer:         common.HexToAddress(p.Buyer()),
+		StartTime:     p.StartTime(),
+		EndTime:       p.EndTime(),
+		ValidatedFrom: p.fulfillmentStartedAt.Load(),
+		ValidatedTo:   time.Now(),
+		TargetGHS:     p.HashrateGHS(),
+		Policy:        p.policy.Name(),
+		Samples:       make([]attestation.Sample, 0, len(entries)),
+		CreatedAt:     time.Now(),
+	}
+
+	stats := &report.Errors
+	for _, e := range entries {
+		report.Samples = append(report.Samples, attestation.Sample{
+			Time:      e.Timestamp,
+			ActualGHS: e.ActualGHS,
+			Error:     e.GlobalError,
+			Status:    e.ValidationStatus,
+		})
+
+		if stats.Checks == 0 || e.ActualGHS < stats.MinGHS {
+			stats.MinGHS = e.ActualGHS
+		}
+		stats.Checks++
+		if e.ValidationStatus == ValidationStatusUnderdeliver || e.ValidationStatus == ValidationStatusShareTimeout {
+			stats.FailedChecks++
+		}
+		stats.MeanError += e.GlobalError
+		stats.MaxError = math.Max(stats.MaxError, e.GlobalError)
+		stats.AvgGHS += float64(e.ActualGHS)
+	}
+	if stats.Checks > 0 {
+		stats.MeanError /= float64(stats.Checks)
+		stats.AvgGHS /= float64(stats.Checks)
+	}
+
+	if worker := p.globalHashrate.GetWorker(p.getWorkerName()); worker != nil {
+		report.Shares.Count = worker.GetTotalShares()
+	}
+	report.Shares.Work, _ = p.globalHashrate.GetTotalWork(p.getWorkerName())
+	if report.Shares.Count > 0 {
+		report.Shares.AvgDifficulty = report.Shares.Work / float64(report.Shares.Count)
+	}
+	report.Shares.LastShareTime, _ = p.globalHashrate.GetLastSubmitTime(p.getWorkerName())
+
+	return report
+}
+
+// deliveryVerdict converts the result of the buyer validation to the verdict of the report
+func deliveryVerdict(err error) attestation.Verdict {
+	if err == nil {
+		return attestation.Verdict{Result: attestation.ResultDelivered}
+	}
+	if errors.Is(err, ErrContractClosed) {
+		return attestation.Verdict{Result: attestation.ResultClosed, Reason: err.Error()}
+	}
+
+	reason := contracts.CloseReasonUnspecified
+	if errors.Is(err, ErrContractDest) {
+		reason = contracts.CloseReasonDestinationUnavailable
+	} else if errors.Is(err, ErrShareTimeout) {
+		reason = contracts.CloseReasonShareTimeout
+	} else if errors.Is(err, ErrUnderdelivery) {
+		reason = contracts.CloseReasonUnderdelivery
+	}
+	return attestation.Verdict{
+		Result:      reason.String(),
+		BlameSeller: !errors.Is(err, ErrContractDest),
+		Reason:      err.Error(),
+	}
+}
@@ -0,0 +1,192 @@
+package contract
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+)
+
+type DestRoutingMode string
+
+const (
+	DestRoutingWeight   DestRoutingMode = "weight"   // connections are split among the destinations proportionally to the weights
+	DestRoutingFailover DestRoutingMode = "failover" // connections go to the first available destination
+)
+
+const (
+	DestRouteRetryAfter = time.Minute
+)
+
+var (
+	ErrInvalidDestRoute = errors.New("invalid destination route, expected [weight:]url")
+)
+
+// DestRoute is the pool the incoming contract hashrate is forwarded to
+type DestRoute struct {
+	URL    *url.URL
+	Weight int
+}
+
+// DestRouteStats is the state of the route, exposed in the api
+type DestRouteStats struct {
+	URL         string
+	Weight      int
+	Connections int       // connections routed to the destination
+	Failures    int       // failed attempts to connect to the destination
+	DownUntil   time.Time `json:",omitempty"` // destination is tried last until this time after the failure
+}
+
+// ParseDestRoutes parses the comma separated list of routes in format [weight:]url, the default weight is 1
+func ParseDestRoutes(s string) ([]DestRoute, error) {
+	var routes []DestRoute
+	for n, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		weight := 1
+		if prefix, rest, ok := strings.Cut(item, ":"); ok {
+			if w, err := strconv.Atoi(prefix); err == nil {
+				if w <= 0 {
+					return nil, lib.WrapError(ErrInvalidDestRoute, fmt.Errorf("route %d weight must be positive", n+1))
+				}
+				weight, item = w, rest
+			}
+		}
+
+		// the url is not included in the error as it contains the pool credentials
+		u, err := url.Parse(item)
+		if err != nil || u.Host == "" {
+			return nil, lib.WrapError(ErrInvalidDestRoute, fmt.Errorf("route %d has invalid url", n+1))
+		}
+		routes = append(routes, DestRoute{URL: u, Weight: weight})
+	}
+	return routes, nil
+}
+
+// DestRouter splits the incoming connections of the buyer contract among several pools. The validation is not
+// affected, as the shares of all connections are counted for the contract regardless of the destination
+type DestRouter struct {
+	// config
+	mode       DestRoutingMode
+	retryAfter time.Duration // failed destination is tried last for this duration
+
+	// state
+	routes  []DestRoute
+	current []int // smooth weighted round-robin state
+	stats   []DestRouteStats
+	mutex   sync.Mutex
+}
+
+func NewDestRouter(mode DestRoutingMode, routes []DestRoute, retryAfter time.Duration) *DestRouter {
+	stats := make([]DestRouteStats, len(routes))
+	for i, r := range routes {
+		stats[i] = DestRouteStats{URL: r.URL.Redacted(), Weight: r.Weight}
+	}
+	return &DestRouter{
+		mode:       mode,
+		retryAfter: retryAfter,
+		routes:     routes,
+		current:    make([]int, len(routes)),
+		stats:      stats,
+	}
+}
+
+// Pick returns the destinations for the new connection in the order they should be tried and the indexes of
+// their routes to report the result with OnResult, the destinations that failed recently go last
+func (r *DestRouter) Pick() ([]*url.URL, []int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	order := make([]int, 0, len(r.routes))
+	if r.mode == DestRoutingWeight {
+		if first := r.nextWeighted(); first >= 0 {
+			order = append(order, first)
+		}
+	}
+	for i := range r.routes {
+		if len(order) == 0 || order[0] != i {
+			order = append(order, i)
+		}
+	}
+
+	now := time.Now()
+	var up, down []int
+	for _, i := range order {
+		if r.stats[i].DownUntil.After(now) {
+			down = append(down, i)
+		} else {
+			up = append(up, i)
+		}
+	}
+	indexes := append(up, down...)
+
+	dests := make([]*url.URL, len(indexes))
+	for n, i := range indexes {
+		dests[n] = lib.CopyURL(r.routes[i].URL)
+	}
+	return dests, indexes
+}
+
+// OnResult records the result of the connection to the route with the index returned by Pick. The routes are
+// matched by index, as several routes may point to the same pool account
+func (r *DestRouter) OnResult(index int, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if index < 0 || index >= len(r.routes) {
+		return
+	}
+	if err != nil {
+		r.stats[index].Failures++
+		r.stats[index].DownUntil = time.Now().Add(r.retryAfter)
+	} else {
+		r.stats[index].Connections++
+		r.stats[index].DownUntil = time.Time{}
+	}
+}
+
+// String returns the routes in the format of ParseDestRoutes
+func (r *DestRouter) String() string {
+	items := make([]string, len(r.routes))
+	for i, route := range r.routes {
+		items[i] = fmt.Sprintf("%d:%s", route.Weight, route.URL.String())
+	}
+	return strings.Join(items, ",")
+}
+
+func (r *DestRouter) GetStats() []DestRouteStats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	res := make([]DestRouteStats, len(r.stats))
+	copy(res, r.stats)
+	return res
+}
+
+// nextWeighted picks the destination with smooth weighted round-robin, skipping the destinations that are down
+func (r *DestRouter) nextWeighted() int {
+	now := time.Now()
+	total, best := 0, -1
+	for i, route := range r.routes {
+		if r.stats[i].DownUntil.After(now) {
+			continue
+		}
+		r.current[i] += route.Weight
+		total += route.Weight
+		if best == -1 || r.current[i] > r.current[best] {
+			best = i
+		}
+	}
+	if best >= 0 {
+		r.current[best] -= total
+	}
+	return best
+}
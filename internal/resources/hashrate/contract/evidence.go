@@ -0,0 +1,137 @@
+package contract
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	ShareSamplesCapacity = 1000 // number of the last shares of the contract kept for the dispute evidence
+	DestErrorsCapacity   = 100  // number of the last destination errors of the contract
+)
+
+// MinerSubmitStats are the shares submitted by the miner to the contract
+type MinerSubmitStats struct {
+	MinerID    string
+	Shares     int
+	Work       float64
+	FirstShare time.Time
+	LastShare  time.Time
+}
+
+// ShareSample is the share submitted to the contract
+type ShareSample struct {
+	Time    time.Time
+	MinerID string
+	Diff    float64
+}
+
+// DestError is the error of the contract destination reported by the proxy
+type DestError struct {
+	Time  time.Time
+	Error string
+}
+
+// SubmitLog keeps the submit statistics of each miner of the contract delivery and the last shares
+type SubmitLog struct {
+	miners  map[string]*MinerSubmitStats
+	samples []ShareSample // ring buffer of ShareSamplesCapacity
+	next    int           // position of the next sample when the buffer is full
+	mutex   sync.RWMutex
+}
+
+func NewSubmitLog() *SubmitLog {
+	return &SubmitLog{
+		miners:  make(map[string]*MinerSubmitStats),
+		samples: make([]ShareSample, 0, ShareSamplesCapacity),
+	}
+}
+
+func (l *SubmitLog) OnSubmit(diff float64, minerID string) {
+	now := time.Now()
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	miner, ok := l.miners[minerID]
+	if !ok {
+		miner = &MinerSubmitStats{MinerID: minerID, FirstShare: now}
+		l.miners[minerID] = miner
+	}
+	miner.Shares++
+	miner.Work += diff
+	miner.LastShare = now
+
+	sample := ShareSample{Time: now, MinerID: minerID, Diff: diff}
+	if len(l.samples) < ShareSamplesCapacity {
+		l.samples = append(l.samples, sample)
+		return
+	}
+	l.samples[l.next] = sample
+	l.next = (l.next + 1) % ShareSamplesCapacity
+}
+
+// Reset clears the log before the new delivery
+func (l *SubmitLog) Reset() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.miners = make(map[string]*MinerSubmitStats)
+	l.samples = l.samples[:0]
+	l.next = 0
+}
+
+// GetMinerStats returns the submit statistics sorted by the miner ID
+func (l *SubmitLog) GetMinerStats() []MinerSubmitStats {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	res := make([]MinerSubmitStats, 0, len(l.miners))
+	for _, miner := range l.miners {
+		res = append(res, *miner)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].MinerID < res[j].MinerID
+	})
+	return res
+}
+
+// GetShareSamples returns the last shares, the oldest first
+func (l *SubmitLog) GetShareSamples() []ShareSample {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	res := make([]ShareSample, 0, len(l.samples))
+	res = append(res, l.samples[l.next:]...)
+	return append(res, l.samples[:l.next]...)
+}
+
+// DestErrorLog keeps the last errors of the contract destination
+type DestErrorLog struct {
+	entries []DestError
+	mutex   sync.RWMutex
+}
+
+func NewDestErrorLog() *DestErrorLog {
+	return &DestErrorLog{}
+}
+
+func (l *DestErrorLog) Add(err error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.entries = append(l.entries, DestError{Time: time.Now(), Error: err.Error()})
+	if len(l.entries) > DestErrorsCapacity {
+		l.entries = l.entries[len(l.entries)-DestErrorsCapacity:]
+	}
+}
+
+func (l *DestErrorLog) GetEntries() []DestError {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	res := make([]DestError, len(l.entries))
+	copy(res, l.entries)
+	return res
+}
@@ -0,0 +1,86 @@
+package contract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/evidence"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/wallet"
+	hr "github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/hashrate"
+	"github.com/stretchr/testify/require"
+)
+
+const testPrivKey = "b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291"
+
+func TestSubmitLog(t *testing.T) {
+	l := NewSubmitLog()
+	for i := 0; i < ShareSamplesCapacity+10; i++ {
+		l.OnSubmit(float64(i), fmt.Sprintf("miner-%d", i%2))
+	}
+
+	miners := l.GetMinerStats()
+	require.Len(t, miners, 2)
+	require.Equal(t, "miner-0", miners[0].MinerID)
+	require.Equal(t, (ShareSamplesCapacity+10)/2, miners[0].Shares)
+
+	// only the last shares are kept, the oldest first
+	samples := l.GetShareSamples()
+	require.Len(t, samples, ShareSamplesCapacity)
+	require.Equal(t, float64(10), samples[0].Diff)
+	require.Equal(t, float64(ShareSamplesCapacity+9), samples[len(samples)-1].Diff)
+
+	l.Reset()
+	require.Empty(t, l.GetMinerStats())
+	require.Empty(t, l.GetShareSamples())
+}
+
+func TestDestErrorLog(t *testing.T) {
+	l := NewDestErrorLog()
+	for i := 0; i < DestErrorsCapacity+5; i++ {
+		l.Add(errors.New(fmt.Sprint(i)))
+	}
+	entries := l.GetEntries()
+	require.Len(t, entries, DestErrorsCapacity)
+	require.Equal(t, "5", entries[0].Error)
+}
+
+func TestEvidenceAfterClose(t *testing.T) {
+	hrFactory := func() *hr.Hashrate { return hr.NewHashrate(map[string]hr.Counter{}) }
+	watcher := NewContractWatcherSellerV2(nil, time.Minute, hr.AlgorithmSHA256, hr.MeanCounterKey, hrFactory, nil, lib.NewTestLogger())
+	watcher.Reset() // delivery start
+	watcher.stats.onFullMinerShare(100, "miner-0")
+	watcher.stats.onPartialMinerShare(50, "miner-1")
+
+	// the controller resets the watcher when the buyer closes the contract
+	watcher.Reset()
+
+	miners, err := json.Marshal(watcher.GetMinerSubmitStats())
+	require.NoError(t, err)
+	shares, err := json.Marshal(watcher.GetShareSamples())
+	require.NoError(t, err)
+
+	signer, err := wallet.NewKeySigner(testPrivKey)
+	require.NoError(t, err)
+	archive, err := evidence.Build(context.Background(), "0x01", []evidence.File{
+		{Name: "miners.json", Data: miners},
+		{Name: "shares.json", Data: shares},
+	}, signer)
+	require.NoError(t, err)
+
+	_, files, err := evidence.Verify(bytes.NewReader(archive))
+	require.NoError(t, err)
+
+	var minerStats []MinerSubmitStats
+	require.NoError(t, json.Unmarshal(files[0].Data, &minerStats))
+	require.Len(t, minerStats, 2)
+
+	var samples []ShareSample
+	require.NoError(t, json.Unmarshal(files[1].Data, &samples))
+	require.Len(t, samples, 2)
+}
@@ -16,18 +16,21 @@ type stats struct {
 	partialMiners          []string
 	deliveryTargetGHS      float64
 	actualHRGHS            *hr.Hashrate
+	submits                *SubmitLog
 }
 
 func (s *stats) onFullMinerShare(diff float64, ID string) {
 	s.jobFullMiners.Add(uint64(diff))
 	s.actualHRGHS.OnSubmit(diff)
 	s.sharesFullMiners.Add(1)
+	s.submits.OnSubmit(diff, ID)
 }
 
 func (s *stats) onPartialMinerShare(diff float64, ID string) {
 	s.jobPartialMiners.Add(uint64(diff))
 	s.actualHRGHS.OnSubmit(diff)
 	s.sharesPartialMiners.Add(1)
+	s.submits.OnSubmit(diff, ID)
 }
 
 func (s *stats) addFullMiners(IDs ...string) {
@@ -0,0 +1,311 @@
+package contract
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/hashrate"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	ValidationModeRolling ValidationMode = "rolling" // hashrate over the last window is compared against the error threshold
+)
+
+const (
+	ValidationStatusOK           = "ok"
+	ValidationStatusNoData       = "no data"
+	ValidationStatusUnderdeliver = "underdelivery"
+	ValidationStatusShareTimeout = "share timeout"
+	ValidationStatusGrace        = "grace"     // failing check ignored after the destination change
+	ValidationStatusTolerated    = "tolerated" // failing check within the outage tolerance
+	ValidationStatusPending      = "pending"   // validation is not started yet after the app start
+)
+
+var (
+	ErrInvalidValidationPolicy = errors.New("invalid validation policy")
+)
+
+// ValidationPolicyConfig is the set of the buyer validation rules of the contract
+type ValidationPolicyConfig struct {
+	Mode            ValidationMode
+	ErrorThreshold  float64       // hashrate relative error threshold for the contract to be considered fulfilling accurately
+	Flatness        time.Duration // artificial parameter of the flatness curve
+	FalsePositive   float64       // probability of the false underdelivery verdict in the confidence mode
+	ShareTimeout    time.Duration // time to wait for the share to arrive
+	Window          time.Duration // averaging window of the rolling mode
+	DestChangeGrace time.Duration // failing checks are ignored for this duration after the destination change
+	OutageTolerance time.Duration // failing checks are ignored until they last for this duration
+}
+
+// ValidationInput is the state of the contract delivery evaluated by the policy
+type ValidationInput struct {
+	Now           time.Time
+	Elapsed       time.Duration // since the fulfillment started
+	TargetGHS     float64
+	ActualGHS     float64 // average hashrate since the fulfillment started by the buyer counter
+	TotalWork     float64 // work submitted since the fulfillment started
+	LastShareTime time.Time
+	DestChangedAt time.Time // zero if the destination did not change
+	Interval      HashrateInterval
+}
+
+// ValidationVerdict is the decision of the policy with the reasoning, Err is ErrShareTimeout or ErrUnderdelivery
+// if the contract should be closed
+type ValidationVerdict struct {
+	Err    error
+	Status string
+	Reason string
+}
+
+// ValidationPolicy decides whether the buyer contract is delivered accurately. The policy keeps the state of the
+// contract between the checks, so a new instance is created for each contract
+type ValidationPolicy interface {
+	Name() string
+	Config() ValidationPolicyConfig
+	Validate(in ValidationInput) ValidationVerdict
+}
+
+// NewValidationPolicy creates the policy for the contract
+func NewValidationPolicy(cfg ValidationPolicyConfig) ValidationPolicy {
+	return &rulesValidationPolicy{cfg: cfg}
+}
+
+type workSample struct {
+	time time.Time
+	work float64
+}
+
+// rulesValidationPolicy checks the share timeout and the hashrate by the mode, then applies the grace period
+// after the destination change and the outage tolerance to the failing verdict
+type rulesValidationPolicy struct {
+	// config
+	cfg ValidationPolicyConfig
+
+	// state
+	failingSince time.Time
+	samples      []workSample // work submitted at the check times, for the rolling mode
+}
+
+func (p *rulesValidationPolicy) Name() string {
+	return string(p.cfg.Mode)
+}
+
+func (p *rulesValidationPolicy) Config() ValidationPolicyConfig {
+	return p.cfg
+}
+
+func (p *rulesValidationPolicy) Validate(in ValidationInput) ValidationVerdict {
+	v := p.check(in)
+	if v.Err == nil {
+		p.failingSince = time.Time{}
+		return v
+	}
+
+	if !in.DestChangedAt.IsZero() && in.Now.Sub(in.DestChangedAt) < p.cfg.DestChangeGrace {
+		return ValidationVerdict{
+			Status: ValidationStatusGrace,
+			Reason: fmt.Sprintf("%s, ignored within %s after the destination change", v.Reason, p.cfg.DestChangeGrace),
+		}
+	}
+
+	if p.failingSince.IsZero() {
+		p.failingSince = in.Now
+	}
+	if failing := in.Now.Sub(p.failingSince); failing < p.cfg.OutageTolerance {
+		return ValidationVerdict{
+			Status: ValidationStatusTolerated,
+			Reason: fmt.Sprintf("%s, failing for %s, tolerated up to %s", v.Reason, failing.Round(time.Second), p.cfg.OutageTolerance),
+		}
+	}
+	return v
+}
+
+func (p *rulesValidationPolicy) check(in ValidationInput) ValidationVerdict {
+	if sinceShare := in.Now.Sub(in.LastShareTime); sinceShare > p.cfg.ShareTimeout {
+		reason := fmt.Sprintf("no share submitted within share timeout (%s), last share at %s", p.cfg.ShareTimeout, in.LastShareTime.Format(time.RFC3339))
+		return ValidationVerdict{
+			Err:    lib.WrapError(ErrShareTimeout, errors.New(reason)),
+			Status: ValidationStatusShareTimeout,
+			Reason: reason,
+		}
+	}
+
+	switch p.cfg.Mode {
+	case ValidationModeConfidence:
+		return p.checkConfidence(in)
+	case ValidationModeRolling:
+		return p.checkRolling(in)
+	default:
+		return p.checkFlatness(in)
+	}
+}
+
+func (p *rulesValidationPolicy) checkFlatness(in ValidationInput) ValidationVerdict {
+	hrError := lib.RelativeError(in.TargetGHS, in.ActualGHS)
+	maxHrError := GetMaxGlobalError(in.Elapsed, p.cfg.ErrorThreshold, p.cfg.Flatness, 5*time.Minute)
+	msg := fmt.Sprintf(
+		"elapsed %s target GHS %.0f, actual GHS %.0f, error %.0f%%, threshold(%.0f%%)",
+		in.Elapsed.Round(time.Second), in.TargetGHS, in.ActualGHS, hrError*100, maxHrError*100,
+	)
+
+	if hrError <= maxHrError {
+		return ValidationVerdict{Status: ValidationStatusOK, Reason: "delivering accurately: " + msg}
+	}
+	if in.ActualGHS > in.TargetGHS {
+		// contract overdelivery is ok for buyer
+		return ValidationVerdict{Status: ValidationStatusOK, Reason: "overdelivering: " + msg}
+	}
+	return ValidationVerdict{Err: ErrUnderdelivery, Status: ValidationStatusUnderdeliver, Reason: "underdelivering: " + msg}
+}
+
+func (p *rulesValidationPolicy) checkConfidence(in ValidationInput) ValidationVerdict {
+	i := in.Interval
+	msg := fmt.Sprintf(
+		"elapsed %s target GHS %.0f, estimate GHS %.0f, interval [%.0f, %.0f] GHS at %.2f%% confidence, min accepted GHS %.0f, shares %d, expected shares %.0f",
+		i.Elapsed.Round(time.Second), in.TargetGHS, i.EstimateGHS, i.LowerGHS, i.UpperGHS,
+		i.Confidence*100, i.MinAcceptedGHS, i.Shares, i.ExpectedShares,
+	)
+
+	if i.IsUnderdelivering() {
+		return ValidationVerdict{Err: ErrUnderdelivery, Status: ValidationStatusUnderdeliver, Reason: "underdelivering: " + msg}
+	}
+	return ValidationVerdict{Status: ValidationStatusOK, Reason: "delivering accurately: " + msg}
+}
+
+// checkRolling compares the hashrate over the last window against the threshold, the verdict is not made
+// until the window is filled, the share timeout covers the contracts that don't deliver at all
+func (p *rulesValidationPolicy) checkRolling(in ValidationInput) ValidationVerdict {
+	if len(p.samples) == 0 {
+		p.samples = append(p.samples, workSample{time: in.Now.Add(-in.Elapsed), work: 0})
+	}
+	p.samples = append(p.samples, workSample{time: in.Now, work: in.TotalWork})
+
+	// keep the newest sample that is at least the window old as the base
+	windowStart := in.Now.Add(-p.cfg.Window)
+	for len(p.samples) > 1 && !p.samples[1].time.After(windowStart) {
+		p.samples = p.samples[1:]
+	}
+	base := p.samples[0]
+	span := in.Now.Sub(base.time)
+	if span < p.cfg.Window || span <= 0 {
+		return ValidationVerdict{
+			Status: ValidationStatusNoData,
+			Reason: fmt.Sprintf("collecting data for the rolling window %s, elapsed %s", p.cfg.Window, span.Round(time.Second)),
+		}
+	}
+
+	windowGHS := hashrate.JobSubmittedToGHSV2(in.TotalWork-base.work, span)
+	minGHS := in.TargetGHS * (1 - p.cfg.ErrorThreshold)
+	msg := fmt.Sprintf(
+		"target GHS %.0f, GHS over last %s %.0f, min accepted GHS %.0f",
+		in.TargetGHS, span.Round(time.Second), windowGHS, minGHS,
+	)
+	if windowGHS < minGHS {
+		return ValidationVerdict{Err: ErrUnderdelivery, Status: ValidationStatusUnderdeliver, Reason: "underdelivering: " + msg}
+	}
+	return ValidationVerdict{Status: ValidationStatusOK, Reason: "delivering accurately: " + msg}
+}
+
+// ParseValidationPolicies parses the per contract policies in format contractID:[mode][,key=value...] separated
+// by semicolon, the omitted values are taken from the defaults. Keys are threshold, flatness, falsePositive,
+// shareTimeout, window, grace and tolerance. The contractID is the contract address or the futures position ID
+func ParseValidationPolicies(s string, defaults ValidationPolicyConfig) (map[string]ValidationPolicyConfig, error) {
+	res := make(map[string]ValidationPolicyConfig)
+	for _, item := range strings.Split(s, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		contractID, spec, ok := strings.Cut(item, ":")
+		if ok {
+			contractID, ok = normalizeContractID(strings.TrimSpace(contractID))
+		}
+		if !ok {
+			return nil, lib.WrapError(ErrInvalidValidationPolicy, fmt.Errorf("expected contractID:policy, got %s", item))
+		}
+		cfg, err := ParseValidationPolicy(spec, defaults)
+		if err != nil {
+			return nil, lib.WrapError(ErrInvalidValidationPolicy, fmt.Errorf("contract %s: %w", contractID, err))
+		}
+		res[contractID] = cfg
+	}
+	return res, nil
+}
+
+// normalizeContractID formats the contract address or the 32-byte futures position ID the way the contract ID() does
+func normalizeContractID(id string) (string, bool) {
+	if common.IsHexAddress(id) {
+		return common.HexToAddress(id).Hex(), true
+	}
+	hexID := strings.TrimPrefix(strings.TrimPrefix(id, "0x"), "0X")
+	if len(hexID) != 2*common.HashLength {
+		return "", false
+	}
+	if _, err := hex.DecodeString(hexID); err != nil {
+		return "", false
+	}
+	return common.HexToHash(hexID).Hex(), true
+}
+
+// ParseValidationPolicy parses the policy in format [mode][,key=value...] on top of the defaults
+func ParseValidationPolicy(spec string, defaults ValidationPolicyConfig) (ValidationPolicyConfig, error) {
+	cfg := defaults
+	for _, token := range strings.Split(spec, ",") {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(token, "=")
+		if !ok {
+			mode := ValidationMode(token)
+			if mode != ValidationModeFlatness && mode != ValidationModeConfidence && mode != ValidationModeRolling {
+				return cfg, fmt.Errorf("unknown validation mode %s", token)
+			}
+			cfg.Mode = mode
+			continue
+		}
+
+		var err error
+		switch key {
+		case "threshold":
+			cfg.ErrorThreshold, err = parseFraction(value)
+		case "falsePositive":
+			cfg.FalsePositive, err = parseFraction(value)
+		case "flatness":
+			cfg.Flatness, err = time.ParseDuration(value)
+		case "shareTimeout":
+			cfg.ShareTimeout, err = time.ParseDuration(value)
+		case "window":
+			cfg.Window, err = time.ParseDuration(value)
+		case "grace":
+			cfg.DestChangeGrace, err = time.ParseDuration(value)
+		case "tolerance":
+			cfg.OutageTolerance, err = time.ParseDuration(value)
+		default:
+			return cfg, fmt.Errorf("unknown key %s", key)
+		}
+		if err != nil {
+			return cfg, fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+	return cfg, nil
+}
+
+func parseFraction(s string) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if v < 0 || v >= 1 {
+		return 0, fmt.Errorf("%s is out of [0, 1) range", s)
+	}
+	return v, nil
+}
@@ -0,0 +1,150 @@
+package contract
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/hashrate"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+var testPolicyConfig = ValidationPolicyConfig{
+	Mode:           ValidationModeFlatness,
+	ErrorThreshold: 0.05,
+	Flatness:       20 * time.Minute,
+	FalsePositive:  0.01,
+	ShareTimeout:   7 * time.Minute,
+	Window:         30 * time.Minute,
+}
+
+func TestValidationPolicyShareTimeout(t *testing.T) {
+	p := NewValidationPolicy(testPolicyConfig)
+	now := time.Now()
+
+	v := p.Validate(ValidationInput{Now: now, Elapsed: time.Hour, TargetGHS: 100, ActualGHS: 100, LastShareTime: now.Add(-8 * time.Minute)})
+	require.ErrorIs(t, v.Err, ErrShareTimeout)
+	require.Equal(t, ValidationStatusShareTimeout, v.Status)
+}
+
+func TestValidationPolicyFlatness(t *testing.T) {
+	p := NewValidationPolicy(testPolicyConfig)
+	now := time.Now()
+
+	v := p.Validate(ValidationInput{Now: now, Elapsed: 10 * time.Hour, TargetGHS: 100, ActualGHS: 97, LastShareTime: now})
+	require.NoError(t, v.Err)
+	require.Equal(t, ValidationStatusOK, v.Status)
+
+	v = p.Validate(ValidationInput{Now: now, Elapsed: 10 * time.Hour, TargetGHS: 100, ActualGHS: 150, LastShareTime: now})
+	require.NoError(t, v.Err)
+	require.Contains(t, v.Reason, "overdelivering")
+
+	v = p.Validate(ValidationInput{Now: now, Elapsed: 10 * time.Hour, TargetGHS: 100, ActualGHS: 80, LastShareTime: now})
+	require.ErrorIs(t, v.Err, ErrUnderdelivery)
+	require.Contains(t, v.Reason, "underdelivering")
+}
+
+func TestValidationPolicyRolling(t *testing.T) {
+	cfg := testPolicyConfig
+	cfg.Mode = ValidationModeRolling
+	p := NewValidationPolicy(cfg)
+
+	start := time.Now()
+	targetGHS := 100_000.0
+	workPerMin := hashrate.GHSToJobSubmittedV2(targetGHS, time.Minute)
+
+	// full delivery for the first hour, then half of the hashrate
+	work := 0.0
+	var v ValidationVerdict
+	for m := 1; m <= 90; m++ {
+		if m <= 60 {
+			work += workPerMin
+		} else {
+			work += workPerMin / 2
+		}
+		now := start.Add(time.Duration(m) * time.Minute)
+		v = p.Validate(ValidationInput{Now: now, Elapsed: now.Sub(start), TargetGHS: targetGHS, TotalWork: work, LastShareTime: now})
+		switch {
+		case m < 30:
+			require.Equal(t, ValidationStatusNoData, v.Status, "minute %d", m)
+		case m <= 61:
+			require.Equal(t, ValidationStatusOK, v.Status, "minute %d", m)
+		}
+	}
+	// the average since the start is 83% of the target, but the last window is 50%
+	require.ErrorIs(t, v.Err, ErrUnderdelivery)
+}
+
+func TestValidationPolicyGraceAndTolerance(t *testing.T) {
+	cfg := testPolicyConfig
+	cfg.DestChangeGrace = 10 * time.Minute
+	cfg.OutageTolerance = 5 * time.Minute
+	p := NewValidationPolicy(cfg)
+
+	now := time.Now()
+	failing := ValidationInput{Elapsed: 10 * time.Hour, TargetGHS: 100, ActualGHS: 50}
+
+	// ignored after the destination change, the tolerance doesn't start
+	failing.Now, failing.LastShareTime, failing.DestChangedAt = now, now, now.Add(-time.Minute)
+	v := p.Validate(failing)
+	require.NoError(t, v.Err)
+	require.Equal(t, ValidationStatusGrace, v.Status)
+
+	failing.DestChangedAt = time.Time{}
+	for _, m := range []int{0, 4} {
+		failing.Now = now.Add(time.Duration(m) * time.Minute)
+		failing.LastShareTime = failing.Now
+		v = p.Validate(failing)
+		require.NoError(t, v.Err)
+		require.Equal(t, ValidationStatusTolerated, v.Status)
+	}
+
+	failing.Now = now.Add(5 * time.Minute)
+	failing.LastShareTime = failing.Now
+	v = p.Validate(failing)
+	require.ErrorIs(t, v.Err, ErrUnderdelivery)
+
+	// successful check resets the tolerance
+	ok := ValidationInput{Now: now.Add(6 * time.Minute), Elapsed: 10 * time.Hour, TargetGHS: 100, ActualGHS: 100, LastShareTime: now.Add(6 * time.Minute)}
+	require.NoError(t, p.Validate(ok).Err)
+	failing.Now = now.Add(7 * time.Minute)
+	failing.LastShareTime = failing.Now
+	require.Equal(t, ValidationStatusTolerated, p.Validate(failing).Status)
+}
+
+func TestParseValidationPolicies(t *testing.T) {
+	policies, err := ParseValidationPolicies(
+		"0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa:rolling,window=1h,threshold=0.1; 0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb:shareTimeout=2m,tolerance=10m",
+		testPolicyConfig,
+	)
+	require.NoError(t, err)
+	require.Len(t, policies, 2)
+
+	p1 := policies[common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Hex()]
+	require.Equal(t, ValidationModeRolling, p1.Mode)
+	require.Equal(t, time.Hour, p1.Window)
+	require.Equal(t, 0.1, p1.ErrorThreshold)
+	require.Equal(t, testPolicyConfig.ShareTimeout, p1.ShareTimeout)
+
+	p2 := policies[common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb").Hex()]
+	require.Equal(t, ValidationModeFlatness, p2.Mode)
+	require.Equal(t, 2*time.Minute, p2.ShareTimeout)
+	require.Equal(t, 10*time.Minute, p2.OutageTolerance)
+
+	// futures position ids are matched in the format of the position ID()
+	positionID := common.HexToHash("0xABCD000000000000000000000000000000000000000000000000000000000001")
+	policies, err = ParseValidationPolicies("0xABCD000000000000000000000000000000000000000000000000000000000001:rolling", testPolicyConfig)
+	require.NoError(t, err)
+	require.Equal(t, ValidationModeRolling, policies[positionID.Hex()].Mode)
+
+	for _, s := range []string{
+		"rolling",
+		"0xabcd:rolling",
+		"0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa:strict",
+		"0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa:threshold=1.5",
+		"0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa:unknown=1",
+	} {
+		_, err := ParseValidationPolicies(s, testPolicyConfig)
+		require.ErrorIs(t, err, ErrInvalidValidationPolicy, s)
+	}
+}
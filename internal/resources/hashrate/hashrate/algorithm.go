@@ -0,0 +1,89 @@
+package hashrate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+)
+
+// Algorithm is the proof of work algorithm of the mined coin. Hashrate counters, contracts and allocator
+// operate on work measured in SHA-256 difficulty-1 shares (2^32 hashes), so the shares of the other
+// algorithms are converted to this unit with DiffToWork before being accounted
+type Algorithm string
+
+const (
+	AlgorithmSHA256 Algorithm = "sha256" // bitcoin, difficulty-1 share is 2^32 hashes
+	AlgorithmScrypt Algorithm = "scrypt" // litecoin, dogecoin, difficulty-1 share is 2^16 hashes
+)
+
+var ErrUnknownAlgorithm = errors.New("unknown algorithm")
+
+func ParseAlgorithm(s string) (Algorithm, error) {
+	switch Algorithm(s) {
+	case AlgorithmSHA256, AlgorithmScrypt:
+		return Algorithm(s), nil
+	case "":
+		return AlgorithmSHA256, nil
+	}
+	return "", lib.WrapError(ErrUnknownAlgorithm, fmt.Errorf("%s", s))
+}
+
+// ParseAlgorithms parses the list in format key:algorithm separated by comma, the key is the part before the last
+// colon, e.g. the listener address 0.0.0.0:3334:scrypt or the contract id 0x01:scrypt
+func ParseAlgorithms(s string) (map[string]Algorithm, error) {
+	res := make(map[string]Algorithm)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.LastIndex(item, ":")
+		if i <= 0 {
+			return nil, lib.WrapError(ErrUnknownAlgorithm, fmt.Errorf("expected key:algorithm, got %s", item))
+		}
+		algorithm, err := ParseAlgorithm(item[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		res[item[:i]] = algorithm
+	}
+	return res, nil
+}
+
+func (a Algorithm) String() string {
+	return string(a)
+}
+
+// HashesPerShare returns the expected number of hashes to find a share with difficulty 1
+func (a Algorithm) HashesPerShare() float64 {
+	switch a {
+	case AlgorithmScrypt:
+		return math.Pow(2, 16)
+	default:
+		return math.Pow(2, 32)
+	}
+}
+
+// DiffToWork converts the share difficulty of the algorithm to the work in SHA-256 difficulty-1 shares
+func (a Algorithm) DiffToWork(diff float64) float64 {
+	return diff * a.HashesPerShare() / AlgorithmSHA256.HashesPerShare()
+}
+
+// WorkToDiff converts the work in SHA-256 difficulty-1 shares to the share difficulty of the algorithm
+func (a Algorithm) WorkToDiff(work float64) float64 {
+	return work * AlgorithmSHA256.HashesPerShare() / a.HashesPerShare()
+}
+
+// JobSubmittedToGHS converts the sum of the algorithm share difficulties submitted within the duration to GH/s
+func (a Algorithm) JobSubmittedToGHS(jobSubmitted float64, duration time.Duration) float64 {
+	return jobSubmitted * a.HashesPerShare() / math.Pow10(9) / duration.Seconds()
+}
+
+// GHSToJobSubmitted converts the hashrate in GH/s to the sum of the algorithm share difficulties expected within the duration
+func (a Algorithm) GHSToJobSubmitted(hrGHS float64, duration time.Duration) float64 {
+	return hrGHS * math.Pow10(9) / a.HashesPerShare() * duration.Seconds()
+}
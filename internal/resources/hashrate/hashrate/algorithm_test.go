@@ -0,0 +1,46 @@
+package hashrate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAlgorithm(t *testing.T) {
+	algo, err := ParseAlgorithm("")
+	require.NoError(t, err)
+	require.Equal(t, AlgorithmSHA256, algo)
+
+	algo, err = ParseAlgorithm("scrypt")
+	require.NoError(t, err)
+	require.Equal(t, AlgorithmScrypt, algo)
+
+	_, err = ParseAlgorithm("x11")
+	require.ErrorIs(t, err, ErrUnknownAlgorithm)
+}
+
+func TestParseAlgorithms(t *testing.T) {
+	res, err := ParseAlgorithms("0.0.0.0:3334:scrypt, 0x01:sha256")
+	require.NoError(t, err)
+	require.Equal(t, map[string]Algorithm{"0.0.0.0:3334": AlgorithmScrypt, "0x01": AlgorithmSHA256}, res)
+
+	_, err = ParseAlgorithms("0.0.0.0:3334:x11")
+	require.ErrorIs(t, err, ErrUnknownAlgorithm)
+
+	_, err = ParseAlgorithms("scrypt")
+	require.ErrorIs(t, err, ErrUnknownAlgorithm)
+}
+
+func TestAlgorithmConvert(t *testing.T) {
+	require.Equal(t, 1024.0, AlgorithmSHA256.DiffToWork(1024))
+	require.Equal(t, 1.0, AlgorithmScrypt.DiffToWork(65536))
+	require.Equal(t, 65536.0, AlgorithmScrypt.WorkToDiff(1))
+
+	job := 9535809.0
+	require.Equal(t, JobSubmittedToGHSV2(job, 5*time.Minute), AlgorithmSHA256.JobSubmittedToGHS(job, 5*time.Minute))
+
+	// same hashrate reported in the counter units regardless of the algorithm
+	scryptDiff := AlgorithmScrypt.GHSToJobSubmitted(10, time.Minute)
+	require.InDelta(t, 10, JobSubmittedToGHSV2(AlgorithmScrypt.DiffToWork(scryptDiff), time.Minute), 1e-9)
+}
@@ -0,0 +1,158 @@
+package hashrate
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+)
+
+type CounterType string
+
+const (
+	CounterTypeEma           CounterType = "ema"    // exponential moving average, window is the half-life
+	CounterTypeSma           CounterType = "sma"    // simple moving average, stores every share within the window
+	CounterTypeSlidingWindow CounterType = "window" // sliding window sum aggregated into fixed number of buckets
+	CounterTypeMean          CounterType = "mean"   // total work divided by total duration, window is not used
+)
+
+var (
+	ErrCounterSpec     = errors.New("invalid hashrate counter spec")
+	ErrCounterNotFound = errors.New("hashrate counter not found")
+)
+
+// CounterSpec describes a hashrate counter configured by the operator
+type CounterSpec struct {
+	Name   string
+	Type   CounterType
+	Window time.Duration
+}
+
+func (s CounterSpec) String() string {
+	if s.Type == CounterTypeMean {
+		return fmt.Sprintf("%s:%s", s.Name, s.Type)
+	}
+	return fmt.Sprintf("%s:%s:%s", s.Name, s.Type, s.Window)
+}
+
+// NewCounter creates a new instance of the counter described by the spec
+func (s CounterSpec) NewCounter() Counter {
+	switch s.Type {
+	case CounterTypeEma:
+		return NewEma(s.Window)
+	case CounterTypeSma:
+		return NewSma(s.Window)
+	case CounterTypeSlidingWindow:
+		return NewSlidingWindow(s.Window)
+	default:
+		return NewMean()
+	}
+}
+
+// ParseCounterSpecs parses comma separated list of counters in the format name:type:window,
+// for example "ema-5m:ema:5m,sma-1h:sma:1h,mean:mean". Window is omitted for the mean counter.
+// The mean counter is always available even if not listed, as it is used to track the total work
+func ParseCounterSpecs(str string) ([]CounterSpec, error) {
+	var specs []CounterSpec
+	names := make(map[string]struct{})
+
+	for _, item := range strings.Split(str, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		spec, err := parseCounterSpec(item)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := names[spec.Name]; ok {
+			return nil, lib.WrapError(ErrCounterSpec, fmt.Errorf("duplicate counter name %s", spec.Name))
+		}
+		names[spec.Name] = struct{}{}
+		specs = append(specs, spec)
+	}
+
+	if _, ok := names[MeanCounterKey]; !ok {
+		specs = append(specs, CounterSpec{Name: MeanCounterKey, Type: CounterTypeMean})
+	}
+
+	return specs, nil
+}
+
+func parseCounterSpec(item string) (CounterSpec, error) {
+	parts := strings.Split(item, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return CounterSpec{}, lib.WrapError(ErrCounterSpec, fmt.Errorf("expected name:type:window, got %s", item))
+	}
+
+	spec := CounterSpec{
+		Name: strings.TrimSpace(parts[0]),
+		Type: CounterType(strings.TrimSpace(parts[1])),
+	}
+	if spec.Name == "" {
+		return CounterSpec{}, lib.WrapError(ErrCounterSpec, fmt.Errorf("empty counter name in %s", item))
+	}
+
+	switch spec.Type {
+	case CounterTypeMean:
+		if spec.Name != MeanCounterKey {
+			return CounterSpec{}, lib.WrapError(ErrCounterSpec, fmt.Errorf("mean counter must be named %s", MeanCounterKey))
+		}
+		if len(parts) == 3 {
+			return CounterSpec{}, lib.WrapError(ErrCounterSpec, fmt.Errorf("mean counter doesn't have a window, got %s", item))
+		}
+		return spec, nil
+	case CounterTypeEma, CounterTypeSma, CounterTypeSlidingWindow:
+	default:
+		return CounterSpec{}, lib.WrapError(ErrCounterSpec, fmt.Errorf("unknown counter type %s", spec.Type))
+	}
+
+	if len(parts) != 3 {
+		return CounterSpec{}, lib.WrapError(ErrCounterSpec, fmt.Errorf("window is required for %s counter %s", spec.Type, spec.Name))
+	}
+	window, err := time.ParseDuration(strings.TrimSpace(parts[2]))
+	if err != nil {
+		return CounterSpec{}, lib.WrapError(ErrCounterSpec, err)
+	}
+	if window <= 0 {
+		return CounterSpec{}, lib.WrapError(ErrCounterSpec, fmt.Errorf("window must be positive, got %s", window))
+	}
+	spec.Window = window
+
+	return spec, nil
+}
+
+// NewHashrateFactoryFromSpecs returns a hashrate factory that creates the configured set of counters
+func NewHashrateFactoryFromSpecs(specs []CounterSpec) HashrateFactory {
+	return func() *Hashrate {
+		counters := make(map[string]Counter, len(specs))
+		for _, spec := range specs {
+			counters[spec.Name] = spec.NewCounter()
+		}
+		return NewHashrate(counters)
+	}
+}
+
+// CheckCounterName returns error if the counter with the given name is not configured
+func CheckCounterName(specs []CounterSpec, name string) error {
+	for _, spec := range specs {
+		if spec.Name == name {
+			return nil
+		}
+	}
+	return lib.WrapError(ErrCounterNotFound, fmt.Errorf("%s, available counters: %s", name, strings.Join(CounterNames(specs), ", ")))
+}
+
+// CounterNames returns sorted names of the configured counters
+func CounterNames(specs []CounterSpec) []string {
+	names := make([]string, len(specs))
+	for i, spec := range specs {
+		names[i] = spec.Name
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,60 @@
+package hashrate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCounterSpecs(t *testing.T) {
+	specs, err := ParseCounterSpecs("ema-5m:ema:5m, sma-1h:sma:1h,win-15m:window:15m")
+	require.NoError(t, err)
+	require.Equal(t, []CounterSpec{
+		{Name: "ema-5m", Type: CounterTypeEma, Window: 5 * time.Minute},
+		{Name: "sma-1h", Type: CounterTypeSma, Window: time.Hour},
+		{Name: "win-15m", Type: CounterTypeSlidingWindow, Window: 15 * time.Minute},
+		{Name: MeanCounterKey, Type: CounterTypeMean},
+	}, specs)
+
+	hr := NewHashrateFactoryFromSpecs(specs)()
+	require.Len(t, hr.GetHashrateAvgGHSAll(), 4)
+
+	require.NoError(t, CheckCounterName(specs, "sma-1h"))
+	require.ErrorIs(t, CheckCounterName(specs, "ema-10m"), ErrCounterNotFound)
+}
+
+func TestParseCounterSpecsInvalid(t *testing.T) {
+	for _, str := range []string{
+		"ema-5m:ema",
+		"ema-5m:ema:5",
+		"ema-5m:ema:-5m",
+		"ema-5m:foo:5m",
+		"ema-5m:ema:5m,ema-5m:sma:5m",
+		"avg:mean",
+		":ema:5m",
+	} {
+		_, err := ParseCounterSpecs(str)
+		require.ErrorIs(t, err, ErrCounterSpec, str)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	nowTime = start
+	defer func() { nowTime = time.Time{} }()
+
+	c := NewSlidingWindow(time.Minute)
+	for i := 0; i < 60; i++ {
+		c.AddWithTimestamp(10, start.Add(time.Duration(i)*time.Second))
+	}
+	nowTime = start.Add(59 * time.Second)
+	require.Equal(t, 600.0, c.Value())
+	require.Equal(t, 10.0, c.ValuePer(time.Second))
+
+	nowTime = start.Add(89 * time.Second)
+	require.Equal(t, 300.0, c.Value())
+
+	nowTime = start.Add(10 * time.Minute)
+	require.Equal(t, 0.0, c.Value())
+}
@@ -1,22 +1,122 @@
 package hashrate
 
 import (
+	"context"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/Lumerin-protocol/proxy-router/internal/lib"
 )
 
+const evictionInterval = time.Minute
+
+type WorkerState string
+
+const (
+	WorkerStateActive WorkerState = "active" // submitted or connected within the idle timeout
+	WorkerStateIdle   WorkerState = "idle"   // no activity within the idle timeout, counters are kept
+	WorkerStateGone   WorkerState = "gone"   // no activity within the ttl, going to be evicted
+)
+
 type GlobalHashrate struct {
-	data      *lib.Collection[*WorkerHashrateModel]
-	hrFactory HashrateFactory
+	data        *lib.Collection[*WorkerHashrateModel]
+	hrFactory   HashrateFactory
+	idleTimeout time.Duration // worker is considered idle after this duration of inactivity
+	ttl         time.Duration // worker is evicted after this duration of inactivity, zero disables eviction
+	evicted     *atomic.Uint64
+}
+
+type GlobalHashrateStats struct {
+	Active  int
+	Idle    int
+	Gone    int
+	Evicted uint64 // total number of evicted workers since startup
 }
 
-func NewGlobalHashrate(hrFactory HashrateFactory) *GlobalHashrate {
+func NewGlobalHashrate(hrFactory HashrateFactory, idleTimeout time.Duration, ttl time.Duration) *GlobalHashrate {
 	return &GlobalHashrate{
-		data:      lib.NewCollection[*WorkerHashrateModel](),
-		hrFactory: hrFactory,
+		data:        lib.NewCollection[*WorkerHashrateModel](),
+		hrFactory:   hrFactory,
+		idleTimeout: idleTimeout,
+		ttl:         ttl,
+		evicted:     &atomic.Uint64{},
+	}
+}
+
+// Run periodically evicts the workers that had no activity within the ttl
+func (t *GlobalHashrate) Run(ctx context.Context) error {
+	if t.ttl == 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(evictionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			t.Evict(now)
+		}
+	}
+}
+
+// Evict removes the workers that are gone at the given time, returns the number of evicted workers.
+// The state is checked again under the worker lock, so the worker is not evicted after a concurrent submit
+func (t *GlobalHashrate) Evict(now time.Time) int {
+	count := 0
+	t.data.Range(func(item *WorkerHashrateModel) bool {
+		if t.getState(item, now) != WorkerStateGone {
+			return true
+		}
+
+		item.mutex.Lock()
+		if t.getState(item, now) == WorkerStateGone {
+			item.evicted = true
+			t.data.Delete(item.ID())
+			count++
+		}
+		item.mutex.Unlock()
+		return true
+	})
+	t.evicted.Add(uint64(count))
+	return count
+}
+
+func (t *GlobalHashrate) GetWorkerState(m *WorkerHashrateModel) WorkerState {
+	return t.getState(m, time.Now())
+}
+
+func (t *GlobalHashrate) getState(m *WorkerHashrateModel, now time.Time) WorkerState {
+	inactive := now.Sub(m.LastActivity())
+	if t.ttl > 0 && inactive > t.ttl {
+		return WorkerStateGone
+	}
+	if inactive > t.idleTimeout {
+		return WorkerStateIdle
+	}
+	return WorkerStateActive
+}
+
+func (t *GlobalHashrate) GetStats() GlobalHashrateStats {
+	stats := GlobalHashrateStats{
+		Evicted: t.evicted.Load(),
 	}
+	now := time.Now()
+	t.data.Range(func(item *WorkerHashrateModel) bool {
+		switch t.getState(item, now) {
+		case WorkerStateActive:
+			stats.Active++
+		case WorkerStateIdle:
+			stats.Idle++
+		case WorkerStateGone:
+			stats.Gone++
+		}
+		return true
+	})
+	return stats
 }
 
 func (t *GlobalHashrate) Initialize(workerName string) {
@@ -24,13 +124,22 @@ func (t *GlobalHashrate) Initialize(workerName string) {
 }
 
 func (t *GlobalHashrate) OnSubmit(workerName string, diff float64) {
-	actual, _ := t.data.LoadOrStore(NewWorkerHashrateModel(workerName, t.hrFactory()))
-	actual.OnSubmit(diff)
+	// the worker evicted between the load and the submit is stored again
+	for {
+		actual, _ := t.data.LoadOrStore(NewWorkerHashrateModel(workerName, t.hrFactory()))
+		if actual.OnSubmit(diff) {
+			return
+		}
+	}
 }
 
 func (t *GlobalHashrate) OnConnect(workerName string) {
-	actual, _ := t.data.LoadOrStore(NewWorkerHashrateModel(workerName, t.hrFactory()))
-	actual.OnConnect()
+	for {
+		actual, _ := t.data.LoadOrStore(NewWorkerHashrateModel(workerName, t.hrFactory()))
+		if actual.OnConnect() {
+			return
+		}
+	}
 }
 
 func (t *GlobalHashrate) GetLastSubmitTime(workerName string) (tm time.Time, ok bool) {
@@ -98,16 +207,24 @@ func (t *GlobalHashrate) GetWorker(workerName string) *WorkerHashrateModel {
 }
 
 type WorkerHashrateModel struct {
-	id         string
-	hr         *Hashrate
-	reconnects *atomic.Uint32
+	id           string
+	hr           *Hashrate
+	reconnects   *atomic.Uint32
+	lastActivity *atomic.Int64 // unix nano time of the last submit, connect or initialization
+
+	mutex   sync.RWMutex // held for writing by the eviction, so the activity is not recorded to the evicted worker
+	evicted bool
 }
 
 func NewWorkerHashrateModel(id string, hr *Hashrate) *WorkerHashrateModel {
+	lastActivity := &atomic.Int64{}
+	lastActivity.Store(time.Now().UnixNano())
+
 	return &WorkerHashrateModel{
-		id:         id,
-		hr:         hr,
-		reconnects: &atomic.Uint32{},
+		id:           id,
+		hr:           hr,
+		reconnects:   &atomic.Uint32{},
+		lastActivity: lastActivity,
 	}
 }
 
@@ -115,12 +232,36 @@ func (m *WorkerHashrateModel) ID() string {
 	return m.id
 }
 
-func (m *WorkerHashrateModel) OnSubmit(diff float64) {
+// OnSubmit records the share, returns false if the worker was evicted
+func (m *WorkerHashrateModel) OnSubmit(diff float64) bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if m.evicted {
+		return false
+	}
 	m.hr.OnSubmit(diff)
+	m.touch()
+	return true
 }
 
-func (m *WorkerHashrateModel) OnConnect() {
+// OnConnect records the reconnect, returns false if the worker was evicted
+func (m *WorkerHashrateModel) OnConnect() bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if m.evicted {
+		return false
+	}
 	m.reconnects.Add(1)
+	m.touch()
+	return true
+}
+
+func (m *WorkerHashrateModel) LastActivity() time.Time {
+	return time.Unix(0, m.lastActivity.Load())
+}
+
+func (m *WorkerHashrateModel) touch() {
+	m.lastActivity.Store(time.Now().UnixNano())
 }
 
 func (m *WorkerHashrateModel) GetHashRateGHS(counterID string) (float64, bool) {
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGlobalHashrate(t *testing.T) {
@@ -29,7 +30,7 @@ func TestGlobalHashrate(t *testing.T) {
 		)
 	}
 
-	hr := NewGlobalHashrate(hashrateFactory)
+	hr := NewGlobalHashrate(hashrateFactory, 10*time.Minute, 24*time.Hour)
 
 	cb := func(thread int) {
 		for i := 0; i < loops; i++ {
@@ -61,3 +62,62 @@ func TestGlobalHashrate(t *testing.T) {
 	fmt.Printf("exp %d act %.0f\n", expected, actual)
 	assert.InEpsilon(t, expected, actual, 0.01, "should be accurate")
 }
+
+func TestGlobalHashrateEviction(t *testing.T) {
+	hr := NewGlobalHashrate(func() *Hashrate {
+		return NewHashrate(map[string]Counter{})
+	}, time.Minute, time.Hour)
+
+	hr.OnSubmit("active", 100)
+	hr.OnSubmit("stale", 100)
+	hr.GetWorker("stale").lastActivity.Store(time.Now().Add(-30 * time.Minute).UnixNano())
+
+	require.Equal(t, WorkerStateActive, hr.GetWorkerState(hr.GetWorker("active")))
+	require.Equal(t, WorkerStateIdle, hr.GetWorkerState(hr.GetWorker("stale")))
+	require.Equal(t, 0, hr.Evict(time.Now()))
+
+	evicted := hr.Evict(time.Now().Add(45 * time.Minute))
+	require.Equal(t, 1, evicted)
+	require.Nil(t, hr.GetWorker("stale"))
+	require.NotNil(t, hr.GetWorker("active"))
+
+	stats := hr.GetStats()
+	require.Equal(t, GlobalHashrateStats{Active: 1, Evicted: 1}, stats)
+}
+
+func TestGlobalHashrateEvictionKeepsConcurrentSubmit(t *testing.T) {
+	hr := NewGlobalHashrate(func() *Hashrate {
+		return NewHashrate(map[string]Counter{})
+	}, time.Minute, time.Hour)
+
+	hr.OnSubmit("worker", 100)
+	stale := hr.GetWorker("worker")
+	stale.lastActivity.Store(time.Now().Add(-2 * time.Hour).UnixNano())
+
+	// the submit to the worker loaded before the eviction is rejected, so it is retried on the new record
+	require.Equal(t, 1, hr.Evict(time.Now()))
+	require.False(t, stale.OnSubmit(100))
+
+	hr.OnSubmit("worker", 200)
+	work, ok := hr.GetTotalWork("worker")
+	require.True(t, ok)
+	require.Equal(t, 200.0, work)
+	require.Equal(t, WorkerStateActive, hr.GetWorkerState(hr.GetWorker("worker")))
+
+	// the worker that submitted after the state check is not evicted
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		hr.GetWorker("worker").lastActivity.Store(time.Now().Add(-2 * time.Hour).UnixNano())
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			hr.Evict(time.Now())
+		}()
+		go func() {
+			defer wg.Done()
+			hr.OnSubmit("worker", 1)
+		}()
+		wg.Wait()
+		require.NotNil(t, hr.GetWorker("worker"), "submit must not be lost to the eviction")
+	}
+}
@@ -98,10 +98,12 @@ func JobSubmittedToGHS(jobSubmitted float64) float64 {
 	return JobSubmittedToHS(jobSubmitted) / math.Pow10(9)
 }
 
+// GHSToJobSubmittedV2 converts hashrate to the work in SHA-256 difficulty-1 shares expected within the duration
 func GHSToJobSubmittedV2(hrGHS float64, duration time.Duration) float64 {
-	return HSToJobSubmitted(hrGHS*math.Pow10(9)) * duration.Seconds()
+	return AlgorithmSHA256.GHSToJobSubmitted(hrGHS, duration)
 }
 
+// JobSubmittedToGHSV2 converts the work in SHA-256 difficulty-1 shares submitted within the duration to hashrate
 func JobSubmittedToGHSV2(jobSubmitted float64, duration time.Duration) float64 {
-	return JobSubmittedToHS(jobSubmitted) / math.Pow10(9) / duration.Seconds()
+	return AlgorithmSHA256.JobSubmittedToGHS(jobSubmitted, duration)
 }
@@ -0,0 +1,107 @@
+package hashrate
+
+import (
+	"sync"
+	"time"
+)
+
+const SlidingWindowBuckets = 60
+
+// SlidingWindow is a counter that sums up the values added within the window. Unlike Sma it doesn't store
+// each measurement, but aggregates them into fixed number of buckets, so the memory usage is constant
+// regardless of the share rate
+type SlidingWindow struct {
+	window         time.Duration
+	bucketDuration time.Duration
+	buckets        []float64
+	lastBucket     int64 // absolute index of the last updated bucket
+	mutex          sync.Mutex
+}
+
+// NewSlidingWindow creates a new SlidingWindow counter with the given window time
+func NewSlidingWindow(window time.Duration) *SlidingWindow {
+	bucketDuration := window / SlidingWindowBuckets
+	if bucketDuration <= 0 {
+		bucketDuration = 1
+	}
+	return &SlidingWindow{
+		window:         window,
+		bucketDuration: bucketDuration,
+		buckets:        make([]float64, SlidingWindowBuckets),
+	}
+}
+
+func (c *SlidingWindow) Start() {
+	// noop
+}
+
+// Add adds a new value to the counter.
+func (c *SlidingWindow) Add(v float64) {
+	c.AddWithTimestamp(v, getNow())
+}
+
+// AddWithTimestamp adds a new value to the counter measured at the given time.
+func (c *SlidingWindow) AddWithTimestamp(v float64, timestamp time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.advance(timestamp)
+	c.buckets[c.bucketIndex(c.lastBucket)] += v
+}
+
+// Value returns the sum of values added within the window
+func (c *SlidingWindow) Value() float64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.advance(getNow())
+	return c.sum()
+}
+
+// ValuePer returns the current value of the counter, normalized to the given interval
+func (c *SlidingWindow) ValuePer(t time.Duration) float64 {
+	return c.Value() * float64(t) / float64(c.window)
+}
+
+func (c *SlidingWindow) Reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i := range c.buckets {
+		c.buckets[i] = 0
+	}
+	c.lastBucket = 0
+}
+
+// advance clears the buckets that went out of the window since the last update
+func (c *SlidingWindow) advance(now time.Time) {
+	current := now.UnixNano() / int64(c.bucketDuration)
+	if current <= c.lastBucket {
+		return
+	}
+
+	if current-c.lastBucket >= int64(len(c.buckets)) {
+		for i := range c.buckets {
+			c.buckets[i] = 0
+		}
+	} else {
+		for i := c.lastBucket + 1; i <= current; i++ {
+			c.buckets[c.bucketIndex(i)] = 0
+		}
+	}
+	c.lastBucket = current
+}
+
+func (c *SlidingWindow) bucketIndex(absIndex int64) int {
+	return int(absIndex % int64(len(c.buckets)))
+}
+
+func (c *SlidingWindow) sum() float64 {
+	var sum float64
+	for _, v := range c.buckets {
+		sum += v
+	}
+	return sum
+}
+
+var _ Counter = new(SlidingWindow)
@@ -0,0 +1,116 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+)
+
+type Kind string
+
+const (
+	KindWorker   Kind = "workers"
+	KindMiner    Kind = "miners"
+	KindContract Kind = "contracts"
+)
+
+var (
+	ErrUnknownKind    = errors.New("unknown series kind")
+	ErrSeriesNotFound = errors.New("series not found")
+)
+
+// Source returns current hashrate in GHS keyed by the worker, miner or contract ID
+type Source func() map[string]float64
+
+// History periodically samples the hashrate of workers, miners and contracts and keeps it in memory
+type History struct {
+	sampleInterval time.Duration
+	resolutions    []Resolution
+	idleTTL        time.Duration // series with zero hashrate for this duration are removed, zero disables
+	sources        map[Kind]Source
+	series         map[Kind]*lib.Collection[*Series]
+	log            interfaces.ILogger
+}
+
+func NewHistory(sources map[Kind]Source, sampleInterval time.Duration, resolutions []Resolution, idleTTL time.Duration, log interfaces.ILogger) *History {
+	series := make(map[Kind]*lib.Collection[*Series], len(sources))
+	for kind := range sources {
+		series[kind] = lib.NewCollection[*Series]()
+	}
+
+	return &History{
+		sampleInterval: sampleInterval,
+		resolutions:    resolutions,
+		idleTTL:        idleTTL,
+		sources:        sources,
+		series:         series,
+		log:            log,
+	}
+}
+
+func (h *History) Run(ctx context.Context) error {
+	h.log.Infof("starting hashrate history sampling with interval %s", h.sampleInterval)
+	ticker := time.NewTicker(h.sampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			h.Sample(now)
+		}
+	}
+}
+
+// Sample records the current values of all sources and removes the series that have no data within the retention period
+// or had zero hashrate within the idle ttl, e.g. of the disconnected workers that are still reported with zero hashrate
+func (h *History) Sample(now time.Time) {
+	for kind, src := range h.sources {
+		col := h.series[kind]
+		for ID, value := range src() {
+			series, _ := col.LoadOrStore(NewSeries(ID, h.resolutions))
+			series.Add(now, value)
+		}
+
+		col.Range(func(series *Series) bool {
+			expired := now.Sub(series.LastUpdate()) > h.maxRetention()
+			idle := h.idleTTL > 0 && now.Sub(series.LastActive()) > h.idleTTL
+			if expired || idle {
+				col.Delete(series.ID())
+				h.log.Debugf("removed hashrate history of %s %s", kind, series.ID())
+			}
+			return true
+		})
+	}
+}
+
+// Query returns the hashrate points of the series for the range ending now, aggregated with the given step
+func (h *History) Query(kind Kind, ID string, rng time.Duration, step time.Duration) ([]Point, time.Duration, error) {
+	col, ok := h.series[kind]
+	if !ok {
+		return nil, 0, lib.WrapError(ErrUnknownKind, fmt.Errorf("%s", kind))
+	}
+	series, ok := col.Load(ID)
+	if !ok {
+		return nil, 0, lib.WrapError(ErrSeriesNotFound, fmt.Errorf("%s %s", kind, ID))
+	}
+
+	now := time.Now()
+	points, resolution := series.Query(now.Add(-rng), now, step, now)
+	return points, resolution, nil
+}
+
+func (h *History) maxRetention() time.Duration {
+	var max time.Duration
+	for _, res := range h.resolutions {
+		if res.Retention > max {
+			max = res.Retention
+		}
+	}
+	return max
+}
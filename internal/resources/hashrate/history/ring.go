@@ -0,0 +1,91 @@
+package history
+
+import (
+	"time"
+)
+
+type Point struct {
+	Timestamp time.Time
+	Value     float64
+}
+
+type slot struct {
+	sum   float64
+	count uint32
+}
+
+// ring is a fixed size buffer of time slots with the given resolution, the values
+// added to the same slot are averaged, older slots are overwritten by the newer ones
+type ring struct {
+	resolution time.Duration
+	slots      []slot
+	head       int64 // absolute index of the latest slot, calculated as unix time / resolution
+}
+
+func newRing(resolution, retention time.Duration) *ring {
+	return &ring{
+		resolution: resolution,
+		slots:      make([]slot, int(retention/resolution)),
+	}
+}
+
+func (r *ring) add(ts time.Time, value float64) {
+	idx := r.slotIndex(ts)
+	if idx <= r.head-int64(len(r.slots)) {
+		// too old to be stored
+		return
+	}
+
+	if idx > r.head {
+		if idx-r.head >= int64(len(r.slots)) {
+			for i := range r.slots {
+				r.slots[i] = slot{}
+			}
+		} else {
+			for i := r.head + 1; i <= idx; i++ {
+				r.slots[r.pos(i)] = slot{}
+			}
+		}
+		r.head = idx
+	}
+
+	s := &r.slots[r.pos(idx)]
+	s.sum += value
+	s.count++
+}
+
+// points returns averaged values of the non-empty slots within [from, to] range
+func (r *ring) points(from, to time.Time) []Point {
+	start, end := r.slotIndex(from), r.slotIndex(to)
+	if oldest := r.head - int64(len(r.slots)) + 1; start < oldest {
+		start = oldest
+	}
+	if end > r.head {
+		end = r.head
+	}
+
+	var points []Point
+	for i := start; i <= end; i++ {
+		s := r.slots[r.pos(i)]
+		if s.count == 0 {
+			continue
+		}
+		points = append(points, Point{
+			Timestamp: time.Unix(0, i*int64(r.resolution)),
+			Value:     s.sum / float64(s.count),
+		})
+	}
+	return points
+}
+
+func (r *ring) retention() time.Duration {
+	return r.resolution * time.Duration(len(r.slots))
+}
+
+func (r *ring) slotIndex(ts time.Time) int64 {
+	return ts.UnixNano() / int64(r.resolution)
+}
+
+func (r *ring) pos(absIndex int64) int {
+	return int(absIndex % int64(len(r.slots)))
+}
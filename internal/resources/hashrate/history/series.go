@@ -0,0 +1,124 @@
+package history
+
+import (
+	"sync"
+	"time"
+)
+
+type Resolution struct {
+	Step      time.Duration
+	Retention time.Duration
+}
+
+// DefaultResolutions keeps 24 hours of minute samples, 7 days of 15-minute averages and 30 days of hourly averages
+var DefaultResolutions = []Resolution{
+	{Step: time.Minute, Retention: 24 * time.Hour},
+	{Step: 15 * time.Minute, Retention: 7 * 24 * time.Hour},
+	{Step: time.Hour, Retention: 30 * 24 * time.Hour},
+}
+
+// Series is a hashrate time-series of a single worker, miner or contract downsampled to multiple resolutions
+type Series struct {
+	id         string
+	rings      []*ring // sorted from the finest to the coarsest resolution
+	lastUpdate time.Time
+	lastActive time.Time // last non-zero sample, or the first sample
+	mutex      sync.RWMutex
+}
+
+func NewSeries(id string, resolutions []Resolution) *Series {
+	rings := make([]*ring, len(resolutions))
+	for i, res := range resolutions {
+		rings[i] = newRing(res.Step, res.Retention)
+	}
+	return &Series{
+		id:    id,
+		rings: rings,
+	}
+}
+
+func (s *Series) ID() string {
+	return s.id
+}
+
+func (s *Series) Add(ts time.Time, value float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, r := range s.rings {
+		r.add(ts, value)
+	}
+	if ts.After(s.lastUpdate) {
+		s.lastUpdate = ts
+	}
+	if (value > 0 || s.lastActive.IsZero()) && ts.After(s.lastActive) {
+		s.lastActive = ts
+	}
+}
+
+func (s *Series) LastUpdate() time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.lastUpdate
+}
+
+func (s *Series) LastActive() time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.lastActive
+}
+
+// Query returns the points within [from, to] range aggregated with the given step. It uses the coarsest
+// resolution that is not coarser than the step and still retains the data at the beginning of the range.
+// Returns the resolution of the underlying data
+func (s *Series) Query(from, to time.Time, step time.Duration, now time.Time) ([]Point, time.Duration) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	r := s.pickRing(from, step, now)
+	points := r.points(from, to)
+
+	if step <= r.resolution {
+		return points, r.resolution
+	}
+	return downsample(points, step), r.resolution
+}
+
+func (s *Series) pickRing(from time.Time, step time.Duration, now time.Time) *ring {
+	for i := len(s.rings) - 1; i >= 0; i-- {
+		r := s.rings[i]
+		if now.Sub(from) <= r.retention() && r.resolution <= step {
+			return r
+		}
+	}
+	for _, r := range s.rings {
+		if now.Sub(from) <= r.retention() {
+			return r
+		}
+	}
+	return s.rings[len(s.rings)-1]
+}
+
+// downsample averages the points into buckets of the given step
+func downsample(points []Point, step time.Duration) []Point {
+	var res []Point
+	var sum float64
+	var count int
+
+	for i, p := range points {
+		sum += p.Value
+		count++
+
+		bucket := p.Timestamp.Truncate(step)
+		if i+1 < len(points) && points[i+1].Timestamp.Truncate(step).Equal(bucket) {
+			continue
+		}
+
+		res = append(res, Point{Timestamp: bucket, Value: sum / float64(count)})
+		sum, count = 0, 0
+	}
+
+	return res
+}
@@ -0,0 +1,92 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeriesQueryResolution(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0).Truncate(time.Hour)
+	series := NewSeries("worker", DefaultResolutions)
+
+	// 48 hours of minute samples, value equals to the hour number
+	for i := 0; i < 48*60; i++ {
+		series.Add(start.Add(time.Duration(i)*time.Minute), float64(i/60))
+	}
+	now := start.Add(48*time.Hour - time.Minute)
+
+	points, res := series.Query(now.Add(-time.Hour), now, 0, now)
+	require.Equal(t, time.Minute, res)
+	require.Len(t, points, 61)
+
+	points, res = series.Query(now.Add(-2*time.Hour), now, time.Hour, now)
+	require.Equal(t, time.Hour, res)
+	require.Len(t, points, 3)
+	require.Equal(t, 47.0, points[len(points)-1].Value)
+
+	// minute data is not retained for 30 hours, so the 15 minute resolution is used
+	points, res = series.Query(now.Add(-30*time.Hour), now, 0, now)
+	require.Equal(t, 15*time.Minute, res)
+	require.Equal(t, 17.0, points[0].Value)
+}
+
+func TestSeriesDownsample(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0).Truncate(time.Hour)
+	series := NewSeries("miner", DefaultResolutions)
+	for i := 0; i < 10; i++ {
+		series.Add(start.Add(time.Duration(i)*time.Minute), float64(i))
+	}
+	now := start.Add(10 * time.Minute)
+
+	points, res := series.Query(start, now, 5*time.Minute, now)
+	require.Equal(t, time.Minute, res)
+	require.Equal(t, []Point{
+		{Timestamp: start, Value: 2},
+		{Timestamp: start.Add(5 * time.Minute), Value: 7},
+	}, points)
+}
+
+func TestHistorySampleEviction(t *testing.T) {
+	values := map[string]float64{"a": 100, "b": 200}
+	h := NewHistory(map[Kind]Source{
+		KindWorker: func() map[string]float64 { return values },
+	}, time.Minute, DefaultResolutions, 0, lib.NewTestLogger())
+
+	now := time.Now()
+	h.Sample(now)
+	_, _, err := h.Query(KindWorker, "a", time.Hour, 0)
+	require.NoError(t, err)
+
+	delete(values, "a")
+	h.Sample(now.Add(31 * 24 * time.Hour))
+	_, _, err = h.Query(KindWorker, "a", time.Hour, 0)
+	require.ErrorIs(t, err, ErrSeriesNotFound)
+
+	_, _, err = h.Query(KindMiner, "b", time.Hour, 0)
+	require.ErrorIs(t, err, ErrUnknownKind)
+}
+
+func TestHistorySampleEvictionIdle(t *testing.T) {
+	values := map[string]float64{"a": 100, "b": 200}
+	h := NewHistory(map[Kind]Source{
+		KindWorker: func() map[string]float64 { return values },
+	}, time.Minute, DefaultResolutions, 24*time.Hour, lib.NewTestLogger())
+
+	now := time.Now()
+	h.Sample(now)
+
+	// the idle worker is still reported by the source with zero hashrate
+	values["a"] = 0
+	h.Sample(now.Add(12 * time.Hour))
+	_, _, err := h.Query(KindWorker, "a", time.Hour, 0)
+	require.NoError(t, err)
+
+	h.Sample(now.Add(25 * time.Hour))
+	_, _, err = h.Query(KindWorker, "a", time.Hour, 0)
+	require.ErrorIs(t, err, ErrSeriesNotFound)
+	_, _, err = h.Query(KindWorker, "b", time.Hour, 0)
+	require.NoError(t, err)
+}
@@ -0,0 +1,77 @@
+package peervalidator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const EvidenceMemorySize = 100 // number of the recent probes kept in memory
+
+// EvidenceStore keeps the recent probes in memory and appends all of them to the json lines file, if the path is set
+type EvidenceStore struct {
+	// config
+	path string
+
+	// state
+	recent []*Evidence
+	mutex  sync.RWMutex
+}
+
+func NewEvidenceStore(path string) *EvidenceStore {
+	return &EvidenceStore{
+		path: path,
+	}
+}
+
+func (s *EvidenceStore) Add(ev *Evidence) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.recent = append(s.recent, ev)
+	if len(s.recent) > EvidenceMemorySize {
+		s.recent = s.recent[len(s.recent)-EvidenceMemorySize:]
+	}
+
+	if s.path == "" {
+		return nil
+	}
+	return s.append(ev)
+}
+
+// GetRecent returns the recent probes of the validator, newest first, or of all validators if addr is nil
+func (s *EvidenceStore) GetRecent(addr *common.Address) []*Evidence {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var res []*Evidence
+	for i := len(s.recent) - 1; i >= 0; i-- {
+		if addr == nil || s.recent[i].Validator == *addr {
+			res = append(res, s.recent[i])
+		}
+	}
+	return res
+}
+
+func (s *EvidenceStore) append(ev *Evidence) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(s.path), 0o755)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
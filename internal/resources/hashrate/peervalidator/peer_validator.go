@@ -4,36 +4,55 @@ import (
 	"context"
 	"fmt"
 	"math/big"
-	"net"
 	"time"
 
 	vr "github.com/Lumerin-protocol/contracts-go/v2/validatorregistry"
 	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/txmanager"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"golang.org/x/exp/rand"
 )
 
 type PeerValidator struct {
-	interval     time.Duration // interval between checking peers aliveness
-	registryAddr common.Address
-	walletAddr   common.Address
-	registry     *vr.Validatorregistry
-	log          interfaces.ILogger
+	// config
+	interval         time.Duration // interval between checking peers
+	failureThreshold int           // number of consecutive failed probes of the peer after which the complaint is submitted
+	registryAddr     common.Address
+	walletAddr       common.Address
+
+	// state
+	failures map[common.Address]int // consecutive failed probes of the suspected peers
+
+	// deps
+	signer    interfaces.Signer
+	registry  *vr.Validatorregistry
+	txManager *txmanager.TxManager
+	probe     *StratumProbe
+	evidence  *EvidenceStore
+	log       interfaces.ILogger
 }
 
-func NewPeerValidator(registryAddress common.Address, walletAddr common.Address, backend bind.ContractBackend, interval time.Duration, log interfaces.ILogger) (*PeerValidator, error) {
+func NewPeerValidator(registryAddress common.Address, signer interfaces.Signer, backend bind.ContractBackend, txManager *txmanager.TxManager, interval time.Duration, failureThreshold int, probeTimeout time.Duration, evidence *EvidenceStore, log interfaces.ILogger) (*PeerValidator, error) {
 	registry, err := vr.NewValidatorregistry(registryAddress, backend)
 	if err != nil {
 		return nil, err
 	}
+	log = log.Named("PEER_VAL")
 
 	return &PeerValidator{
-		registryAddr: registryAddress,
-		walletAddr:   walletAddr,
-		interval:     interval,
-		registry:     registry,
-		log:          log.Named("PEER_VAL"),
+		registryAddr:     registryAddress,
+		walletAddr:       signer.Address(),
+		signer:           signer,
+		interval:         interval,
+		failureThreshold: failureThreshold,
+		failures:         make(map[common.Address]int),
+		registry:         registry,
+		txManager:        txManager,
+		probe:            NewStratumProbe(probeTimeout, log),
+		evidence:         evidence,
+		log:              log,
 	}, nil
 }
 
@@ -56,7 +75,7 @@ func (v *PeerValidator) Run(ctx context.Context) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-ticker.C:
-			err := v.validateRandomPeer(ctx)
+			err := v.validateNextPeer(ctx)
 			if err != nil {
 				v.log.Error("failed to validate peer", err)
 			}
@@ -64,25 +83,77 @@ func (v *PeerValidator) Run(ctx context.Context) error {
 	}
 }
 
-func (v *PeerValidator) validateRandomPeer(ctx context.Context) error {
-	validator, err := v.getRandomValidator(ctx)
+func (v *PeerValidator) validateNextPeer(ctx context.Context) error {
+	validator, err := v.getNextValidator(ctx)
 	if err != nil {
 		return err
 	}
+	if validator.Addr == v.walletAddr {
+		v.log.Debugf("skipping validation of self")
+		return nil
+	}
 	v.log.Infof("validating peer %s, host %s", validator.Addr.Hex(), validator.Host)
 
-	// validate peer
-	ok, err := v.validatePeer(ctx, validator.Host)
-	if err != nil {
-		return err
+	ev := v.probe.Probe(ctx, validator.Addr, validator.Host)
+	if ctx.Err() != nil {
+		return ctx.Err()
 	}
-	if ok {
+
+	var shouldComplain bool
+	shouldComplain, ev.Failures = v.recordProbe(validator.Addr, ev.OK())
+	if ev.OK() {
 		v.log.Infof("peer %s is valid", validator.Addr.Hex())
-		return nil
+	} else {
+		v.log.Warnf("peer %s failed validation %d/%d times, checks %+v", validator.Addr.Hex(), ev.Failures, v.failureThreshold, ev.Checks)
+	}
+
+	if shouldComplain {
+		if validator.LastComplainer == v.walletAddr {
+			v.log.Warnf("already complained on peer %s", validator.Addr.Hex())
+		} else {
+			txHash, err := v.complain(ctx, validator.Addr)
+			if err != nil {
+				v.log.Errorf("failed to complain on peer %s: %s", validator.Addr.Hex(), err)
+			} else {
+				ev.ComplaintTx = &txHash
+				v.log.Warnf("complained on peer %s, tx %s", validator.Addr.Hex(), txHash.Hex())
+			}
+		}
+	}
+
+	return v.evidence.Add(ev)
+}
+
+// recordProbe updates the consecutive failures of the peer and returns true if the complaint should be submitted
+func (v *PeerValidator) recordProbe(addr common.Address, ok bool) (shouldComplain bool, failures int) {
+	if ok {
+		delete(v.failures, addr)
+		return false, 0
 	}
 
-	v.log.Infof("peer %s is invalid", validator.Addr.Hex())
-	return v.complain(ctx, validator.Addr)
+	failures = v.failures[addr] + 1
+	if failures >= v.failureThreshold {
+		delete(v.failures, addr)
+		return true, failures
+	}
+	v.failures[addr] = failures
+	return false, failures
+}
+
+// getNextValidator returns the suspected peer to confirm its failure, or the random one
+func (v *PeerValidator) getNextValidator(ctx context.Context) (*vr.ValidatorRegistryValidator, error) {
+	for addr := range v.failures {
+		validator, err := v.getValidator(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		if validator.Addr == addr {
+			return validator, nil
+		}
+		// the peer is no longer registered
+		delete(v.failures, addr)
+	}
+	return v.getRandomValidator(ctx)
 }
 
 func (v *PeerValidator) getRandomValidator(ctx context.Context) (*vr.ValidatorRegistryValidator, error) {
@@ -91,6 +162,10 @@ func (v *PeerValidator) getRandomValidator(ctx context.Context) (*vr.ValidatorRe
 		return nil, err
 	}
 
+	if activeValidatorsCount.Sign() == 0 {
+		return nil, fmt.Errorf("no active validators")
+	}
+
 	// get random from range 0 to activeValidatorsCount
 	index := rand.Intn(int(activeValidatorsCount.Int64()))
 
@@ -123,25 +198,13 @@ func (v *PeerValidator) getValidator(ctx context.Context, addr common.Address) (
 	return &validator, err
 }
 
-func (v *PeerValidator) complain(ctx context.Context, addr common.Address) error {
-	_, err := v.registry.ValidatorComplain(&bind.TransactOpts{Context: ctx}, addr)
-	return err
-}
-
-func (v *PeerValidator) validatePeer(ctx context.Context, host string) (bool, error) {
-	if _, ok := ctx.Deadline(); !ok {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
-		defer cancel()
-	}
-	d := net.Dialer{}
-	_, err := d.DialContext(ctx, "tcp", host)
+// complain submits the complaint on the validator signed by the wallet
+func (v *PeerValidator) complain(ctx context.Context, addr common.Address) (common.Hash, error) {
+	receipt, err := v.txManager.Transact(ctx, v.signer, fmt.Sprintf("complain on validator %s", addr.Hex()), func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return v.registry.ValidatorComplain(opts, addr)
+	})
 	if err != nil {
-		// server error
-		return false, nil
-
-		// client error
-		// return false, err
+		return common.Hash{}, err
 	}
-	return true, nil
+	return receipt.TxHash, nil
 }
@@ -0,0 +1,52 @@
+package peervalidator
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordProbeComplainsAfterConsecutiveFailures(t *testing.T) {
+	v := &PeerValidator{failureThreshold: 3, failures: make(map[common.Address]int)}
+	addr := common.HexToAddress("0x1")
+
+	complain, failures := v.recordProbe(addr, false)
+	require.False(t, complain)
+	require.Equal(t, 1, failures)
+
+	// the successful probe resets the failures
+	complain, _ = v.recordProbe(addr, true)
+	require.False(t, complain)
+
+	for i := 1; i < 3; i++ {
+		complain, failures = v.recordProbe(addr, false)
+		require.False(t, complain)
+		require.Equal(t, i, failures)
+	}
+	complain, failures = v.recordProbe(addr, false)
+	require.True(t, complain)
+	require.Equal(t, 3, failures)
+
+	// the counter starts over after the complaint
+	complain, failures = v.recordProbe(addr, false)
+	require.False(t, complain)
+	require.Equal(t, 1, failures)
+}
+
+func TestEvidenceStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "evidence", "peers.jsonl")
+	store := NewEvidenceStore(path)
+	addr1, addr2 := common.HexToAddress("0x1"), common.HexToAddress("0x2")
+
+	require.NoError(t, store.Add(&Evidence{Validator: addr1, Checks: []Check{{Name: CheckHandshake, OK: true}}}))
+	require.NoError(t, store.Add(&Evidence{Validator: addr2, Checks: []Check{{Name: CheckHandshake, Detail: "refused"}}}))
+	require.NoError(t, store.Add(&Evidence{Validator: addr1, Failures: 1}))
+
+	recent := store.GetRecent(&addr1)
+	require.Len(t, recent, 2)
+	require.Equal(t, 1, recent[0].Failures)
+	require.Len(t, store.GetRecent(nil), 3)
+	require.FileExists(t, path)
+}
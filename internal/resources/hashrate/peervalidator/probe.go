@@ -0,0 +1,296 @@
+package peervalidator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/hashrate"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/proxy"
+	i "github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/proxy/interfaces"
+	sm "github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/proxy/stratumv1_message"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/validator"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	ProbeWorkerName     = "peer-validator"
+	UnknownContractWait = 10 * time.Second // time to wait for the jobs of the unknown contract
+
+	CheckHandshake       = "handshake"
+	CheckJobs            = "jobs"
+	CheckShareValidation = "share validation"
+	CheckUnknownJob      = "unknown job rejected"
+	CheckUnknownContract = "unknown contract rejected"
+)
+
+var (
+	ErrNoJob = errors.New("no job received from peer")
+)
+
+// Check is the outcome of the single step of the peer probe
+type Check struct {
+	Name   string
+	OK     bool
+	Detail string `json:",omitempty"`
+}
+
+// Evidence is the record of the peer probe, kept to justify the complaint
+type Evidence struct {
+	Validator   common.Address
+	Host        string
+	StartedAt   time.Time
+	Duration    time.Duration
+	Checks      []Check
+	Failures    int          // consecutive failed probes of the peer including this one
+	ComplaintTx *common.Hash `json:",omitempty"`
+}
+
+// OK returns true if all of the checks passed
+func (e *Evidence) OK() bool {
+	for _, c := range e.Checks {
+		if !c.OK {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Evidence) add(name string, err error) bool {
+	check := Check{Name: name, OK: err == nil}
+	if err != nil {
+		check.Detail = err.Error()
+	}
+	e.Checks = append(e.Checks, check)
+	return check.OK
+}
+
+// StratumProbe checks the peer the same way the seller uses it: performs the stratum handshake, waits for the
+// jobs proxied from the peer pool and submits the synthetic shares, which verdict is known in advance,
+// to verify that the peer validates them. Then it delivers to the synthetic contract, which is unknown to the
+// peer, to verify the peer doesn't route the hashrate it can't validate
+type StratumProbe struct {
+	// config
+	timeout time.Duration
+
+	// deps
+	log interfaces.ILogger
+}
+
+func NewStratumProbe(timeout time.Duration, log interfaces.ILogger) *StratumProbe {
+	return &StratumProbe{
+		timeout: timeout,
+		log:     log,
+	}
+}
+
+func (p *StratumProbe) Probe(ctx context.Context, validatorAddr common.Address, host string) *Evidence {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	ev := &Evidence{
+		Validator: validatorAddr,
+		Host:      host,
+		StartedAt: time.Now(),
+	}
+	defer func() {
+		ev.Duration = time.Since(ev.StartedAt)
+	}()
+
+	if p.probeMining(ctx, ev, host) {
+		ev.add(CheckUnknownContract, p.probeUnknownContract(ctx, host))
+	}
+	return ev
+}
+
+// probeMining runs the checks on the regular connection to the peer, returns false if the peer is unusable
+func (p *StratumProbe) probeMining(ctx context.Context, ev *Evidence, host string) bool {
+	dest, done, err := p.connect(ctx, host)
+	if err == nil {
+		defer p.close(dest, done)
+		err = p.handshake(ctx, dest, 1)
+	}
+	if !ev.add(CheckHandshake, err) {
+		return false
+	}
+
+	job, err := p.awaitJob(ctx, dest)
+	if !ev.add(CheckJobs, err) {
+		return false
+	}
+
+	ev.add(CheckShareValidation, p.checkShare(ctx, dest, job, 4))
+	ev.add(CheckUnknownJob, p.checkUnknownJob(ctx, dest, job, 5))
+	return true
+}
+
+// probeUnknownContract connects as a seller of the synthetic contract, the peer shouldn't provide any jobs for it
+func (p *StratumProbe) probeUnknownContract(ctx context.Context, host string) error {
+	dest, done, err := p.connect(ctx, host)
+	if err != nil {
+		return err
+	}
+	defer p.close(dest, done)
+
+	ctx, cancel := context.WithTimeout(ctx, UnknownContractWait)
+	defer cancel()
+
+	contract := syntheticContract()
+	cfg := sm.NewMiningConfigure(1, nil)
+	cfg.SetLMRContractAddress(contract)
+
+	msgs := []i.MiningMessageGeneric{
+		cfg,
+		sm.NewMiningSubscribe(2, "stratum-proxy", "1.0.0"),
+		sm.NewMiningAuthorize(3, contract, ""),
+	}
+	for _, msg := range msgs {
+		err := dest.Write(ctx, msg)
+		if err != nil {
+			// the peer closed the connection
+			return nil
+		}
+	}
+
+	select {
+	case <-dest.GetFirstJobSignal():
+		return fmt.Errorf("peer provided jobs for the unknown contract %s", contract)
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+func (p *StratumProbe) connect(ctx context.Context, host string) (*proxy.ConnDest, <-chan struct{}, error) {
+	destURL := &url.URL{
+		Scheme: "stratum+tcp",
+		User:   url.User(ProbeWorkerName),
+		Host:   host,
+	}
+	valid := validator.NewValidator(hashrate.AlgorithmSHA256, p.timeout)
+	dest, err := proxy.ConnectDest(ctx, destURL, valid, p.timeout, p.timeout, p.log)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	done := make(chan struct{})
+	dest.AutoReadStart(ctx, func(err error) {
+		if err != nil {
+			p.log.Debugf("probe connection closed: %s", err)
+		}
+		close(done)
+	})
+	return dest, done, nil
+}
+
+func (p *StratumProbe) close(dest *proxy.ConnDest, done <-chan struct{}) {
+	_ = dest.Close()
+	<-done
+}
+
+func (p *StratumProbe) handshake(ctx context.Context, dest *proxy.ConnDest, msgID int) error {
+	res, err := dest.WriteAwaitRes(ctx, sm.NewMiningSubscribe(msgID, "stratum-proxy", "1.0.0"))
+	if err != nil {
+		return err
+	}
+	subRes, err := sm.ToMiningSubscribeResult(res.(*sm.MiningResult))
+	if err != nil {
+		return err
+	}
+	if subRes.IsError() {
+		return fmt.Errorf("subscribe error: %s", subRes.GetError())
+	}
+	dest.SetExtraNonce(subRes.GetExtranonce())
+
+	res, err = dest.WriteAwaitRes(ctx, sm.NewMiningAuthorize(msgID+1, ProbeWorkerName, ""))
+	if err != nil {
+		return err
+	}
+	authRes := res.(*sm.MiningResult)
+	if authRes.IsError() {
+		return fmt.Errorf("authorize error: %s", authRes.GetError())
+	}
+	return nil
+}
+
+func (p *StratumProbe) awaitJob(ctx context.Context, dest *proxy.ConnDest) (*validator.MiningJob, error) {
+	select {
+	case <-ctx.Done():
+		return nil, lib.WrapError(ErrNoJob, ctx.Err())
+	case <-dest.GetFirstJobSignal():
+	}
+
+	job, ok := dest.GetLatestJob()
+	if !ok {
+		return nil, ErrNoJob
+	}
+	notify := job.GetNotify()
+	if len(notify.GetPrevBlockHash()) != 64 || len(notify.GetNbits()) != 8 || len(notify.GetNtime()) != 8 {
+		return nil, fmt.Errorf("malformed job %s", string(notify.Serialize()))
+	}
+	if job.GetDiff() <= 0 {
+		return nil, fmt.Errorf("difficulty is not set before the job %s", notify.GetJobID())
+	}
+	return job, nil
+}
+
+// checkShare submits the random share which verdict is computed locally and compares it with the peer response.
+// With the pool difficulty the share is almost always invalid, so the peer accepting it doesn't validate shares
+func (p *StratumProbe) checkShare(ctx context.Context, dest *proxy.ConnDest, job *validator.MiningJob, msgID int) error {
+	notify := job.GetNotify()
+	submit := sm.NewMiningSubmit(ProbeWorkerName, notify.GetJobID(), randomHex(job.GetExtraNonce2Size()), notify.GetNtime(), randomHex(4))
+	submit.SetID(msgID)
+
+	shareRes, localErr := dest.ValidateShare(submit)
+
+	res, err := dest.WriteAwaitRes(ctx, submit)
+	if err != nil {
+		return err
+	}
+	peerAccepted := !res.(*sm.MiningResult).IsError()
+
+	if peerAccepted && localErr != nil {
+		return fmt.Errorf("peer accepted invalid share (%s), share difficulty %.2f, job difficulty %.2f", localErr, shareRes.Diff, job.GetDiff())
+	}
+	if !peerAccepted && localErr == nil {
+		return fmt.Errorf("peer rejected valid share, share difficulty %.2f, job difficulty %.2f", shareRes.Diff, job.GetDiff())
+	}
+	return nil
+}
+
+func (p *StratumProbe) checkUnknownJob(ctx context.Context, dest *proxy.ConnDest, job *validator.MiningJob, msgID int) error {
+	jobID := "ff" + randomHex(4)
+	submit := sm.NewMiningSubmit(ProbeWorkerName, jobID, randomHex(job.GetExtraNonce2Size()), job.GetNotify().GetNtime(), randomHex(4))
+	submit.SetID(msgID)
+
+	res, err := dest.WriteAwaitRes(ctx, submit)
+	if err != nil {
+		return err
+	}
+	if !res.(*sm.MiningResult).IsError() {
+		return fmt.Errorf("peer accepted share for unknown job %s", jobID)
+	}
+	return nil
+}
+
+// syntheticContract returns the random contract address, which doesn't exist on chain
+func syntheticContract() string {
+	return common.BytesToAddress(randomBytes(common.AddressLength)).Hex()
+}
+
+func randomHex(size int) string {
+	return hex.EncodeToString(randomBytes(size))
+}
+
+func randomBytes(size int) []byte {
+	b := make([]byte, size)
+	_, _ = rand.Read(b)
+	return b
+}
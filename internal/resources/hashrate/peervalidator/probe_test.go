@@ -0,0 +1,144 @@
+package peervalidator
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/hashrate"
+	sm "github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/proxy/stratumv1_message"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/validator"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testNotify = `{"id":null,"method":"mining.notify","params":["2dc3427c2e","221a7d5aeda279d8b8455fe56c8dc7d05582575d00038fbf0000000000000000","01000000010000000000000000000000000000000000000000000000000000000000000000ffffffff4b03e1360cfabe6d6ddecabad1af6410018e1f62f26730ccb9c8a4a55c1c90fb96d7b124a68f126bcf0100000000000000","2e7c42c32d2f736c7573682f000000000383d02826000000001976a9147c154ed1dc59609e3d26abb2df2ea3d587cd8c4188ac00000000000000002c6a4c2952534b424c4f434b3aa126fd3abcfed0d9d2fdf56d5650fda514e1a35408b1b8445c907d21005402510000000000000000266a24aa21a9ed217bdf1fc8e2ca2f98f2f3dc804fa19609ad045e8761e3fcd6b60baf80d1f5bf00000000",["fd90b0aa15698f631ae06aba1d688db974c899389c874f03b2c91784733ac50c"],"20000004","17056102","64c25820",false]}`
+	testXNonce = "11650804a6c84c"
+	testDiff   = 699.0
+)
+
+// fakePeer is the stratum server imitating the validator, the dishonest one accepts any share and contract
+type fakePeer struct {
+	listener net.Listener
+	honest   bool
+}
+
+func newFakePeer(t *testing.T, honest bool) *fakePeer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	p := &fakePeer{listener: listener, honest: honest}
+	t.Cleanup(func() { _ = listener.Close() })
+	go p.serve()
+	return p
+}
+
+func (p *fakePeer) serve() {
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		go p.handle(conn)
+	}
+}
+
+func (p *fakePeer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	notify, _ := sm.ParseMiningNotify([]byte(testNotify))
+	valid := validator.NewValidator(hashrate.AlgorithmSHA256, time.Minute)
+	valid.AddNewJob(notify, testDiff, testXNonce, 8)
+
+	write := func(msg interface{ Serialize() []byte }) {
+		_, _ = conn.Write(append(msg.Serialize(), '\n'))
+	}
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		msg, err := sm.ParseStratumMessage(line)
+		if err != nil {
+			return
+		}
+
+		switch typed := msg.(type) {
+		case *sm.MiningConfigure:
+			if typed.GetLMRContractAddress() != "" && p.honest {
+				return
+			}
+			write(sm.NewMiningConfigureResult(typed.GetID(), false, ""))
+		case *sm.MiningSubscribe:
+			write(sm.NewMiningSubscribeResult(typed.GetID(), testXNonce, 8))
+		case *sm.MiningAuthorize:
+			write(sm.NewMiningResultSuccess(typed.GetID()))
+			write(sm.NewMiningSetDifficulty(testDiff))
+			write(notify)
+		case *sm.MiningSubmit:
+			if !p.honest {
+				write(sm.NewMiningResultSuccess(typed.GetID()))
+				continue
+			}
+			_, err := valid.ValidateShare(typed)
+			switch {
+			case err == nil:
+				write(sm.NewMiningResultSuccess(typed.GetID()))
+			case errors.Is(err, validator.ErrJobNotFound):
+				write(sm.NewMiningResultJobNotFound(typed.GetID()))
+			case errors.Is(err, validator.ErrDuplicateShare):
+				write(sm.NewMiningResultDuplicatedShare(typed.GetID()))
+			default:
+				write(sm.NewMiningResultLowDifficulty(typed.GetID()))
+			}
+		}
+	}
+}
+
+func TestProbeHonestPeer(t *testing.T) {
+	peer := newFakePeer(t, true)
+	probe := NewStratumProbe(5*time.Second, lib.NewTestLogger())
+
+	ev := probe.Probe(context.Background(), common.HexToAddress("0x1"), peer.listener.Addr().String())
+	require.True(t, ev.OK(), "%+v", ev.Checks)
+	require.Len(t, ev.Checks, 5)
+}
+
+func TestProbeDishonestPeer(t *testing.T) {
+	peer := newFakePeer(t, false)
+	probe := NewStratumProbe(5*time.Second, lib.NewTestLogger())
+
+	ev := probe.Probe(context.Background(), common.HexToAddress("0x1"), peer.listener.Addr().String())
+	require.False(t, ev.OK())
+
+	failed := map[string]bool{}
+	for _, c := range ev.Checks {
+		failed[c.Name] = !c.OK
+	}
+	require.Equal(t, map[string]bool{
+		CheckHandshake:       false,
+		CheckJobs:            false,
+		CheckShareValidation: true,
+		CheckUnknownJob:      true,
+		CheckUnknownContract: true,
+	}, failed)
+}
+
+func TestProbeUnreachablePeer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	probe := NewStratumProbe(time.Second, lib.NewTestLogger())
+	ev := probe.Probe(context.Background(), common.HexToAddress("0x1"), addr)
+	require.False(t, ev.OK())
+	require.Len(t, ev.Checks, 1)
+	require.Equal(t, CheckHandshake, ev.Checks[0].Name)
+}
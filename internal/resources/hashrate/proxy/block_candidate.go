@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+)
+
+const BlockCandidateLogSize = 100
+
+// BlockCandidate is a share that meets the network target, so it may be a solution for the next block
+type BlockCandidate struct {
+	Timestamp          time.Time
+	BlockHash          string
+	ShareDiff          float64
+	NetworkDiff        float64
+	JobID              string
+	SourceAddr         string
+	SourceWorker       string
+	DestURL            string   // destination url without the password
+	IncomingContractID string   // set if the miner is a buyer contract connection
+	ContractIDs        []string // contracts the destination belongs to, filled by the BlockCandidateHandler owner
+	WeAccepted         bool
+	PoolAccepted       bool
+	PoolError          string
+}
+
+// BlockCandidateHandler is called after the block candidate share was forwarded to the pool
+type BlockCandidateHandler func(c BlockCandidate)
+
+// BlockCandidateLog keeps last block candidates in memory
+type BlockCandidateLog struct {
+	items []BlockCandidate
+	size  int
+	mutex sync.RWMutex
+}
+
+func NewBlockCandidateLog(size int) *BlockCandidateLog {
+	return &BlockCandidateLog{size: size}
+}
+
+func (l *BlockCandidateLog) Add(c BlockCandidate) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.items = append(l.items, c)
+	if len(l.items) > l.size {
+		l.items = l.items[len(l.items)-l.size:]
+	}
+}
+
+// GetAll returns the block candidates starting from the most recent one
+func (l *BlockCandidateLog) GetAll() []BlockCandidate {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	res := make([]BlockCandidate, len(l.items))
+	for i, item := range l.items {
+		res[len(l.items)-1-i] = item
+	}
+	return res
+}
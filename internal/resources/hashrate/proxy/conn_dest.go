@@ -119,6 +119,10 @@ func (c *ConnDest) AutoRead(ctx context.Context) error {
 	}
 }
 
+func (c *ConnDest) Close() error {
+	return c.conn.Close()
+}
+
 func (c *ConnDest) ID() string {
 	return c.conn.GetID()
 }
@@ -172,6 +176,12 @@ func (c *ConnDest) GetDiff() float64 {
 	return float64(c.diff.Load())
 }
 
+// GetWork returns the work of the share with the current difficulty in SHA-256 difficulty-1 shares,
+// which is the unit used by the hashrate counters regardless of the algorithm
+func (c *ConnDest) GetWork() float64 {
+	return c.validator.GetAlgorithm().DiffToWork(c.GetDiff())
+}
+
 func (c *ConnDest) GetHR() gi.Hashrate {
 	return c.hr
 }
@@ -305,6 +315,17 @@ func (c *ConnDest) ValidateAndAddShare(msg *sm.MiningSubmit) (float64, error) {
 	return c.validator.ValidateAndAddShare(msg)
 }
 
+func (c *ConnDest) ValidateShare(msg *sm.MiningSubmit) (validator.ShareResult, error) {
+	return c.validator.ValidateShare(msg)
+}
+
+// GetRedactedURL returns the destination url with the password removed
+func (c *ConnDest) GetRedactedURL() string {
+	c.destLock.RLock()
+	defer c.destLock.RUnlock()
+	return c.destUrl.Redacted()
+}
+
 func (c *ConnDest) GetLatestJob() (*validator.MiningJob, bool) {
 	return c.validator.GetLatestJob()
 }
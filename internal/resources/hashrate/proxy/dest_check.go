@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	gi "github.com/Lumerin-protocol/proxy-router/internal/interfaces"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/hashrate"
+	sm "github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/proxy/stratumv1_message"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/validator"
+)
+
+// CheckDest opens the stratum session to the destination and subscribes, to verify in advance that the destination
+// accepts connections. Returns the time it took to establish the session
+func CheckDest(ctx context.Context, destURL *url.URL, log gi.ILogger) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, DIAL_TIMEOUT+RESPONSE_TIMEOUT)
+	defer cancel()
+
+	startedAt := time.Now()
+	valid := validator.NewValidator(hashrate.AlgorithmSHA256, RESPONSE_TIMEOUT)
+	dest, err := ConnectDest(ctx, destURL, valid, RESPONSE_TIMEOUT, RESPONSE_TIMEOUT, log)
+	if err != nil {
+		return 0, err
+	}
+
+	done := make(chan struct{})
+	dest.AutoReadStart(ctx, func(err error) { close(done) })
+	defer func() {
+		_ = dest.Close()
+		<-done
+	}()
+
+	err = subscribe(ctx, dest)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Since(startedAt), nil
+}
+
+// DestSession is the stratum session to the destination opened without a miner, it keeps reading the jobs
+// until it is closed or the destination disconnects
+type DestSession struct {
+	dest *ConnDest
+	done chan struct{}
+	err  error
+}
+
+// OpenDestSession opens the stratum session to the destination, subscribes and authorizes with the credentials
+// of the url. The connection is closed if there were no messages for idleTimeout
+func OpenDestSession(ctx context.Context, destURL *url.URL, idleTimeout time.Duration, log gi.ILogger) (*DestSession, error) {
+	handshakeCtx, cancel := context.WithTimeout(ctx, DIAL_TIMEOUT+RESPONSE_TIMEOUT)
+	defer cancel()
+
+	valid := validator.NewValidator(hashrate.AlgorithmSHA256, RESPONSE_TIMEOUT)
+	dest, err := ConnectDest(handshakeCtx, destURL, valid, idleTimeout, idleTimeout, log)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &DestSession{dest: dest, done: make(chan struct{})}
+	dest.AutoReadStart(ctx, func(err error) {
+		s.err = err
+		close(s.done)
+	})
+
+	err = subscribe(handshakeCtx, dest)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	pwd, _ := destURL.User.Password()
+	res, err := dest.WriteAwaitRes(handshakeCtx, sm.NewMiningAuthorize(2, destURL.User.Username(), pwd))
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	if authRes := res.(*sm.MiningResult); authRes.IsError() {
+		s.Close()
+		return nil, fmt.Errorf("authorize error: %s", authRes.GetError())
+	}
+
+	return s, nil
+}
+
+// Done is closed when the session ends
+func (s *DestSession) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the reading error the session ended with
+func (s *DestSession) Err() error {
+	<-s.done
+	return s.err
+}
+
+func (s *DestSession) Close() {
+	_ = s.dest.Close()
+	<-s.done
+}
+
+func subscribe(ctx context.Context, dest *ConnDest) error {
+	res, err := dest.WriteAwaitRes(ctx, sm.NewMiningSubscribe(1, "stratum-proxy", "1.0.0"))
+	if err != nil {
+		return err
+	}
+	subRes, err := sm.ToMiningSubscribeResult(res.(*sm.MiningResult))
+	if err != nil {
+		return err
+	}
+	if subRes.IsError() {
+		return fmt.Errorf("subscribe error: %s", subRes.GetError())
+	}
+	return nil
+}
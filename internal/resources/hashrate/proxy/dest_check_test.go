@@ -0,0 +1,114 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	sm "github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/proxy/stratumv1_message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDest(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		line, err := bufio.NewReader(conn).ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		msg, err := sm.ParseStratumMessage(line)
+		if err != nil {
+			return
+		}
+		if sub, ok := msg.(*sm.MiningSubscribe); ok {
+			_, _ = conn.Write(append(sm.NewMiningSubscribeResult(sub.GetID(), "0000", 8).Serialize(), '\n'))
+		}
+		_, _ = bufio.NewReader(conn).ReadBytes('\n')
+	}()
+
+	destURL := &url.URL{Scheme: "stratum+tcp", User: url.UserPassword("worker", ""), Host: listener.Addr().String()}
+	latency, err := CheckDest(context.Background(), destURL, lib.NewTestLogger())
+	require.NoError(t, err)
+	require.Positive(t, latency)
+}
+
+func TestCheckDestUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	destURL := &url.URL{Scheme: "stratum+tcp", User: url.UserPassword("worker", ""), Host: addr}
+	_, err = CheckDest(context.Background(), destURL, lib.NewTestLogger())
+	require.Error(t, err)
+}
+
+func TestOpenDestSession(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	authorized := make(chan string, 1)
+	closeConn := make(chan struct{})
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				return
+			}
+			msg, err := sm.ParseStratumMessage(line)
+			if err != nil {
+				return
+			}
+			switch m := msg.(type) {
+			case *sm.MiningSubscribe:
+				_, _ = conn.Write(append(sm.NewMiningSubscribeResult(m.GetID(), "0000", 8).Serialize(), '\n'))
+			case *sm.MiningAuthorize:
+				_, _ = conn.Write(append(sm.NewMiningResultSuccess(m.GetID()).Serialize(), '\n'))
+				authorized <- m.GetUserName()
+				<-closeConn
+				return
+			}
+		}
+	}()
+
+	destURL := &url.URL{Scheme: "stratum+tcp", User: url.UserPassword("0x01", ""), Host: listener.Addr().String()}
+	session, err := OpenDestSession(context.Background(), destURL, time.Minute, lib.NewTestLogger())
+	require.NoError(t, err)
+	require.Equal(t, "0x01", <-authorized)
+
+	// the session is kept open until the destination disconnects
+	select {
+	case <-session.Done():
+		t.Fatal("session closed")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(closeConn)
+
+	select {
+	case <-session.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("session is not closed after disconnect")
+	}
+	session.Close()
+}
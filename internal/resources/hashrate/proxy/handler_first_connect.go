@@ -112,17 +112,32 @@ func (p *HandlerFirstConnect) handleDest(ctx context.Context, msg i.MiningMessag
 	return nil, nil
 }
 
-func (p *HandlerFirstConnect) getPoolDest(contractID string) (*url.URL, error) {
+// multiDestContract is implemented by the buyer contracts that can split the incoming hashrate among several pools
+type multiDestContract interface {
+	PoolDests() ([]*url.URL, func(n int, err error))
+}
+
+// getPoolDests returns the destinations of the contract in the order they should be tried
+// and the callback to report the result of the connection to the n-th of them, if the contract tracks it
+func (p *HandlerFirstConnect) getPoolDests(contractID string) ([]*url.URL, func(n int, err error), error) {
 	contract, isExist := p.proxy.getContractFromStoreFn(contractID)
 	if !isExist {
-		return nil, lib.WrapError(ErrUnknownContract, fmt.Errorf("contract id: %s", contractID))
+		return nil, nil, lib.WrapError(ErrUnknownContract, fmt.Errorf("contract id: %s", contractID))
 	}
 	if contract.Validator() == contractID {
-		return nil, lib.WrapError(ErrNotAValidator, fmt.Errorf("contract id: %s", contract))
+		return nil, nil, lib.WrapError(ErrNotAValidator, fmt.Errorf("contract id: %s", contract))
 	}
 	p.proxy.contractID = &contractID
-	poolDestStr := contract.PoolDest()
-	return url.Parse(poolDestStr)
+
+	if multiDest, ok := contract.(multiDestContract); ok {
+		dests, onResult := multiDest.PoolDests()
+		return dests, onResult, nil
+	}
+	poolDest, err := url.Parse(contract.PoolDest())
+	if err != nil {
+		return nil, nil, err
+	}
+	return []*url.URL{poolDest}, nil, nil
 }
 
 // The following handlers are responsible for managing the initial connection of the miner to the proxy and destination.
@@ -180,24 +195,37 @@ func (p *HandlerFirstConnect) onMiningConfigure(ctx context.Context, msgTyped *m
 
 func (p *HandlerFirstConnect) maybeCreateDestConn(ctx context.Context, msgTyped *sm.MiningConfigure) error {
 	if p.proxy.dest == nil {
-		var destURL *url.URL
+		var (
+			destURLs []*url.URL
+			onResult func(n int, err error)
+			err      error
+		)
 		var contractID string
 		if msgTyped != nil {
 			contractID = msgTyped.GetLMRContractAddress()
 		}
 		if contractID != "" {
-			poolDestStr, err := p.getPoolDest(contractID)
+			destURLs, onResult, err = p.getPoolDests(contractID)
 			if err != nil {
 				return err
 			}
-			destURL = poolDestStr
 		}
 
-		if destURL == nil {
-			destURL = p.proxy.destURL.Load()
+		if len(destURLs) == 0 {
+			destURLs = []*url.URL{p.proxy.destURL.Load()}
 		}
 
-		destConn, err := p.proxy.destFactory(ctx, destURL, p.proxy.GetSourceWorkerName(), p.proxy.source.conn.conn.RemoteAddr().String()) // set dest from contract store
+		// the destinations are tried in order, so the contract can fail over to the next pool
+		var destConn *ConnDest
+		for n, destURL := range destURLs {
+			destConn, err = p.proxy.destFactory(ctx, destURL, p.proxy.GetSourceWorkerName(), p.proxy.source.conn.conn.RemoteAddr().String()) // set dest from contract store
+			if onResult != nil {
+				onResult(n, err)
+			}
+			if err == nil {
+				break
+			}
+		}
 		if err != nil {
 			return err
 		}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	i "github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/proxy/interfaces"
 	m "github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/proxy/stratumv1_message"
@@ -75,14 +76,15 @@ func (p *HandlerMining) onMiningSubmit(ctx context.Context, msgTyped *m.MiningSu
 	var res *m.MiningResult
 
 	// searching for a job in main destination
-	diff, err := dest.ValidateAndAddShare(msgTyped)
+	shareRes, err := dest.ValidateShare(msgTyped)
+	diff := shareRes.Diff
 	weAccepted := err == nil
 
 	// if share has old destination the error is job not found
 	// or low difficulty (in case of job ID collision)
 	if errors.Is(err, validator.ErrJobNotFound) || errors.Is(err, validator.ErrLowDifficulty) {
 		// searching for a job in previous destination
-		d, _, err := p.proxy.GetDestByJobIDAndValidate(msgTyped)
+		d, r, err := p.proxy.GetDestByJobIDAndValidate(msgTyped)
 		if err != nil {
 			weAccepted = false
 			p.proxy.logWarnf("job %s not found in previous destinations", msgTyped.GetJobId())
@@ -90,13 +92,20 @@ func (p *HandlerMining) onMiningSubmit(ctx context.Context, msgTyped *m.MiningSu
 			weAccepted = true
 			p.proxy.logWarnf("job %s found in different dest %s", msgTyped.GetJobId(), d.ID())
 			dest = d
+			shareRes = r
 		}
 
 	}
 
+	if shareRes.IsBlockCandidate {
+		p.proxy.source.GetStats().IncBlockCandidates()
+		p.proxy.logDebugf("block candidate found, hash %s, share diff %.0f, network diff %.0f, jobID %s, we accepted %t",
+			shareRes.BlockHash, shareRes.Diff, shareRes.NetworkDiff, msgTyped.GetJobId(), weAccepted)
+	}
+
 	if !weAccepted {
 		count := p.consequentInvalidShareCount.Inc()
-		if count > MAX_CONSEQUENT_INVALID_SHARES {
+		if count > MAX_CONSEQUENT_INVALID_SHARES && !shareRes.IsBlockCandidate {
 			p.proxy.logWarnf("too many consequent invalid shares (> %d), canceling run", MAX_CONSEQUENT_INVALID_SHARES)
 			p.proxy.cancelRun()
 			return nil, nil
@@ -117,10 +126,12 @@ func (p *HandlerMining) onMiningSubmit(ctx context.Context, msgTyped *m.MiningSu
 		p.consequentInvalidShareCount.Store(0)
 		p.proxy.source.GetStats().IncWeAcceptedShares()
 
+		work := dest.GetWork()
+
 		// miner hashrate
-		p.proxy.hashrate.OnSubmit(dest.GetDiff())
+		p.proxy.hashrate.OnSubmit(work)
 		// workername hashrate
-		p.proxy.globalHashrate.OnSubmit(p.proxy.source.GetUserName(), dest.GetDiff())
+		p.proxy.globalHashrate.OnSubmit(p.proxy.source.GetUserName(), work)
 		if p.proxy.hashrate.GetTotalShares() > p.proxy.vettingShares {
 			select {
 			case <-p.proxy.vettingDoneCh:
@@ -132,7 +143,7 @@ func (p *HandlerMining) onMiningSubmit(ctx context.Context, msgTyped *m.MiningSu
 		// contract hashrate
 		p.proxy.onSubmitMutex.RLock()
 		if p.proxy.onSubmit != nil {
-			p.proxy.onSubmit(dest.GetDiff())
+			p.proxy.onSubmit(work)
 		}
 		p.proxy.onSubmitMutex.RUnlock()
 
@@ -146,6 +157,16 @@ func (p *HandlerMining) onMiningSubmit(ctx context.Context, msgTyped *m.MiningSu
 	go func(res1 *m.MiningResult) {
 		defer p.proxy.unansweredMsg.Done()
 
+		// block candidate is submitted to the pool before responding to the miner
+		if shareRes.IsBlockCandidate {
+			poolRes, err := p.submitToPool(ctx, dest, msgTyped, weAccepted, diff)
+			p.emitBlockCandidate(dest, msgTyped, shareRes, weAccepted, poolRes, err)
+			if err != nil {
+				p.proxy.cancelRun()
+				return
+			}
+		}
+
 		err = p.proxy.source.Write(ctx, res1)
 		if err != nil {
 			p.proxy.logErrorf("cannot write response (%d) to miner: %s", res1.ID, err)
@@ -153,33 +174,78 @@ func (p *HandlerMining) onMiningSubmit(ctx context.Context, msgTyped *m.MiningSu
 			return
 		}
 
-		// send and await submit response from pool
-		msgTyped.SetUserName(dest.GetUserName())
-		res, err := dest.WriteAwaitRes(ctx, msgTyped)
+		if shareRes.IsBlockCandidate {
+			return
+		}
+
+		_, err = p.submitToPool(ctx, dest, msgTyped, weAccepted, diff)
 		if err != nil {
-			p.proxy.logErrorf("cannot write response to pool: %s", err)
 			p.proxy.cancelRun()
 			return
 		}
+	}(res)
 
-		if res.(*m.MiningResult).IsError() {
-			if weAccepted {
-				p.proxy.source.GetStats().IncWeAcceptedTheyRejected()
-				dest.GetStats().IncWeAcceptedTheyAccepted()
-				p.proxy.logWarnf("we accepted share, they rejected with err %s", res.(*m.MiningResult).GetError())
-			} else {
-				p.proxy.logWarnf("we rejected share, and they rejected with err %s", res.(*m.MiningResult).GetError())
-			}
+	return nil, nil
+}
+
+// submitToPool sends the share to the destination, awaits for the response and updates the stats
+func (p *HandlerMining) submitToPool(ctx context.Context, dest *ConnDest, msgTyped *m.MiningSubmit, weAccepted bool, diff float64) (*m.MiningResult, error) {
+	// send and await submit response from pool
+	msgTyped.SetUserName(dest.GetUserName())
+	res, err := dest.WriteAwaitRes(ctx, msgTyped)
+	if err != nil {
+		p.proxy.logErrorf("cannot write response to pool: %s", err)
+		return nil, err
+	}
+	poolRes := res.(*m.MiningResult)
+
+	if poolRes.IsError() {
+		if weAccepted {
+			p.proxy.source.GetStats().IncWeAcceptedTheyRejected()
+			dest.GetStats().IncWeAcceptedTheyAccepted()
+			p.proxy.logWarnf("we accepted share, they rejected with err %s", poolRes.GetError())
 		} else {
-			if weAccepted {
-				dest.GetStats().IncWeAcceptedTheyAccepted()
-			} else {
-				dest.GetStats().IncWeRejectedTheyAccepted()
-				p.proxy.source.GetStats().IncWeRejectedTheyAccepted()
-				p.proxy.logWarnf("we rejected share, but dest accepted, diff: %.f", diff)
-			}
+			p.proxy.logWarnf("we rejected share, and they rejected with err %s", poolRes.GetError())
 		}
-	}(res)
+	} else {
+		if weAccepted {
+			dest.GetStats().IncWeAcceptedTheyAccepted()
+		} else {
+			dest.GetStats().IncWeRejectedTheyAccepted()
+			p.proxy.source.GetStats().IncWeRejectedTheyAccepted()
+			p.proxy.logWarnf("we rejected share, but dest accepted, diff: %.f", diff)
+		}
+	}
 
-	return nil, nil
+	return poolRes, nil
+}
+
+func (p *HandlerMining) emitBlockCandidate(dest *ConnDest, msgTyped *m.MiningSubmit, shareRes validator.ShareResult, weAccepted bool, poolRes *m.MiningResult, poolErr error) {
+	candidate := BlockCandidate{
+		Timestamp:    time.Now(),
+		BlockHash:    shareRes.BlockHash,
+		ShareDiff:    shareRes.Diff,
+		NetworkDiff:  shareRes.NetworkDiff,
+		JobID:        msgTyped.GetJobId(),
+		SourceAddr:   p.proxy.source.GetID(),
+		SourceWorker: p.proxy.source.GetUserName(),
+		DestURL:      dest.GetRedactedURL(),
+		WeAccepted:   weAccepted,
+	}
+	if contractID := p.proxy.GetIncomingContractID(); contractID != nil {
+		candidate.IncomingContractID = *contractID
+	}
+	if poolErr != nil {
+		candidate.PoolError = poolErr.Error()
+	} else if poolRes.IsError() {
+		candidate.PoolError = poolRes.GetError()
+	} else {
+		candidate.PoolAccepted = true
+	}
+
+	p.proxy.logDebugf("block candidate %s submitted to %s, pool accepted %t %s", candidate.BlockHash, candidate.DestURL, candidate.PoolAccepted, candidate.PoolError)
+
+	if p.proxy.onBlockCandidate != nil {
+		p.proxy.onBlockCandidate(candidate)
+	}
 }
@@ -63,9 +63,10 @@ type Proxy struct {
 	destFactory            DestConnFactory       // factory to create new destination connections
 	log                    gi.ILogger
 	getContractFromStoreFn GetContractFromStoreFn
+	onBlockCandidate       BlockCandidateHandler // optional callback for the shares that meet the network target
 }
 
-func NewProxy(ID string, source *ConnSource, destFactory DestConnFactory, hashrateFactory HashrateFactory, globalHashrate GlobalHashrateCounter, destURL *url.URL, notPropagateWorkerName bool, vettingShares int, maxCachedDests int, log gi.ILogger, getContractFromStoreFn GetContractFromStoreFn) *Proxy {
+func NewProxy(ID string, source *ConnSource, destFactory DestConnFactory, hashrateFactory HashrateFactory, globalHashrate GlobalHashrateCounter, destURL *url.URL, notPropagateWorkerName bool, vettingShares int, maxCachedDests int, log gi.ILogger, getContractFromStoreFn GetContractFromStoreFn, onBlockCandidate BlockCandidateHandler) *Proxy {
 	proxy := &Proxy{
 		ID:                     ID,
 		destURL:                atomic.NewPointer(destURL),
@@ -83,6 +84,7 @@ func NewProxy(ID string, source *ConnSource, destFactory DestConnFactory, hashra
 		globalHashrate:         globalHashrate,
 		onSubmit:               nil,
 		getContractFromStoreFn: getContractFromStoreFn,
+		onBlockCandidate:       onBlockCandidate,
 	}
 
 	return proxy
@@ -353,16 +355,16 @@ func (p *Proxy) GetDestByJobID(jobID string) *ConnDest {
 	return dest
 }
 
-func (p *Proxy) GetDestByJobIDAndValidate(msg *stratumv1_message.MiningSubmit) (*ConnDest, float64, error) {
+func (p *Proxy) GetDestByJobIDAndValidate(msg *stratumv1_message.MiningSubmit) (*ConnDest, validator.ShareResult, error) {
 	var dest *ConnDest
-	var diff float64
+	var res validator.ShareResult
 
 	p.destMap.Range(func(d *ConnDest) bool {
 		if d.HasJob(msg.GetJobId()) {
-			difficulty, err := d.ValidateAndAddShare(msg)
+			r, err := d.ValidateShare(msg)
 			if err == nil {
 				dest = d
-				diff = difficulty
+				res = r
 				return false
 			}
 		}
@@ -370,10 +372,10 @@ func (p *Proxy) GetDestByJobIDAndValidate(msg *stratumv1_message.MiningSubmit) (
 	})
 
 	if dest != nil {
-		return dest, diff, nil
+		return dest, res, nil
 	}
 
-	return nil, 0, validator.ErrJobNotFound
+	return nil, validator.ShareResult{}, validator.ErrJobNotFound
 }
 
 // Getters
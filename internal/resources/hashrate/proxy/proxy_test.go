@@ -27,7 +27,7 @@ func RunTestProxy() (p *Proxy, s *StratumConnection, d *StratumConnection, cance
 	log.Warnf("started server")
 
 	sourceConn := NewSourceConn(CreateConnection(sourceClient, "", timeout, timeout, log), log)
-	valid := validator.NewValidator(time.Minute)
+	valid := validator.NewValidator(hashrate.AlgorithmSHA256, time.Minute)
 	destConn := NewDestConn(CreateConnection(destClient, destURL.String(), timeout, timeout, log), valid, destURL, log)
 
 	destConnFactory := func(ctx context.Context, url *url.URL, srcWorker string, srcAddr string) (*ConnDest, error) {
@@ -37,11 +37,11 @@ func RunTestProxy() (p *Proxy, s *StratumConnection, d *StratumConnection, cance
 		return hashrate.NewHashrate(map[string]hashrate.Counter{})
 	}
 
-	globalHashrate := hashrate.NewGlobalHashrate(hashrateFactory)
+	globalHashrate := hashrate.NewGlobalHashrate(hashrateFactory, 10*time.Minute, 24*time.Hour)
 
 	proxy := NewProxy("test", sourceConn, destConnFactory, hashrateFactory, globalHashrate, destURL, true, 1, 5, log, func(id string) (resources.Contract, bool) {
 		return nil, false
-	})
+	}, nil)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	runErrorCh := make(chan error)
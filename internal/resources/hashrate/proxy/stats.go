@@ -33,6 +33,7 @@ type SourceStats struct {
 	WeRejectedShares       atomic.Uint64 // shares that failed during validation (incl RejectedUsAcceptedThem)
 	WeAcceptedTheyRejected atomic.Uint64 // shares that passed our validator, but rejected by the destination
 	WeRejectedTheyAccepted atomic.Uint64 // shares that failed our validator, but accepted by the destination
+	BlockCandidates        atomic.Uint64 // shares that met the network target
 }
 
 func (s *SourceStats) IncWeAcceptedShares() {
@@ -51,11 +52,16 @@ func (s *SourceStats) IncWeRejectedTheyAccepted() {
 	s.WeRejectedTheyAccepted.Add(1)
 }
 
+func (s *SourceStats) IncBlockCandidates() {
+	s.BlockCandidates.Add(1)
+}
+
 func (s *SourceStats) GetStatsMap() map[string]int {
 	return map[string]int{
 		"we_accepted_shares":        int(s.WeAcceptedShares.Load()),
 		"we_rejected_shares":        int(s.WeRejectedShares.Load()),
 		"we_accepted_they_rejected": int(s.WeAcceptedTheyRejected.Load()),
 		"we_rejected_they_accepted": int(s.WeRejectedTheyAccepted.Load()),
+		"block_candidates":          int(s.BlockCandidates.Load()),
 	}
 }
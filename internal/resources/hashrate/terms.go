@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/Lumerin-protocol/proxy-router/internal/interfaces"
 	"github.com/Lumerin-protocol/proxy-router/internal/lib"
 )
 
@@ -30,12 +31,12 @@ func (p *Terms) PoolDest() *url.URL {
 }
 
 // No-op for unencrypted terms
-func (p *Terms) Decrypt(privateKey string) (*Terms, error) {
+func (p *Terms) Decrypt(signer interfaces.Signer) (*Terms, error) {
 	return p, nil
 }
 
 // No-op for unencrypted terms
-func (p *Terms) DecryptPoolDest(privateKey string) (*Terms, error) {
+func (p *Terms) DecryptPoolDest(signer interfaces.Signer) (*Terms, error) {
 	return p, nil
 }
 
@@ -70,7 +71,7 @@ type EncryptedTerms struct {
 }
 
 // Decrypt decrypts the validator url, if error returns the terms with dest set to nil and error
-func (t *EncryptedTerms) Decrypt(privateKey string) (*Terms, error) {
+func (t *EncryptedTerms) Decrypt(signer interfaces.Signer) (*Terms, error) {
 	var (
 		returnErr error
 	)
@@ -84,7 +85,7 @@ func (t *EncryptedTerms) Decrypt(privateKey string) (*Terms, error) {
 		return terms, nil
 	}
 
-	dest, err := lib.DecryptString(t.ValidatorUrlEncrypted, privateKey)
+	dest, err := lib.DecryptStringWith(t.ValidatorUrlEncrypted, signer.Decrypt)
 	if err != nil {
 		return terms, lib.WrapError(ErrCannotDecryptDest, fmt.Errorf("%s: %s", err, t.ValidatorUrlEncrypted))
 	}
@@ -99,7 +100,7 @@ func (t *EncryptedTerms) Decrypt(privateKey string) (*Terms, error) {
 }
 
 // Decrypt decrypts the destination pool url, if error returns the terms with dest set to nil and error
-func (t *EncryptedTerms) DecryptPoolDest(privateKey string) (*Terms, error) {
+func (t *EncryptedTerms) DecryptPoolDest(signer interfaces.Signer) (*Terms, error) {
 	var (
 		returnErr error
 	)
@@ -113,7 +114,7 @@ func (t *EncryptedTerms) DecryptPoolDest(privateKey string) (*Terms, error) {
 		return terms, nil
 	}
 
-	dest, err := lib.DecryptString(t.DestEncrypted, privateKey)
+	dest, err := lib.DecryptStringWith(t.DestEncrypted, signer.Decrypt)
 	if err != nil {
 		return terms, lib.WrapError(ErrCannotDecryptDest, fmt.Errorf("%s: %s", err, t.DestEncrypted))
 	}
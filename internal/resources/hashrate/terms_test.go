@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/repositories/wallet"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/stretchr/testify/require"
@@ -18,6 +19,8 @@ const (
 	privateKey = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
 )
 
+var signer, _ = wallet.NewKeySigner(privateKey)
+
 func TestEncryptDecrypt(t *testing.T) {
 	msg := "zuck"
 
@@ -45,7 +48,7 @@ func TestDecryptInvalidCypher(t *testing.T) {
 		ValidatorUrlEncrypted: "[object Promise]",
 	}
 
-	decrypted, err := terms.Decrypt(privateKey)
+	decrypted, err := terms.Decrypt(signer)
 	require.ErrorIs(t, err, ErrCannotDecryptDest)
 
 	require.Equal(t, terms.BaseTerms, decrypted.BaseTerms)
@@ -71,7 +74,7 @@ func TestDecryptInvalidDestURL(t *testing.T) {
 		ValidatorUrlEncrypted: encrypted,
 	}
 
-	decrypted, err := terms.Decrypt(privateKey)
+	decrypted, err := terms.Decrypt(signer)
 	require.ErrorIs(t, err, ErrInvalidDestURL)
 
 	require.Equal(t, terms.BaseTerms, decrypted.BaseTerms)
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/Lumerin-protocol/proxy-router/internal/lib"
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/hashrate"
 	sm "github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/proxy/stratumv1_message"
 )
 
@@ -20,20 +21,28 @@ var (
 )
 
 type Validator struct {
+	// config
+	algorithm hashrate.Algorithm
+
 	// state
 	jobs               *lib.BoundStackMap[*MiningJob]
 	versionRollingMask string
 	cleanJobTimeout    time.Duration // duration after which jobs are removed from the cache after calling ScheduleCleanJobs()
 }
 
-func NewValidator(cleanJobTimeout time.Duration) *Validator {
+func NewValidator(algorithm hashrate.Algorithm, cleanJobTimeout time.Duration) *Validator {
 	return &Validator{
+		algorithm:          algorithm,
 		jobs:               lib.NewBoundStackMap[*MiningJob](JOB_CACHE_SIZE),
 		versionRollingMask: "00000000",
 		cleanJobTimeout:    cleanJobTimeout,
 	}
 }
 
+func (v *Validator) GetAlgorithm() hashrate.Algorithm {
+	return v.algorithm
+}
+
 func (v *Validator) SetVersionRollingMask(mask string) {
 	v.versionRollingMask = mask
 }
@@ -60,32 +69,59 @@ func (v *Validator) ScheduleCleanJobs() {
 	})
 }
 
+// ShareResult is the outcome of the share validation
+type ShareResult struct {
+	Diff             float64 // actual difficulty of the share
+	BlockHash        string  // header hash, set only if the share was hashed
+	NetworkDiff      float64 // network difficulty decoded from the job nbits
+	IsBlockCandidate bool    // share meets the network target
+}
+
 func (v *Validator) ValidateAndAddShare(msg *sm.MiningSubmit) (float64, error) {
+	res, err := v.ValidateShare(msg)
+	return res.Diff, err
+}
+
+// ValidateShare validates the share and checks if it meets the network target. The share of the expired job
+// is still hashed, so the block candidate is reported even if the share is rejected by our validator
+func (v *Validator) ValidateShare(msg *sm.MiningSubmit) (ShareResult, error) {
 	var (
 		job *MiningJob
 		ok  bool
 	)
 
 	if job, ok = v.jobs.Get(msg.GetJobId()); !ok {
-		return 0, ErrJobNotFound
+		return ShareResult{}, ErrJobNotFound
 	}
 
 	if !job.expirationTime.IsZero() && job.expirationTime.Before(time.Now()) {
-		return 0, ErrJobNotFound
+		return v.hashShare(job, msg), ErrJobNotFound
 	}
 
 	if job.CheckDuplicateAndAddShare(msg) {
-		return 0, ErrDuplicateShare
+		return ShareResult{}, ErrDuplicateShare
 	}
 
-	diff, ok := ValidateDiff(job.extraNonce1, uint(job.extraNonce2Size), uint64(job.diff), v.versionRollingMask, job.notify, msg)
-	diffFloat := float64(diff)
-	if !ok {
+	res := v.hashShare(job, msg)
+	diff := uint64(res.Diff)
+
+	if diff < uint64(job.diff) {
 		err := lib.WrapError(ErrLowDifficulty, fmt.Errorf("expected %.2f actual %d xn=%s, xnsize=%d, diff=%d, vrmsk=%s", job.diff, diff, job.extraNonce1, uint(job.extraNonce2Size), uint64(job.diff), v.versionRollingMask))
-		return diffFloat, err
+		return res, err
 	}
 
-	return diffFloat, nil
+	return res, nil
+}
+
+func (v *Validator) hashShare(job *MiningJob, msg *sm.MiningSubmit) ShareResult {
+	header, nbits := BuildHeader(job.extraNonce1, v.versionRollingMask, job.notify, msg)
+	powHash := PowHash(v.algorithm, header)
+	return ShareResult{
+		Diff:             float64(HashToDiff(powHash, v.algorithm)),
+		BlockHash:        BlockHashString(sha256d(header)),
+		NetworkDiff:      NetworkDiff(nbits, v.algorithm),
+		IsBlockCandidate: IsBlockCandidate(powHash, nbits),
+	}
 }
 
 func (v *Validator) GetLatestJob() (*MiningJob, bool) {
@@ -6,15 +6,63 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
+	"math"
 	"math/big"
 
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/hashrate"
 	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/proxy/stratumv1_message"
+	"golang.org/x/crypto/scrypt"
 )
 
 /* The meat... */
 
+// diff1Target is the target of the SHA-256 share with difficulty 1
+var diff1Target = new(big.Int).Lsh(big.NewInt(0xffff), 208)
+
+// scryptDiff1Target is the target of the scrypt share with difficulty 1, as used by the litecoin pools
+var scryptDiff1Target = new(big.Int).Lsh(big.NewInt(0xffff), 224)
+
+// ValidateDiff validates SHA-256 share
 func ValidateDiff(en1 string, en2_size uint, job_diff uint64, version_mask string,
 	job *stratumv1_message.MiningNotify, submit *stratumv1_message.MiningSubmit) (uint64, bool) {
+	header, _ := BuildHeader(en1, version_mask, job, submit)
+	diff := HashToDiff(PowHash(hashrate.AlgorithmSHA256, header), hashrate.AlgorithmSHA256)
+	return diff, diff >= job_diff
+}
+
+// HashShare builds the block header from the job and the submitted share and returns its double sha256 hash
+// along with the compact network target (nbits) of the job
+func HashShare(en1 string, version_mask string, job *stratumv1_message.MiningNotify, submit *stratumv1_message.MiningSubmit) ([32]byte, string) {
+	header, nbits := BuildHeader(en1, version_mask, job, submit)
+	return sha256d(header), nbits
+}
+
+// PowHash returns the proof of work hash of the block header, for SHA-256 it is the same as the block hash
+func PowHash(algo hashrate.Algorithm, header []byte) [32]byte {
+	switch algo {
+	case hashrate.AlgorithmScrypt:
+		var hash [32]byte
+		key, _ := scrypt.Key(header, header, 1024, 1, 1, 32)
+		copy(hash[:], key)
+		return hash
+	default:
+		return sha256d(header)
+	}
+}
+
+// Diff1Target returns the target of the share with difficulty 1 for the algorithm
+func Diff1Target(algo hashrate.Algorithm) *big.Int {
+	switch algo {
+	case hashrate.AlgorithmScrypt:
+		return scryptDiff1Target
+	default:
+		return diff1Target
+	}
+}
+
+// BuildHeader builds the 80-byte block header from the job and the submitted share
+// and returns it along with the compact network target (nbits) of the job
+func BuildHeader(en1 string, version_mask string, job *stratumv1_message.MiningNotify, submit *stratumv1_message.MiningSubmit) ([]byte, string) {
 	var prev_hash string
 	var gen1 string
 	var gen2 string
@@ -62,17 +110,68 @@ func ValidateDiff(en1 string, en2_size uint, job_diff uint64, version_mask strin
 	header.Write(decode_swap(ntime))
 	header.Write(decode_swap(nbits))
 	header.Write(decode_swap(nonce))
-	hash := sha256d(header.Bytes())
-
-	/* check result */
-	h := new(big.Int)
-	b := new(big.Int)
-	t := new(big.Int)
-	h.SetBytes(reverse(hash[:]))
-	b.SetUint64(0xffff)
-	b.Lsh(b, 208)
-	t.Div(b, h)
-	return t.Uint64(), t.Uint64() >= job_diff
+	return header.Bytes(), nbits
+}
+
+// HashToDiff returns the difficulty of the share with the given proof of work hash
+func HashToDiff(hash [32]byte, algo hashrate.Algorithm) uint64 {
+	h := hashToBig(hash)
+	if h.Sign() == 0 {
+		return math.MaxUint64
+	}
+	return new(big.Int).Div(Diff1Target(algo), h).Uint64()
+}
+
+// IsBlockCandidate returns true if the proof of work hash meets the network target encoded in nbits
+func IsBlockCandidate(hash [32]byte, nbits string) bool {
+	target := NbitsToTarget(nbits)
+	if target.Sign() == 0 {
+		return false
+	}
+	return hashToBig(hash).Cmp(target) <= 0
+}
+
+// NbitsToTarget decodes the compact representation of the network target, nbits is a big-endian hex string
+// as sent in mining.notify. Returns zero for invalid or negative values
+func NbitsToTarget(nbits string) *big.Int {
+	b := decode(nbits)
+	if len(b) != 4 {
+		return new(big.Int)
+	}
+	compact := binary.BigEndian.Uint32(b)
+	exponent := uint(compact >> 24)
+	mantissa := int64(compact & 0x007fffff)
+	if compact&0x00800000 != 0 {
+		// negative target is invalid for proof of work
+		return new(big.Int)
+	}
+
+	target := big.NewInt(mantissa)
+	if exponent <= 3 {
+		return target.Rsh(target, 8*(3-exponent))
+	}
+	return target.Lsh(target, 8*(exponent-3))
+}
+
+// NetworkDiff returns the network difficulty of the algorithm for the given nbits
+func NetworkDiff(nbits string, algo hashrate.Algorithm) float64 {
+	target := NbitsToTarget(nbits)
+	if target.Sign() == 0 {
+		return 0
+	}
+	diff, _ := new(big.Rat).SetFrac(Diff1Target(algo), target).Float64()
+	return diff
+}
+
+// BlockHashString returns the hash in the conventional block explorer notation
+func BlockHashString(hash [32]byte) string {
+	h := hash
+	return hex.EncodeToString(reverse(h[:]))
+}
+
+func hashToBig(hash [32]byte) *big.Int {
+	h := hash
+	return new(big.Int).SetBytes(reverse(h[:]))
 }
 
 func reverse(bytes []byte) []byte {
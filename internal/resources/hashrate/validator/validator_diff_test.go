@@ -3,8 +3,10 @@ package validator
 import (
 	"crypto/rand"
 	"crypto/sha256"
+	"math/big"
 	"testing"
 
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/hashrate"
 	"github.com/stretchr/testify/require"
 )
 
@@ -16,6 +18,52 @@ func TestValidatorDiff(t *testing.T) {
 	require.Truef(t, ok, "Result diff (%d) doesn't meet difficulty target (%.2f)", diff, msg.diff)
 }
 
+func TestNetworkTarget(t *testing.T) {
+	require.Equal(t, diff1Target, NbitsToTarget("1d00ffff"))
+	require.Equal(t, 1.0, NetworkDiff("1d00ffff", hashrate.AlgorithmSHA256))
+	require.InDelta(t, 16307.42, NetworkDiff("1b0404cb", hashrate.AlgorithmSHA256), 0.01)
+	require.Equal(t, 0, NbitsToTarget("1d80ffff").Sign(), "negative target")
+	require.Equal(t, 0, NbitsToTarget("xyz").Sign(), "malformed nbits")
+}
+
+func TestNetworkTargetScrypt(t *testing.T) {
+	// litecoin genesis block nbits, the getdifficulty of 0.000244 multiplied by 2^16 in the pool units
+	require.Equal(t, 16.0, NetworkDiff("1e0ffff0", hashrate.AlgorithmScrypt))
+	require.Equal(t, 1.0, NetworkDiff("1f00ffff", hashrate.AlgorithmScrypt))
+}
+
+func TestPowHashScrypt(t *testing.T) {
+	msg := GetTestMsg()
+	header, _ := BuildHeader(msg.xnonce, msg.vmask, msg.notify, msg.submit1)
+
+	hash1 := PowHash(hashrate.AlgorithmScrypt, header)
+	hash2 := PowHash(hashrate.AlgorithmScrypt, header)
+	require.Equal(t, hash1, hash2)
+	require.NotEqual(t, hash1, PowHash(hashrate.AlgorithmSHA256, header))
+	require.Equal(t, sha256d(header), PowHash(hashrate.AlgorithmSHA256, header))
+
+	// hash equal to the diff1 target has difficulty 1
+	var target [32]byte
+	copy(target[:], reverse(Diff1Target(hashrate.AlgorithmScrypt).FillBytes(make([]byte, 32))))
+	require.Equal(t, uint64(1), HashToDiff(target, hashrate.AlgorithmScrypt))
+	require.Zero(t, big.NewInt(0xffff).Cmp(new(big.Int).Rsh(Diff1Target(hashrate.AlgorithmScrypt), 224)))
+}
+
+func TestIsBlockCandidate(t *testing.T) {
+	var hash [32]byte // little-endian, the most significant bytes are at the end
+	hash[0] = 0x01
+	require.True(t, IsBlockCandidate(hash, "1b0404cb"))
+
+	hash[27] = 0xff
+	require.False(t, IsBlockCandidate(hash, "1b0404cb"))
+	require.True(t, IsBlockCandidate(hash, "1d00ffff"))
+
+	msg := GetTestMsg()
+	shareHash, nbits := HashShare(msg.xnonce, msg.vmask, msg.notify, msg.submit1)
+	require.False(t, IsBlockCandidate(shareHash, nbits))
+	require.Equal(t, BlockHashString(shareHash)[:8], "00000000")
+}
+
 func TestValidatorDiffInvalidMsg(t *testing.T) {}
 
 func TestValidatorDiffMalformedMsg(t *testing.T) {}
@@ -4,13 +4,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Lumerin-protocol/proxy-router/internal/resources/hashrate/hashrate"
 	"github.com/stretchr/testify/require"
 )
 
 func TestValidatorValidateUniqueShare(t *testing.T) {
 	msg := GetTestMsg()
 
-	validator := NewValidator(time.Minute)
+	validator := NewValidator(hashrate.AlgorithmSHA256, time.Minute)
 	validator.SetVersionRollingMask(msg.vmask)
 	validator.AddNewJob(msg.notify, msg.diff, msg.xnonce, msg.xnonce2size)
 
@@ -24,7 +25,7 @@ func TestValidatorValidateUniqueShare(t *testing.T) {
 func TestValidatorValidateDuplicateShare(t *testing.T) {
 	msg := GetTestMsg()
 
-	validator := NewValidator(time.Minute)
+	validator := NewValidator(hashrate.AlgorithmSHA256, time.Minute)
 	validator.SetVersionRollingMask(msg.vmask)
 	validator.AddNewJob(msg.notify, msg.diff, msg.xnonce, msg.xnonce2size)
 
@@ -39,7 +40,7 @@ func TestValidatorExpiredJobs(t *testing.T) {
 	msg := GetTestMsg()
 	timeout := 100 * time.Millisecond
 
-	validator := NewValidator(timeout)
+	validator := NewValidator(hashrate.AlgorithmSHA256, timeout)
 	validator.SetVersionRollingMask(msg.vmask)
 	validator.AddNewJob(msg.notify, msg.diff, msg.xnonce, msg.xnonce2size)
 